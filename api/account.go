@@ -8,6 +8,7 @@ import (
 
 	"simple-bank-system/db/pkg"
 	"simple-bank-system/db/services"
+	"simple-bank-system/notification"
 	"simple-bank-system/util"
 
 	"github.com/go-playground/locales/en"
@@ -47,16 +48,11 @@ type addressResponse struct {
 // because it has pasword data
 func newaccountResponse(account *pkg.Account) accountResponse {
 	return accountResponse{
-		AccountNumber: account.AccountNumber,
-		Username:      account.Username,
-		FullName:      account.FullName,
-		DateOfBirth:   account.DateOfBirth.Format(time.DateOnly),
-		Address: addressResponse{
-			Provinces: account.Address.Provinces,
-			City:      account.Address.City,
-			ZIP:       account.Address.ZIP,
-			Street:    account.Address.Street,
-		},
+		AccountNumber:    account.AccountNumber,
+		Username:         account.Username,
+		FullName:         account.FullName,
+		DateOfBirth:      account.DateOfBirth.Format(time.DateOnly),
+		Address:          newAddressResponse(account.Address),
 		Email:            account.Email,
 		PasswordChangeAt: account.PasswordChangeAt,
 		CreatedAt:        account.CreatedAt,
@@ -129,7 +125,14 @@ func (server *Server) createAccount(w http.ResponseWriter, r *http.Request, _ ht
 		return
 	}
 
-	account, err := server.store.CreateAccount(server.ctx, arg)
+	walletArg := services.CreateWalletParams{
+		Name:     "Primary Wallet",
+		Currency: "IDR",
+		Balance:  1000000,
+	}
+
+	// the primary wallet number is the account number
+	account, _, err := server.store.CreateAccountTx(server.ctx, arg, walletArg)
 	//log.Println("--- (done)Create account:", account)
 	if err != nil {
 		//log.Println("--- (err)CreateAccount")
@@ -138,40 +141,25 @@ func (server *Server) createAccount(w http.ResponseWriter, r *http.Request, _ ht
 		json.NewEncoder(w).Encode(err.Error())
 		return
 	}
+	setAuditActor(r, account.ID)
+	auditTarget(r, "account_number", account.AccountNumber)
 	response := newaccountResponse(account)
 
-	walletArg := services.CreateWalletParams{
-		AccountID:    account.ID,
-		Name:         "Primary Wallet",
-		WalletNumber: account.AccountNumber,
-		Currency:     "IDR",
-		Balance:      1000000,
-	}
-
-	_, err = server.store.CreatePrimaryWallet(server.ctx, walletArg)
-	if err != nil {
-		if err == util.ErrUsernameEmpty {
-			http.Error(w, err.Error(), 403)
-			return
-		}
-		http.Error(w, "failed to pass data into database", (http.StatusInternalServerError))
-		json.NewEncoder(w).Encode(err.Error())
-		return
-	}
-
 	w.Header().Add("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(response)
 }
 
 type loginRequest struct {
-	Username string `json:"username" validate:"required"`
-	Password string `json:"password" validate:"required"`
+	Username string `json:"username" validate:"required,max=255"`
+	Password string `json:"password" validate:"required,max=72"`
 }
 
 type loginResponse struct {
-	AccessToken string          `json:"access_token"`
-	Account     accountResponse `json:"account"`
+	AccessToken           string          `json:"access_token"`
+	RefreshToken          string          `json:"refresh_token"`
+	RefreshTokenExpiresAt time.Time       `json:"refresh_token_expires_at"`
+	Account               accountResponse `json:"account"`
 }
 
 func (server *Server) loginAccount(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
@@ -184,23 +172,57 @@ func (server *Server) loginAccount(w http.ResponseWriter, r *http.Request, _ htt
 		return
 	}
 
+	if err = validate.Struct(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	auditTarget(r, "username", req.Username)
+	keys := loginThrottleKeys(req.Username, clientIP(r))
+	if !server.checkLoginLock(w, keys) {
+		return
+	}
+
+	// unknown username and wrong password get the same answer, the password is hashed in both cases
 	account, err := server.store.GetAccount(server.ctx, req.Username)
-	if err != nil {
+	if err != nil && err != util.ErrNotExist {
 		log.Println("--- (2) login, err:", err)
-		http.Error(w, "account with input username doesn't exist", (http.StatusBadRequest))
+		http.Error(w, "Can't get account", http.StatusInternalServerError)
 		json.NewEncoder(w).Encode(err.Error())
 		return
 	}
+	hashedPassword := dummyPasswordHash
+	if account != nil {
+		setAuditActor(r, account.ID)
+		hashedPassword = account.HashedPassword
+	}
+	if err = util.VerifyPassword(req.Password, hashedPassword); err != nil || account == nil {
+		server.loginFailed(w, r, keys, account)
+		return
+	}
 
-	err = util.VerifyPassword(req.Password, account.HashedPassword)
-	if err != nil {
-		log.Println("--- (3) login, err:", err)
-		http.Error(w, "Your password is wrong", http.StatusBadRequest)
+	// accounts with 2FA get a challenge, the tokens are created by 'loginTwoFactor',
+	// the failures are only forgotten by 'finishLogin' so the password can't be used to reset the count of wrong codes
+	totp, err := server.store.GetAccountTOTP(server.ctx, account.ID)
+	if err != nil && err != util.ErrNotExist {
+		log.Println("--- (4) login, err:", err)
+		http.Error(w, "Can't get 2FA", http.StatusInternalServerError)
 		json.NewEncoder(w).Encode(err.Error())
 		return
 	}
+	if totp != nil && totp.EnabledAt.Valid {
+		server.writeLoginChallenge(w, r, account.ID)
+		return
+	}
+
+	server.finishLogin(w, r, account)
+}
 
-	token, err := server.tokenMaker.CreateToken(account.ID, server.duration)
+// finishLogin create the access token and the session of the account whose password (and 2FA) was checked.
+func (server *Server) finishLogin(w http.ResponseWriter, r *http.Request, account *pkg.Account) {
+	server.clearLoginFailures(loginThrottleKeys(account.Username, clientIP(r)))
+
+	token, err := server.createAccessToken(account.ID, account.Role)
 	if err != nil {
 		log.Println("--- (4) login, err:", err)
 		http.Error(w, "Failed to create encryted token", http.StatusInternalServerError)
@@ -208,9 +230,34 @@ func (server *Server) loginAccount(w http.ResponseWriter, r *http.Request, _ htt
 		return
 	}
 
+	refreshToken, session, err := server.createSession(r, account.ID)
+	if err != nil {
+		log.Println("--- (5) login, err:", err)
+		http.Error(w, "Failed to create session", http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(err.Error())
+		return
+	}
+	auditTarget(r, "session_id", session.ID)
+
+	err = server.notifier.Notify(r.Context(), notification.Event{
+		AccountID: account.ID,
+		Type:      notification.EventLogin,
+		Message:   "New login to your account",
+		Data: map[string]interface{}{
+			"ip":         r.RemoteAddr,
+			"user_agent": r.UserAgent(),
+		},
+		CreatedAt: time.Now(),
+	})
+	if err != nil {
+		log.Println("--- (err) notify login:", err)
+	}
+
 	response := loginResponse{
-		AccessToken: token,
-		Account:     newaccountResponse(account),
+		AccessToken:           token,
+		RefreshToken:          refreshToken,
+		RefreshTokenExpiresAt: session.ExpiresAt,
+		Account:               newaccountResponse(account),
 	}
 	w.Header().Add("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
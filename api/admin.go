@@ -0,0 +1,391 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"simple-bank-system/authz"
+	"simple-bank-system/db/pkg"
+	"simple-bank-system/db/services"
+	"simple-bank-system/token"
+	"simple-bank-system/util"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+// Admin API, the staff roles look up any account or wallet, freeze wallets and read the ledger.
+// The permissions of the routes are in the 'authz' package.
+
+type adminAccountResponse struct {
+	accountResponse
+	Role    string
+	Wallets []walletResponse
+}
+
+type walletFreezeResponse struct {
+	Reason    string
+	FrozenBy  int64
+	CreatedAt time.Time
+}
+
+type adminWalletResponse struct {
+	walletResponse
+	AccountNumber int64
+	Freeze        *walletFreezeResponse `json:",omitempty"`
+}
+
+type ledgerEntryResponse struct {
+	ID           int64
+	WalletNumber int64
+	Amount       int64
+	TransferID   *int64 `json:",omitempty"`
+	CreatedAt    time.Time
+}
+
+type setRoleRequest struct {
+	Role string `json:"role" validate:"required"`
+}
+
+type freezeWalletRequest struct {
+	Reason string `json:"reason" validate:"required,max=500"`
+}
+
+// positive amounts credit the wallet, negative ones debit it
+type adjustBalanceRequest struct {
+	Amount int64  `json:"amount" validate:"required"`
+	Reason string `json:"reason" validate:"required,max=500"`
+}
+
+// accountByParam read the account of the 'number' url parameter.
+func (server *Server) accountByParam(w http.ResponseWriter, ps httprouter.Params) (*pkg.Account, bool) {
+	accountNumber, err := strconv.ParseInt(ps.ByName("number"), 10, 64)
+	if err != nil {
+		http.Error(w, "account number must be a number", http.StatusBadRequest)
+		return nil, false
+	}
+
+	account, err := server.store.GetAccountByNumber(server.ctx, accountNumber)
+	if err != nil {
+		if err == util.ErrNotExist {
+			http.Error(w, "account doesn't exist", http.StatusNotFound)
+			return nil, false
+		}
+		http.Error(w, "Can't get account", (http.StatusInternalServerError))
+		json.NewEncoder(w).Encode(err.Error())
+		return nil, false
+	}
+	return account, true
+}
+
+// adminWalletByParam is 'walletByParam' of the admin API, the wallet can belong to any account.
+func (server *Server) adminWalletByParam(w http.ResponseWriter, ps httprouter.Params) (*pkg.Wallet, bool) {
+	walletNumber, err := strconv.ParseInt(ps.ByName("number"), 10, 64)
+	if err != nil {
+		http.Error(w, "wallet number must be a number", http.StatusBadRequest)
+		return nil, false
+	}
+
+	wallet, err := server.store.GetWalletByNumber(server.ctx, walletNumber)
+	if err != nil {
+		if err == util.ErrNotExist {
+			http.Error(w, "wallet doesn't exist", http.StatusNotFound)
+			return nil, false
+		}
+		http.Error(w, "Can't get wallet", (http.StatusInternalServerError))
+		json.NewEncoder(w).Encode(err.Error())
+		return nil, false
+	}
+	return wallet, true
+}
+
+func (server *Server) adminGetAccount(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	account, ok := server.accountByParam(w, ps)
+	if !ok {
+		return
+	}
+
+	wallets, err := server.store.ListAccountWallets(server.ctx, account.ID)
+	if err != nil {
+		http.Error(w, "Can't get account wallets", (http.StatusInternalServerError))
+		json.NewEncoder(w).Encode(err.Error())
+		return
+	}
+
+	response := adminAccountResponse{
+		accountResponse: newaccountResponse(account),
+		Role:            account.Role,
+		Wallets:         []walletResponse{},
+	}
+	for i := range wallets {
+		response.Wallets = append(response.Wallets, newWalletResponse(&wallets[i]))
+	}
+
+	w.Header().Add("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
+
+// adminSetAccountRole change the role of the account, its tokens of the previous role are refused by
+// 'authMiddleware' and the sessions get tokens of the new role when they are refreshed.
+func (server *Server) adminSetAccountRole(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	var req setRoleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := validate.Struct(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if !authz.ValidRole(req.Role) {
+		http.Error(w, "role must be one of customer, support, operator or admin", http.StatusBadRequest)
+		return
+	}
+
+	account, ok := server.accountByParam(w, ps)
+	if !ok {
+		return
+	}
+
+	// an admin can't remove its own rights, another admin has to do it
+	authPayload := r.Context().Value("authPayloadKey").(*token.Payload)
+	if account.ID == authPayload.AccountID {
+		http.Error(w, "you can't change your own role", http.StatusForbidden)
+		return
+	}
+
+	err := server.store.SetAccountRole(server.ctx, account.ID, req.Role)
+	if err != nil {
+		http.Error(w, "Failed to update role", (http.StatusInternalServerError))
+		json.NewEncoder(w).Encode(err.Error())
+		return
+	}
+	auditTarget(r, "account_number", account.AccountNumber)
+	auditChange(r, "role", account.Role, req.Role)
+
+	account.Role = req.Role
+	response := adminAccountResponse{
+		accountResponse: newaccountResponse(account),
+		Role:            account.Role,
+	}
+
+	w.Header().Add("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
+
+func (server *Server) adminGetWallet(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	wallet, ok := server.adminWalletByParam(w, ps)
+	if !ok {
+		return
+	}
+
+	accountNumber, err := server.store.GetAccountByID(server.ctx, wallet.AccountID)
+	if err != nil {
+		http.Error(w, "Can't get wallet owner", (http.StatusInternalServerError))
+		json.NewEncoder(w).Encode(err.Error())
+		return
+	}
+
+	response := adminWalletResponse{
+		walletResponse: newWalletResponse(wallet),
+		AccountNumber:  *accountNumber,
+	}
+	freeze, err := server.store.GetWalletFreeze(server.ctx, wallet.ID)
+	if err != nil && err != util.ErrNotExist {
+		http.Error(w, "Can't get wallet freeze", (http.StatusInternalServerError))
+		json.NewEncoder(w).Encode(err.Error())
+		return
+	}
+	if freeze != nil {
+		response.Freeze = newWalletFreezeResponse(freeze)
+	}
+
+	w.Header().Add("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
+
+func newWalletFreezeResponse(freeze *pkg.WalletFreeze) *walletFreezeResponse {
+	return &walletFreezeResponse{
+		Reason:    freeze.Reason,
+		FrozenBy:  freeze.FrozenBy,
+		CreatedAt: freeze.CreatedAt,
+	}
+}
+
+// adminFreezeWallet block every outgoing transfer and change of the wallet, it can still receive money.
+func (server *Server) adminFreezeWallet(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	var req freezeWalletRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := validate.Struct(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	wallet, ok := server.adminWalletByParam(w, ps)
+	if !ok {
+		return
+	}
+	auditTarget(r, "wallet_number", wallet.WalletNumber)
+
+	authPayload := r.Context().Value("authPayloadKey").(*token.Payload)
+	freeze, err := server.store.FreezeWalletTx(server.ctx, *wallet, req.Reason, authPayload.AccountID)
+	if err != nil {
+		if err == util.ErrDuplicate {
+			http.Error(w, "wallet is already frozen", http.StatusConflict)
+			return
+		}
+		http.Error(w, "Failed to freeze wallet", (http.StatusInternalServerError))
+		json.NewEncoder(w).Encode(err.Error())
+		return
+	}
+	auditChange(r, "frozen", false, true)
+
+	w.Header().Add("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(newWalletFreezeResponse(freeze))
+}
+
+func (server *Server) adminUnfreezeWallet(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	wallet, ok := server.adminWalletByParam(w, ps)
+	if !ok {
+		return
+	}
+	auditTarget(r, "wallet_number", wallet.WalletNumber)
+
+	err := server.store.UnfreezeWalletTx(server.ctx, *wallet)
+	if err != nil {
+		if err == util.ErrDeleteFailed {
+			http.Error(w, "wallet isn't frozen", http.StatusConflict)
+			return
+		}
+		http.Error(w, "Failed to unfreeze wallet", (http.StatusInternalServerError))
+		json.NewEncoder(w).Encode(err.Error())
+		return
+	}
+	auditChange(r, "frozen", true, false)
+
+	w.Header().Add("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode("Wallet unfrozen!")
+}
+
+/*
+ * adminAdjustBalance correct the balance of a customer wallet with a transfer from (or to) the balance
+ * adjustment wallet of the bank, the reason is kept in the audit log with the transfer.
+ */
+func (server *Server) adminAdjustBalance(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	var req adjustBalanceRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := validate.Struct(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	wallet, ok := server.adminWalletByParam(w, ps)
+	if !ok {
+		return
+	}
+	auditTarget(r, "wallet_number", wallet.WalletNumber)
+	if wallet.Type == services.WalletTypeSystem {
+		http.Error(w, "the balance of a bank wallet can't be adjusted", http.StatusBadRequest)
+		return
+	}
+
+	result, err := server.store.AdjustBalanceTx(server.ctx, services.AdjustBalanceParams{Wallet: *wallet, Amount: req.Amount})
+	if err != nil {
+		switch {
+		case services.IsInsufficientFunds(err):
+			http.Error(w, "the balance of the wallet is less than the amount", http.StatusBadRequest)
+		case err == util.ErrWalletLocked || err == util.ErrWalletFrozen:
+			http.Error(w, err.Error(), http.StatusForbidden)
+		default:
+			http.Error(w, "Failed to adjust the balance", (http.StatusInternalServerError))
+			json.NewEncoder(w).Encode(err.Error())
+		}
+		return
+	}
+	auditTarget(r, "transfer_id", result.Transfer.ID)
+	balance := result.ToWallet.Balance
+	if req.Amount < 0 {
+		balance = result.FromWallet.Balance
+	}
+	auditChange(r, "balance", wallet.Balance, balance)
+	auditChange(r, "reason", nil, req.Reason)
+
+	w.Header().Add("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(newTransferTxResponse(result))
+}
+
+// adminListLedger list the entries of every wallet, newest first. Filters: wallet, from, to, before (entry id) and limit.
+func (server *Server) adminListLedger(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	query := r.URL.Query()
+	arg := services.ListLedgerParams{Limit: 50}
+
+	var err error
+	parseID := func(name string, dst *int64) bool {
+		if value := query.Get(name); value != "" {
+			if *dst, err = strconv.ParseInt(value, 10, 64); err != nil {
+				http.Error(w, name+" must be a number", http.StatusBadRequest)
+				return false
+			}
+		}
+		return true
+	}
+	parseTime := func(name string, dst *time.Time) bool {
+		if value := query.Get(name); value != "" {
+			if *dst, err = time.Parse(time.RFC3339, value); err != nil {
+				http.Error(w, name+" must be a RFC 3339 time", http.StatusBadRequest)
+				return false
+			}
+		}
+		return true
+	}
+	if !parseID("wallet", &arg.WalletNumber) || !parseID("before", &arg.BeforeID) || !parseTime("from", &arg.From) || !parseTime("to", &arg.To) {
+		return
+	}
+	if value := query.Get("limit"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 || n > 500 {
+			http.Error(w, "limit must be 1 to 500", http.StatusBadRequest)
+			return
+		}
+		arg.Limit = n
+	}
+
+	entries, err := server.store.ListLedgerEntries(server.ctx, arg)
+	if err != nil {
+		http.Error(w, "Failed to get List", (http.StatusInternalServerError))
+		json.NewEncoder(w).Encode(err.Error())
+		return
+	}
+
+	response := []ledgerEntryResponse{}
+	for _, entry := range entries {
+		item := ledgerEntryResponse{
+			ID:           entry.ID,
+			WalletNumber: entry.WalletNumber,
+			Amount:       entry.Amount,
+			CreatedAt:    entry.CreatedAt,
+		}
+		if entry.TransferID.Valid {
+			transferID := entry.TransferID.Int64
+			item.TransferID = &transferID
+		}
+		response = append(response, item)
+	}
+
+	w.Header().Add("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
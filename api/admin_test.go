@@ -0,0 +1,76 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"simple-bank-system/authz"
+	"simple-bank-system/db/services"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAdminAdjustBalance(t *testing.T) {
+	customer := loginAccount(t)
+	operator := loginWithRole(t, authz.RoleOperator)
+	wallet := createWalletCurrency(t, customer.AccessToken, "IDR")
+	path := fmt.Sprintf("/admin/wallets/%d/adjustments", wallet.WalletNumber)
+
+	// credit
+	status, body := sendRequest(t, "POST", path, operator.AccessToken, adjustBalanceRequest{Amount: 5000, Reason: "failed top up"})
+	require.Equal(t, http.StatusOK, status, string(body))
+	var response transferTxResponse
+	require.NoError(t, json.Unmarshal(body, &response))
+	assert.Equal(t, int64(1010000009), response.Transfer.FromWalletNumber)
+	assert.Equal(t, wallet.WalletNumber, response.Transfer.ToWalletNumber)
+	assert.Equal(t, int64(5000), response.ToWallet.Balance)
+
+	// debit
+	status, body = sendRequest(t, "POST", path, operator.AccessToken, adjustBalanceRequest{Amount: -2000, Reason: "duplicated top up"})
+	require.Equal(t, http.StatusOK, status, string(body))
+	require.NoError(t, json.Unmarshal(body, &response))
+	assert.Equal(t, wallet.WalletNumber, response.Transfer.FromWalletNumber)
+	assert.Equal(t, int64(3000), response.FromWallet.Balance)
+
+	// both corrections are in the ledger of the wallet
+	entries, err := testStore.ListLedgerEntries(testCtx, services.ListLedgerParams{WalletNumber: wallet.WalletNumber, Limit: 10})
+	require.NoError(t, err)
+	assert.Len(t, entries, 2)
+
+	// and in the audit log with the reason
+	logs := listAuditLogs(t, operator.AccessToken, fmt.Sprintf("action=admin.wallet.adjust_balance&target=wallet_number:%d", wallet.WalletNumber))
+	require.Len(t, logs, 2)
+	assert.Contains(t, string(logs[0].Changes), "duplicated top up")
+
+	testCases := []struct {
+		name   string
+		token  string
+		path   string
+		body   adjustBalanceRequest
+		status int
+	}{
+		{name: "Customer", token: customer.AccessToken, path: path, body: adjustBalanceRequest{Amount: 100, Reason: "gift"}, status: http.StatusForbidden},
+		{name: "Support", token: loginWithRole(t, authz.RoleSupport).AccessToken, path: path, body: adjustBalanceRequest{Amount: 100, Reason: "gift"}, status: http.StatusForbidden},
+		{name: "ZeroAmount", token: operator.AccessToken, path: path, body: adjustBalanceRequest{Amount: 0, Reason: "nothing"}, status: http.StatusBadRequest},
+		{name: "NoReason", token: operator.AccessToken, path: path, body: adjustBalanceRequest{Amount: 100}, status: http.StatusBadRequest},
+		{name: "InsufficientFunds", token: operator.AccessToken, path: path, body: adjustBalanceRequest{Amount: -10000, Reason: "too much"}, status: http.StatusBadRequest},
+		{name: "BankWallet", token: operator.AccessToken, path: "/admin/wallets/1010000009/adjustments", body: adjustBalanceRequest{Amount: 100, Reason: "bank"}, status: http.StatusBadRequest},
+		{name: "WalletNotExist", token: operator.AccessToken, path: "/admin/wallets/9999999999/adjustments", body: adjustBalanceRequest{Amount: 100, Reason: "none"}, status: http.StatusNotFound},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			status, body := sendRequest(t, "POST", tc.path, tc.token, tc.body)
+			require.Equal(t, tc.status, status, string(body))
+		})
+	}
+
+	// the denied requests didn't change the balance
+	updated, err := testStore.GetWalletByNumber(testCtx, wallet.WalletNumber)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3000), updated.Balance)
+}
@@ -0,0 +1,277 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"simple-bank-system/db/pkg"
+	"simple-bank-system/db/services"
+	"simple-bank-system/token"
+
+	"github.com/google/uuid"
+	"github.com/julienschmidt/httprouter"
+)
+
+// auditRecord collect the details of 1 state-changing request, the handler add the targets and changes it knows.
+type auditRecord struct {
+	mu      sync.Mutex
+	actorID int64
+	targets map[string]string
+	changes map[string]auditDiff
+}
+
+type auditDiff struct {
+	Before interface{} `json:"before"`
+	After  interface{} `json:"after"`
+}
+
+func auditRecordOf(r *http.Request) *auditRecord {
+	record, _ := r.Context().Value("auditRecordKey").(*auditRecord)
+	return record
+}
+
+// setAuditActor set the actor of requests without token, e.g. the account that log in.
+func setAuditActor(r *http.Request, accountID int64) {
+	if record := auditRecordOf(r); record != nil {
+		record.mu.Lock()
+		record.actorID = accountID
+		record.mu.Unlock()
+	}
+}
+
+// auditTarget add the id of an object that the request changed, the url parameters are added by the middleware.
+func auditTarget(r *http.Request, name string, id interface{}) {
+	if record := auditRecordOf(r); record != nil {
+		record.mu.Lock()
+		record.targets[name] = fmt.Sprint(id)
+		record.mu.Unlock()
+	}
+}
+
+// auditChange add the old and new value of a field, fields that don't change aren't added.
+func auditChange(r *http.Request, field string, before, after interface{}) {
+	if before == after {
+		return
+	}
+	if record := auditRecordOf(r); record != nil {
+		record.mu.Lock()
+		record.changes[field] = auditDiff{Before: before, After: after}
+		record.mu.Unlock()
+	}
+}
+
+// auditResponseWriter keep the status code of the response.
+type auditResponseWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *auditResponseWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *auditResponseWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+/*
+ * audit write 1 audit log for every call of the handler, also when it fails. It's used inside 'authMiddleware',
+ * so the actor is the account of the token. The request id is the 'X-Request-ID' header of the client or a
+ * new one, it's returned in the response header. A log that can't be written doesn't fail the request.
+ */
+func (server *Server) audit(action string, next httprouter.Handle) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		requestID := r.Header.Get("X-Request-ID")
+		if requestID == "" || len(requestID) > 64 {
+			requestID = uuid.NewString()
+		}
+		w.Header().Set("X-Request-ID", requestID)
+
+		record := &auditRecord{
+			targets: map[string]string{},
+			changes: map[string]auditDiff{},
+		}
+		for _, param := range ps {
+			record.targets[param.Key] = param.Value
+		}
+		if authPayload, ok := r.Context().Value("authPayloadKey").(*token.Payload); ok {
+			record.actorID = authPayload.AccountID
+		}
+
+		rw := &auditResponseWriter{ResponseWriter: w}
+		next(rw, r.WithContext(context.WithValue(r.Context(), "auditRecordKey", record)), ps)
+		if rw.status == 0 {
+			rw.status = http.StatusOK
+		}
+
+		record.mu.Lock()
+		defer record.mu.Unlock()
+		targets, _ := json.Marshal(record.targets)
+		changes, _ := json.Marshal(record.changes)
+
+		err := server.store.CreateAuditLog(server.ctx, services.CreateAuditLogParams{
+			ActorID:    record.actorID,
+			Action:     action,
+			Method:     r.Method,
+			Path:       r.URL.Path,
+			StatusCode: rw.status,
+			Targets:    targets,
+			Changes:    changes,
+			RequestID:  requestID,
+			ClientIP:   clientIP(r),
+			UserAgent:  r.UserAgent(),
+		})
+		if err != nil {
+			log.Printf("--- (err) audit log %s, request %s: %s\n", action, requestID, err)
+		}
+	}
+}
+
+// auditEvent write an audit log of something that the request caused (e.g. a lockout), with the request id of the request.
+func (server *Server) auditEvent(w http.ResponseWriter, r *http.Request, action string, actorID int64, status int, targets map[string]string, changes map[string]auditDiff) {
+	targetsJSON, _ := json.Marshal(targets)
+	changesJSON, _ := json.Marshal(changes)
+
+	requestID := w.Header().Get("X-Request-ID")
+	err := server.store.CreateAuditLog(server.ctx, services.CreateAuditLogParams{
+		ActorID:    actorID,
+		Action:     action,
+		Method:     r.Method,
+		Path:       r.URL.Path,
+		StatusCode: status,
+		Targets:    targetsJSON,
+		Changes:    changesJSON,
+		RequestID:  requestID,
+		ClientIP:   clientIP(r),
+		UserAgent:  r.UserAgent(),
+	})
+	if err != nil {
+		log.Printf("--- (err) audit log %s, request %s: %s\n", action, requestID, err)
+	}
+}
+
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+type auditLogResponse struct {
+	ID         int64
+	ActorID    *int64
+	Action     string
+	Method     string
+	Path       string
+	StatusCode int64
+	Targets    json.RawMessage
+	Changes    json.RawMessage
+	RequestID  string
+	ClientIP   string
+	UserAgent  string
+	CreatedAt  time.Time
+}
+
+func newAuditLogResponse(entry pkg.AuditLog) auditLogResponse {
+	response := auditLogResponse{
+		ID:         entry.ID,
+		Action:     entry.Action,
+		Method:     entry.Method,
+		Path:       entry.Path,
+		StatusCode: entry.StatusCode,
+		Targets:    entry.Targets,
+		Changes:    entry.Changes,
+		RequestID:  entry.RequestID,
+		ClientIP:   entry.ClientIP,
+		UserAgent:  entry.UserAgent,
+		CreatedAt:  entry.CreatedAt,
+	}
+	if entry.ActorID.Valid {
+		response.ActorID = &entry.ActorID.Int64
+	}
+	return response
+}
+
+/*
+ * listAuditLogs return the audit logs newest first. Filters: '?actor=<account id>', '?action=', '?target=<name>:<id>'
+ * (e.g. 'number:1010000001'), '?request_id=', '?from=' and '?to=' (RFC 3339), '?before=<log id>' for the next page
+ * and '?limit=' (50 by default, max 500).
+ */
+func (server *Server) listAuditLogs(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	query := r.URL.Query()
+	arg := services.ListAuditLogsParams{
+		Action:    query.Get("action"),
+		RequestID: query.Get("request_id"),
+		Limit:     50,
+	}
+
+	var err error
+	parseID := func(name string, dst *int64) bool {
+		if value := query.Get(name); value != "" {
+			if *dst, err = strconv.ParseInt(value, 10, 64); err != nil {
+				http.Error(w, name+" must be a number", http.StatusBadRequest)
+				return false
+			}
+		}
+		return true
+	}
+	parseTime := func(name string, dst *time.Time) bool {
+		if value := query.Get(name); value != "" {
+			if *dst, err = time.Parse(time.RFC3339, value); err != nil {
+				http.Error(w, name+" must be a RFC 3339 time", http.StatusBadRequest)
+				return false
+			}
+		}
+		return true
+	}
+	if !parseID("actor", &arg.ActorID) || !parseID("before", &arg.BeforeID) || !parseTime("from", &arg.From) || !parseTime("to", &arg.To) {
+		return
+	}
+	if value := query.Get("limit"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 || n > 500 {
+			http.Error(w, "limit must be 1 to 500", http.StatusBadRequest)
+			return
+		}
+		arg.Limit = n
+	}
+	if value := query.Get("target"); value != "" {
+		name, id, ok := strings.Cut(value, ":")
+		if !ok || name == "" {
+			http.Error(w, "target must be <name>:<id>", http.StatusBadRequest)
+			return
+		}
+		arg.Target, _ = json.Marshal(map[string]string{name: id})
+	}
+
+	logs, err := server.store.ListAuditLogs(server.ctx, arg)
+	if err != nil {
+		http.Error(w, "Failed to get List", (http.StatusInternalServerError))
+		json.NewEncoder(w).Encode(err.Error())
+		return
+	}
+
+	response := []auditLogResponse{}
+	for _, entry := range logs {
+		response = append(response, newAuditLogResponse(entry))
+	}
+
+	w.Header().Add("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,81 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"simple-bank-system/authz"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// listAuditLogs return the audit logs of the query as an operator.
+func listAuditLogs(t *testing.T, accessToken, query string) []auditLogResponse {
+	status, body := sendRequest(t, "GET", "/admin/audit-logs?"+query, accessToken, nil)
+	require.Equal(t, http.StatusOK, status, string(body))
+
+	var logs []auditLogResponse
+	require.NoError(t, json.Unmarshal(body, &logs))
+	return logs
+}
+
+func TestListAuditLogs(t *testing.T) {
+	customer := loginAccount(t)
+	operator := loginWithRole(t, authz.RoleOperator)
+
+	account, err := testStore.GetAccountByNumber(testCtx, customer.Account.AccountNumber)
+	require.NoError(t, err)
+
+	// a successful and a failed request of the customer
+	wallet := createWalletCurrency(t, customer.AccessToken, "IDR")
+	status, _ := transferMoney(t, customer.AccessToken, wallet.WalletNumber, customer.Account.AccountNumber, 10)
+	require.NotEqual(t, http.StatusOK, status)
+
+	logs := listAuditLogs(t, operator.AccessToken, fmt.Sprintf("actor=%d&action=wallet.create", account.ID))
+	require.Len(t, logs, 1)
+	assert.Equal(t, account.ID, *logs[0].ActorID)
+	assert.Equal(t, "POST", logs[0].Method)
+	assert.Equal(t, "/wallet", logs[0].Path)
+	assert.Equal(t, int64(http.StatusOK), logs[0].StatusCode)
+	assert.NotEmpty(t, logs[0].RequestID)
+
+	// the failed request is also logged, with its status
+	logs = listAuditLogs(t, operator.AccessToken, fmt.Sprintf("actor=%d&action=transfer.create", account.ID))
+	require.Len(t, logs, 1)
+	assert.Equal(t, int64(status), logs[0].StatusCode)
+
+	// the filters are combined
+	logs = listAuditLogs(t, operator.AccessToken, fmt.Sprintf("actor=%d&limit=1", account.ID))
+	require.Len(t, logs, 1)
+	assert.Equal(t, "transfer.create", logs[0].Action)
+	logs = listAuditLogs(t, operator.AccessToken, fmt.Sprintf("actor=%d&before=%d", account.ID, logs[0].ID))
+	for _, entry := range logs {
+		assert.NotEqual(t, "transfer.create", entry.Action)
+	}
+
+	testCases := []struct {
+		name   string
+		token  string
+		query  string
+		status int
+	}{
+		{name: "Customer", token: customer.AccessToken, query: "", status: http.StatusForbidden},
+		{name: "Support", token: loginWithRole(t, authz.RoleSupport).AccessToken, query: "", status: http.StatusForbidden},
+		{name: "NoToken", token: "", query: "", status: http.StatusUnauthorized},
+		{name: "BadActor", token: operator.AccessToken, query: "actor=abc", status: http.StatusBadRequest},
+		{name: "BadFrom", token: operator.AccessToken, query: "from=yesterday", status: http.StatusBadRequest},
+		{name: "BadLimit", token: operator.AccessToken, query: "limit=501", status: http.StatusBadRequest},
+		{name: "BadTarget", token: operator.AccessToken, query: "target=number", status: http.StatusBadRequest},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			status, body := sendRequest(t, "GET", "/admin/audit-logs?"+tc.query, tc.token, nil)
+			require.Equal(t, tc.status, status, string(body))
+		})
+	}
+}
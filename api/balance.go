@@ -0,0 +1,70 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"simple-bank-system/db/services"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+type walletBalanceResponse struct {
+	WalletNumber int64
+	Currency     string
+	At           time.Time
+	Balance      int64
+}
+
+// parseBalanceTime return the time of the 'at' query, a date (YYYY-MM-DD) is the end of the day and empty is now.
+func parseBalanceTime(value string, now time.Time) (time.Time, bool) {
+	if value == "" {
+		return now, true
+	}
+	if day, err := time.Parse(time.DateOnly, value); err == nil {
+		return day.AddDate(0, 0, 1), true
+	}
+	at, err := time.Parse(time.RFC3339, value)
+	return at, err == nil
+}
+
+// getWalletBalance return the balance of the wallet at the 'at' time, it can't be in the future.
+func (server *Server) getWalletBalance(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	now := time.Now()
+	at, ok := parseBalanceTime(r.URL.Query().Get("at"), now)
+	if !ok {
+		http.Error(w, "at must be a date (YYYY-MM-DD) or RFC 3339 time", http.StatusBadRequest)
+		return
+	}
+	if at.After(now) {
+		http.Error(w, "at can't be in the future", http.StatusBadRequest)
+		return
+	}
+
+	wallet, ok := server.walletByParam(w, ps)
+	if !ok {
+		return
+	}
+	if !server.authorizeWallet(w, r, wallet, services.WalletRoleViewer) {
+		return
+	}
+
+	balance, err := server.store.BalanceAsOf(server.ctx, *wallet, at)
+	if err != nil {
+		http.Error(w, "Can't get wallet balance", (http.StatusInternalServerError))
+		json.NewEncoder(w).Encode(err.Error())
+		return
+	}
+
+	response := walletBalanceResponse{
+		WalletNumber: wallet.WalletNumber,
+		Currency:     wallet.Currency,
+		At:           at,
+		Balance:      balance,
+	}
+
+	w.Header().Add("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
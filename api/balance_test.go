@@ -0,0 +1,59 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetWalletBalance(t *testing.T) {
+	accRes := loginAccount(t)
+	stranger := loginAccount(t)
+	wallet := createWalletCurrency(t, accRes.AccessToken, "IDR")
+	path := "/wallet/" + strconv.FormatInt(wallet.WalletNumber, 10) + "/balance"
+
+	// RFC 3339 in UTC, so the time need no escaping in the query
+	before := time.Now().Add(-time.Second)
+	time.Sleep(time.Second)
+	status, body := transferMoney(t, accRes.AccessToken, accRes.Account.AccountNumber, wallet.WalletNumber, 5000)
+	require.Equal(t, http.StatusOK, status, string(body))
+
+	testCases := []struct {
+		name    string
+		token   string
+		at      string
+		status  int
+		balance int64
+	}{
+		{name: "Now", token: accRes.AccessToken, status: http.StatusOK, balance: 5000},
+		{name: "BeforeTransfer", token: accRes.AccessToken, at: before.UTC().Format(time.RFC3339), status: http.StatusOK, balance: 0},
+		{name: "BeforeWallet", token: accRes.AccessToken, at: "2000-01-01", status: http.StatusOK, balance: 0},
+		{name: "Future", token: accRes.AccessToken, at: time.Now().Add(time.Hour).UTC().Format(time.RFC3339), status: http.StatusBadRequest},
+		{name: "InvalidTime", token: accRes.AccessToken, at: "yesterday", status: http.StatusBadRequest},
+		{name: "OtherAccount", token: stranger.AccessToken, status: http.StatusUnauthorized},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			query := ""
+			if tc.at != "" {
+				query = "?at=" + tc.at
+			}
+			status, body := sendRequest(t, "GET", path+query, tc.token, nil)
+			require.Equal(t, tc.status, status, string(body))
+			if status != http.StatusOK {
+				return
+			}
+
+			var response walletBalanceResponse
+			require.NoError(t, json.Unmarshal(body, &response))
+			assert.Equal(t, tc.balance, response.Balance)
+		})
+	}
+}
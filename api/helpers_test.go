@@ -0,0 +1,104 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"simple-bank-system/db/services"
+	"simple-bank-system/util"
+
+	"github.com/stretchr/testify/require"
+)
+
+const baseURL = "http://localhost:8080"
+
+// sendRequest send a JSON request to the test server and return the status code and the body of the response.
+func sendRequest(t *testing.T, method, path, accessToken string, body interface{}) (int, []byte) {
+	res, resBody := sendRequestResponse(t, method, path, accessToken, body)
+	return res.StatusCode, resBody
+}
+
+// sendRequestResponse is sendRequest for the tests that check the headers, the body of the response is already read.
+func sendRequestResponse(t *testing.T, method, path, accessToken string, body interface{}) (*http.Response, []byte) {
+	var reader io.Reader
+	if body != nil {
+		argMarshaled, err := json.Marshal(body)
+		require.NoError(t, err)
+		reader = bytes.NewReader(argMarshaled)
+	}
+
+	req, err := http.NewRequest(method, baseURL+path, reader)
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+	if accessToken != "" {
+		req.Header.Set("Authorization", "Bearer "+accessToken)
+	}
+
+	client := http.Client{Timeout: 10 * time.Second}
+	res, err := client.Do(req)
+	require.NoError(t, err, "can't send request")
+	defer res.Body.Close()
+
+	resBody, err := io.ReadAll(res.Body)
+	require.NoError(t, err)
+	return res, resBody
+}
+
+// login the account and return the response, the status code must be 200.
+func login(t *testing.T, username, password string) loginResponse {
+	status, body := sendRequest(t, "POST", "/account/login", "", loginRequest{Username: username, Password: password})
+	require.Equal(t, http.StatusOK, status, string(body))
+
+	var response loginResponse
+	require.NoError(t, json.Unmarshal(body, &response))
+	return response
+}
+
+// loginWithRole create an account with the role and login, the role is in the new token.
+func loginWithRole(t *testing.T, role string) loginResponse {
+	accRes := loginAccount(t)
+	password := Password
+
+	account, err := testStore.GetAccountByNumber(testCtx, accRes.Account.AccountNumber)
+	require.NoError(t, err)
+	require.NoError(t, testStore.SetAccountRole(testCtx, account.ID, role))
+
+	return login(t, accRes.Account.Username, password)
+}
+
+// transferMoney send an IDR transfer between two wallets and return the status code and the body of the response.
+func transferMoney(t *testing.T, accessToken string, from, to, amount int64) (int, []byte) {
+	return sendRequest(t, "POST", "/transfer", accessToken, transferRequest{
+		FromWalletNumber: from,
+		ToWalletNumber:   to,
+		Amount:           amount,
+		Currency:         "IDR",
+	})
+}
+
+// enableTOTP enable 2FA of the logged in account and return the secret, no code of the secret is used yet.
+func enableTOTP(t *testing.T, loginRes loginResponse) string {
+	status, body := sendRequest(t, "POST", "/account/me/2fa/totp", loginRes.AccessToken, nil)
+	require.Equal(t, http.StatusCreated, status, string(body))
+
+	var enrollment enrollTOTPResponse
+	require.NoError(t, json.Unmarshal(body, &enrollment))
+
+	// enabled without the confirmation code, so the code of the current step can be used by the test
+	account, err := testStore.GetAccountByNumber(testCtx, loginRes.Account.AccountNumber)
+	require.NoError(t, err)
+	err = testStore.EnableTOTPTx(testCtx, services.EnableTOTPParams{AccountID: account.ID})
+	require.NoError(t, err)
+	return enrollment.Secret
+}
+
+// totpCode return the code of the secret for now.
+func totpCode(t *testing.T, secret string) string {
+	code, err := util.TOTPCode(secret, util.TOTPStep(time.Now()))
+	require.NoError(t, err)
+	return code
+}
@@ -0,0 +1,123 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"simple-bank-system/db/services"
+	"simple-bank-system/util"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+type walletInterestResponse struct {
+	WalletNumber    int64
+	WalletType      string
+	AnnualRateBps   int64
+	InterestYTD     int64
+	AccruedInterest float64
+}
+
+// getWalletInterest return interest rate, year-to-date posted interest and unposted accrued interest of the wallet.
+func (server *Server) getWalletInterest(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	walletNumber, err := strconv.ParseInt(ps.ByName("number"), 10, 64)
+	if err != nil {
+		http.Error(w, "failed to convert url parameter to int", (http.StatusInternalServerError))
+		return
+	}
+
+	wallet, err := server.store.GetWalletByNumber(server.ctx, walletNumber)
+	if err != nil {
+		if err == util.ErrNotExist {
+			http.Error(w, err.Error(), 403)
+			return
+		}
+		http.Error(w, "Can't get wallet", (http.StatusInternalServerError))
+		json.NewEncoder(w).Encode(err.Error())
+		return
+	}
+
+	if !server.authorizeWallet(w, r, wallet, services.WalletRoleViewer) {
+		return
+	}
+
+	interest, err := server.store.GetWalletInterest(server.ctx, *wallet)
+	if err != nil {
+		http.Error(w, "Can't get wallet interest", (http.StatusInternalServerError))
+		json.NewEncoder(w).Encode(err.Error())
+		return
+	}
+
+	response := walletInterestResponse{
+		WalletNumber:    wallet.WalletNumber,
+		WalletType:      wallet.Type,
+		AnnualRateBps:   interest.AnnualRateBps,
+		InterestYTD:     interest.InterestYTD,
+		AccruedInterest: interest.AccruedInterest,
+	}
+
+	w.Header().Add("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
+
+type setInterestRateRequest struct {
+	// basis points, 250 = 2.5%
+	AnnualRateBps *int64 `json:"annual_rate_bps" validate:"required,min=0,max=10000"`
+}
+
+type interestRateResponse struct {
+	WalletType    string
+	AnnualRateBps int64
+	UpdatedAt     time.Time
+}
+
+// adminSetInterestRate change the yearly rate of a wallet type, it's used from the next accrued day.
+func (server *Server) adminSetInterestRate(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	var req setInterestRateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := validate.Struct(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	walletType := ps.ByName("wallet_type")
+	if walletType != services.WalletTypeRegular && walletType != services.WalletTypeSavings {
+		http.Error(w, "wallet type must be regular or savings", http.StatusBadRequest)
+		return
+	}
+	auditTarget(r, "wallet_type", walletType)
+
+	before, err := server.store.GetInterestRate(server.ctx, walletType)
+	if err != nil && err != util.ErrNotExist {
+		http.Error(w, "Can't get interest rate", (http.StatusInternalServerError))
+		json.NewEncoder(w).Encode(err.Error())
+		return
+	}
+
+	rate, err := server.store.SetInterestRate(server.ctx, services.SetInterestRateParams{
+		WalletType:    walletType,
+		AnnualRateBps: *req.AnnualRateBps,
+	})
+	if err != nil {
+		http.Error(w, "Failed to update interest rate", (http.StatusInternalServerError))
+		json.NewEncoder(w).Encode(err.Error())
+		return
+	}
+	if before != nil {
+		auditChange(r, "annual_rate_bps", before.AnnualRateBps, rate.AnnualRateBps)
+	}
+
+	w.Header().Add("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(interestRateResponse{
+		WalletType:    rate.WalletType,
+		AnnualRateBps: rate.AnnualRateBps,
+		UpdatedAt:     rate.UpdatedAt,
+	})
+}
@@ -0,0 +1,71 @@
+package api
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetWalletInterest(t *testing.T) {
+	accRes := loginAccount(t)
+	wallet := createRandomWallet(t, accRes.AccessToken)
+
+	newUrl := url + "/" + strconv.FormatInt(wallet.WalletNumber, 10) + "/interest"
+	req, err := http.NewRequest("GET", newUrl, nil)
+	require.NoError(t, err)
+
+	req.Header.Add("Content-Type", "application/json")
+	req.Header.Add("Authorization", "Bearer "+accRes.AccessToken)
+
+	client := http.Client{Timeout: 10 * time.Second}
+	res, err := client.Do(req)
+	require.NoError(t, err, "can't send request")
+	assert.True(t, res.StatusCode == 200, "status code is wrong, status code:", res.StatusCode, res.Status)
+
+	defer res.Body.Close()
+
+	var response walletInterestResponse
+	resBody, err := io.ReadAll(res.Body)
+	require.NoError(t, err)
+	err = json.Unmarshal(resBody, &response)
+	require.NoError(t, err)
+
+	assert.Equal(t, wallet.WalletNumber, response.WalletNumber)
+	assert.Equal(t, "regular", response.WalletType)
+	assert.Zero(t, response.AnnualRateBps)
+	assert.Zero(t, response.InterestYTD)
+	assert.Zero(t, response.AccruedInterest)
+}
+
+func TestAdminSetInterestRate(t *testing.T) {
+	arg := setInterestRateRequest{AnnualRateBps: new(int64)}
+
+	// customers and support staff can't change the rates
+	customer := loginAccount(t)
+	status, _ := sendRequest(t, "PUT", "/admin/interest-rates/regular", customer.AccessToken, arg)
+	assert.Equal(t, http.StatusForbidden, status)
+	support := loginWithRole(t, "support")
+	status, _ = sendRequest(t, "PUT", "/admin/interest-rates/regular", support.AccessToken, arg)
+	assert.Equal(t, http.StatusForbidden, status)
+
+	operator := loginWithRole(t, "operator")
+	status, _ = sendRequest(t, "PUT", "/admin/interest-rates/system", operator.AccessToken, arg)
+	assert.Equal(t, http.StatusBadRequest, status, "system wallets don't earn interest")
+	tooHigh := int64(10001)
+	status, _ = sendRequest(t, "PUT", "/admin/interest-rates/regular", operator.AccessToken, setInterestRateRequest{AnnualRateBps: &tooHigh})
+	assert.Equal(t, http.StatusUnprocessableEntity, status)
+
+	// the regular rate is 0, it isn't changed by the test
+	status, body := sendRequest(t, "PUT", "/admin/interest-rates/regular", operator.AccessToken, arg)
+	require.Equal(t, http.StatusOK, status, string(body))
+	var response interestRateResponse
+	require.NoError(t, json.Unmarshal(body, &response))
+	assert.Equal(t, "regular", response.WalletType)
+	assert.Zero(t, response.AnnualRateBps)
+}
@@ -0,0 +1,127 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"simple-bank-system/db/pkg"
+	"simple-bank-system/db/services"
+	"simple-bank-system/util"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+// Brute-force protection of the login, the failed logins are counted per username and per client IP.
+
+const loginFailedMessage = "username or password is wrong"
+
+// dummyPasswordHash is verified when the username doesn't exist, so the response time doesn't tell it.
+var dummyPasswordHash, _ = util.HashingPassword("not the password of any account")
+
+func loginThrottleKeys(username, ip string) []services.LoginThrottleKey {
+	return []services.LoginThrottleKey{
+		{Scope: services.LoginScopeUsername, Key: username},
+		{Scope: services.LoginScopeIP, Key: ip},
+	}
+}
+
+// checkLoginLock refuse the login while the username or the IP is locked, the password isn't checked.
+func (server *Server) checkLoginLock(w http.ResponseWriter, keys []services.LoginThrottleKey) bool {
+	now := time.Now()
+	lockedUntil, err := server.store.GetLoginLock(server.ctx, keys, now)
+	if err != nil {
+		http.Error(w, "Can't check login lock", http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(err.Error())
+		return false
+	}
+	if lockedUntil.IsZero() {
+		return true
+	}
+
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(lockedUntil.Sub(now).Seconds()))))
+	http.Error(w, "too many failed logins, try again later", http.StatusTooManyRequests)
+	return false
+}
+
+// loginFailed count the failure of every key and answer with the generic message, 'account' is nil for unknown usernames.
+func (server *Server) loginFailed(w http.ResponseWriter, r *http.Request, keys []services.LoginThrottleKey, account *pkg.Account) {
+	server.recordLoginFailure(w, r, keys, account)
+	http.Error(w, loginFailedMessage, http.StatusUnauthorized)
+}
+
+// recordLoginFailure count the failure of every key and write the lockouts to the audit log, nothing is answered.
+func (server *Server) recordLoginFailure(w http.ResponseWriter, r *http.Request, keys []services.LoginThrottleKey, account *pkg.Account) {
+	now := time.Now()
+	for _, key := range keys {
+		maxAttempts := server.loginThrottle.maxAttempts
+		if key.Scope == services.LoginScopeIP {
+			maxAttempts = server.loginThrottle.ipMaxAttempts
+		}
+
+		throttle, locked, err := server.store.RecordLoginFailureTx(server.ctx, services.RecordLoginFailureParams{
+			LoginThrottleKey: key,
+			MaxAttempts:      maxAttempts,
+			Window:           server.loginThrottle.window,
+			Lockout:          server.loginThrottle.lockout,
+			MaxLockout:       server.loginThrottle.maxLockout,
+			Now:              now,
+		})
+		if err != nil {
+			log.Printf("--- (err) login failure %s %s: %v\n", key.Scope, key.Key, err)
+			continue
+		}
+		if !locked {
+			continue
+		}
+
+		var actorID int64
+		if account != nil && key.Scope == services.LoginScopeUsername {
+			actorID = account.ID
+		}
+		server.auditEvent(w, r, "account.lockout", actorID, http.StatusTooManyRequests,
+			map[string]string{key.Scope: key.Key, "lockouts": fmt.Sprint(throttle.Lockouts)},
+			map[string]auditDiff{"locked_until": {Before: nil, After: throttle.LockedUntil.Time}})
+	}
+}
+
+// clearLoginFailures forget the failures of the username after a complete login (password and 2FA), the IP keep its count.
+func (server *Server) clearLoginFailures(keys []services.LoginThrottleKey) {
+	for _, key := range keys {
+		if key.Scope != services.LoginScopeUsername {
+			continue
+		}
+		if err := server.store.ClearLoginThrottle(server.ctx, key); err != nil && err != util.ErrDeleteFailed {
+			log.Printf("--- (err) clear login failures %s: %v\n", key.Key, err)
+		}
+	}
+}
+
+// adminUnlockAccount remove the lockout and the failed logins of the account.
+func (server *Server) adminUnlockAccount(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	account, ok := server.accountByParam(w, ps)
+	if !ok {
+		return
+	}
+	auditTarget(r, "account_number", account.AccountNumber)
+
+	err := server.store.ClearLoginThrottle(server.ctx, services.LoginThrottleKey{Scope: services.LoginScopeUsername, Key: account.Username})
+	if err != nil {
+		if err == util.ErrDeleteFailed {
+			http.Error(w, "account isn't locked", http.StatusConflict)
+			return
+		}
+		http.Error(w, "Failed to unlock account", (http.StatusInternalServerError))
+		json.NewEncoder(w).Encode(err.Error())
+		return
+	}
+	auditChange(r, "locked", true, false)
+
+	w.Header().Add("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode("Account unlocked!")
+}
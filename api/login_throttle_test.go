@@ -0,0 +1,130 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"testing"
+
+	"simple-bank-system/authz"
+
+	"github.com/stretchr/testify/require"
+)
+
+// the code is never a TOTP or a recovery code
+const wrongCode = "not-a-code"
+
+// postLogin send the login request and return the response.
+func postLogin(t *testing.T, username, password string) *http.Response {
+	res, _ := sendRequestResponse(t, "POST", "/account/login", "", loginRequest{Username: username, Password: password})
+	return res
+}
+
+// loginChallenge login the account with 2FA and return the challenge token.
+func loginChallenge(t *testing.T, username string) string {
+	status, body := sendRequest(t, "POST", "/account/login", "", loginRequest{Username: username, Password: Password})
+	require.Equal(t, http.StatusOK, status, string(body))
+
+	var challenge loginChallengeResponse
+	require.NoError(t, json.Unmarshal(body, &challenge))
+	require.True(t, challenge.TwoFactorRequired)
+	return challenge.ChallengeToken
+}
+
+// requireLocked check the right password is refused with a Retry-After.
+func requireLocked(t *testing.T, username string) {
+	res := postLogin(t, username, Password)
+	require.Equal(t, http.StatusTooManyRequests, res.StatusCode)
+	retryAfter, err := strconv.Atoi(res.Header.Get("Retry-After"))
+	require.NoError(t, err)
+	require.Positive(t, retryAfter)
+}
+
+func TestLoginLockout(t *testing.T) {
+	loginRes := loginAccount(t)
+	username := loginRes.Account.Username
+
+	for i := 0; i < testConfig.LoginMaxAttempts; i++ {
+		res := postLogin(t, username, "wrong password")
+		require.Equal(t, http.StatusUnauthorized, res.StatusCode)
+	}
+	requireLocked(t, username)
+
+	testCases := []struct {
+		name   string
+		token  string
+		status int
+	}{
+		{name: "NoToken", token: "", status: http.StatusUnauthorized},
+		{name: "Customer", token: loginRes.AccessToken, status: http.StatusForbidden},
+		{name: "Support", token: loginWithRole(t, authz.RoleSupport).AccessToken, status: http.StatusOK},
+		{name: "NotLocked", token: loginWithRole(t, authz.RoleSupport).AccessToken, status: http.StatusConflict},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			path := "/admin/accounts/" + strconv.FormatInt(loginRes.Account.AccountNumber, 10) + "/unlock"
+			status, body := sendRequest(t, "POST", path, tc.token, nil)
+			require.Equal(t, tc.status, status, string(body))
+		})
+	}
+
+	login(t, username, Password)
+}
+
+func TestLoginTwoFactorLockout(t *testing.T) {
+	t.Run("PasswordDoesNotClearFailures", func(t *testing.T) {
+		loginRes := loginAccount(t)
+		username := loginRes.Account.Username
+		enableTOTP(t, loginRes)
+
+		for i := 0; i < testConfig.LoginMaxAttempts-1; i++ {
+			res := postLogin(t, username, "wrong password")
+			require.Equal(t, http.StatusUnauthorized, res.StatusCode)
+		}
+
+		// the right password only give a challenge, the wrong code is the last failure
+		challengeToken := loginChallenge(t, username)
+		status, body := sendRequest(t, "POST", "/account/login/2fa", "", loginTwoFactorRequest{ChallengeToken: challengeToken, Code: wrongCode})
+		require.Equal(t, http.StatusUnauthorized, status, string(body))
+
+		requireLocked(t, username)
+	})
+
+	t.Run("WrongCodes", func(t *testing.T) {
+		loginRes := loginAccount(t)
+		username := loginRes.Account.Username
+		secret := enableTOTP(t, loginRes)
+
+		var challengeToken string
+		for i := 0; i < testConfig.LoginMaxAttempts; i++ {
+			challengeToken = loginChallenge(t, username)
+			status, body := sendRequest(t, "POST", "/account/login/2fa", "", loginTwoFactorRequest{ChallengeToken: challengeToken, Code: wrongCode})
+			require.Equal(t, http.StatusUnauthorized, status, string(body))
+		}
+		requireLocked(t, username)
+
+		// the right code of a challenge is also refused while the username is locked
+		status, body := sendRequest(t, "POST", "/account/login/2fa", "", loginTwoFactorRequest{ChallengeToken: challengeToken, Code: totpCode(t, secret)})
+		require.Equal(t, http.StatusTooManyRequests, status, string(body))
+	})
+
+	t.Run("LoginClearFailures", func(t *testing.T) {
+		loginRes := loginAccount(t)
+		username := loginRes.Account.Username
+		secret := enableTOTP(t, loginRes)
+
+		for i := 0; i < testConfig.LoginMaxAttempts-1; i++ {
+			res := postLogin(t, username, "wrong password")
+			require.Equal(t, http.StatusUnauthorized, res.StatusCode)
+		}
+		status, body := sendRequest(t, "POST", "/account/login/2fa", "", loginTwoFactorRequest{ChallengeToken: loginChallenge(t, username), Code: totpCode(t, secret)})
+		require.Equal(t, http.StatusOK, status, string(body))
+
+		// the count start again after the complete login
+		res := postLogin(t, username, "wrong password")
+		require.Equal(t, http.StatusUnauthorized, res.StatusCode)
+		loginChallenge(t, username)
+	})
+}
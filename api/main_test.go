@@ -14,6 +14,10 @@ import (
 
 var (
 	server *Server
+	// the tests that need staff accounts or fixtures change the database directly
+	testStore  *services.Store
+	testConfig util.Config
+	testCtx    = context.Background()
 )
 
 func TestMain(m *testing.M) {
@@ -27,6 +31,11 @@ func TestMain(m *testing.M) {
 		log.Fatal("Cannot load config: ", err)
 	}
 
+	// every test call the API from localhost
+	config.RateLimitLogin = "1000/1m"
+	config.LoginIPMaxAttempts = 1000
+	testConfig = config
+
 	dbpool, err := pgxpool.Connect(context.Background(), config.DBSource)
 	if err != nil {
 		log.Fatal("Failed connect to db: ", err)
@@ -37,6 +46,7 @@ func TestMain(m *testing.M) {
 	defer cancel()
 
 	store := services.NewStore(dbpool)
+	testStore = store
 	server, err = NewServer(store, ctx, config)
 	if err != nil {
 		log.Fatal("Can't create server, \nerr: ", err)
@@ -3,11 +3,15 @@ package api
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log"
 	"net/http"
 	"strings"
 
+	"simple-bank-system/authz"
 	"simple-bank-system/token"
+	"simple-bank-system/util"
 
 	"github.com/julienschmidt/httprouter"
 )
@@ -42,7 +46,12 @@ func CORSMiddleware(next httprouter.Handle) httprouter.Handle {
 	}
 }
 
-func authMiddleware(tokenMaker token.Maker, next httprouter.Handle) httprouter.Handle {
+/*
+ * authMiddleware verify the access token and put its payload in the request context. Tokens that were
+ * issued before the last password change of the account are rejected, so changing the password log out
+ * every other device.
+ */
+func (server *Server) authMiddleware(next httprouter.Handle) httprouter.Handle {
 	return (func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 		authHeader := r.Header.Get("Authorization")
 		if len(authHeader) == 0 {
@@ -63,7 +72,7 @@ func authMiddleware(tokenMaker token.Maker, next httprouter.Handle) httprouter.H
 		}
 
 		accessToken := fields[1]
-		payload, err := tokenMaker.VerifyToken(accessToken)
+		payload, err := server.tokenMaker.VerifyToken(accessToken)
 		if err != nil {
 			w.Header().Set("WWW-Authenticate", `Basic realm="restricted", charset="UTF-8"`)
 			http.Error(w, "token is wrong", http.StatusUnauthorized)
@@ -71,6 +80,21 @@ func authMiddleware(tokenMaker token.Maker, next httprouter.Handle) httprouter.H
 			return
 		}
 
+		err = server.tokenValid(r.Context(), payload)
+		switch err {
+		case nil:
+		case util.ErrNotExist:
+			http.Error(w, "account doesn't exist", http.StatusUnauthorized)
+			return
+		case errTokenBeforePasswordChange, errTokenRevoked, errTokenRoleChanged:
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		default:
+			http.Error(w, "Can't check token", http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(err.Error())
+			return
+		}
+
 		ctx := context.WithValue(r.Context(), "authPayloadKey", payload)
 		r = r.WithContext(ctx)
 
@@ -78,3 +102,61 @@ func authMiddleware(tokenMaker token.Maker, next httprouter.Handle) httprouter.H
 		next(w, r, ps)
 	})
 }
+
+var (
+	errTokenBeforePasswordChange = errors.New("token was issued before the last password change, login again")
+	errTokenRevoked              = errors.New("token is revoked, login again")
+	errTokenRoleChanged          = errors.New("role of the account changed since the token was issued, login again")
+)
+
+// tokenValid check that the token isn't revoked, was issued after the last password change of its account and
+// has the current role of the account (the tokens copy the role, a demoted staff must lose its rights at once),
+// it's checked on every request and again while a stream is open.
+func (server *Server) tokenValid(ctx context.Context, payload *token.Payload) error {
+	account, err := server.store.GetAccountAuth(ctx, payload.AccountID)
+	if err != nil {
+		return err
+	}
+	if payload.IssuedBefore(account.PasswordChangeAt) {
+		return errTokenBeforePasswordChange
+	}
+	if payload.Role != account.Role {
+		return errTokenRoleChanged
+	}
+	if server.revocations.Revoked(payload) {
+		return errTokenRevoked
+	}
+	return nil
+}
+
+/*
+ * streamAuthMiddleware also accept the token in the 'access_token' query, browsers' EventSource can't
+ * send the Authorization header.
+ */
+func (server *Server) streamAuthMiddleware(next httprouter.Handle) httprouter.Handle {
+	auth := server.authMiddleware(next)
+	return func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		if accessToken := r.URL.Query().Get("access_token"); r.Header.Get("Authorization") == "" && accessToken != "" {
+			r.Header.Set("Authorization", "Bearer "+accessToken)
+		}
+		auth(w, r, ps)
+	}
+}
+
+// authorize only let the roles (and the token scopes) that have the permission call the handler, it's used inside 'authMiddleware'.
+func authorize(permission authz.Permission, next httprouter.Handle) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		authPayload := r.Context().Value("authPayloadKey").(*token.Payload)
+		if !authz.Allows(authPayload.Role, permission) {
+			http.Error(w, fmt.Sprintf("your role '%s' doesn't have the permission '%s'", authz.RoleOf(authPayload.Role), permission), http.StatusForbidden)
+			return
+		}
+		// restricted tokens also need the scope of the route
+		if scope := authz.ScopeOf(permission); !authPayload.HasScope(scope) {
+			w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer error="insufficient_scope", scope="%s"`, scope))
+			http.Error(w, fmt.Sprintf("your token doesn't have the scope '%s'", scope), http.StatusForbidden)
+			return
+		}
+		next(w, r, ps)
+	}
+}
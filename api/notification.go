@@ -0,0 +1,201 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"simple-bank-system/db/pkg"
+	"simple-bank-system/db/services"
+	"simple-bank-system/notification"
+	"simple-bank-system/token"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+type notificationResponse struct {
+	ID        int64
+	Type      string
+	Title     string
+	Body      string
+	Data      json.RawMessage
+	Read      bool
+	ReadAt    *time.Time
+	CreatedAt time.Time
+}
+
+type listNotificationsResponse struct {
+	Unread        int64
+	Notifications []notificationResponse
+}
+
+type readNotificationsRequest struct {
+	// empty to mark every notification as read
+	IDs []int64 `json:"ids"`
+}
+
+type notificationPreference struct {
+	EventType string `json:"event_type" validate:"required"`
+	Email     bool   `json:"email"`
+	InApp     bool   `json:"in_app"`
+}
+
+func newNotificationResponse(n pkg.Notification) notificationResponse {
+	response := notificationResponse{
+		ID:        n.ID,
+		Type:      n.Type,
+		Title:     n.Title,
+		Body:      n.Body,
+		Data:      n.Data,
+		Read:      n.ReadAt.Valid,
+		CreatedAt: n.CreatedAt,
+	}
+	if n.ReadAt.Valid {
+		response.ReadAt = &n.ReadAt.Time
+	}
+	return response
+}
+
+// listNotifications return the inbox newest first, '?unread=true' only return unread notifications,
+// '?before=<id>' return the next page and '?limit=' is 50 by default and max 200.
+func (server *Server) listNotifications(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authPayload := r.Context().Value("authPayloadKey").(*token.Payload)
+	query := r.URL.Query()
+
+	arg := services.ListNotificationsParams{
+		AccountID:  authPayload.AccountID,
+		UnreadOnly: query.Get("unread") == "true",
+		Limit:      50,
+	}
+	if value := query.Get("limit"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 || n > 200 {
+			http.Error(w, "limit must be 1 to 200", http.StatusBadRequest)
+			return
+		}
+		arg.Limit = n
+	}
+	if value := query.Get("before"); value != "" {
+		id, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			http.Error(w, "before must be a notification id", http.StatusBadRequest)
+			return
+		}
+		arg.BeforeID = id
+	}
+
+	notifications, err := server.store.ListNotifications(server.ctx, arg)
+	if err != nil {
+		http.Error(w, "Failed to get List", (http.StatusInternalServerError))
+		json.NewEncoder(w).Encode(err.Error())
+		return
+	}
+	unread, err := server.store.CountUnreadNotifications(server.ctx, authPayload.AccountID)
+	if err != nil {
+		http.Error(w, "Failed to count unread notifications", (http.StatusInternalServerError))
+		json.NewEncoder(w).Encode(err.Error())
+		return
+	}
+
+	response := listNotificationsResponse{
+		Unread:        unread,
+		Notifications: []notificationResponse{},
+	}
+	for _, n := range notifications {
+		response.Notifications = append(response.Notifications, newNotificationResponse(n))
+	}
+
+	w.Header().Add("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
+
+// readNotifications mark the notifications of 'ids' as read, or all of them without 'ids'.
+func (server *Server) readNotifications(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	var req readNotificationsRequest
+	if r.ContentLength != 0 {
+		err := json.NewDecoder(r.Body).Decode(&req)
+		if err != nil {
+			http.Error(w, "failed to decode input data", (http.StatusInternalServerError))
+			json.NewEncoder(w).Encode(err.Error())
+			return
+		}
+	}
+
+	authPayload := r.Context().Value("authPayloadKey").(*token.Payload)
+	n, err := server.store.MarkNotificationsRead(server.ctx, authPayload.AccountID, req.IDs)
+	if err != nil {
+		http.Error(w, "Failed to mark notifications as read", http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(err.Error())
+		return
+	}
+
+	w.Header().Add("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]int64{"Read": n})
+}
+
+// getNotificationPreferences return the channels of every event type, including the defaults.
+func (server *Server) getNotificationPreferences(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authPayload := r.Context().Value("authPayloadKey").(*token.Payload)
+
+	saved, err := server.store.GetNotificationPreferences(server.ctx, authPayload.AccountID)
+	if err != nil {
+		http.Error(w, "Failed to get notification preferences", http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(err.Error())
+		return
+	}
+
+	response := []notificationPreference{}
+	for _, preference := range notification.Preferences(saved) {
+		response = append(response, notificationPreference{
+			EventType: preference.EventType,
+			Email:     preference.Email,
+			InApp:     preference.InApp,
+		})
+	}
+
+	w.Header().Add("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
+
+// updateNotificationPreferences save the preferences of the event types in the body, other event types don't change.
+func (server *Server) updateNotificationPreferences(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	var req []notificationPreference
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		http.Error(w, "failed to decode input data", (http.StatusInternalServerError))
+		json.NewEncoder(w).Encode(err.Error())
+		return
+	}
+
+	var preferences []pkg.NotificationPreference
+	for _, preference := range req {
+		if err = validate.Struct(preference); err != nil {
+			http.Error(w, "Format input data is wrong", (http.StatusBadRequest))
+			json.NewEncoder(w).Encode(err.Error())
+			return
+		}
+		if !notification.ValidEventType(preference.EventType) {
+			http.Error(w, "event type "+preference.EventType+" doesn't exist", http.StatusBadRequest)
+			return
+		}
+		preferences = append(preferences, pkg.NotificationPreference{
+			EventType: preference.EventType,
+			Email:     preference.Email,
+			InApp:     preference.InApp,
+		})
+	}
+
+	authPayload := r.Context().Value("authPayloadKey").(*token.Payload)
+	err = server.store.SetNotificationPreferences(server.ctx, authPayload.AccountID, preferences)
+	if err != nil {
+		http.Error(w, "Failed to save notification preferences", http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(err.Error())
+		return
+	}
+
+	server.getNotificationPreferences(w, r, ps)
+}
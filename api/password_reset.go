@@ -0,0 +1,127 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"time"
+
+	"simple-bank-system/db/services"
+	"simple-bank-system/notification"
+	"simple-bank-system/util"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+type forgotPasswordRequest struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+type resetPasswordRequest struct {
+	Token       string `json:"token" validate:"required"`
+	NewPassword string `json:"new_password" validate:"required,min=6"`
+}
+
+/*
+ * forgotPassword always answer the same, the token is created and emailed after the response. So neither
+ * the body nor the response time tell if an account use the email, or if it reached the limit of requests.
+ */
+func (server *Server) forgotPassword(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	var req forgotPasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := validate.Struct(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	go server.sendPasswordReset(req.Email)
+
+	w.Header().Add("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode("If an account use this email, a reset token was sent to it")
+}
+
+// sendPasswordReset create the reset token of the account of the email and email it, errors are only logged.
+func (server *Server) sendPasswordReset(email string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	account, err := server.store.GetAccountByEmail(ctx, email)
+	if err != nil {
+		if err != util.ErrNotExist {
+			log.Println("--- (err) password reset, get account:", err)
+		}
+		return
+	}
+
+	resetToken, err := util.RandomToken(32)
+	if err != nil {
+		log.Println("--- (err) password reset, token:", err)
+		return
+	}
+
+	now := time.Now()
+	reset, err := server.store.CreatePasswordResetTx(ctx, services.CreatePasswordResetParams{
+		AccountID: account.ID,
+		TokenHash: util.HashToken(resetToken),
+		ExpiresAt: now.Add(server.passwordResetDuration),
+		Limit:     server.passwordResetLimit,
+		Since:     now.Add(-time.Hour),
+	})
+	if err != nil {
+		log.Printf("--- (err) password reset, account %d: %v\n", account.ID, err)
+		return
+	}
+
+	err = server.notifier.Notify(ctx, notification.Event{
+		AccountID: account.ID,
+		Type:      notification.EventPasswordReset,
+		Email:     account.Email,
+		Data: map[string]interface{}{
+			"token":      resetToken,
+			"expires_at": reset.ExpiresAt.UTC().Format("2006-01-02 15:04 MST"),
+		},
+		CreatedAt: reset.CreatedAt,
+	})
+	if err != nil {
+		log.Printf("--- (err) password reset, account %d, email: %v\n", account.ID, err)
+	}
+}
+
+// resetPassword change the password with the emailed token, the access tokens of the account are revoked.
+func (server *Server) resetPassword(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	var req resetPasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := validate.Struct(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	accountID, err := server.store.ResetPasswordTx(server.ctx, services.ResetPasswordParams{
+		TokenHash: util.HashToken(req.Token),
+		Password:  req.NewPassword,
+		ChangedAt: time.Now(),
+	})
+	if err != nil {
+		switch err {
+		case util.ErrNotExist, util.ErrTokenExpired:
+			http.Error(w, "reset token is wrong or expired, request a new one", http.StatusBadRequest)
+			return
+		}
+		http.Error(w, "Failed to reset password", (http.StatusInternalServerError))
+		json.NewEncoder(w).Encode(err.Error())
+		return
+	}
+	setAuditActor(r, accountID)
+
+	w.Header().Add("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode("Password changed, login with the new password")
+}
@@ -0,0 +1,89 @@
+package api
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"simple-bank-system/db/services"
+	"simple-bank-system/util"
+
+	"github.com/stretchr/testify/require"
+)
+
+// createPasswordReset save a reset token of the account, the server only email it.
+func createPasswordReset(t *testing.T, accountNumber int64, expiresAt time.Time) string {
+	account, err := testStore.GetAccountByNumber(testCtx, accountNumber)
+	require.NoError(t, err)
+
+	resetToken, err := util.RandomToken(32)
+	require.NoError(t, err)
+	_, err = testStore.CreatePasswordResetTx(testCtx, services.CreatePasswordResetParams{
+		AccountID: account.ID,
+		TokenHash: util.HashToken(resetToken),
+		ExpiresAt: expiresAt,
+		Limit:     10,
+		Since:     time.Now().Add(-time.Hour),
+	})
+	require.NoError(t, err)
+	return resetToken
+}
+
+func TestForgotPassword(t *testing.T) {
+	loginRes := loginAccount(t)
+
+	// the answer doesn't tell if an account use the email
+	status, known := sendRequest(t, "POST", "/account/password/forgot", "", forgotPasswordRequest{Email: loginRes.Account.Email})
+	require.Equal(t, http.StatusAccepted, status, string(known))
+	status, unknown := sendRequest(t, "POST", "/account/password/forgot", "", forgotPasswordRequest{Email: util.RandomEmail()})
+	require.Equal(t, http.StatusAccepted, status, string(unknown))
+	require.Equal(t, string(known), string(unknown))
+
+	status, _ = sendRequest(t, "POST", "/account/password/forgot", "", forgotPasswordRequest{Email: "not an email"})
+	require.Equal(t, http.StatusUnprocessableEntity, status)
+}
+
+func TestResetPassword(t *testing.T) {
+	loginRes := loginAccount(t)
+	resetToken := createPasswordReset(t, loginRes.Account.AccountNumber, time.Now().Add(time.Hour))
+	expiredToken := createPasswordReset(t, loginRes.Account.AccountNumber, time.Now().Add(-time.Minute))
+	newPassword := util.RandomPassword()
+
+	testCases := []struct {
+		name   string
+		body   resetPasswordRequest
+		status int
+	}{
+		{name: "WrongToken", body: resetPasswordRequest{Token: "wrong", NewPassword: newPassword}, status: http.StatusBadRequest},
+		{name: "ExpiredToken", body: resetPasswordRequest{Token: expiredToken, NewPassword: newPassword}, status: http.StatusBadRequest},
+		{name: "ShortPassword", body: resetPasswordRequest{Token: resetToken, NewPassword: "abc"}, status: http.StatusUnprocessableEntity},
+		{name: "NoToken", body: resetPasswordRequest{NewPassword: newPassword}, status: http.StatusUnprocessableEntity},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			status, body := sendRequest(t, "POST", "/account/password/reset", "", tc.body)
+			require.Equal(t, tc.status, status, string(body))
+		})
+	}
+	// the failed resets didn't change the password
+	getProfile(t, loginRes.AccessToken)
+
+	status, body := sendRequest(t, "POST", "/account/password/reset", "", resetPasswordRequest{Token: resetToken, NewPassword: newPassword})
+	require.Equal(t, http.StatusOK, status, string(body))
+
+	// the token is used once
+	status, _ = sendRequest(t, "POST", "/account/password/reset", "", resetPasswordRequest{Token: resetToken, NewPassword: util.RandomPassword()})
+	require.Equal(t, http.StatusBadRequest, status)
+
+	// the tokens and sessions of before the reset are rejected
+	status, _ = sendRequest(t, "GET", "/account/me", loginRes.AccessToken, nil)
+	require.Equal(t, http.StatusUnauthorized, status)
+	status, _ = sendRequest(t, "POST", "/account/token/refresh", "", refreshTokenRequest{RefreshToken: loginRes.RefreshToken})
+	require.Equal(t, http.StatusUnauthorized, status)
+
+	status, _ = sendRequest(t, "POST", "/account/login", "", loginRequest{Username: loginRes.Account.Username, Password: Password})
+	require.Equal(t, http.StatusUnauthorized, status)
+	login(t, loginRes.Account.Username, newPassword)
+}
@@ -0,0 +1,227 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"simple-bank-system/db/pkg"
+	"simple-bank-system/db/services"
+	"simple-bank-system/token"
+	"simple-bank-system/util"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+type createPayeeRequest struct {
+	Nickname string `json:"nickname" validate:"required,max=50"`
+	// wallet number or account number (primary wallet) of the recipient
+	Number int64 `json:"number" validate:"required,min=1010000000,max=1019999999"`
+}
+
+type payeeResponse struct {
+	ID           int64
+	Nickname     string
+	WalletNumber int64
+	DisplayName  string
+	// true while large transfers to the payee aren't allowed yet
+	CoolingOff bool
+	CreatedAt  time.Time
+}
+
+type lookupPayeeResponse struct {
+	WalletNumber int64
+	DisplayName  string
+}
+
+func (server *Server) newPayeeResponse(payee pkg.Payee) payeeResponse {
+	return payeeResponse{
+		ID:           payee.ID,
+		Nickname:     payee.Nickname,
+		WalletNumber: payee.WalletNumber,
+		DisplayName:  payee.DisplayName,
+		CoolingOff:   services.PayeeInCoolingOff(payee, server.payeeCoolingOff, time.Now()),
+		CreatedAt:    payee.CreatedAt,
+	}
+}
+
+// lookupPayee return the masked name of the recipient, so the user can confirm it before saving the payee.
+func (server *Server) lookupPayee(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	number, err := strconv.ParseInt(ps.ByName("number"), 10, 64)
+	if err != nil {
+		http.Error(w, "failed to convert url parameter to int", (http.StatusInternalServerError))
+		return
+	}
+
+	name, err := server.store.GetRecipientName(server.ctx, number)
+	if err != nil {
+		if err == util.ErrNotExist {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Can't get the recipient", (http.StatusInternalServerError))
+		json.NewEncoder(w).Encode(err.Error())
+		return
+	}
+
+	w.Header().Add("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(lookupPayeeResponse{WalletNumber: number, DisplayName: name})
+}
+
+func (server *Server) createPayee(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	var req createPayeeRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		http.Error(w, "failed to decode input data", (http.StatusInternalServerError))
+		json.NewEncoder(w).Encode(err.Error())
+		return
+	}
+
+	err = validate.Struct(req)
+	if err != nil {
+		http.Error(w, "Format input data is wrong", (http.StatusBadRequest))
+		json.NewEncoder(w).Encode(err.Error())
+		return
+	}
+
+	name, err := server.store.GetRecipientName(server.ctx, req.Number)
+	if err != nil {
+		if err == util.ErrNotExist {
+			http.Error(w, "recipient wallet doesn't exist", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Can't get the recipient", (http.StatusInternalServerError))
+		json.NewEncoder(w).Encode(err.Error())
+		return
+	}
+
+	authPayload := r.Context().Value("authPayloadKey").(*token.Payload)
+	payee, err := server.store.CreatePayee(server.ctx, services.CreatePayeeParams{
+		AccountID:    authPayload.AccountID,
+		Nickname:     req.Nickname,
+		WalletNumber: req.Number,
+		DisplayName:  name,
+	})
+	if err != nil {
+		switch err {
+		case util.ErrNotExist, util.ErrDuplicate:
+			http.Error(w, err.Error(), 403)
+			return
+		}
+		http.Error(w, "failed to pass data into database", (http.StatusInternalServerError))
+		json.NewEncoder(w).Encode(err.Error())
+		return
+	}
+
+	auditTarget(r, "payee_id", payee.ID)
+	w.Header().Add("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(server.newPayeeResponse(*payee))
+}
+
+func (server *Server) listPayees(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authPayload := r.Context().Value("authPayloadKey").(*token.Payload)
+
+	payees, err := server.store.ListPayees(server.ctx, authPayload.AccountID)
+	if err != nil {
+		http.Error(w, "Failed to get List", (http.StatusInternalServerError))
+		json.NewEncoder(w).Encode(err.Error())
+		return
+	}
+
+	response := []payeeResponse{}
+	for _, payee := range payees {
+		response = append(response, server.newPayeeResponse(payee))
+	}
+
+	w.Header().Add("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
+
+func (server *Server) deletePayee(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	id, err := strconv.ParseInt(ps.ByName("id"), 10, 64)
+	if err != nil {
+		http.Error(w, "failed to convert url parameter to int", (http.StatusInternalServerError))
+		return
+	}
+
+	authPayload := r.Context().Value("authPayloadKey").(*token.Payload)
+	err = server.store.DeletePayee(server.ctx, id, authPayload.AccountID)
+	if err != nil {
+		if err == util.ErrDeleteFailed {
+			http.Error(w, err.Error(), 403)
+			return
+		}
+		http.Error(w, "Failed delete payee from database", http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(err.Error())
+		return
+	}
+
+	w.Header().Add("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode("Payee deleted!")
+}
+
+// payeeWalletNumber resolve 'payee_id' of a transfer to the wallet number of the payee.
+func (server *Server) payeeWalletNumber(w http.ResponseWriter, r *http.Request, payeeID int64) (int64, bool) {
+	authPayload := r.Context().Value("authPayloadKey").(*token.Payload)
+
+	payee, err := server.store.GetPayee(server.ctx, payeeID, authPayload.AccountID)
+	if err != nil {
+		if err == util.ErrNotExist {
+			http.Error(w, "payee doesn't exist", http.StatusNotFound)
+			return 0, false
+		}
+		http.Error(w, "Can't get the payee", (http.StatusInternalServerError))
+		json.NewEncoder(w).Encode(err.Error())
+		return 0, false
+	}
+
+	return payee.WalletNumber, true
+}
+
+/*
+ * recipientAllowed check the destination of a transfer against the payee cooling-off, whatever way the
+ * recipient is given. Transfers above the cooling-off limit are only allowed to the wallets of the sender
+ * and of the owner of 'from', and to saved payees that finished the cooling-off.
+ */
+func (server *Server) recipientAllowed(ctx context.Context, authPayload *token.Payload, from, to *pkg.Wallet, amount int64) error {
+	if amount <= server.payeeCoolingOffLimit {
+		return nil
+	}
+	if to.AccountID == authPayload.AccountID || to.AccountID == from.AccountID {
+		return nil
+	}
+
+	payee, err := server.store.GetPayeeByWallet(ctx, authPayload.AccountID, to.WalletNumber)
+	if err == util.ErrNotExist {
+		return util.ErrNotPayee
+	}
+	if err != nil {
+		return err
+	}
+	if services.PayeeInCoolingOff(*payee, server.payeeCoolingOff, time.Now()) {
+		return util.ErrPayeeCoolingOff
+	}
+	return nil
+}
+
+// checkRecipient write the error response and return false when 'recipientAllowed' refuse the transfer.
+func (server *Server) checkRecipient(w http.ResponseWriter, r *http.Request, from, to *pkg.Wallet, amount int64) bool {
+	authPayload := r.Context().Value("authPayloadKey").(*token.Payload)
+	err := server.recipientAllowed(r.Context(), authPayload, from, to, amount)
+	if err == nil {
+		return true
+	}
+	if err == util.ErrNotPayee || err == util.ErrPayeeCoolingOff {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return false
+	}
+	http.Error(w, "Can't check the recipient", (http.StatusInternalServerError))
+	json.NewEncoder(w).Encode(err.Error())
+	return false
+}
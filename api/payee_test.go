@@ -0,0 +1,66 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTransferToNewRecipient(t *testing.T) {
+	sender := loginAccount(t)
+	recipient := loginAccount(t)
+	limit := testConfig.PayeeCoolingOffLimit
+
+	// a recipient that isn't a payee is limited too
+	status, body := transferMoney(t, sender.AccessToken, sender.Account.AccountNumber, recipient.Account.AccountNumber, limit+1)
+	assert.Equal(t, http.StatusForbidden, status, string(body))
+
+	status, body = sendRequest(t, "POST", "/payees", sender.AccessToken, createPayeeRequest{
+		Nickname: "friend",
+		Number:   recipient.Account.AccountNumber,
+	})
+	require.Equal(t, http.StatusOK, status, string(body))
+	var payee payeeResponse
+	require.NoError(t, json.Unmarshal(body, &payee))
+	require.True(t, payee.CoolingOff)
+
+	// the new payee is limited with 'payee_id' and with the wallet number
+	status, body = sendRequest(t, "POST", "/transfer", sender.AccessToken, transferRequest{
+		FromWalletNumber: sender.Account.AccountNumber,
+		PayeeID:          payee.ID,
+		Amount:           limit + 1,
+		Currency:         "IDR",
+	})
+	assert.Equal(t, http.StatusForbidden, status, string(body))
+
+	status, body = transferMoney(t, sender.AccessToken, sender.Account.AccountNumber, recipient.Account.AccountNumber, limit+1)
+	assert.Equal(t, http.StatusForbidden, status, string(body))
+
+	status, body = transferMoney(t, sender.AccessToken, sender.Account.AccountNumber, recipient.Account.AccountNumber, 1000)
+	assert.Equal(t, http.StatusOK, status, string(body))
+}
+
+func TestPayPaymentRequestNewRecipient(t *testing.T) {
+	requester := loginAccount(t)
+	payer := loginAccount(t)
+	amount := testConfig.PayeeCoolingOffLimit + 1
+
+	status, body := sendRequest(t, "POST", "/payment-requests", requester.AccessToken, createPaymentRequestRequest{
+		WalletNumber: requester.Account.AccountNumber,
+		Amount:       amount,
+		ExpiresAt:    time.Now().AddDate(0, 0, 7).Format(time.DateOnly),
+		Payers:       []paymentPayerRequest{{AccountNumber: payer.Account.AccountNumber}},
+	})
+	require.Equal(t, http.StatusOK, status, string(body))
+	var request paymentRequestResponse
+	require.NoError(t, json.Unmarshal(body, &request))
+
+	path := "/payment-requests/" + strconv.FormatInt(request.ID, 10) + "/pay"
+	status, body = sendRequest(t, "POST", path, payer.AccessToken, payPaymentRequestRequest{WalletNumber: payer.Account.AccountNumber})
+	assert.Equal(t, http.StatusForbidden, status, string(body))
+}
@@ -0,0 +1,335 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"net/http"
+	"time"
+
+	"simple-bank-system/db/pkg"
+	"simple-bank-system/db/services"
+	"simple-bank-system/iso20022"
+	"simple-bank-system/token"
+	"simple-bank-system/util"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+// Max size of an uploaded pain.001 file
+const maxPaymentFileSize = 10 << 20
+
+// Header of the TOTP code of a payment file, the body is the file
+const paymentFileTOTPHeader = "X-TOTP-Code"
+
+// An upload claim its file for 'paymentFileLease', the claim is extended while the file is executed.
+// When the claim of an interrupted upload expire, the file is resumed by the next upload.
+const paymentFileLease = 5 * time.Minute
+
+/*
+ * uploadPaymentFile execute the ISO 20022 pain.001 file of the body and answer with the pain.002 status
+ * report. Every transaction is 1 'TransferTx', so a rejected transaction doesn't stop the others.
+ * A file that's uploaded again with the same message id isn't executed twice, it get the first report.
+ * The result of every transaction is saved as it runs, so when an upload is interrupted the same file
+ * uploaded again (after the lease) resume it, the transactions that already have a result are skipped.
+ * A file whose total is from 'twoFactorTransferAmount' need a TOTP code in the 'X-TOTP-Code' header
+ * when the account has 2FA, the code is checked once for the whole file.
+ */
+func (server *Server) uploadPaymentFile(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	file, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxPaymentFileSize))
+	if err != nil {
+		http.Error(w, "Format input data is wrong", (http.StatusBadRequest))
+		json.NewEncoder(w).Encode(err.Error())
+		return
+	}
+	doc, err := iso20022.ParsePain001(bytes.NewReader(file))
+	if err != nil {
+		http.Error(w, "Format input data is wrong", (http.StatusBadRequest))
+		json.NewEncoder(w).Encode(err.Error())
+		return
+	}
+
+	if !server.checkTransferTOTP(w, r, paymentFileTotal(doc), r.Header.Get(paymentFileTOTPHeader)) {
+		return
+	}
+
+	authPayload := r.Context().Value("authPayloadKey").(*token.Payload)
+	hash := sha256.Sum256(file)
+	fileHash := hex.EncodeToString(hash[:])
+	now := time.Now()
+	fileID, err := server.store.CreatePaymentFile(server.ctx, services.CreatePaymentFileParams{
+		AccountID:    authPayload.AccountID,
+		MsgID:        doc.GrpHdr.MsgID,
+		FileHash:     fileHash,
+		ClaimedUntil: now.Add(paymentFileLease),
+	})
+	if err == util.ErrDuplicate {
+		claim, err := server.store.ClaimPaymentFileTx(server.ctx, services.ClaimPaymentFileParams{
+			AccountID:    authPayload.AccountID,
+			MsgID:        doc.GrpHdr.MsgID,
+			FileHash:     fileHash,
+			Now:          now,
+			ClaimedUntil: now.Add(paymentFileLease),
+		})
+		if err == util.ErrPaymentFileRunning || err == util.ErrPaymentFileChanged {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		if err != nil {
+			http.Error(w, "Can't get the payment file", http.StatusInternalServerError)
+			return
+		}
+		if claim.Report != "" {
+			w.Header().Add("Content-Type", "application/xml")
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte(claim.Report))
+			return
+		}
+		// the upload was interrupted, it's resumed
+		fileID = claim.ID
+	} else if err != nil {
+		http.Error(w, "failed to pass data into database", (http.StatusInternalServerError))
+		json.NewEncoder(w).Encode(err.Error())
+		return
+	}
+
+	run, err := server.newPaymentFileRun(fileID)
+	if err != nil {
+		http.Error(w, "Can't get the payment file", http.StatusInternalServerError)
+		return
+	}
+	for i, pmt := range doc.Payments {
+		server.executePayment(authPayload, run, i, pmt)
+	}
+
+	// the report is made of the saved results, some of them may be from the interrupted upload
+	results, err := server.store.ListPaymentFileTransactions(server.ctx, fileID)
+	if err != nil {
+		http.Error(w, "Can't get the payment file", http.StatusInternalServerError)
+		return
+	}
+	var report bytes.Buffer
+	msgID := fmt.Sprintf("PSR%d", fileID)
+	err = iso20022.NewPain002(msgID, doc, paymentFileStatuses(doc, results), time.Now()).Write(&report)
+	if err != nil {
+		http.Error(w, "Failed to write the status report", http.StatusInternalServerError)
+		return
+	}
+
+	err = server.store.SetPaymentFileReport(server.ctx, fileID, report.String())
+	if err != nil {
+		log.Println("--- (err) save payment file report:", err)
+	}
+
+	w.Header().Add("Content-Type", "application/xml")
+	w.WriteHeader(http.StatusOK)
+	w.Write(report.Bytes())
+}
+
+// paymentFileKey is the position of a transaction in the file.
+type paymentFileKey struct {
+	payment     int
+	transaction int
+}
+
+// paymentFileRun is the execution of a file by 1 upload.
+type paymentFileRun struct {
+	fileID int64
+	// transactions that already have a result
+	done      map[paymentFileKey]bool
+	claimedAt time.Time
+}
+
+func (server *Server) newPaymentFileRun(fileID int64) (*paymentFileRun, error) {
+	results, err := server.store.ListPaymentFileTransactions(server.ctx, fileID)
+	if err != nil {
+		return nil, err
+	}
+
+	run := &paymentFileRun{fileID: fileID, done: map[paymentFileKey]bool{}, claimedAt: time.Now()}
+	for _, result := range results {
+		run.done[paymentFileKey{result.PaymentIndex, result.TransactionIndex}] = true
+	}
+	return run, nil
+}
+
+// extendClaim extend the claim of the file when half of the lease is gone.
+func (server *Server) extendClaim(run *paymentFileRun) {
+	if time.Since(run.claimedAt) < paymentFileLease/2 {
+		return
+	}
+	run.claimedAt = time.Now()
+	if err := server.store.ExtendPaymentFileClaim(server.ctx, run.fileID, run.claimedAt.Add(paymentFileLease)); err != nil {
+		log.Println("--- (err) extend payment file claim:", err)
+	}
+}
+
+// paymentFileStatuses return the status of every transaction of the file from the saved results.
+func paymentFileStatuses(doc *iso20022.Pain001, results []services.PaymentFileTransaction) []iso20022.PaymentStatus {
+	saved := map[paymentFileKey]services.PaymentFileTransaction{}
+	for _, result := range results {
+		saved[paymentFileKey{result.PaymentIndex, result.TransactionIndex}] = result
+	}
+
+	var payments []iso20022.PaymentStatus
+	for i, pmt := range doc.Payments {
+		payment := iso20022.PaymentStatus{PmtInfID: pmt.PmtInfID}
+		for j, tx := range pmt.Transactions {
+			status := iso20022.TransactionStatus{
+				InstrID:    tx.InstrID,
+				EndToEndID: tx.EndToEndID,
+				Status:     iso20022.StatusRejected,
+				Reason:     iso20022.ReasonNarrative,
+				Info:       "transfer failed",
+			}
+			if result, ok := saved[paymentFileKey{i, j}]; ok {
+				status.Status, status.Reason, status.Info = result.Status, result.Reason, result.Info
+			}
+			payment.Transactions = append(payment.Transactions, status)
+		}
+		payments = append(payments, payment)
+	}
+	return payments
+}
+
+// paymentFileTotal is the sum of the valid amounts of every transaction of the file, whatever their debtor is.
+// It stop at math.MaxInt64, an overflow can't make the total small.
+func paymentFileTotal(doc *iso20022.Pain001) int64 {
+	var total int64
+	for _, pmt := range doc.Payments {
+		for _, tx := range pmt.Transactions {
+			amount, err := tx.WholeAmount()
+			if err != nil || amount <= 0 {
+				continue
+			}
+			if amount > math.MaxInt64-total {
+				return math.MaxInt64
+			}
+			total += amount
+		}
+	}
+	return total
+}
+
+// executePayment run every transaction of the payment information from the debtor wallet and save their results,
+// the transactions that already have a result are skipped.
+func (server *Server) executePayment(authPayload *token.Payload, run *paymentFileRun, index int, pmt iso20022.PaymentInformation) {
+	wallet, reason, info := server.debtorWallet(authPayload, pmt.DbtrAcct)
+	for i, tx := range pmt.Transactions {
+		key := paymentFileKey{index, i}
+		if run.done[key] {
+			continue
+		}
+		server.extendClaim(run)
+
+		record := services.PaymentFileTransaction{
+			FileID:           run.fileID,
+			PaymentIndex:     index,
+			TransactionIndex: i,
+			Status:           iso20022.StatusRejected,
+			Reason:           reason,
+			Info:             info,
+		}
+		if wallet != nil {
+			record.Reason, record.Info = server.executeCreditTransfer(server.ctx, authPayload, wallet, tx, record)
+			if record.Reason == "" {
+				// saved with the transfer
+				run.done[key] = true
+				continue
+			}
+		}
+
+		if err := server.store.SavePaymentFileTransaction(server.ctx, record); err != nil {
+			log.Println("--- (err) save payment file transaction:", err)
+		}
+		run.done[key] = true
+	}
+}
+
+// debtorWallet return the wallet of the debtor account, or the reason why the account can't pay.
+func (server *Server) debtorWallet(authPayload *token.Payload, account iso20022.Account) (*pkg.Wallet, string, string) {
+	number, err := account.Number()
+	if err != nil {
+		return nil, iso20022.ReasonIncorrectAccount, err.Error()
+	}
+
+	wallet, err := server.store.GetWalletByNumber(server.ctx, number)
+	if err != nil {
+		return nil, iso20022.ReasonIncorrectAccount, "debtor account doesn't exist"
+	}
+	if account.Ccy != "" && iso20022.WalletCurrency(account.Ccy) != wallet.Currency {
+		return nil, iso20022.ReasonWrongCurrency, "debtor account currency is " + wallet.Currency
+	}
+
+	if !authPayload.AllowsWallet(wallet.WalletNumber) {
+		return nil, iso20022.ReasonForbidden, "your token isn't allowed to use the debtor account"
+	}
+	role, err := server.store.GetWalletRole(server.ctx, *wallet, authPayload.AccountID)
+	if err != nil || !services.WalletRoleAllows(role, services.WalletRoleSpender) {
+		return nil, iso20022.ReasonForbidden, "debtor account doesn't belong to you"
+	}
+
+	return wallet, "", ""
+}
+
+// executeCreditTransfer move the money of 1 transaction and save its settled result with the transfer,
+// it return the reason code and information when it's rejected.
+func (server *Server) executeCreditTransfer(ctx context.Context, authPayload *token.Payload, wallet *pkg.Wallet, tx iso20022.CreditTransfer, record services.PaymentFileTransaction) (string, string) {
+	amount, err := tx.WholeAmount()
+	if err != nil || amount <= 0 {
+		return iso20022.ReasonInvalidAmount, "amount must be a positive whole number"
+	}
+	if iso20022.WalletCurrency(tx.Amount.Ccy) != wallet.Currency {
+		return iso20022.ReasonWrongCurrency, "debtor account currency is " + wallet.Currency
+	}
+
+	number, err := tx.CdtrAcct.Number()
+	if err != nil {
+		return iso20022.ReasonIncorrectAccount, err.Error()
+	}
+	creditor, err := server.store.GetWalletByNumber(ctx, number)
+	if err != nil {
+		return iso20022.ReasonIncorrectAccount, "creditor account doesn't exist"
+	}
+	if creditor.Currency != wallet.Currency {
+		return iso20022.ReasonWrongCurrency, "creditor account currency is " + creditor.Currency
+	}
+	err = server.recipientAllowed(ctx, authPayload, wallet, creditor, amount)
+	if err == util.ErrNotPayee || err == util.ErrPayeeCoolingOff {
+		return iso20022.ReasonForbidden, err.Error()
+	}
+	if err != nil {
+		log.Println("--- (err) payment file recipient:", err)
+		return iso20022.ReasonNarrative, "transfer failed"
+	}
+
+	record.Status = iso20022.StatusSettled
+	transfer, err := server.store.PaymentFileTransferTx(ctx, services.TransferTxParams{
+		AccountID:        wallet.AccountID,
+		WalletID:         wallet.ID,
+		FromWalletNumber: wallet.WalletNumber,
+		ToWalletNumber:   creditor.WalletNumber,
+		Amount:           amount,
+	}, record)
+	switch {
+	case err == nil:
+		server.notifyGoalReached(ctx, transfer.ToWallet, transfer.GoalReached)
+		return "", ""
+	case err == util.ErrDuplicate:
+		// executed by another upload of the file, its result is already saved
+		return "", ""
+	case err == util.ErrWalletLocked, err == util.ErrWalletFrozen:
+		return iso20022.ReasonForbidden, err.Error()
+	case services.IsInsufficientFunds(err):
+		return iso20022.ReasonInsufficientFunds, "insufficient funds"
+	}
+
+	log.Println("--- (err) payment file transfer:", err)
+	return iso20022.ReasonNarrative, "transfer failed"
+}
@@ -0,0 +1,205 @@
+package api
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"simple-bank-system/db/services"
+	"simple-bank-system/iso20022"
+	"simple-bank-system/util"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testCreditTransfer struct {
+	endToEndID string
+	creditor   int64
+	amount     string
+}
+
+type testPayment struct {
+	debtor       int64
+	transactions []testCreditTransfer
+}
+
+// newPain001 write a pain.001 file with the payments, all amounts are in IDR.
+func newPain001(msgID string, payments []testPayment) []byte {
+	var body strings.Builder
+	var total int
+	for i, pmt := range payments {
+		fmt.Fprintf(&body, `<PmtInf><PmtInfId>PMT-%d</PmtInfId><PmtMtd>TRF</PmtMtd><NbOfTxs>%d</NbOfTxs><ReqdExctnDt>%s</ReqdExctnDt>
+<DbtrAcct><Id><Othr><Id>%d</Id></Othr></Id><Ccy>IDR</Ccy></DbtrAcct>`, i+1, len(pmt.transactions), time.Now().Format(time.DateOnly), pmt.debtor)
+		for _, tx := range pmt.transactions {
+			fmt.Fprintf(&body, `<CdtTrfTxInf><PmtId><EndToEndId>%s</EndToEndId></PmtId><Amt><InstdAmt Ccy="IDR">%s</InstdAmt></Amt>
+<CdtrAcct><Id><Othr><Id>%d</Id></Othr></Id></CdtrAcct></CdtTrfTxInf>`, tx.endToEndID, tx.amount, tx.creditor)
+		}
+		body.WriteString("</PmtInf>")
+		total += len(pmt.transactions)
+	}
+
+	return []byte(fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8"?>
+<Document xmlns="%s"><CstmrCdtTrfInitn><GrpHdr><MsgId>%s</MsgId><CreDtTm>%s</CreDtTm><NbOfTxs>%d</NbOfTxs></GrpHdr>%s</CstmrCdtTrfInitn></Document>`,
+		iso20022.Pain001Namespace, msgID, time.Now().UTC().Format("2006-01-02T15:04:05Z"), total, body.String()))
+}
+
+// uploadPaymentFile send the pain.001 file and return the status code and the body of the response.
+func uploadPaymentFile(t *testing.T, accessToken string, file []byte) (int, []byte) {
+	return uploadPaymentFileWithCode(t, accessToken, "", file)
+}
+
+// uploadPaymentFileWithCode send the pain.001 file with a TOTP code.
+func uploadPaymentFileWithCode(t *testing.T, accessToken, totpCode string, file []byte) (int, []byte) {
+	req, err := http.NewRequest("POST", baseURL+"/payment-files", bytes.NewReader(file))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/xml")
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	if totpCode != "" {
+		req.Header.Set(paymentFileTOTPHeader, totpCode)
+	}
+
+	client := http.Client{Timeout: 10 * time.Second}
+	res, err := client.Do(req)
+	require.NoError(t, err, "can't send request")
+	defer res.Body.Close()
+
+	resBody, err := io.ReadAll(res.Body)
+	require.NoError(t, err)
+	return res.StatusCode, resBody
+}
+
+// transactionStatus return the status and the reason code of every transaction of the pain.002 report by EndToEndId.
+func transactionStatus(t *testing.T, report []byte) map[string][2]string {
+	var doc iso20022.Pain002
+	require.NoError(t, xml.Unmarshal(report, &doc))
+
+	res := map[string][2]string{}
+	for _, pmt := range doc.OrgnlPmtInfAndSts {
+		for _, tx := range pmt.TxInfAndSts {
+			var reason string
+			if tx.StsRsnInf != nil {
+				reason = tx.StsRsnInf.Code
+			}
+			res[tx.OrgnlEndToEndID] = [2]string{tx.TxSts, reason}
+		}
+	}
+	return res
+}
+
+func TestUploadPaymentFile(t *testing.T) {
+	accRes := loginAccount(t)
+	recipient := loginAccount(t)
+	stranger := loginAccount(t)
+	limit := testConfig.PayeeCoolingOffLimit
+
+	msgID := "TEST-" + util.RandomString(20)
+	file := newPain001(msgID, []testPayment{
+		{
+			debtor: accRes.Account.AccountNumber,
+			transactions: []testCreditTransfer{
+				{endToEndID: "SETTLED", creditor: recipient.Account.AccountNumber, amount: "1000"},
+				{endToEndID: "NO-CREDITOR", creditor: 1010000001, amount: "1000"},
+				{endToEndID: "FRACTION", creditor: recipient.Account.AccountNumber, amount: "10.5"},
+				{endToEndID: "NOT-PAYEE", creditor: recipient.Account.AccountNumber, amount: strconv.FormatInt(limit+1, 10)},
+				{endToEndID: "NO-FUNDS", creditor: recipient.Account.AccountNumber, amount: strconv.FormatInt(limit, 10)},
+			},
+		},
+		{
+			debtor: stranger.Account.AccountNumber,
+			transactions: []testCreditTransfer{
+				{endToEndID: "OTHER-DEBTOR", creditor: recipient.Account.AccountNumber, amount: "1000"},
+			},
+		},
+	})
+
+	status, report := uploadPaymentFile(t, accRes.AccessToken, file)
+	require.Equal(t, http.StatusOK, status, string(report))
+
+	statuses := transactionStatus(t, report)
+	assert.Equal(t, [2]string{iso20022.StatusSettled, ""}, statuses["SETTLED"])
+	assert.Equal(t, [2]string{iso20022.StatusRejected, iso20022.ReasonIncorrectAccount}, statuses["NO-CREDITOR"])
+	assert.Equal(t, [2]string{iso20022.StatusRejected, iso20022.ReasonInvalidAmount}, statuses["FRACTION"])
+	assert.Equal(t, [2]string{iso20022.StatusRejected, iso20022.ReasonForbidden}, statuses["NOT-PAYEE"])
+	assert.Equal(t, [2]string{iso20022.StatusRejected, iso20022.ReasonInsufficientFunds}, statuses["NO-FUNDS"])
+	assert.Equal(t, [2]string{iso20022.StatusRejected, iso20022.ReasonForbidden}, statuses["OTHER-DEBTOR"])
+
+	// the same file isn't executed twice, it get the first report
+	status, again := uploadPaymentFile(t, accRes.AccessToken, file)
+	require.Equal(t, http.StatusOK, status, string(again))
+	assert.Equal(t, string(report), string(again))
+
+	status, body := uploadPaymentFile(t, accRes.AccessToken, []byte("<Document>"))
+	assert.Equal(t, http.StatusBadRequest, status, string(body))
+}
+
+func TestResumePaymentFile(t *testing.T) {
+	accRes := loginAccount(t)
+	recipient := loginAccount(t)
+	account, err := testStore.GetAccountByNumber(testCtx, accRes.Account.AccountNumber)
+	require.NoError(t, err)
+
+	msgID := "TEST-" + util.RandomString(20)
+	file := newPain001(msgID, []testPayment{
+		{
+			debtor: accRes.Account.AccountNumber,
+			transactions: []testCreditTransfer{
+				{endToEndID: "FIRST", creditor: recipient.Account.AccountNumber, amount: "1000"},
+				{endToEndID: "SECOND", creditor: recipient.Account.AccountNumber, amount: "1000"},
+			},
+		},
+	})
+	hash := sha256.Sum256(file)
+
+	// an upload that stopped after the first transaction, its claim is still running
+	fileID, err := testStore.CreatePaymentFile(testCtx, services.CreatePaymentFileParams{
+		AccountID:    account.ID,
+		MsgID:        msgID,
+		FileHash:     hex.EncodeToString(hash[:]),
+		ClaimedUntil: time.Now().Add(time.Minute),
+	})
+	require.NoError(t, err)
+	err = testStore.SavePaymentFileTransaction(testCtx, services.PaymentFileTransaction{
+		FileID: fileID, PaymentIndex: 0, TransactionIndex: 0, Status: iso20022.StatusSettled,
+	})
+	require.NoError(t, err)
+
+	status, body := uploadPaymentFile(t, accRes.AccessToken, file)
+	require.Equal(t, http.StatusConflict, status, string(body))
+
+	// after the claim, only another file with the same MsgId is refused
+	_, err = testStore.ClaimPaymentFileTx(testCtx, services.ClaimPaymentFileParams{
+		AccountID: account.ID, MsgID: msgID, FileHash: hex.EncodeToString(hash[:]), Now: time.Now().Add(2 * time.Minute), ClaimedUntil: time.Now(),
+	})
+	require.NoError(t, err)
+	other := newPain001(msgID, []testPayment{
+		{
+			debtor:       accRes.Account.AccountNumber,
+			transactions: []testCreditTransfer{{endToEndID: "OTHER", creditor: recipient.Account.AccountNumber, amount: "5"}},
+		},
+	})
+	status, body = uploadPaymentFile(t, accRes.AccessToken, other)
+	require.Equal(t, http.StatusConflict, status, string(body))
+
+	before, err := testStore.GetWalletByNumber(testCtx, accRes.Account.AccountNumber)
+	require.NoError(t, err)
+
+	status, report := uploadPaymentFile(t, accRes.AccessToken, file)
+	require.Equal(t, http.StatusOK, status, string(report))
+	statuses := transactionStatus(t, report)
+	assert.Equal(t, [2]string{iso20022.StatusSettled, ""}, statuses["FIRST"])
+	assert.Equal(t, [2]string{iso20022.StatusSettled, ""}, statuses["SECOND"])
+
+	// only the second transaction was executed by the resume
+	after, err := testStore.GetWalletByNumber(testCtx, accRes.Account.AccountNumber)
+	require.NoError(t, err)
+	assert.Equal(t, before.Balance-1000, after.Balance)
+}
@@ -0,0 +1,438 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"simple-bank-system/db/pkg"
+	"simple-bank-system/db/services"
+	"simple-bank-system/notification"
+	"simple-bank-system/token"
+	"simple-bank-system/util"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+type paymentPayerRequest struct {
+	AccountNumber int64 `json:"account_number" validate:"required,min=1010000000,max=1019999999"`
+	// 0 for every payer -> the amount is split evenly
+	Amount int64 `json:"amount" validate:"min=0"`
+}
+
+type createPaymentRequestRequest struct {
+	// wallet of the requester that receive the payment
+	WalletNumber int64                 `json:"wallet_number" validate:"required,min=1010000000,max=1019999999"`
+	Amount       int64                 `json:"amount" validate:"required,gt=0"`
+	Memo         string                `json:"memo" validate:"max=255"`
+	ExpiresAt    string                `json:"expires_at" validate:"required"`
+	Payers       []paymentPayerRequest `json:"payers" validate:"required,min=1,dive"`
+}
+
+type payPaymentRequestRequest struct {
+	WalletNumber int64 `json:"wallet_number" validate:"required,min=1010000000,max=1019999999"`
+	// needed from 'twoFactorTransferAmount' when the account has 2FA
+	TOTPCode string `json:"totp_code"`
+}
+
+type paymentShareResponse struct {
+	AccountNumber int64
+	Amount        int64
+	Status        string
+	UpdatedAt     time.Time
+}
+
+type paymentRequestResponse struct {
+	ID           int64
+	WalletNumber int64
+	Amount       int64
+	Currency     string
+	Memo         string
+	ExpiresAt    string
+	Expired      bool
+	PaidAmount   int64
+	CreatedAt    time.Time
+	Shares       []paymentShareResponse
+}
+
+type payPaymentRequestResponse struct {
+	Request  paymentRequestResponse
+	Transfer transferTxResponse
+}
+
+func newPaymentRequestResponse(request *pkg.PaymentRequest) paymentRequestResponse {
+	res := paymentRequestResponse{
+		ID:           request.ID,
+		WalletNumber: request.WalletNumber,
+		Amount:       request.Amount,
+		Currency:     request.Currency,
+		Memo:         request.Memo,
+		ExpiresAt:    request.ExpiresAt.Format(time.DateOnly),
+		Expired:      services.PaymentRequestExpired(*request, time.Now()),
+		PaidAmount:   request.PaidAmount,
+		CreatedAt:    request.CreatedAt,
+		Shares:       []paymentShareResponse{},
+	}
+	for _, share := range request.Shares {
+		res.Shares = append(res.Shares, paymentShareResponse{
+			AccountNumber: share.AccountNumber,
+			Amount:        share.Amount,
+			Status:        share.Status,
+			UpdatedAt:     share.UpdatedAt,
+		})
+	}
+	return res
+}
+
+// shares() return the amount of every payer, the amount is split evenly when no payer has an amount.
+func (req createPaymentRequestRequest) shares() ([]int64, error) {
+	var total int64
+	for _, payer := range req.Payers {
+		total += payer.Amount
+	}
+	if total == 0 {
+		return services.SplitAmount(req.Amount, len(req.Payers)), nil
+	}
+
+	amounts := make([]int64, len(req.Payers))
+	for i, payer := range req.Payers {
+		if payer.Amount <= 0 {
+			return nil, fmt.Errorf("amount of payer %d must be greater than 0", payer.AccountNumber)
+		}
+		amounts[i] = payer.Amount
+	}
+	if total != req.Amount {
+		return nil, fmt.Errorf("total of payer amounts %d doesn't match amount %d", total, req.Amount)
+	}
+	return amounts, nil
+}
+
+func (server *Server) createPaymentRequest(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	var req createPaymentRequestRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		http.Error(w, "failed to decode input data", (http.StatusInternalServerError))
+		json.NewEncoder(w).Encode(err.Error())
+		return
+	}
+
+	err = validate.Struct(req)
+	if err != nil {
+		http.Error(w, "Format input data is wrong", (http.StatusBadRequest))
+		json.NewEncoder(w).Encode(err.Error())
+		return
+	}
+
+	expiresAt, err := time.Parse(time.DateOnly, req.ExpiresAt)
+	if err != nil || expiresAt.Before(time.Now().Truncate(24*time.Hour)) {
+		http.Error(w, "expires_at must be a date (YYYY-MM-DD) that isn't in the past", (http.StatusBadRequest))
+		return
+	}
+
+	amounts, err := req.shares()
+	if err != nil {
+		http.Error(w, "Format input data is wrong", (http.StatusBadRequest))
+		json.NewEncoder(w).Encode(err.Error())
+		return
+	}
+
+	wallet, err := server.store.GetWalletByNumber(server.ctx, req.WalletNumber)
+	if err != nil {
+		if err == util.ErrNotExist {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Can't get wallet", (http.StatusInternalServerError))
+		json.NewEncoder(w).Encode(err.Error())
+		return
+	}
+	if !server.authorizeWallet(w, r, wallet, services.WalletRoleSpender) {
+		return
+	}
+
+	authPayload := r.Context().Value("authPayloadKey").(*token.Payload)
+	arg := services.CreatePaymentRequestParams{
+		RequesterID: authPayload.AccountID,
+		WalletID:    wallet.ID,
+		Memo:        req.Memo,
+		ExpiresAt:   expiresAt,
+	}
+	for i, payer := range req.Payers {
+		account, err := server.store.GetAccountByNumber(server.ctx, payer.AccountNumber)
+		if err != nil {
+			if err == util.ErrNotExist {
+				http.Error(w, fmt.Sprintf("payer %d doesn't exist", payer.AccountNumber), http.StatusNotFound)
+				return
+			}
+			http.Error(w, "Can't get payer account", (http.StatusInternalServerError))
+			json.NewEncoder(w).Encode(err.Error())
+			return
+		}
+		if account.ID == authPayload.AccountID {
+			http.Error(w, "you can't request payment from yourself", http.StatusBadRequest)
+			return
+		}
+		arg.Shares = append(arg.Shares, services.PaymentShareParams{PayerID: account.ID, Amount: amounts[i]})
+	}
+
+	request, err := server.store.CreatePaymentRequestTx(server.ctx, arg)
+	if err != nil {
+		switch err {
+		case util.ErrAccUser, util.ErrDuplicate:
+			http.Error(w, err.Error(), 403)
+			return
+		}
+		http.Error(w, "failed to pass data into database", (http.StatusInternalServerError))
+		json.NewEncoder(w).Encode(err.Error())
+		return
+	}
+
+	for _, share := range request.Shares {
+		err = server.notifier.Notify(r.Context(), notification.Event{
+			AccountID: share.PayerID,
+			Type:      notification.EventPaymentRequest,
+			Message:   fmt.Sprintf("You're asked to pay %d %s: %s", share.Amount, request.Currency, request.Memo),
+			Data: map[string]interface{}{
+				"request_id": request.ID,
+				"amount":     share.Amount,
+				"expires_at": request.ExpiresAt.Format(time.DateOnly),
+			},
+			CreatedAt: request.CreatedAt,
+		})
+		if err != nil {
+			log.Println("--- (err) notify payment request:", err)
+		}
+	}
+
+	auditTarget(r, "payment_request_id", request.ID)
+	w.Header().Add("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(newPaymentRequestResponse(request))
+}
+
+// listPaymentRequests return requests made by the account, or with 'direction=incoming' the
+// requests that wait for the account to pay.
+func (server *Server) listPaymentRequests(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	var req listWalletsRequest
+	var err error
+
+	req.PageID, err = strconv.Atoi(r.URL.Query().Get("page_id"))
+	if err != nil {
+		http.Error(w, "Failed convert page_id query to int", (http.StatusInternalServerError))
+		return
+	}
+	req.PageSize, err = strconv.Atoi(r.URL.Query().Get("page_size"))
+	if err != nil {
+		http.Error(w, "Failed convert page_size query to int", (http.StatusInternalServerError))
+		return
+	}
+
+	err = validate.Struct(req)
+	if err != nil {
+		http.Error(w, "Format input data is wrong", (http.StatusBadRequest))
+		json.NewEncoder(w).Encode(err.Error())
+		return
+	}
+
+	authPayload := r.Context().Value("authPayloadKey").(*token.Payload)
+	requests, err := server.store.ListPaymentRequests(server.ctx, services.ListPaymentRequestsParams{
+		AccountID: authPayload.AccountID,
+		Incoming:  r.URL.Query().Get("direction") == "incoming",
+		Limit:     req.PageSize,
+		Offset:    (req.PageID - 1) * req.PageSize,
+	})
+	if err != nil {
+		http.Error(w, "Failed to get List", (http.StatusInternalServerError))
+		json.NewEncoder(w).Encode(err.Error())
+		return
+	}
+
+	response := []paymentRequestResponse{}
+	for i := range requests {
+		response = append(response, newPaymentRequestResponse(&requests[i]))
+	}
+
+	w.Header().Add("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
+
+// paymentRequestByParam read the request of the 'id' url parameter, only the requester and the payers can see it.
+func (server *Server) paymentRequestByParam(w http.ResponseWriter, r *http.Request, ps httprouter.Params) (*pkg.PaymentRequest, bool) {
+	id, err := strconv.ParseInt(ps.ByName("id"), 10, 64)
+	if err != nil {
+		http.Error(w, "failed to convert url parameter to int", (http.StatusInternalServerError))
+		return nil, false
+	}
+
+	request, err := server.store.GetPaymentRequest(server.ctx, id)
+	if err != nil {
+		if err == util.ErrNotExist {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return nil, false
+		}
+		http.Error(w, "Can't get payment request", (http.StatusInternalServerError))
+		json.NewEncoder(w).Encode(err.Error())
+		return nil, false
+	}
+
+	authPayload := r.Context().Value("authPayloadKey").(*token.Payload)
+	if request.RequesterID == authPayload.AccountID {
+		return request, true
+	}
+	for _, share := range request.Shares {
+		if share.PayerID == authPayload.AccountID {
+			return request, true
+		}
+	}
+
+	http.Error(w, "payment request doesn't belong to you", (http.StatusUnauthorized))
+	return nil, false
+}
+
+// pendingShare return the share of the payer that isn't paid or declined yet.
+func pendingShare(request *pkg.PaymentRequest, payerID int64) (*pkg.PaymentRequestShare, bool) {
+	for i := range request.Shares {
+		share := &request.Shares[i]
+		if share.PayerID == payerID && share.Status == services.ShareStatusPending {
+			return share, true
+		}
+	}
+	return nil, false
+}
+
+func (server *Server) getPaymentRequest(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	request, ok := server.paymentRequestByParam(w, r, ps)
+	if !ok {
+		return
+	}
+
+	w.Header().Add("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(newPaymentRequestResponse(request))
+}
+
+// payPaymentRequest pay the share of the logged in account from one of its wallets.
+func (server *Server) payPaymentRequest(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	var req payPaymentRequestRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		http.Error(w, "failed to decode input data", (http.StatusInternalServerError))
+		json.NewEncoder(w).Encode(err.Error())
+		return
+	}
+
+	err = validate.Struct(req)
+	if err != nil {
+		http.Error(w, "Format input data is wrong", (http.StatusBadRequest))
+		json.NewEncoder(w).Encode(err.Error())
+		return
+	}
+
+	request, ok := server.paymentRequestByParam(w, r, ps)
+	if !ok {
+		return
+	}
+
+	_, valid := server.validWallet(w, req.WalletNumber, request.Currency)
+	if !valid {
+		return
+	}
+
+	wallet, err := server.store.GetWalletByNumber(server.ctx, req.WalletNumber)
+	if err != nil {
+		http.Error(w, "Failed to get the wallet", (http.StatusBadRequest))
+		return
+	}
+	if !server.authorizeWallet(w, r, wallet, services.WalletRoleSpender) {
+		return
+	}
+
+	authPayload := r.Context().Value("authPayloadKey").(*token.Payload)
+	share, ok := pendingShare(request, authPayload.AccountID)
+	if !ok {
+		http.Error(w, "there's no pending payment for you in this request", http.StatusNotFound)
+		return
+	}
+	requesterWallet, err := server.store.GetWalletByNumber(server.ctx, request.WalletNumber)
+	if err != nil {
+		http.Error(w, "Failed to get the wallet", (http.StatusBadRequest))
+		return
+	}
+	if !server.checkRecipient(w, r, wallet, requesterWallet, share.Amount) {
+		return
+	}
+	if !server.checkTransferTOTP(w, r, share.Amount, req.TOTPCode) {
+		return
+	}
+
+	result, err := server.store.PayPaymentRequestTx(server.ctx, services.PayPaymentRequestParams{
+		RequestID:  request.ID,
+		PayerID:    authPayload.AccountID,
+		FromWallet: *wallet,
+	})
+	if err != nil {
+		switch err {
+		case util.ErrNotExist:
+			http.Error(w, "there's no pending payment for you in this request", http.StatusNotFound)
+			return
+		case util.ErrRequestExpired, util.ErrWalletLocked, util.ErrWalletFrozen:
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+		http.Error(w, "Failed to tranfer", http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(err.Error())
+		return
+	}
+	server.notifyGoalReached(r.Context(), result.Transfer.ToWallet, result.Transfer.GoalReached)
+
+	err = server.notifier.Notify(r.Context(), notification.Event{
+		AccountID: result.Request.RequesterID,
+		Type:      notification.EventPaymentRequestPaid,
+		Message:   fmt.Sprintf("Your payment request %q is paid %d of %d %s", result.Request.Memo, result.Request.PaidAmount, result.Request.Amount, result.Request.Currency),
+		Data: map[string]interface{}{
+			"request_id":  result.Request.ID,
+			"paid_amount": result.Request.PaidAmount,
+		},
+		CreatedAt: result.Transfer.Transfer.CreatedAt,
+	})
+	if err != nil {
+		log.Println("--- (err) notify payment request paid:", err)
+	}
+
+	response := payPaymentRequestResponse{
+		Request:  newPaymentRequestResponse(result.Request),
+		Transfer: newTransferTxResponse(&result.Transfer),
+	}
+
+	w.Header().Add("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
+
+// declinePaymentRequest decline the share of the logged in account.
+func (server *Server) declinePaymentRequest(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	request, ok := server.paymentRequestByParam(w, r, ps)
+	if !ok {
+		return
+	}
+
+	authPayload := r.Context().Value("authPayloadKey").(*token.Payload)
+	err := server.store.DeclinePaymentShare(server.ctx, request.ID, authPayload.AccountID)
+	if err != nil {
+		if err == util.ErrNotExist {
+			http.Error(w, "there's no pending payment for you in this request", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed parsing data into database", http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(err.Error())
+		return
+	}
+
+	w.Header().Add("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode("Payment request declined")
+}
@@ -0,0 +1,138 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreatePaymentRequestInvalid(t *testing.T) {
+	requester := loginAccount(t)
+	payer := loginAccount(t)
+	expiresAt := time.Now().AddDate(0, 0, 7).Format(time.DateOnly)
+
+	testCases := []struct {
+		name   string
+		req    createPaymentRequestRequest
+		status int
+	}{
+		{
+			name: "ExpiredDate",
+			req: createPaymentRequestRequest{
+				WalletNumber: requester.Account.AccountNumber,
+				Amount:       1000,
+				ExpiresAt:    "2000-01-01",
+				Payers:       []paymentPayerRequest{{AccountNumber: payer.Account.AccountNumber}},
+			},
+			status: http.StatusBadRequest,
+		},
+		{
+			name: "PayerIsRequester",
+			req: createPaymentRequestRequest{
+				WalletNumber: requester.Account.AccountNumber,
+				Amount:       1000,
+				ExpiresAt:    expiresAt,
+				Payers:       []paymentPayerRequest{{AccountNumber: requester.Account.AccountNumber}},
+			},
+			status: http.StatusBadRequest,
+		},
+		{
+			name: "SharesMismatch",
+			req: createPaymentRequestRequest{
+				WalletNumber: requester.Account.AccountNumber,
+				Amount:       1000,
+				ExpiresAt:    expiresAt,
+				Payers:       []paymentPayerRequest{{AccountNumber: payer.Account.AccountNumber, Amount: 900}},
+			},
+			status: http.StatusBadRequest,
+		},
+		{
+			name: "NotOwnWallet",
+			req: createPaymentRequestRequest{
+				WalletNumber: payer.Account.AccountNumber,
+				Amount:       1000,
+				ExpiresAt:    expiresAt,
+				Payers:       []paymentPayerRequest{{AccountNumber: payer.Account.AccountNumber}},
+			},
+			status: http.StatusUnauthorized,
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			status, body := sendRequest(t, "POST", "/payment-requests", requester.AccessToken, tc.req)
+			assert.Equal(t, tc.status, status, string(body))
+		})
+	}
+}
+
+func TestPayPaymentRequest(t *testing.T) {
+	requester := loginAccount(t)
+	payer1 := loginAccount(t)
+	payer2 := loginAccount(t)
+	stranger := loginAccount(t)
+
+	status, body := sendRequest(t, "POST", "/payment-requests", requester.AccessToken, createPaymentRequestRequest{
+		WalletNumber: requester.Account.AccountNumber,
+		Amount:       3000,
+		Memo:         "dinner",
+		ExpiresAt:    time.Now().AddDate(0, 0, 7).Format(time.DateOnly),
+		Payers: []paymentPayerRequest{
+			{AccountNumber: payer1.Account.AccountNumber, Amount: 2000},
+			{AccountNumber: payer2.Account.AccountNumber, Amount: 1000},
+		},
+	})
+	require.Equal(t, http.StatusOK, status, string(body))
+	var request paymentRequestResponse
+	require.NoError(t, json.Unmarshal(body, &request))
+	require.Len(t, request.Shares, 2)
+
+	path := "/payment-requests/" + strconv.FormatInt(request.ID, 10)
+
+	status, _ = sendRequest(t, "GET", path, stranger.AccessToken, nil)
+	assert.Equal(t, http.StatusUnauthorized, status)
+
+	// only the payers have a share to pay
+	status, _ = sendRequest(t, "POST", path+"/pay", stranger.AccessToken, payPaymentRequestRequest{WalletNumber: stranger.Account.AccountNumber})
+	assert.Equal(t, http.StatusUnauthorized, status)
+
+	// payer can't pay from a wallet of other account
+	status, _ = sendRequest(t, "POST", path+"/pay", payer1.AccessToken, payPaymentRequestRequest{WalletNumber: payer2.Account.AccountNumber})
+	assert.Equal(t, http.StatusUnauthorized, status)
+
+	status, body = sendRequest(t, "POST", path+"/pay", payer1.AccessToken, payPaymentRequestRequest{WalletNumber: payer1.Account.AccountNumber})
+	require.Equal(t, http.StatusOK, status, string(body))
+	var paid payPaymentRequestResponse
+	require.NoError(t, json.Unmarshal(body, &paid))
+	assert.Equal(t, int64(2000), paid.Request.PaidAmount)
+	assert.Equal(t, int64(2000), paid.Transfer.Transfer.Amount)
+
+	// a share is paid once
+	status, _ = sendRequest(t, "POST", path+"/pay", payer1.AccessToken, payPaymentRequestRequest{WalletNumber: payer1.Account.AccountNumber})
+	assert.Equal(t, http.StatusNotFound, status)
+
+	status, body = sendRequest(t, "POST", path+"/decline", payer2.AccessToken, nil)
+	require.Equal(t, http.StatusOK, status, string(body))
+
+	status, _ = sendRequest(t, "POST", path+"/pay", payer2.AccessToken, payPaymentRequestRequest{WalletNumber: payer2.Account.AccountNumber})
+	assert.Equal(t, http.StatusNotFound, status)
+
+	status, body = sendRequest(t, "GET", path, requester.AccessToken, nil)
+	require.Equal(t, http.StatusOK, status, string(body))
+	require.NoError(t, json.Unmarshal(body, &request))
+	assert.Equal(t, int64(2000), request.PaidAmount)
+	for _, share := range request.Shares {
+		switch share.AccountNumber {
+		case payer1.Account.AccountNumber:
+			assert.Equal(t, "paid", share.Status)
+		case payer2.Account.AccountNumber:
+			assert.Equal(t, "declined", share.Status)
+		}
+	}
+}
@@ -0,0 +1,367 @@
+package api
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"time"
+
+	"simple-bank-system/db/pkg"
+	"simple-bank-system/db/services"
+	"simple-bank-system/notification"
+	"simple-bank-system/token"
+	"simple-bank-system/util"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+// Profile of the logged in account ('/account/me'), the changes are written to the audit log.
+
+type pendingEmailResponse struct {
+	Email     string
+	ExpiresAt time.Time
+}
+
+type profileResponse struct {
+	accountResponse
+	// new email that isn't verified yet
+	PendingEmail *pendingEmailResponse `json:",omitempty"`
+}
+
+type addressHistoryResponse struct {
+	Address   addressResponse
+	ValidFrom time.Time
+	ValidTo   time.Time
+}
+
+type updateFullNameRequest struct {
+	FullName string `json:"fullname" validate:"required"`
+}
+
+type changeEmailRequest struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+type changePasswordRequest struct {
+	CurrentPassword string `json:"current_password" validate:"required"`
+	NewPassword     string `json:"new_password" validate:"required,min=6"`
+}
+
+type changePasswordResponse struct {
+	AccessToken           string    `json:"access_token"`
+	RefreshToken          string    `json:"refresh_token"`
+	RefreshTokenExpiresAt time.Time `json:"refresh_token_expires_at"`
+	PasswordChangeAt      time.Time `json:"password_change_at"`
+}
+
+type verifyEmailRequest struct {
+	Token string `json:"token" validate:"required"`
+}
+
+func newAddressResponse(address pkg.Addresses) addressResponse {
+	return addressResponse{
+		Provinces: address.Provinces,
+		City:      address.City,
+		ZIP:       address.ZIP,
+		Street:    address.Street,
+	}
+}
+
+// profileAccount read the account of the access token.
+func (server *Server) profileAccount(w http.ResponseWriter, r *http.Request) (*pkg.Account, bool) {
+	authPayload := r.Context().Value("authPayloadKey").(*token.Payload)
+
+	account, err := server.store.GetAccountProfile(server.ctx, authPayload.AccountID)
+	if err != nil {
+		if err == util.ErrNotExist {
+			http.Error(w, "account doesn't exist", http.StatusNotFound)
+			return nil, false
+		}
+		http.Error(w, "Can't get account", (http.StatusInternalServerError))
+		json.NewEncoder(w).Encode(err.Error())
+		return nil, false
+	}
+	return account, true
+}
+
+// writeProfile write the profile of the account with its pending email.
+func (server *Server) writeProfile(w http.ResponseWriter, account *pkg.Account) {
+	response := profileResponse{accountResponse: newaccountResponse(account)}
+
+	change, err := server.store.GetPendingEmailChange(server.ctx, account.ID)
+	if err != nil && err != util.ErrNotExist {
+		http.Error(w, "Can't get pending email", (http.StatusInternalServerError))
+		json.NewEncoder(w).Encode(err.Error())
+		return
+	}
+	if change != nil {
+		response.PendingEmail = &pendingEmailResponse{Email: change.Email, ExpiresAt: change.ExpiresAt}
+	}
+
+	w.Header().Add("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
+
+func (server *Server) getProfile(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	account, ok := server.profileAccount(w, r)
+	if !ok {
+		return
+	}
+	server.writeProfile(w, account)
+}
+
+func (server *Server) updateFullName(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	var req updateFullNameRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := validate.Struct(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	account, ok := server.profileAccount(w, r)
+	if !ok {
+		return
+	}
+
+	err := server.store.UpdateAccountFullName(server.ctx, account.ID, req.FullName)
+	if err != nil {
+		http.Error(w, "Failed to update full name", accErrHandling(err))
+		json.NewEncoder(w).Encode(err.Error())
+		return
+	}
+	auditChange(r, "full_name", account.FullName, req.FullName)
+
+	account.FullName = req.FullName
+	server.writeProfile(w, account)
+}
+
+// updateAddress save the new address, the current one is moved to the address history.
+func (server *Server) updateAddress(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	var req pkg.Addresses
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := validate.Struct(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	account, ok := server.profileAccount(w, r)
+	if !ok {
+		return
+	}
+
+	address, err := server.store.UpdateAddressTx(server.ctx, account.ID, req)
+	if err != nil {
+		http.Error(w, "Failed to update address", accErrHandling(err))
+		json.NewEncoder(w).Encode(err.Error())
+		return
+	}
+	auditChange(r, "address", newAddressResponse(account.Address), newAddressResponse(*address))
+
+	account.Address = *address
+	server.writeProfile(w, account)
+}
+
+func (server *Server) listAddressHistory(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authPayload := r.Context().Value("authPayloadKey").(*token.Payload)
+
+	history, err := server.store.ListAddressHistory(server.ctx, authPayload.AccountID)
+	if err != nil {
+		http.Error(w, "Failed to get List", (http.StatusInternalServerError))
+		json.NewEncoder(w).Encode(err.Error())
+		return
+	}
+
+	response := []addressHistoryResponse{}
+	for _, item := range history {
+		response = append(response, addressHistoryResponse{
+			Address:   newAddressResponse(item.Address),
+			ValidFrom: item.ValidFrom,
+			ValidTo:   item.ValidTo,
+		})
+	}
+
+	w.Header().Add("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
+
+/*
+ * changeEmail send a verification token to the new email, the email of the account is only changed
+ * by 'verifyEmail'. A new request replace the pending one.
+ */
+func (server *Server) changeEmail(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	var req changeEmailRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := validate.Struct(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	account, ok := server.profileAccount(w, r)
+	if !ok {
+		return
+	}
+	if req.Email == account.Email {
+		http.Error(w, "it's already your email", http.StatusBadRequest)
+		return
+	}
+	// the email is sent in the background, its error can't be returned
+	if !server.emailEnabled {
+		http.Error(w, "Can't send the verification email, email isn't configured", http.StatusServiceUnavailable)
+		return
+	}
+
+	verificationToken, err := util.RandomToken(32)
+	if err != nil {
+		http.Error(w, "Failed to create verification token", (http.StatusInternalServerError))
+		json.NewEncoder(w).Encode(err.Error())
+		return
+	}
+
+	change, err := server.store.CreateEmailChangeTx(server.ctx, services.CreateEmailChangeParams{
+		AccountID: account.ID,
+		Email:     req.Email,
+		TokenHash: util.HashToken(verificationToken),
+		ExpiresAt: time.Now().Add(server.emailVerificationDuration),
+	})
+	if err != nil {
+		http.Error(w, "Failed to change email", accErrHandling(err))
+		json.NewEncoder(w).Encode(err.Error())
+		return
+	}
+	auditTarget(r, "email_change_id", change.ID)
+
+	err = server.notifier.Notify(r.Context(), notification.Event{
+		AccountID: account.ID,
+		Type:      notification.EventEmailVerification,
+		Email:     change.Email,
+		Data: map[string]interface{}{
+			"email":      change.Email,
+			"token":      verificationToken,
+			"expires_at": change.ExpiresAt.UTC().Format("2006-01-02 15:04 MST"),
+		},
+		CreatedAt: change.CreatedAt,
+	})
+	if err != nil {
+		log.Println("--- (err) email verification:", err)
+		http.Error(w, "Failed to queue the verification email, try again later", http.StatusServiceUnavailable)
+		return
+	}
+
+	w.Header().Add("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(pendingEmailResponse{Email: change.Email, ExpiresAt: change.ExpiresAt})
+}
+
+func (server *Server) verifyEmail(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	var req verifyEmailRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := validate.Struct(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	account, ok := server.profileAccount(w, r)
+	if !ok {
+		return
+	}
+
+	change, err := server.store.VerifyEmailChangeTx(server.ctx, account.ID, util.HashToken(req.Token))
+	if err != nil {
+		switch err {
+		case util.ErrNotExist:
+			http.Error(w, "verification token is wrong", http.StatusBadRequest)
+			return
+		case util.ErrTokenExpired:
+			http.Error(w, "verification token is expired, change the email again", http.StatusBadRequest)
+			return
+		}
+		http.Error(w, "Failed to verify email", accErrHandling(err))
+		json.NewEncoder(w).Encode(err.Error())
+		return
+	}
+	auditTarget(r, "email_change_id", change.ID)
+	auditChange(r, "email", account.Email, change.Email)
+
+	account.Email = change.Email
+	server.writeProfile(w, account)
+}
+
+/*
+ * changePassword need the current password. Every access token issued before the change is rejected by
+ * 'authMiddleware' (e.g. the token of a stolen phone) and the older sessions can't be refreshed, the response
+ * has new tokens for this device.
+ */
+func (server *Server) changePassword(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	var req changePasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := validate.Struct(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	account, ok := server.profileAccount(w, r)
+	if !ok {
+		return
+	}
+	if err := util.VerifyPassword(req.CurrentPassword, account.HashedPassword); err != nil {
+		http.Error(w, "Your password is wrong", http.StatusForbidden)
+		return
+	}
+	if req.NewPassword == req.CurrentPassword {
+		http.Error(w, "new password must be different", http.StatusBadRequest)
+		return
+	}
+
+	changedAt := time.Now()
+	err := server.store.UpdatePassword(server.ctx, services.UpdatePasswordParams{
+		AccountID: account.ID,
+		Password:  req.NewPassword,
+		ChangedAt: changedAt,
+	})
+	if err != nil {
+		http.Error(w, "Failed to change password", (http.StatusInternalServerError))
+		json.NewEncoder(w).Encode(err.Error())
+		return
+	}
+	auditChange(r, "password_change_at", account.PasswordChangeAt, changedAt)
+
+	accessToken, err := server.createAccessToken(account.ID, account.Role)
+	if err != nil {
+		http.Error(w, "Failed to create encryted token", http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(err.Error())
+		return
+	}
+	// the sessions of the other devices can't be refreshed anymore, this device get a new one
+	refreshToken, session, err := server.createSession(r, account.ID)
+	if err != nil {
+		http.Error(w, "Failed to create session", http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(err.Error())
+		return
+	}
+
+	w.Header().Add("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(changePasswordResponse{
+		AccessToken:           accessToken,
+		RefreshToken:          refreshToken,
+		RefreshTokenExpiresAt: session.ExpiresAt,
+		PasswordChangeAt:      changedAt,
+	})
+}
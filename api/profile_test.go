@@ -0,0 +1,180 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"simple-bank-system/db/pkg"
+	"simple-bank-system/db/services"
+	"simple-bank-system/util"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// getProfile return the profile of the token's account.
+func getProfile(t *testing.T, accessToken string) profileResponse {
+	status, body := sendRequest(t, "GET", "/account/me", accessToken, nil)
+	require.Equal(t, http.StatusOK, status, string(body))
+
+	var profile profileResponse
+	require.NoError(t, json.Unmarshal(body, &profile))
+	return profile
+}
+
+func TestUpdateProfile(t *testing.T) {
+	loginRes := loginAccount(t)
+
+	profile := getProfile(t, loginRes.AccessToken)
+	assert.Equal(t, loginRes.Account.AccountNumber, profile.AccountNumber)
+	assert.Nil(t, profile.PendingEmail)
+
+	fullName := util.RandomOwner()
+	status, body := sendRequest(t, "PUT", "/account/me/fullname", loginRes.AccessToken, updateFullNameRequest{FullName: fullName})
+	require.Equal(t, http.StatusOK, status, string(body))
+	assert.Equal(t, fullName, getProfile(t, loginRes.AccessToken).FullName)
+
+	// the old address is moved to the history
+	oldAddress := profile.Address
+	address := pkg.Addresses{Provinces: "Jawa Barat", City: "Bandung", ZIP: 40111, Street: util.RandomString(10)}
+	status, body = sendRequest(t, "PUT", "/account/me/address", loginRes.AccessToken, address)
+	require.Equal(t, http.StatusOK, status, string(body))
+	assert.Equal(t, address.Street, getProfile(t, loginRes.AccessToken).Address.Street)
+
+	status, body = sendRequest(t, "GET", "/account/me/addresses", loginRes.AccessToken, nil)
+	require.Equal(t, http.StatusOK, status, string(body))
+	var history []addressHistoryResponse
+	require.NoError(t, json.Unmarshal(body, &history))
+	require.Len(t, history, 1)
+	assert.Equal(t, oldAddress, history[0].Address)
+
+	testCases := []struct {
+		name   string
+		method string
+		path   string
+		token  string
+		body   interface{}
+		status int
+	}{
+		{name: "NoToken", method: "GET", path: "/account/me", token: "", status: http.StatusUnauthorized},
+		{name: "EmptyFullName", method: "PUT", path: "/account/me/fullname", token: loginRes.AccessToken, body: updateFullNameRequest{}, status: http.StatusUnprocessableEntity},
+		{name: "AddressWithoutCity", method: "PUT", path: "/account/me/address", token: loginRes.AccessToken, body: pkg.Addresses{Provinces: "Jawa Barat", ZIP: 40111, Street: "Jalan"}, status: http.StatusUnprocessableEntity},
+		{name: "InvalidEmail", method: "PUT", path: "/account/me/email", token: loginRes.AccessToken, body: changeEmailRequest{Email: "not an email"}, status: http.StatusUnprocessableEntity},
+		{name: "SameEmail", method: "PUT", path: "/account/me/email", token: loginRes.AccessToken, body: changeEmailRequest{Email: profile.Email}, status: http.StatusBadRequest},
+		{name: "WrongVerificationToken", method: "POST", path: "/account/me/email/verify", token: loginRes.AccessToken, body: verifyEmailRequest{Token: "wrong"}, status: http.StatusBadRequest},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			status, body := sendRequest(t, tc.method, tc.path, tc.token, tc.body)
+			require.Equal(t, tc.status, status, string(body))
+		})
+	}
+}
+
+func TestVerifyEmail(t *testing.T) {
+	loginRes := loginAccount(t)
+	other := loginAccount(t)
+
+	account, err := testStore.GetAccountByNumber(testCtx, loginRes.Account.AccountNumber)
+	require.NoError(t, err)
+
+	// the token is only emailed, the test save its own
+	verificationToken, err := util.RandomToken(32)
+	require.NoError(t, err)
+	newEmail := util.RandomEmail()
+	_, err = testStore.CreateEmailChangeTx(testCtx, services.CreateEmailChangeParams{
+		AccountID: account.ID,
+		Email:     newEmail,
+		TokenHash: util.HashToken(verificationToken),
+		ExpiresAt: time.Now().Add(time.Hour),
+	})
+	require.NoError(t, err)
+
+	profile := getProfile(t, loginRes.AccessToken)
+	require.NotNil(t, profile.PendingEmail)
+	assert.Equal(t, newEmail, profile.PendingEmail.Email)
+	assert.NotEqual(t, newEmail, profile.Email, "the email only change after the verification")
+
+	// the token belong to the account that changed the email
+	status, body := sendRequest(t, "POST", "/account/me/email/verify", other.AccessToken, verifyEmailRequest{Token: verificationToken})
+	require.Equal(t, http.StatusBadRequest, status, string(body))
+
+	status, body = sendRequest(t, "POST", "/account/me/email/verify", loginRes.AccessToken, verifyEmailRequest{Token: verificationToken})
+	require.Equal(t, http.StatusOK, status, string(body))
+	profile = getProfile(t, loginRes.AccessToken)
+	assert.Equal(t, newEmail, profile.Email)
+	assert.Nil(t, profile.PendingEmail)
+
+	// the token is used once
+	status, body = sendRequest(t, "POST", "/account/me/email/verify", loginRes.AccessToken, verifyEmailRequest{Token: verificationToken})
+	require.Equal(t, http.StatusBadRequest, status, string(body))
+
+	// an expired token can't be used
+	expiredToken, err := util.RandomToken(32)
+	require.NoError(t, err)
+	_, err = testStore.CreateEmailChangeTx(testCtx, services.CreateEmailChangeParams{
+		AccountID: account.ID,
+		Email:     util.RandomEmail(),
+		TokenHash: util.HashToken(expiredToken),
+		ExpiresAt: time.Now().Add(-time.Minute),
+	})
+	require.NoError(t, err)
+	status, body = sendRequest(t, "POST", "/account/me/email/verify", loginRes.AccessToken, verifyEmailRequest{Token: expiredToken})
+	require.Equal(t, http.StatusBadRequest, status, string(body))
+	assert.Equal(t, newEmail, getProfile(t, loginRes.AccessToken).Email)
+}
+
+func TestChangePassword(t *testing.T) {
+	loginRes := loginAccount(t)
+	otherDevice := login(t, loginRes.Account.Username, Password)
+	newPassword := util.RandomPassword()
+
+	testCases := []struct {
+		name   string
+		body   changePasswordRequest
+		status int
+	}{
+		{name: "WrongPassword", body: changePasswordRequest{CurrentPassword: "wrong password", NewPassword: newPassword}, status: http.StatusForbidden},
+		{name: "SamePassword", body: changePasswordRequest{CurrentPassword: Password, NewPassword: Password}, status: http.StatusBadRequest},
+		{name: "ShortPassword", body: changePasswordRequest{CurrentPassword: Password, NewPassword: "abc"}, status: http.StatusUnprocessableEntity},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			status, body := sendRequest(t, "PUT", "/account/me/password", loginRes.AccessToken, tc.body)
+			require.Equal(t, tc.status, status, string(body))
+		})
+	}
+	// the failed changes didn't revoke anything
+	getProfile(t, otherDevice.AccessToken)
+
+	status, body := sendRequest(t, "PUT", "/account/me/password", loginRes.AccessToken, changePasswordRequest{CurrentPassword: Password, NewPassword: newPassword})
+	require.Equal(t, http.StatusOK, status, string(body))
+	var response changePasswordResponse
+	require.NoError(t, json.Unmarshal(body, &response))
+	require.NotEmpty(t, response.AccessToken)
+	require.NotEmpty(t, response.RefreshToken)
+
+	// the tokens and sessions of before the change are rejected, on every device
+	for _, accessToken := range []string{loginRes.AccessToken, otherDevice.AccessToken} {
+		status, _ = sendRequest(t, "GET", "/account/me", accessToken, nil)
+		require.Equal(t, http.StatusUnauthorized, status)
+	}
+	status, _ = sendRequest(t, "POST", "/account/token/refresh", "", refreshTokenRequest{RefreshToken: otherDevice.RefreshToken})
+	require.Equal(t, http.StatusUnauthorized, status)
+
+	// this device continue with the new tokens
+	getProfile(t, response.AccessToken)
+	status, body = sendRequest(t, "POST", "/account/token/refresh", "", refreshTokenRequest{RefreshToken: response.RefreshToken})
+	require.Equal(t, http.StatusOK, status, string(body))
+
+	// only the new password can login
+	status, _ = sendRequest(t, "POST", "/account/login", "", loginRequest{Username: loginRes.Account.Username, Password: Password})
+	require.Equal(t, http.StatusUnauthorized, status)
+	login(t, loginRes.Account.Username, newPassword)
+}
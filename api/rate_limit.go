@@ -0,0 +1,84 @@
+package api
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"simple-bank-system/db/services"
+	"simple-bank-system/ratelimit"
+	"simple-bank-system/token"
+	"simple-bank-system/util"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+// policies of the route groups
+type rateLimitPolicies struct {
+	login    ratelimit.Policy
+	transfer ratelimit.Policy
+	read     ratelimit.Policy
+}
+
+func newRateLimiter(store *services.Store, config util.Config) (ratelimit.Backend, rateLimitPolicies, error) {
+	var policies rateLimitPolicies
+	var err error
+	if policies.login, err = ratelimit.ParsePolicy("login", config.RateLimitLogin); err != nil {
+		return nil, policies, err
+	}
+	if policies.transfer, err = ratelimit.ParsePolicy("transfer", config.RateLimitTransfer); err != nil {
+		return nil, policies, err
+	}
+	if policies.read, err = ratelimit.ParsePolicy("read", config.RateLimitRead); err != nil {
+		return nil, policies, err
+	}
+
+	switch config.RateLimitBackend {
+	case "", "memory":
+		return ratelimit.NewMemory(), policies, nil
+	case "postgres":
+		return ratelimit.NewPostgres(store), policies, nil
+	}
+	return nil, policies, fmt.Errorf("rate limit backend must be memory or postgres, not '%s'", config.RateLimitBackend)
+}
+
+// seconds round up a duration for the headers.
+func seconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
+
+/*
+ * rateLimit limit the calls of the handler with the policy. The bucket is the one of the account when the request
+ * has a token (inside 'authMiddleware') and the one of the client IP otherwise. The 'RateLimit-*' headers are
+ * in every response, a refused request get 429 with 'Retry-After'. When the backend fails the request is let through.
+ */
+func (server *Server) rateLimit(policy ratelimit.Policy, next httprouter.Handle) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		key := policy.Name + ":ip:" + clientIP(r)
+		if authPayload, ok := r.Context().Value("authPayloadKey").(*token.Payload); ok {
+			key = fmt.Sprintf("%s:account:%d", policy.Name, authPayload.AccountID)
+		}
+
+		result, err := server.rateLimiter.Take(r.Context(), key, policy, time.Now())
+		if err != nil {
+			log.Printf("--- (err) rate limit %s: %v\n", key, err)
+			next(w, r, ps)
+			return
+		}
+
+		w.Header().Set("RateLimit-Policy", fmt.Sprintf("%d;w=%s", policy.Limit, seconds(policy.Period)))
+		w.Header().Set("RateLimit-Limit", strconv.Itoa(result.Limit))
+		w.Header().Set("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+		w.Header().Set("RateLimit-Reset", seconds(result.Reset))
+		if !result.Allowed {
+			w.Header().Set("Retry-After", seconds(result.RetryAfter))
+			http.Error(w, "too many requests, try again later", http.StatusTooManyRequests)
+			return
+		}
+
+		next(w, r, ps)
+	}
+}
@@ -0,0 +1,67 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"testing"
+
+	"simple-bank-system/ratelimit"
+
+	"github.com/stretchr/testify/require"
+)
+
+// requireRateLimitHeaders check the headers of the policy and return the remaining requests.
+func requireRateLimitHeaders(t *testing.T, res *http.Response, limit int) int {
+	require.Equal(t, strconv.Itoa(limit), res.Header.Get("RateLimit-Limit"))
+	require.NotEmpty(t, res.Header.Get("RateLimit-Policy"))
+	require.NotEmpty(t, res.Header.Get("RateLimit-Reset"))
+
+	remaining, err := strconv.Atoi(res.Header.Get("RateLimit-Remaining"))
+	require.NoError(t, err)
+	return remaining
+}
+
+func TestRateLimitTransfer(t *testing.T) {
+	loginRes := loginAccount(t)
+	other := loginAccount(t)
+	transfer, err := ratelimit.ParsePolicy("transfer", testConfig.RateLimitTransfer)
+	require.NoError(t, err)
+	read, err := ratelimit.ParsePolicy("read", testConfig.RateLimitRead)
+	require.NoError(t, err)
+	limit := transfer.Limit
+
+	// the requests are counted before the handler, the refused transfers too
+	for i := 0; i < limit; i++ {
+		res, body := sendRequestResponse(t, "POST", "/transfer", loginRes.AccessToken, transferRequest{})
+		require.Equal(t, http.StatusBadRequest, res.StatusCode, string(body))
+		require.Equal(t, limit-i-1, requireRateLimitHeaders(t, res, limit))
+	}
+
+	res, body := sendRequestResponse(t, "POST", "/transfer", loginRes.AccessToken, transferRequest{})
+	require.Equal(t, http.StatusTooManyRequests, res.StatusCode, string(body))
+	require.Equal(t, 0, requireRateLimitHeaders(t, res, limit))
+	retryAfter, err := strconv.Atoi(res.Header.Get("Retry-After"))
+	require.NoError(t, err)
+	require.Positive(t, retryAfter)
+
+	// the other routes of the policy share the bucket of the account
+	status, body := sendRequest(t, "POST", "/payment-requests/1/pay", loginRes.AccessToken, nil)
+	require.Equal(t, http.StatusTooManyRequests, status, string(body))
+
+	// the bucket is the one of the account, not of the client IP
+	res, body = sendRequestResponse(t, "POST", "/transfer", other.AccessToken, transferRequest{})
+	require.Equal(t, http.StatusBadRequest, res.StatusCode, string(body))
+	require.Equal(t, limit-1, requireRateLimitHeaders(t, res, limit))
+
+	// the read routes have their own policy
+	res, body = sendRequestResponse(t, "GET", "/account/me", loginRes.AccessToken, nil)
+	require.Equal(t, http.StatusOK, res.StatusCode, string(body))
+	requireRateLimitHeaders(t, res, read.Limit)
+}
+
+func TestRateLimitUnauthorized(t *testing.T) {
+	// the token is checked before the rate limit, a request without token use no bucket
+	res, _ := sendRequestResponse(t, "POST", "/transfer", "", transferRequest{})
+	require.Equal(t, http.StatusUnauthorized, res.StatusCode)
+	require.Empty(t, res.Header.Get("RateLimit-Limit"))
+}
@@ -0,0 +1,89 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"simple-bank-system/db/pkg"
+	"simple-bank-system/db/services"
+	"simple-bank-system/notification"
+)
+
+// Optional goal of a wallet created by 'createWallet', the wallet become savings wallet.
+type savingsGoalRequest struct {
+	TargetAmount int64  `json:"target_amount" validate:"required,gt=0"`
+	TargetDate   string `json:"target_date" validate:"required"`
+	LockMode     string `json:"lock_mode" validate:"omitempty,oneof=none block penalty"`
+	PenaltyBps   int64  `json:"penalty_bps" validate:"min=0,max=10000"`
+}
+
+type savingsGoalResponse struct {
+	TargetAmount int64
+	TargetDate   string
+	LockMode     string
+	PenaltyBps   int64
+	Locked       bool
+	// saved balance compared to the target in percent (0-100)
+	Progress  float64
+	ReachedAt *time.Time
+}
+
+func newSavingsGoalResponse(goal *pkg.SavingsGoal, balance int64) *savingsGoalResponse {
+	if goal == nil {
+		return nil
+	}
+
+	res := &savingsGoalResponse{
+		TargetAmount: goal.TargetAmount,
+		TargetDate:   goal.TargetDate.Format(time.DateOnly),
+		LockMode:     goal.LockMode,
+		PenaltyBps:   goal.PenaltyBps,
+		Locked:       services.GoalLocked(*goal, time.Now()),
+		Progress:     services.GoalProgress(*goal, balance),
+	}
+	if goal.ReachedAt.Valid {
+		res.ReachedAt = &goal.ReachedAt.Time
+	}
+	return res
+}
+
+func (req savingsGoalRequest) params() (services.CreateSavingsGoalParams, error) {
+	date, err := time.Parse(time.DateOnly, req.TargetDate)
+	if err != nil {
+		return services.CreateSavingsGoalParams{}, err
+	}
+	if !date.After(time.Now()) {
+		return services.CreateSavingsGoalParams{}, fmt.Errorf("target_date %s must be in the future", req.TargetDate)
+	}
+
+	return services.CreateSavingsGoalParams{
+		TargetAmount: req.TargetAmount,
+		TargetDate:   date,
+		LockMode:     req.LockMode,
+		PenaltyBps:   req.PenaltyBps,
+	}, nil
+}
+
+// notifyGoalReached send notification to the owner of the wallet that reached its goal.
+func (server *Server) notifyGoalReached(ctx context.Context, wallet *pkg.Wallet, goal *pkg.SavingsGoal) {
+	if goal == nil {
+		return
+	}
+
+	err := server.notifier.Notify(ctx, notification.Event{
+		AccountID: wallet.AccountID,
+		Type:      notification.EventSavingsGoalReached,
+		Message:   fmt.Sprintf("Your wallet %q reached its savings goal of %d %s", wallet.Name, goal.TargetAmount, wallet.Currency),
+		Data: map[string]interface{}{
+			"wallet_number": wallet.WalletNumber,
+			"target_amount": goal.TargetAmount,
+			"balance":       wallet.Balance,
+		},
+		CreatedAt: goal.ReachedAt.Time,
+	})
+	if err != nil {
+		log.Println("--- (err) notify goal reached:", err)
+	}
+}
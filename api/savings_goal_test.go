@@ -0,0 +1,105 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"simple-bank-system/db/pkg"
+	"simple-bank-system/db/services"
+	"simple-bank-system/util"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateGoalWallet(t *testing.T) {
+	accRes := loginAccount(t)
+
+	wallArg := createWalletRequest{
+		Name:     util.RandomOwner(),
+		Currency: "IDR",
+		Goal: &savingsGoalRequest{
+			TargetAmount: 5000000,
+			TargetDate:   time.Now().AddDate(1, 0, 0).Format(time.DateOnly),
+			LockMode:     "block",
+		},
+	}
+
+	argMarshaled, err := json.Marshal(wallArg)
+	require.NoError(t, err)
+
+	req, err := http.NewRequest("POST", url, bytes.NewReader(argMarshaled))
+	require.NoError(t, err)
+
+	req.Header.Add("Content-Type", "application/json")
+	req.Header.Add("Authorization", "Bearer "+accRes.AccessToken)
+
+	client := http.Client{Timeout: 10 * time.Second}
+	res, err := client.Do(req)
+	require.NoError(t, err, "can't send request")
+	assert.True(t, res.StatusCode == 200, "status code is wrong, status code:", res.StatusCode, res.Status)
+
+	defer res.Body.Close()
+
+	var response walletResponse
+	resBody, err := io.ReadAll(res.Body)
+	require.NoError(t, err)
+	err = json.Unmarshal(resBody, &response)
+	require.NoError(t, err)
+
+	assert.Equal(t, wallArg.Name, response.Name)
+	assert.Equal(t, "savings", response.Type)
+	require.NotNil(t, response.Goal)
+	assert.Equal(t, wallArg.Goal.TargetAmount, response.Goal.TargetAmount)
+	assert.Equal(t, wallArg.Goal.TargetDate, response.Goal.TargetDate)
+	assert.True(t, response.Goal.Locked)
+	assert.Zero(t, response.Goal.Progress)
+	assert.Nil(t, response.Goal.ReachedAt)
+
+	wallet := getWalletTest(t, accRes.AccessToken, response)
+	require.NotNil(t, wallet.Goal)
+	assert.Equal(t, response.Goal.TargetAmount, wallet.Goal.TargetAmount)
+}
+
+func TestDeletePenaltyGoalWallet(t *testing.T) {
+	accRes := loginAccount(t)
+	primary := accRes.Account.AccountNumber
+
+	status, body := sendRequest(t, "POST", "/wallet", accRes.AccessToken, createWalletRequest{
+		Name:     util.RandomOwner(),
+		Currency: "IDR",
+		Goal: &savingsGoalRequest{
+			TargetAmount: 5000000,
+			TargetDate:   time.Now().AddDate(1, 0, 0).Format(time.DateOnly),
+			LockMode:     "penalty",
+			PenaltyBps:   500,
+		},
+	})
+	require.Equal(t, http.StatusOK, status, string(body))
+	var goalWallet walletResponse
+	require.NoError(t, json.Unmarshal(body, &goalWallet))
+
+	status, body = sendRequest(t, "POST", "/transfer", accRes.AccessToken, transferRequest{
+		FromWalletNumber: primary,
+		ToWalletNumber:   goalWallet.WalletNumber,
+		Amount:           10000,
+		Currency:         "IDR",
+	})
+	require.Equal(t, http.StatusOK, status, string(body))
+
+	// the locked goal wallet can be closed, the penalty is paid from its balance
+	status, body = sendRequest(t, "DELETE", "/wallet/delete/"+strconv.FormatInt(goalWallet.WalletNumber, 10), accRes.AccessToken, nil)
+	require.Equal(t, http.StatusOK, status, string(body))
+
+	status, body = sendRequest(t, "GET", "/wallet/"+strconv.FormatInt(primary, 10), accRes.AccessToken, nil)
+	require.Equal(t, http.StatusOK, status, string(body))
+	var wallet walletResponse
+	require.NoError(t, json.Unmarshal(body, &wallet))
+	penalty := services.GoalPenaltyIncluded(pkg.SavingsGoal{PenaltyBps: 500}, 10000)
+	assert.Equal(t, int64(1000000)-penalty, wallet.Balance)
+}
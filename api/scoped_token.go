@@ -0,0 +1,170 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"simple-bank-system/authz"
+	"simple-bank-system/token"
+	"simple-bank-system/util"
+
+	"github.com/google/uuid"
+	"github.com/julienschmidt/httprouter"
+)
+
+// Restricted access tokens, minted from a login session for other apps (e.g. a read-only token for a budgeting app).
+
+type createScopedTokenRequest struct {
+	Scopes []string `json:"scopes" validate:"required,min=1,max=8,dive,required"`
+	// wallet numbers the token can use, every wallet when it's empty
+	Wallets []int64 `json:"wallets" validate:"max=20,dive,min=1010000000,max=1019999999"`
+	// Go duration (e.g. "720h"), SCOPED_TOKEN_DURATION when it's empty
+	Duration string `json:"duration"`
+}
+
+type scopedTokenResponse struct {
+	ID          uuid.UUID `json:"id"`
+	AccessToken string    `json:"access_token"`
+	Scopes      []string  `json:"scopes"`
+	Wallets     []int64   `json:"wallets,omitempty"`
+	ExpiresAt   time.Time `json:"expires_at"`
+}
+
+// sessionPayload return the payload of the token, restricted tokens can't mint or revoke other tokens.
+func sessionPayload(w http.ResponseWriter, r *http.Request) (*token.Payload, bool) {
+	authPayload := r.Context().Value("authPayloadKey").(*token.Payload)
+	if authPayload.Restricted() {
+		http.Error(w, "restricted tokens can't manage tokens, use the token of a login", http.StatusForbidden)
+		return nil, false
+	}
+	return authPayload, true
+}
+
+// longestTokenDuration is the lifetime of the longest access tokens, their revocations must last as long.
+func (server *Server) longestTokenDuration() time.Duration {
+	if server.scopedTokenMaxDuration > server.duration {
+		return server.scopedTokenMaxDuration
+	}
+	return server.duration
+}
+
+/*
+ * createScopedToken mint an access token that only has some scopes (and optionally some wallets) of the account.
+ * The role can't be widened: a scope is only given when the role has a permission of it, and the wallets
+ * must belong to the account or be shared with it. The token can't be refreshed, it's revoked with
+ * 'DELETE /account/tokens/:id' or by a logout on every device.
+ */
+func (server *Server) createScopedToken(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authPayload, ok := sessionPayload(w, r)
+	if !ok {
+		return
+	}
+
+	var req createScopedTokenRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "failed to decode input data", http.StatusBadRequest)
+		json.NewEncoder(w).Encode(err.Error())
+		return
+	}
+	if err := validate.Struct(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	duration := server.scopedTokenDuration
+	if req.Duration != "" {
+		var err error
+		duration, err = time.ParseDuration(req.Duration)
+		if err != nil || duration <= 0 || duration > server.scopedTokenMaxDuration {
+			http.Error(w, fmt.Sprintf("duration must be between 0 and %s", server.scopedTokenMaxDuration), http.StatusUnprocessableEntity)
+			return
+		}
+	}
+
+	restriction := token.Restriction{}
+	seen := map[string]bool{}
+	for _, scope := range req.Scopes {
+		if seen[scope] {
+			continue
+		}
+		seen[scope] = true
+		if !authz.ScopeAllows(authPayload.Role, scope) {
+			http.Error(w, fmt.Sprintf("unknown scope '%s' or your role '%s' can't use it", scope, authz.RoleOf(authPayload.Role)), http.StatusForbidden)
+			return
+		}
+		restriction.Scopes = append(restriction.Scopes, scope)
+	}
+
+	seenWallets := map[int64]bool{}
+	for _, number := range req.Wallets {
+		if seenWallets[number] {
+			continue
+		}
+		seenWallets[number] = true
+		wallet, err := server.store.GetWalletByNumber(server.ctx, number)
+		if err == nil {
+			_, err = server.store.GetWalletRole(server.ctx, *wallet, authPayload.AccountID)
+		}
+		if err != nil {
+			if err == util.ErrNotExist {
+				http.Error(w, fmt.Sprintf("wallet %d doesn't belong to you", number), http.StatusForbidden)
+				return
+			}
+			http.Error(w, "Can't check wallet", http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(err.Error())
+			return
+		}
+		restriction.Wallets = append(restriction.Wallets, number)
+	}
+
+	accessToken, payload, err := server.tokenMaker.CreateRestrictedToken(authPayload.AccountID, authPayload.Role, restriction, duration)
+	if err != nil {
+		http.Error(w, "Failed to create token", http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(err.Error())
+		return
+	}
+	auditTarget(r, "token_id", payload.ID)
+
+	w.Header().Add("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(scopedTokenResponse{
+		ID:          payload.ID,
+		AccessToken: accessToken,
+		Scopes:      payload.Scopes,
+		Wallets:     payload.Wallets,
+		ExpiresAt:   payload.ExpiredAt,
+	})
+}
+
+// revokeScopedToken revoke a token minted by 'createScopedToken' with its id.
+func (server *Server) revokeScopedToken(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authPayload, ok := sessionPayload(w, r)
+	if !ok {
+		return
+	}
+
+	tokenID, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "token id is wrong", http.StatusBadRequest)
+		return
+	}
+	auditTarget(r, "token_id", tokenID)
+
+	// the expiry of the token isn't known, the revocation is kept as long as the longest token
+	err = server.revocations.Revoke(server.ctx, &token.Payload{
+		ID:        tokenID,
+		AccountID: authPayload.AccountID,
+		ExpiredAt: time.Now().Add(server.longestTokenDuration()),
+	})
+	if err != nil {
+		http.Error(w, "Failed to revoke token", http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(err.Error())
+		return
+	}
+
+	w.Header().Add("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode("Token revoked")
+}
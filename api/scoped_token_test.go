@@ -0,0 +1,107 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"simple-bank-system/authz"
+
+	"github.com/stretchr/testify/require"
+)
+
+// createScopedToken mint a restricted token with the token of a login, the status code must be 201.
+func createScopedToken(t *testing.T, accessToken string, req createScopedTokenRequest) scopedTokenResponse {
+	status, body := sendRequest(t, "POST", "/account/tokens", accessToken, req)
+	require.Equal(t, http.StatusCreated, status, string(body))
+
+	var response scopedTokenResponse
+	require.NoError(t, json.Unmarshal(body, &response))
+	return response
+}
+
+func TestCreateScopedToken(t *testing.T) {
+	loginRes := loginAccount(t)
+	other := loginAccount(t)
+	walletNumber := loginRes.Account.AccountNumber
+
+	readOnly := createScopedToken(t, loginRes.AccessToken, createScopedTokenRequest{Scopes: []string{authz.ScopeWalletsRead}})
+	oneWallet := createScopedToken(t, loginRes.AccessToken, createScopedTokenRequest{
+		Scopes:  []string{authz.ScopeWalletsRead, authz.ScopeTransfersWrite},
+		Wallets: []int64{walletNumber},
+	})
+
+	testCases := []struct {
+		name   string
+		token  string
+		method string
+		path   string
+		body   interface{}
+		status int
+	}{
+		{name: "ReadWallet", token: readOnly.AccessToken, method: "GET", path: fmt.Sprintf("/wallet/%d", walletNumber), status: http.StatusOK},
+		{name: "ScopeMissing", token: readOnly.AccessToken, method: "POST", path: "/transfer", body: transferRequest{
+			FromWalletNumber: walletNumber, ToWalletNumber: other.Account.AccountNumber, Amount: 10, Currency: "IDR",
+		}, status: http.StatusForbidden},
+		{name: "MintFromRestricted", token: readOnly.AccessToken, method: "POST", path: "/account/tokens", body: createScopedTokenRequest{
+			Scopes: []string{authz.ScopeWalletsRead},
+		}, status: http.StatusForbidden},
+		{name: "RevokeFromRestricted", token: readOnly.AccessToken, method: "DELETE", path: "/account/tokens/" + oneWallet.ID.String(), status: http.StatusForbidden},
+		{name: "WalletNotInToken", token: oneWallet.AccessToken, method: "GET", path: fmt.Sprintf("/wallet/%d", other.Account.AccountNumber), status: http.StatusForbidden},
+		{name: "ScopeOfOtherRole", token: loginRes.AccessToken, method: "POST", path: "/account/tokens", body: createScopedTokenRequest{
+			Scopes: []string{authz.ScopeAdmin},
+		}, status: http.StatusForbidden},
+		{name: "UnknownScope", token: loginRes.AccessToken, method: "POST", path: "/account/tokens", body: createScopedTokenRequest{
+			Scopes: []string{"everything"},
+		}, status: http.StatusForbidden},
+		{name: "OtherWallet", token: loginRes.AccessToken, method: "POST", path: "/account/tokens", body: createScopedTokenRequest{
+			Scopes: []string{authz.ScopeWalletsRead}, Wallets: []int64{other.Account.AccountNumber},
+		}, status: http.StatusForbidden},
+		{name: "TooLong", token: loginRes.AccessToken, method: "POST", path: "/account/tokens", body: createScopedTokenRequest{
+			Scopes: []string{authz.ScopeWalletsRead}, Duration: (testConfig.ScopedTokenMaxDuration + 1).String(),
+		}, status: http.StatusUnprocessableEntity},
+		{name: "NoScope", token: loginRes.AccessToken, method: "POST", path: "/account/tokens", body: createScopedTokenRequest{}, status: http.StatusUnprocessableEntity},
+		{name: "BadTokenID", token: loginRes.AccessToken, method: "DELETE", path: "/account/tokens/abc", status: http.StatusBadRequest},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			status, body := sendRequest(t, tc.method, tc.path, tc.token, tc.body)
+			require.Equal(t, tc.status, status, string(body))
+		})
+	}
+
+	// a revoked token can't be used anymore
+	status, body := sendRequest(t, "DELETE", "/account/tokens/"+readOnly.ID.String(), loginRes.AccessToken, nil)
+	require.Equal(t, http.StatusOK, status, string(body))
+	status, _ = sendRequest(t, "GET", fmt.Sprintf("/wallet/%d", walletNumber), readOnly.AccessToken, nil)
+	require.Equal(t, http.StatusUnauthorized, status)
+}
+
+func TestScopedTokenRoleChange(t *testing.T) {
+	support := loginWithRole(t, authz.RoleSupport)
+	admin := loginWithRole(t, authz.RoleAdmin)
+	scoped := createScopedToken(t, support.AccessToken, createScopedTokenRequest{Scopes: []string{authz.ScopeAdmin}})
+
+	// the account isn't locked, but the support can try to unlock it
+	unlockPath := fmt.Sprintf("/admin/accounts/%d/unlock", admin.Account.AccountNumber)
+	status, body := sendRequest(t, "POST", unlockPath, scoped.AccessToken, nil)
+	require.Equal(t, http.StatusConflict, status, string(body))
+
+	rolePath := fmt.Sprintf("/admin/accounts/%d/role", support.Account.AccountNumber)
+	status, body = sendRequest(t, "PUT", rolePath, admin.AccessToken, setRoleRequest{Role: authz.RoleCustomer})
+	require.Equal(t, http.StatusOK, status, string(body))
+
+	// the tokens of the previous role are refused at once, the scoped one too
+	for _, accessToken := range []string{support.AccessToken, scoped.AccessToken} {
+		status, body = sendRequest(t, "POST", unlockPath, accessToken, nil)
+		require.Equal(t, http.StatusUnauthorized, status, string(body))
+	}
+
+	// the session get a token of the new role
+	refreshed := refreshSession(t, support.RefreshToken)
+	status, body = sendRequest(t, "POST", unlockPath, refreshed.AccessToken, nil)
+	require.Equal(t, http.StatusForbidden, status, string(body))
+}
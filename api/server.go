@@ -3,12 +3,19 @@ package api
 import (
 	"bufio"
 	"context"
+	"fmt"
 	"log"
 	"net/http"
 	"os"
+	"simple-bank-system/authz"
 	"simple-bank-system/db/services"
+	"simple-bank-system/notification"
+	"simple-bank-system/ratelimit"
+	"simple-bank-system/revocation"
+	"simple-bank-system/stream"
 	"simple-bank-system/token"
 	"simple-bank-system/util"
+	"strings"
 	"time"
 
 	"github.com/go-playground/validator/v10"
@@ -26,23 +33,108 @@ type Server struct {
 	handler    http.Handler
 	tokenMaker token.Maker
 	duration   time.Duration
+	// the handlers only queue the notifications, they are sent in the background
+	notifier notification.Notifier
+	broker   *stream.Broker
+	// access tokens revoked by a logout
+	revocations *revocation.List
+	// public keys of the v4.public tokens, nil with symmetric tokens
+	keyRing *token.KeyRing
+	// lifetime of the sessions (refresh tokens)
+	refreshDuration time.Duration
+	// default and longest lifetime of the restricted tokens minted by the accounts
+	scopedTokenDuration    time.Duration
+	scopedTokenMaxDuration time.Duration
+	// transfer limit to new payees
+	payeeCoolingOff      time.Duration
+	payeeCoolingOffLimit int64
+	// time to verify a new email, it can't be verified when email isn't configured
+	emailVerificationDuration time.Duration
+	emailEnabled              bool
+	passwordResetDuration     time.Duration
+	passwordResetLimit        int
+	// 2FA login challenge and transfer amount that need a TOTP code
+	twoFactorChallengeDuration time.Duration
+	twoFactorTransferAmount    int64
+	loginThrottle              loginThrottleConfig
+	rateLimiter                ratelimit.Backend
+	rateLimits                 rateLimitPolicies
+}
+
+const (
+	// events waiting to be sent, the next ones are dropped and logged
+	notificationQueueSize = 1000
+	// time to send 1 notification with every sender
+	notificationTimeout = 30 * time.Second
+)
+
+// failed logins before a lockout and its duration
+type loginThrottleConfig struct {
+	maxAttempts   int
+	ipMaxAttempts int
+	window        time.Duration
+	lockout       time.Duration
+	maxLockout    time.Duration
 }
 
 // This func will create new "Server" instance, and setup all HTTP API routes for services on that server
 var validate *validator.Validate
 
 func NewServer(store *services.Store, ctx context.Context, config util.Config) (*Server, error) {
-	maker, err := CreatePasetoMaker([]byte(config.TokenSymmetricKey))
+	maker, keyRing, err := createTokenMaker(config)
 	if err != nil {
 		return nil, err
 	}
+	rateLimiter, rateLimits, err := newRateLimiter(store, config)
+	if err != nil {
+		return nil, err
+	}
+	notifications := notification.NewQueue(notification.NewService(store, config), notificationQueueSize, notificationTimeout)
 	server := &Server{
 		store:      store,
 		ctx:        ctx,
 		tokenMaker: maker,
+		keyRing:    keyRing,
 		duration:   config.AccessTokenDuration,
+		notifier:   notifications,
+		broker:     stream.NewBroker(),
+
+		revocations: revocation.NewList(store),
+
+		refreshDuration: config.RefreshTokenDuration,
+
+		scopedTokenDuration:    config.ScopedTokenDuration,
+		scopedTokenMaxDuration: config.ScopedTokenMaxDuration,
+
+		payeeCoolingOff:      config.PayeeCoolingOff,
+		payeeCoolingOffLimit: config.PayeeCoolingOffLimit,
+
+		emailVerificationDuration: config.EmailVerificationDuration,
+		emailEnabled:              config.SMTPAddr != "",
+		passwordResetDuration:     config.PasswordResetDuration,
+		passwordResetLimit:        config.PasswordResetLimit,
+
+		twoFactorChallengeDuration: config.TwoFactorChallengeDuration,
+		twoFactorTransferAmount:    config.TwoFactorTransferAmount,
+
+		loginThrottle: loginThrottleConfig{
+			maxAttempts:   config.LoginMaxAttempts,
+			ipMaxAttempts: config.LoginIPMaxAttempts,
+			window:        config.LoginFailureWindow,
+			lockout:       config.LoginLockoutDuration,
+			maxLockout:    config.LoginMaxLockoutDuration,
+		},
+		rateLimiter: rateLimiter,
+		rateLimits:  rateLimits,
 	}
 
+	// the wallet streams are woken up by the outbox notifications for the whole life of the process
+	go server.broker.Run(context.Background(), store)
+	// the notifications of the handlers are sent for the whole life of the process
+	go notifications.Run(context.Background())
+	// the revocations of the other servers are loaded every interval
+	go server.revocations.Run(context.Background(), config.RevocationRefreshInterval)
+
 	validate = validator.New()
 	validate.RegisterValidation("currency", validCurrency)
 
@@ -58,22 +150,112 @@ func NewServer(store *services.Store, ctx context.Context, config util.Config) (
 func (server *Server) setupRouter() (*httprouter.Router, http.Handler) {
 	router := httprouter.New()
 
+	// Every state-changing route is wrapped by 'server.audit' with its action name
+	// and every authenticated route by 'authorize' with the permission it needs (see the 'authz' package).
+	// 'server.rateLimit' apply the policy of the route group: login (by client IP), transfers and reads (by account)
 	// "createAccount" is made to be a method of the server, so it get access to the "store" object
 	// in order to save new account ro the database
-	router.POST("/account", server.createAccount)
-	router.POST("/account/login", server.loginAccount)
+	// Public keys of the v4.public tokens, so other services verify the tokens offline
+	router.GET("/token/keys", server.rateLimit(server.rateLimits.read, server.listTokenKeys))
+	router.POST("/account", server.rateLimit(server.rateLimits.login, server.audit("account.create", server.createAccount)))
+	router.POST("/account/login", server.rateLimit(server.rateLimits.login, server.audit("account.login", server.loginAccount)))
+	// New access token for the refresh token of the login, the refresh token is rotated
+	router.POST("/account/token/refresh", server.rateLimit(server.rateLimits.login, server.audit("session.refresh", server.refreshToken)))
+	// Forgot password, the single-use token is emailed and exchanged for a new password
+	router.POST("/account/password/forgot", server.rateLimit(server.rateLimits.login, server.audit("account.forgot_password", server.forgotPassword)))
+	router.POST("/account/password/reset", server.rateLimit(server.rateLimits.login, server.audit("account.reset_password", server.resetPassword)))
+	// Second step of the login of the accounts with 2FA, the challenge token is exchanged with a TOTP or recovery code
+	router.POST("/account/login/2fa", server.rateLimit(server.rateLimits.login, server.audit("account.login_2fa", server.loginTwoFactor)))
+	// Logout revoke the access token of the request, logout/all revoke every token and session of the account
+	router.POST("/account/logout", server.authMiddleware(server.audit("account.logout", authorize(authz.ProfileManage, server.logout))))
+	router.POST("/account/logout/all", server.authMiddleware(server.audit("account.logout_all", authorize(authz.ProfileManage, server.logoutAll))))
+	// Restricted tokens minted from a login for other apps (scopes and wallets), revoked with their id
+	router.POST("/account/tokens", server.authMiddleware(server.audit("account.token_create", authorize(authz.ProfileManage, server.createScopedToken))))
+	router.DELETE("/account/tokens/:id", server.authMiddleware(server.audit("account.token_revoke", authorize(authz.ProfileManage, server.revokeScopedToken))))
+
+	// Profile of the logged in account, a new email is only used after it's verified and
+	// a new password revoke the access tokens that were issued before
+	router.GET("/account/me", server.authMiddleware(server.rateLimit(server.rateLimits.read, authorize(authz.ProfileManage, server.getProfile))))
+	router.PUT("/account/me/fullname", server.authMiddleware(server.audit("account.update_fullname", authorize(authz.ProfileManage, server.updateFullName))))
+	router.PUT("/account/me/address", server.authMiddleware(server.audit("account.update_address", authorize(authz.ProfileManage, server.updateAddress))))
+	router.GET("/account/me/addresses", server.authMiddleware(server.rateLimit(server.rateLimits.read, authorize(authz.ProfileManage, server.listAddressHistory))))
+	router.PUT("/account/me/password", server.authMiddleware(server.audit("account.change_password", authorize(authz.ProfileManage, server.changePassword))))
+	router.PUT("/account/me/email", server.authMiddleware(server.audit("account.change_email", authorize(authz.ProfileManage, server.changeEmail))))
+	// TOTP 2FA, the secret is enabled by the first code and the recovery codes are only shown once
+	router.POST("/account/me/2fa/totp", server.authMiddleware(server.audit("account.totp_enroll", authorize(authz.ProfileManage, server.enrollTOTP))))
+	router.POST("/account/me/2fa/totp/confirm", server.authMiddleware(server.audit("account.totp_enable", authorize(authz.ProfileManage, server.confirmTOTP))))
+	router.DELETE("/account/me/2fa/totp", server.authMiddleware(server.audit("account.totp_disable", authorize(authz.ProfileManage, server.disableTOTP))))
+	router.POST("/account/me/2fa/recovery-codes", server.authMiddleware(server.audit("account.recovery_codes", authorize(authz.ProfileManage, server.regenerateRecoveryCodes))))
+	router.POST("/account/me/email/verify", server.authMiddleware(server.audit("account.verify_email", authorize(authz.ProfileManage, server.verifyEmail))))
 
 	// Add middleware auth to handler
-	router.POST("/wallet", authMiddleware(server.tokenMaker, server.createWallet))
-	router.GET("/wallet/:number", authMiddleware(server.tokenMaker, server.getWallet))
-	router.GET("/wallet", authMiddleware(server.tokenMaker, server.listWallets))
-	router.PUT("/wallet/update/:number", authMiddleware(server.tokenMaker, server.updateWallet))
-	router.PUT("/wallet/updateInfo/:number", authMiddleware(server.tokenMaker, server.updateWalletInfo))
-	router.DELETE("/wallet/delete/:number", authMiddleware(server.tokenMaker, server.deleteWallet))
+	router.POST("/wallet", server.authMiddleware(server.audit("wallet.create", authorize(authz.WalletWrite, server.createWallet))))
+	router.GET("/wallet/:number", server.authMiddleware(server.rateLimit(server.rateLimits.read, authorize(authz.WalletRead, server.getWallet))))
+	router.GET("/wallet/:number/interest", server.authMiddleware(server.rateLimit(server.rateLimits.read, authorize(authz.WalletRead, server.getWalletInterest))))
+	router.GET("/wallet/:number/statement", server.authMiddleware(server.rateLimit(server.rateLimits.read, authorize(authz.WalletRead, server.exportStatement))))
+	router.GET("/wallet/:number/balance", server.authMiddleware(server.rateLimit(server.rateLimits.read, authorize(authz.WalletRead, server.getWalletBalance))))
+	router.GET("/wallet", server.authMiddleware(server.rateLimit(server.rateLimits.read, authorize(authz.WalletRead, server.listWallets))))
+	// Server-Sent Events of the balance changes of all wallets of the account
+	router.GET("/wallets/stream", server.streamAuthMiddleware(server.rateLimit(server.rateLimits.read, authorize(authz.WalletRead, server.streamWallets))))
+	router.PUT("/wallet/updateInfo/:number", server.authMiddleware(server.audit("wallet.update_info", authorize(authz.WalletWrite, server.updateWalletInfo))))
+	router.DELETE("/wallet/delete/:number", server.authMiddleware(server.audit("wallet.delete", authorize(authz.WalletWrite, server.deleteWallet))))
+
+	// Shared wallet, the owner invite other accounts with a role (owner, spender or viewer)
+	router.POST("/wallet/:number/members", server.authMiddleware(server.audit("wallet_member.invite", authorize(authz.WalletWrite, server.inviteWalletMember))))
+	router.GET("/wallet/:number/members", server.authMiddleware(server.rateLimit(server.rateLimits.read, authorize(authz.WalletRead, server.listWalletMembers))))
+	router.PUT("/wallet/members/:number/:account_number", server.authMiddleware(server.audit("wallet_member.update", authorize(authz.WalletWrite, server.updateWalletMember))))
+	router.DELETE("/wallet/members/:number/:account_number", server.authMiddleware(server.audit("wallet_member.remove", authorize(authz.WalletWrite, server.removeWalletMember))))
+	router.GET("/invitations", server.authMiddleware(server.rateLimit(server.rateLimits.read, authorize(authz.WalletRead, server.listInvitations))))
+	router.POST("/invitations/:number/accept", server.authMiddleware(server.audit("invitation.accept", authorize(authz.WalletWrite, server.acceptInvitation))))
+	router.POST("/invitations/:number/decline", server.authMiddleware(server.audit("invitation.decline", authorize(authz.WalletWrite, server.declineInvitation))))
+
+	router.POST("/transfer", server.authMiddleware(server.rateLimit(server.rateLimits.transfer, server.audit("transfer.create", authorize(authz.TransferCreate, server.createTransfer)))))
+
+	// Saved payees, transfer can use 'payee_id' in place of 'to_wallet_number'
+	router.POST("/payees", server.authMiddleware(server.audit("payee.create", authorize(authz.PayeeManage, server.createPayee))))
+	router.GET("/payees", server.authMiddleware(server.rateLimit(server.rateLimits.read, authorize(authz.PayeeManage, server.listPayees))))
+	router.GET("/payees/lookup/:number", server.authMiddleware(server.rateLimit(server.rateLimits.read, authorize(authz.PayeeManage, server.lookupPayee))))
+	router.DELETE("/payees/:id", server.authMiddleware(server.audit("payee.delete", authorize(authz.PayeeManage, server.deletePayee))))
+
+	// In-app notifications and the channels of every event type
+	router.GET("/notifications", server.authMiddleware(server.rateLimit(server.rateLimits.read, authorize(authz.NotificationManage, server.listNotifications))))
+	router.POST("/notifications/read", server.authMiddleware(server.audit("notification.read", authorize(authz.NotificationManage, server.readNotifications))))
+	router.GET("/notifications/preferences", server.authMiddleware(server.rateLimit(server.rateLimits.read, authorize(authz.NotificationManage, server.getNotificationPreferences))))
+	router.PUT("/notifications/preferences", server.authMiddleware(server.audit("notification_preferences.update", authorize(authz.NotificationManage, server.updateNotificationPreferences))))
+
+	// Webhook subscriptions of the account, deliveries are signed with the secret of the webhook
+	router.POST("/webhooks", server.authMiddleware(server.audit("webhook.create", authorize(authz.WebhookManage, server.createWebhook))))
+	router.GET("/webhooks", server.authMiddleware(server.rateLimit(server.rateLimits.read, authorize(authz.WebhookManage, server.listWebhooks))))
+	router.GET("/webhooks/:id", server.authMiddleware(server.rateLimit(server.rateLimits.read, authorize(authz.WebhookManage, server.getWebhook))))
+	router.DELETE("/webhooks/:id", server.authMiddleware(server.audit("webhook.delete", authorize(authz.WebhookManage, server.deleteWebhook))))
+	router.POST("/webhooks/:id/rotate-secret", server.authMiddleware(server.audit("webhook.rotate_secret", authorize(authz.WebhookManage, server.rotateWebhookSecret))))
+	router.GET("/webhooks/:id/deliveries", server.authMiddleware(server.rateLimit(server.rateLimits.read, authorize(authz.WebhookManage, server.listWebhookDeliveries))))
+	router.GET("/webhooks/:id/deliveries/:delivery_id", server.authMiddleware(server.rateLimit(server.rateLimits.read, authorize(authz.WebhookManage, server.getWebhookDelivery))))
+	router.POST("/webhooks/:id/deliveries/:delivery_id/replay", server.authMiddleware(server.audit("webhook_delivery.replay", authorize(authz.WebhookManage, server.replayWebhookDelivery))))
+
+	// ISO 20022 pain.001 payment file, the response is the pain.002 status report
+	router.POST("/payment-files", server.authMiddleware(server.rateLimit(server.rateLimits.transfer, server.audit("payment_file.upload", authorize(authz.TransferCreate, server.uploadPaymentFile)))))
 
-	router.POST("/transfer", authMiddleware(server.tokenMaker, server.createTransfer))
-	//router.GET("/transfer/:number", authMiddleware(server.tokenMaker, server.createTransfer))
-	//router.GET("/transfer/list/:number", authMiddleware(server.tokenMaker, server.listTransfer))
+	// Payment request, 1 or more payers (split bill) pay, decline or ignore their share
+	router.POST("/payment-requests", server.authMiddleware(server.audit("payment_request.create", authorize(authz.PaymentRequestManage, server.createPaymentRequest))))
+	router.GET("/payment-requests", server.authMiddleware(server.rateLimit(server.rateLimits.read, authorize(authz.PaymentRequestManage, server.listPaymentRequests))))
+	router.GET("/payment-requests/:id", server.authMiddleware(server.rateLimit(server.rateLimits.read, authorize(authz.PaymentRequestManage, server.getPaymentRequest))))
+	router.POST("/payment-requests/:id/pay", server.authMiddleware(server.rateLimit(server.rateLimits.transfer, server.audit("payment_request.pay", authorize(authz.PaymentRequestManage, server.payPaymentRequest)))))
+	router.POST("/payment-requests/:id/decline", server.authMiddleware(server.audit("payment_request.decline", authorize(authz.PaymentRequestManage, server.declinePaymentRequest))))
+
+	// Admin API of the staff roles (support, operator and admin)
+	router.GET("/admin/accounts/:number", server.authMiddleware(server.rateLimit(server.rateLimits.read, authorize(authz.AdminAccountRead, server.adminGetAccount))))
+	router.POST("/admin/accounts/:number/unlock", server.authMiddleware(server.audit("admin.account.unlock", authorize(authz.AdminAccountUnlock, server.adminUnlockAccount))))
+	router.PUT("/admin/accounts/:number/role", server.authMiddleware(server.audit("admin.account.role", authorize(authz.AdminRoleAssign, server.adminSetAccountRole))))
+	router.GET("/admin/wallets/:number", server.authMiddleware(server.rateLimit(server.rateLimits.read, authorize(authz.AdminWalletRead, server.adminGetWallet))))
+	router.POST("/admin/wallets/:number/freeze", server.authMiddleware(server.audit("admin.wallet.freeze", authorize(authz.AdminWalletFreeze, server.adminFreezeWallet))))
+	router.POST("/admin/wallets/:number/unfreeze", server.authMiddleware(server.audit("admin.wallet.unfreeze", authorize(authz.AdminWalletFreeze, server.adminUnfreezeWallet))))
+	router.POST("/admin/wallets/:number/adjustments", server.authMiddleware(server.audit("admin.wallet.adjust_balance", authorize(authz.AdminBalanceAdjust, server.adminAdjustBalance))))
+	router.GET("/admin/ledger", server.authMiddleware(server.rateLimit(server.rateLimits.read, authorize(authz.AdminLedgerRead, server.adminListLedger))))
+	router.GET("/admin/audit-logs", server.authMiddleware(server.rateLimit(server.rateLimits.read, authorize(authz.AdminAuditRead, server.listAuditLogs))))
+	router.PUT("/admin/interest-rates/:wallet_type", server.authMiddleware(server.audit("admin.interest_rate.set", authorize(authz.AdminInterestRateSet, server.adminSetInterestRate))))
+	//router.GET("/transfer/:number", server.authMiddleware(server.createTransfer))
+	//router.GET("/transfer/list/:number", server.authMiddleware(server.listTransfer))
 
 	handler := cors.Default().Handler(router)
 
@@ -94,6 +276,39 @@ func (server *Server) Start(address string) {
 	}
 }
 
+// createTokenMaker return the maker of 'TOKEN_TYPE', the key ring is only set for v4.public tokens.
+func createTokenMaker(config util.Config) (token.Maker, *token.KeyRing, error) {
+	switch config.TokenType {
+	case "", "paseto":
+		maker, err := CreatePasetoMaker([]byte(config.TokenSymmetricKey))
+		return maker, nil, err
+	case "paseto-public":
+		if config.TokenSigningKey == "" {
+			return nil, nil, fmt.Errorf("TOKEN_SIGNING_KEY is required by paseto-public tokens")
+		}
+		ring, err := token.ParseKeyRing(config.TokenSigningKeyID, config.TokenSigningKey, config.TokenVerificationKeys)
+		if err != nil {
+			return nil, nil, err
+		}
+		maker, err := token.NewPasetoPublicMaker(ring)
+		return maker, ring, err
+	case "jwt":
+		var algorithms []string
+		for _, alg := range strings.Split(config.TokenJWTAlgorithms, ",") {
+			if alg = strings.TrimSpace(alg); alg != "" {
+				algorithms = append(algorithms, alg)
+			}
+		}
+		maker, err := token.NewJWTMakerWithOptions(config.TokenSymmetricKey, token.JWTOptions{
+			Issuer:     config.TokenIssuer,
+			Audience:   config.TokenAudience,
+			Algorithms: algorithms,
+		})
+		return maker, nil, err
+	}
+	return nil, nil, fmt.Errorf("unknown TOKEN_TYPE '%s', use paseto, paseto-public or jwt", config.TokenType)
+}
+
 func CreatePasetoMaker(key []byte) (token.Maker, error) {
 	maker, err := token.NewPasetoMaker(key)
 	if err != nil {
@@ -0,0 +1,228 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"simple-bank-system/db/pkg"
+	"simple-bank-system/db/services"
+	"simple-bank-system/token"
+	"simple-bank-system/util"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+// Login sessions, the short access token is renewed with the refresh token of the session.
+
+type refreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token" validate:"required"`
+}
+
+// the refresh token of the session is optional, the session is revoked with the access token
+type logoutRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+type refreshTokenResponse struct {
+	AccessToken           string    `json:"access_token"`
+	RefreshToken          string    `json:"refresh_token"`
+	RefreshTokenExpiresAt time.Time `json:"refresh_token_expires_at"`
+}
+
+// parseRefreshToken split the refresh token '<session id>.<secret>'.
+func parseRefreshToken(refreshToken string) (int64, string, bool) {
+	id, secret, ok := strings.Cut(refreshToken, ".")
+	if !ok || secret == "" {
+		return 0, "", false
+	}
+	sessionID, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		return 0, "", false
+	}
+	return sessionID, secret, true
+}
+
+// createSession start the session of the device that sent the request and return its refresh token.
+func (server *Server) createSession(r *http.Request, accountID int64) (string, *pkg.Session, error) {
+	secret, err := util.RandomToken(32)
+	if err != nil {
+		return "", nil, err
+	}
+
+	session, err := server.store.CreateSession(server.ctx, services.CreateSessionParams{
+		AccountID:        accountID,
+		RefreshTokenHash: util.HashToken(secret),
+		UserAgent:        r.UserAgent(),
+		ClientIP:         clientIP(r),
+		ExpiresAt:        time.Now().Add(server.refreshDuration),
+	})
+	if err != nil {
+		return "", nil, err
+	}
+	return fmt.Sprintf("%d.%s", session.ID, secret), session, nil
+}
+
+/*
+ * createAccessToken create an access token that 'tokenValid' accept. The JWT 'iat' is in whole seconds, so
+ * a token issued in the same second as a password change (or a logout everywhere) is taken as older than it:
+ * the token is issued again at the next second.
+ */
+func (server *Server) createAccessToken(accountID int64, role string) (string, error) {
+	for attempt := 0; ; attempt++ {
+		accessToken, err := server.tokenMaker.CreateToken(accountID, role, server.duration)
+		if err != nil {
+			return "", err
+		}
+		payload, err := server.tokenMaker.VerifyToken(accessToken)
+		if err != nil {
+			return "", err
+		}
+
+		err = server.tokenValid(server.ctx, payload)
+		if (err == errTokenBeforePasswordChange || err == errTokenRevoked) && attempt == 0 {
+			time.Sleep(time.Until(payload.IssuedAt.Truncate(time.Second).Add(time.Second)))
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+		return accessToken, nil
+	}
+}
+
+/*
+ * refreshToken exchange the refresh token for a new access token and a new refresh token, the used one
+ * can't be used again: if it is, the whole session is revoked.
+ */
+func (server *Server) refreshToken(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	var req refreshTokenRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := validate.Struct(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	sessionID, secret, ok := parseRefreshToken(req.RefreshToken)
+	if !ok {
+		http.Error(w, "refresh token is wrong", http.StatusUnauthorized)
+		return
+	}
+	auditTarget(r, "session_id", sessionID)
+
+	newSecret, err := util.RandomToken(32)
+	if err != nil {
+		http.Error(w, "Failed to create refresh token", (http.StatusInternalServerError))
+		json.NewEncoder(w).Encode(err.Error())
+		return
+	}
+
+	session, err := server.store.RotateSessionTx(server.ctx, services.RotateSessionParams{
+		ID:                  sessionID,
+		RefreshTokenHash:    util.HashToken(secret),
+		NewRefreshTokenHash: util.HashToken(newSecret),
+		UserAgent:           r.UserAgent(),
+		ClientIP:            clientIP(r),
+	})
+	if err != nil {
+		switch err {
+		case util.ErrNotExist:
+			http.Error(w, "refresh token is wrong", http.StatusUnauthorized)
+			return
+		case util.ErrTokenReused, util.ErrSessionRevoked, util.ErrTokenExpired:
+			http.Error(w, err.Error()+", login again", http.StatusUnauthorized)
+			return
+		}
+		http.Error(w, "Failed to refresh token", (http.StatusInternalServerError))
+		json.NewEncoder(w).Encode(err.Error())
+		return
+	}
+	setAuditActor(r, session.AccountID)
+
+	// the role may have changed since the login
+	account, err := server.store.GetAccountProfile(server.ctx, session.AccountID)
+	if err != nil {
+		http.Error(w, "Can't get account", http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(err.Error())
+		return
+	}
+
+	accessToken, err := server.createAccessToken(account.ID, account.Role)
+	if err != nil {
+		http.Error(w, "Failed to create encryted token", http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(err.Error())
+		return
+	}
+
+	w.Header().Add("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(refreshTokenResponse{
+		AccessToken:           accessToken,
+		RefreshToken:          fmt.Sprintf("%d.%s", session.ID, newSecret),
+		RefreshTokenExpiresAt: session.ExpiresAt,
+	})
+}
+
+// logout revoke the access token of the request and the session of the refresh token.
+func (server *Server) logout(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	var req logoutRequest
+	// the body can be empty
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	authPayload := r.Context().Value("authPayloadKey").(*token.Payload)
+
+	if req.RefreshToken != "" {
+		sessionID, _, ok := parseRefreshToken(req.RefreshToken)
+		if !ok {
+			http.Error(w, "refresh token is wrong", http.StatusBadRequest)
+			return
+		}
+		auditTarget(r, "session_id", sessionID)
+
+		err := server.store.RevokeSession(server.ctx, sessionID, authPayload.AccountID)
+		if err != nil {
+			if err == util.ErrNotExist {
+				http.Error(w, "refresh token is wrong", http.StatusBadRequest)
+				return
+			}
+			http.Error(w, "Failed to revoke session", (http.StatusInternalServerError))
+			json.NewEncoder(w).Encode(err.Error())
+			return
+		}
+	}
+
+	if err := server.revocations.Revoke(server.ctx, authPayload); err != nil {
+		http.Error(w, "Failed to revoke token", (http.StatusInternalServerError))
+		json.NewEncoder(w).Encode(err.Error())
+		return
+	}
+
+	w.Header().Add("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode("Logged out!")
+}
+
+// logoutAll revoke every access token of the account and every session, on all the devices.
+func (server *Server) logoutAll(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authPayload := r.Context().Value("authPayloadKey").(*token.Payload)
+
+	err := server.revocations.RevokeAccount(server.ctx, authPayload.AccountID, time.Now(), server.longestTokenDuration())
+	if err != nil {
+		http.Error(w, "Failed to revoke tokens", (http.StatusInternalServerError))
+		json.NewEncoder(w).Encode(err.Error())
+		return
+	}
+
+	w.Header().Add("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode("Logged out on every device!")
+}
@@ -0,0 +1,127 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// refreshSession exchange the refresh token, the status code must be 200.
+func refreshSession(t *testing.T, refreshToken string) refreshTokenResponse {
+	status, body := sendRequest(t, "POST", "/account/token/refresh", "", refreshTokenRequest{RefreshToken: refreshToken})
+	require.Equal(t, http.StatusOK, status, string(body))
+
+	var response refreshTokenResponse
+	require.NoError(t, json.Unmarshal(body, &response))
+	return response
+}
+
+func TestRefreshToken(t *testing.T) {
+	loginRes := loginAccount(t)
+	require.NotEmpty(t, loginRes.RefreshToken)
+	assert.WithinDuration(t, time.Now().Add(testConfig.RefreshTokenDuration), loginRes.RefreshTokenExpiresAt, time.Minute)
+
+	refreshed := refreshSession(t, loginRes.RefreshToken)
+	require.NotEqual(t, loginRes.RefreshToken, refreshed.RefreshToken, "the refresh token is rotated")
+	getProfile(t, refreshed.AccessToken)
+
+	// the next refresh use the new token
+	refreshed = refreshSession(t, refreshed.RefreshToken)
+
+	testCases := []struct {
+		name         string
+		refreshToken string
+		status       int
+	}{
+		{name: "Empty", refreshToken: "", status: http.StatusUnprocessableEntity},
+		{name: "NoSecret", refreshToken: "123.", status: http.StatusUnauthorized},
+		{name: "NotNumber", refreshToken: "abc.secret", status: http.StatusUnauthorized},
+		{name: "SessionNotExist", refreshToken: "999999999999.secret", status: http.StatusUnauthorized},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			status, body := sendRequest(t, "POST", "/account/token/refresh", "", refreshTokenRequest{RefreshToken: tc.refreshToken})
+			require.Equal(t, tc.status, status, string(body))
+		})
+	}
+}
+
+func TestRefreshTokenReused(t *testing.T) {
+	loginRes := loginAccount(t)
+	refreshed := refreshSession(t, loginRes.RefreshToken)
+
+	// a rotated token is used again, someone else may have it
+	status, body := sendRequest(t, "POST", "/account/token/refresh", "", refreshTokenRequest{RefreshToken: loginRes.RefreshToken})
+	require.Equal(t, http.StatusUnauthorized, status, string(body))
+
+	// the whole session is revoked, also the latest token
+	status, body = sendRequest(t, "POST", "/account/token/refresh", "", refreshTokenRequest{RefreshToken: refreshed.RefreshToken})
+	require.Equal(t, http.StatusUnauthorized, status, string(body))
+
+	// the other sessions of the account aren't
+	other := login(t, loginRes.Account.Username, Password)
+	refreshSession(t, other.RefreshToken)
+}
+
+func TestLogout(t *testing.T) {
+	loginRes := loginAccount(t)
+	otherDevice := login(t, loginRes.Account.Username, Password)
+	stranger := loginAccount(t)
+
+	testCases := []struct {
+		name         string
+		refreshToken string
+		status       int
+	}{
+		{name: "WrongRefreshToken", refreshToken: "abc", status: http.StatusBadRequest},
+		{name: "OtherAccountSession", refreshToken: stranger.RefreshToken, status: http.StatusBadRequest},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			status, body := sendRequest(t, "POST", "/account/logout", loginRes.AccessToken, logoutRequest{RefreshToken: tc.refreshToken})
+			require.Equal(t, tc.status, status, string(body))
+		})
+	}
+	// the session of the other account isn't revoked
+	refreshSession(t, stranger.RefreshToken)
+
+	status, body := sendRequest(t, "POST", "/account/logout", loginRes.AccessToken, logoutRequest{RefreshToken: loginRes.RefreshToken})
+	require.Equal(t, http.StatusOK, status, string(body))
+
+	// the token and the session of this device can't be used anymore
+	status, _ = sendRequest(t, "GET", "/account/me", loginRes.AccessToken, nil)
+	require.Equal(t, http.StatusUnauthorized, status)
+	status, _ = sendRequest(t, "POST", "/account/token/refresh", "", refreshTokenRequest{RefreshToken: loginRes.RefreshToken})
+	require.Equal(t, http.StatusUnauthorized, status)
+
+	// the other device is still logged in
+	getProfile(t, otherDevice.AccessToken)
+	refreshSession(t, otherDevice.RefreshToken)
+}
+
+func TestLogoutAll(t *testing.T) {
+	loginRes := loginAccount(t)
+	otherDevice := login(t, loginRes.Account.Username, Password)
+
+	status, body := sendRequest(t, "POST", "/account/logout/all", loginRes.AccessToken, nil)
+	require.Equal(t, http.StatusOK, status, string(body))
+
+	for _, device := range []loginResponse{loginRes, otherDevice} {
+		status, _ = sendRequest(t, "GET", "/account/me", device.AccessToken, nil)
+		require.Equal(t, http.StatusUnauthorized, status)
+		status, _ = sendRequest(t, "POST", "/account/token/refresh", "", refreshTokenRequest{RefreshToken: device.RefreshToken})
+		require.Equal(t, http.StatusUnauthorized, status)
+	}
+
+	// a new login isn't revoked
+	newLogin := login(t, loginRes.Account.Username, Password)
+	getProfile(t, newLogin.AccessToken)
+}
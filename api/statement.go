@@ -0,0 +1,107 @@
+package api
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"simple-bank-system/db/services"
+	"simple-bank-system/statement"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+// statementMaxDays is the longest statement, a longer period is exported in several statements.
+const statementMaxDays = 366
+
+/*
+ * exportStatement write the statement of the wallet from 'from' until 'to' (inclusive dates, YYYY-MM-DD)
+ * in 'format' csv (default), ofx, mt940 or camt053. The entries are streamed to the response by pages.
+ */
+func (server *Server) exportStatement(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	query := r.URL.Query()
+	from, err := time.Parse(time.DateOnly, query.Get("from"))
+	if err != nil {
+		http.Error(w, "from must be a date (YYYY-MM-DD)", http.StatusBadRequest)
+		return
+	}
+	to, err := time.Parse(time.DateOnly, query.Get("to"))
+	if err != nil || to.Before(from) {
+		http.Error(w, "to must be a date (YYYY-MM-DD) that isn't before from", http.StatusBadRequest)
+		return
+	}
+	if to.After(from.AddDate(0, 0, statementMaxDays-1)) {
+		http.Error(w, fmt.Sprintf("the statement can't be longer than %d days", statementMaxDays), http.StatusBadRequest)
+		return
+	}
+
+	format := query.Get("format")
+	if format == "" {
+		format = statement.FormatCSV
+	}
+	writer, err := statement.NewWriter(format, w)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	wallet, ok := server.walletByParam(w, ps)
+	if !ok {
+		return
+	}
+	if !server.authorizeWallet(w, r, wallet, services.WalletRoleViewer) {
+		return
+	}
+
+	accountNumber, err := server.store.GetAccountByID(r.Context(), wallet.AccountID)
+	if err != nil {
+		http.Error(w, "Can't get the wallet owner", http.StatusInternalServerError)
+		return
+	}
+	account, err := server.store.GetAccountByNumber(r.Context(), *accountNumber)
+	if err != nil {
+		http.Error(w, "Can't get the wallet owner", http.StatusInternalServerError)
+		return
+	}
+
+	headerSent := false
+	err = server.store.StatementTx(r.Context(), services.StatementTxParams{
+		WalletID: wallet.ID,
+		From:     from,
+		To:       to.AddDate(0, 0, 1),
+		WriteHeader: func(opening, closing int64) error {
+			contentType, extension := statement.ContentType(format)
+			w.Header().Add("Content-Type", contentType)
+			w.Header().Add("Content-Disposition", fmt.Sprintf("attachment; filename=\"statement_%d_%s_%s.%s\"", wallet.WalletNumber, from.Format(time.DateOnly), to.Format(time.DateOnly), extension))
+			w.WriteHeader(http.StatusOK)
+			headerSent = true
+
+			return writer.WriteHeader(statement.Header{
+				AccountNumber:  account.AccountNumber,
+				HolderName:     account.FullName,
+				WalletNumber:   wallet.WalletNumber,
+				WalletName:     wallet.Name,
+				WalletType:     wallet.Type,
+				Currency:       wallet.Currency,
+				From:           from,
+				To:             to,
+				OpeningBalance: opening,
+				ClosingBalance: closing,
+				GeneratedAt:    time.Now(),
+			})
+		},
+		WriteLine: writer.WriteLine,
+	})
+	if err == nil {
+		err = writer.Close()
+	}
+	if err != nil {
+		if !headerSent {
+			http.Error(w, "Can't read the statement", http.StatusInternalServerError)
+			return
+		}
+		// the status is already sent, errors while streaming can only be logged
+		log.Println("--- (err) export statement:", err)
+	}
+}
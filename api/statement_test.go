@@ -0,0 +1,67 @@
+package api
+
+import (
+	"bytes"
+	"encoding/csv"
+	"net/http"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExportStatement(t *testing.T) {
+	accRes := loginAccount(t)
+	stranger := loginAccount(t)
+	wallet := createWalletCurrency(t, accRes.AccessToken, "IDR")
+
+	for i := 0; i < 2; i++ {
+		status, body := transferMoney(t, accRes.AccessToken, accRes.Account.AccountNumber, wallet.WalletNumber, 1000)
+		require.Equal(t, http.StatusOK, status, string(body))
+	}
+
+	path := "/wallet/" + strconv.FormatInt(accRes.Account.AccountNumber, 10) + "/statement"
+	today := wallet.CreatedAt.Format("2006-01-02")
+
+	testCases := []struct {
+		name   string
+		token  string
+		query  string
+		status int
+	}{
+		{name: "NoFrom", token: accRes.AccessToken, query: "?to=" + today, status: http.StatusBadRequest},
+		{name: "ToBeforeFrom", token: accRes.AccessToken, query: "?from=" + today + "&to=2000-01-01", status: http.StatusBadRequest},
+		{name: "TooLong", token: accRes.AccessToken, query: "?from=2000-01-01&to=" + today, status: http.StatusBadRequest},
+		{name: "UnknownFormat", token: accRes.AccessToken, query: "?from=" + today + "&to=" + today + "&format=pdf", status: http.StatusBadRequest},
+		{name: "OtherAccount", token: stranger.AccessToken, query: "?from=" + today + "&to=" + today, status: http.StatusUnauthorized},
+	}
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			status, body := sendRequest(t, "GET", path+tc.query, tc.token, nil)
+			assert.Equal(t, tc.status, status, string(body))
+		})
+	}
+
+	status, body := sendRequest(t, "GET", path+"?from="+today+"&to="+today, accRes.AccessToken, nil)
+	require.Equal(t, http.StatusOK, status, string(body))
+
+	rows, err := csv.NewReader(bytes.NewReader(body)).ReadAll()
+	require.NoError(t, err)
+	// column header, opening balance, entries, closing balance
+	require.GreaterOrEqual(t, len(rows), 5)
+
+	opening, err := strconv.ParseInt(rows[1][6], 10, 64)
+	require.NoError(t, err)
+	closing, err := strconv.ParseInt(rows[len(rows)-1][6], 10, 64)
+	require.NoError(t, err)
+
+	balance := opening
+	for _, row := range rows[2 : len(rows)-1] {
+		amount, err := strconv.ParseInt(row[5], 10, 64)
+		require.NoError(t, err)
+		balance += amount
+	}
+	assert.Equal(t, closing, balance)
+}
@@ -0,0 +1,209 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"simple-bank-system/stream"
+	"simple-bank-system/token"
+	"simple-bank-system/util"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+const (
+	// the events are read again after this period when there was no notification, and a comment keep the connection open
+	streamHeartbeat = 15 * time.Second
+	// delay before the browser reconnect
+	streamRetry = 3 * time.Second
+	// max events that are read from the db at once
+	streamBatchSize = 100
+)
+
+// First event of a new stream, later events carry the new balance of their wallet
+type walletSnapshot struct {
+	WalletNumber int64  `json:"wallet_number"`
+	Name         string `json:"name"`
+	Currency     string `json:"currency"`
+	Balance      int64  `json:"balance"`
+}
+
+type walletStreamData struct {
+	WalletNumber int64           `json:"wallet_number"`
+	Data         json.RawMessage `json:"data"`
+	CreatedAt    time.Time       `json:"created_at"`
+}
+
+/*
+ * streamWallets send the events of the wallets the account can see ('transfer.completed', 'wallet.created',
+ * 'wallet.closed', 'savings_goal.reached') as Server-Sent Events. The id of every event is its outbox id.
+ * A new stream start with a 'snapshot' of the balances, a stream that reconnect with 'Last-Event-ID'
+ * (or '?last_event_id=') get the events after that id instead. The stream end with 'token_expired' when the
+ * token expire, the client reconnect with a new token and the last event id. The token is checked again on
+ * every heartbeat, the stream end with 'token_revoked' after a logout, a password change, a role change or a closed account.
+ */
+func (server *Server) streamWallets(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming isn't supported", http.StatusInternalServerError)
+		return
+	}
+
+	authPayload := r.Context().Value("authPayloadKey").(*token.Payload)
+	ctx := r.Context()
+
+	lastEventID := r.Header.Get("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = r.URL.Query().Get("last_event_id")
+	}
+
+	var lastID int64
+	var snapshot []walletSnapshot
+	if lastEventID != "" {
+		id, err := strconv.ParseInt(lastEventID, 10, 64)
+		if err != nil {
+			http.Error(w, "last event id must be a number", http.StatusBadRequest)
+			return
+		}
+		exists, err := server.store.OutboxEventExists(ctx, id)
+		if err != nil {
+			http.Error(w, "Can't get last event", http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(err.Error())
+			return
+		}
+		if !exists {
+			http.Error(w, "last event doesn't exist", http.StatusBadRequest)
+			return
+		}
+		lastID = id
+	} else {
+		// the cursor is read before the balances, so no change is missed between them
+		id, err := server.store.LastWalletStreamEventID(ctx, authPayload.AccountID)
+		if err != nil {
+			http.Error(w, "Can't get last event", http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(err.Error())
+			return
+		}
+		lastID = id
+
+		wallets, err := server.store.ListStreamWallets(ctx, authPayload.AccountID)
+		if err != nil {
+			http.Error(w, "Failed to get List", http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(err.Error())
+			return
+		}
+		snapshot = []walletSnapshot{}
+		for _, wallet := range wallets {
+			if !authPayload.AllowsWallet(wallet.WalletNumber) {
+				continue
+			}
+			snapshot = append(snapshot, walletSnapshot{
+				WalletNumber: wallet.WalletNumber,
+				Name:         wallet.Name,
+				Currency:     wallet.Currency,
+				Balance:      wallet.Balance,
+			})
+		}
+	}
+
+	// subscribe before the first read, a notification in between isn't lost
+	wake, unsubscribe := server.broker.Subscribe()
+	defer unsubscribe()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	stream.Retry(w, streamRetry)
+	if snapshot != nil {
+		data, err := json.Marshal(snapshot)
+		if err != nil {
+			return
+		}
+		stream.Write(w, stream.Event{Type: "snapshot", Data: data})
+	}
+
+	expired := time.NewTimer(time.Until(authPayload.ExpiredAt))
+	defer expired.Stop()
+	heartbeat := time.NewTicker(streamHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		err := server.sendWalletEvents(ctx, w, authPayload, &lastID)
+		if err != nil {
+			if ctx.Err() == nil {
+				log.Printf("--- (stream) account %d: %s\n", authPayload.AccountID, err)
+			}
+			return
+		}
+		flusher.Flush()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-expired.C:
+			stream.Write(w, stream.Event{Type: "token_expired", Data: []byte(strconv.FormatInt(lastID, 10))})
+			flusher.Flush()
+			return
+		case <-wake:
+		case <-heartbeat.C:
+			err = server.tokenValid(ctx, authPayload)
+			switch err {
+			case nil:
+			case util.ErrNotExist, errTokenBeforePasswordChange, errTokenRevoked, errTokenRoleChanged:
+				stream.Write(w, stream.Event{Type: "token_revoked", Data: []byte(strconv.FormatInt(lastID, 10))})
+				flusher.Flush()
+				return
+			default:
+				if ctx.Err() == nil {
+					log.Printf("--- (stream) account %d: %s\n", authPayload.AccountID, err)
+				}
+				return
+			}
+			if err = stream.Comment(w, "ping"); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// sendWalletEvents write all events after 'lastID' and move it to the last written event,
+// the events of the wallets the token can't use are skipped.
+func (server *Server) sendWalletEvents(ctx context.Context, w http.ResponseWriter, authPayload *token.Payload, lastID *int64) error {
+	for {
+		events, err := server.store.ListWalletStreamEvents(ctx, authPayload.AccountID, *lastID, streamBatchSize)
+		if err != nil {
+			return err
+		}
+
+		for _, event := range events {
+			if !authPayload.AllowsWallet(event.WalletNumber) {
+				*lastID = event.ID
+				continue
+			}
+			data, err := json.Marshal(walletStreamData{
+				WalletNumber: event.WalletNumber,
+				Data:         event.Payload,
+				CreatedAt:    event.CreatedAt,
+			})
+			if err != nil {
+				return err
+			}
+			err = stream.Write(w, stream.Event{ID: event.ID, Type: event.Type, Data: data})
+			if err != nil {
+				return err
+			}
+			*lastID = event.ID
+		}
+
+		if len(events) < streamBatchSize {
+			return nil
+		}
+	}
+}
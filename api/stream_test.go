@@ -0,0 +1,50 @@
+package api
+
+import (
+	"bufio"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// readStreamEvent read the stream until an event of the type, the other events and the comments are skipped.
+func readStreamEvent(t *testing.T, reader *bufio.Reader, eventType string) {
+	for {
+		line, err := reader.ReadString('\n')
+		require.NoError(t, err, "the stream ended before '%s'", eventType)
+		if strings.TrimSpace(line) == "event: "+eventType {
+			return
+		}
+	}
+}
+
+func TestStreamWalletsLogout(t *testing.T) {
+	loginRes := loginAccount(t)
+
+	req, err := http.NewRequest("GET", baseURL+"/wallets/stream", nil)
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer "+loginRes.AccessToken)
+
+	// longer than the heartbeat, the token is checked again there
+	client := http.Client{Timeout: 2*streamHeartbeat + 10*time.Second}
+	res, err := client.Do(req)
+	require.NoError(t, err, "can't open the stream")
+	defer res.Body.Close()
+	require.Equal(t, http.StatusOK, res.StatusCode)
+
+	reader := bufio.NewReader(res.Body)
+	readStreamEvent(t, reader, "snapshot")
+
+	status, body := sendRequest(t, "POST", "/account/logout", loginRes.AccessToken, nil)
+	require.Equal(t, http.StatusOK, status, string(body))
+
+	// the open stream end after the next heartbeat
+	readStreamEvent(t, reader, "token_revoked")
+
+	// and it can't be opened again with the token
+	status, _ = sendRequest(t, "GET", "/wallets/stream", loginRes.AccessToken, nil)
+	require.Equal(t, http.StatusUnauthorized, status)
+}
@@ -0,0 +1,33 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"simple-bank-system/token"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+type tokenKeysResponse struct {
+	// id of the key that sign the new tokens
+	SigningKeyID string            `json:"signing_kid"`
+	Keys         []token.PublicKey `json:"keys"`
+}
+
+// listTokenKeys publish the keys that verify the access tokens, the token footer has the 'kid' of its key.
+func (server *Server) listTokenKeys(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	if server.keyRing == nil {
+		http.Error(w, "access tokens aren't signed with public keys", http.StatusNotFound)
+		return
+	}
+
+	// the keys only change with a restart, the services can cache them a little
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	w.Header().Add("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(tokenKeysResponse{
+		SigningKeyID: server.keyRing.SigningID(),
+		Keys:         server.keyRing.PublicKeys(),
+	})
+}
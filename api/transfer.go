@@ -8,7 +8,6 @@ import (
 	"time"
 
 	"simple-bank-system/db/services"
-	"simple-bank-system/token"
 	"simple-bank-system/util"
 
 	"github.com/go-playground/locales/en"
@@ -19,9 +18,13 @@ import (
 
 type transferRequest struct {
 	FromWalletNumber int64  `json:"from_wallet_number" validate:"required,min=1010000000,max=1019999999"`
-	ToWalletNumber   int64  `json:"to_wallet_number" validate:"required,min=1010000000,max=1019999999"`
+	ToWalletNumber   int64  `json:"to_wallet_number" validate:"omitempty,min=1010000000,max=1019999999"`
 	Amount           int64  `json:"amount" validate:"required,gt=0"`
 	Currency         string `json:"currency" validate:"required,currency"`
+	// saved payee in place of 'to_wallet_number'
+	PayeeID int64 `json:"payee_id" validate:"omitempty,gt=0"`
+	// needed from 'twoFactorTransferAmount' when the account has 2FA
+	TOTPCode string `json:"totp_code"`
 }
 
 type transferResponse struct {
@@ -43,10 +46,12 @@ type transferTxResponse struct {
 	ToWallet   walletResponse
 	FromEntry  entryResponse
 	ToEntry    entryResponse
+	// Early withdrawal penalty of a locked savings goal wallet
+	Penalty *transferResponse `json:",omitempty"`
 }
 
 func newTransferTxResponse(tx *services.TransferTXResult) transferTxResponse {
-	return transferTxResponse{
+	response := transferTxResponse{
 		Transfer: transferResponse{
 			FromWalletNumber: tx.Transfer.FromWalletNumber,
 			ToWalletNumber:   tx.Transfer.ToWalletNumber,
@@ -58,6 +63,7 @@ func newTransferTxResponse(tx *services.TransferTXResult) transferTxResponse {
 			WalletNumber: tx.FromWallet.WalletNumber,
 			Balance:      tx.FromWallet.Balance,
 			Currency:     tx.FromWallet.Currency,
+			Type:         tx.FromWallet.Type,
 			CreatedAt:    tx.FromWallet.CreatedAt,
 		},
 		ToWallet: walletResponse{
@@ -65,6 +71,7 @@ func newTransferTxResponse(tx *services.TransferTXResult) transferTxResponse {
 			WalletNumber: tx.ToWallet.WalletNumber,
 			Balance:      tx.ToWallet.Balance,
 			Currency:     tx.ToWallet.Currency,
+			Type:         tx.ToWallet.Type,
 			CreatedAt:    tx.ToWallet.CreatedAt,
 		},
 		FromEntry: entryResponse{
@@ -78,6 +85,16 @@ func newTransferTxResponse(tx *services.TransferTXResult) transferTxResponse {
 			CreatedAt:    tx.ToEntry.CreatedAt,
 		},
 	}
+
+	if tx.Penalty != nil {
+		response.Penalty = &transferResponse{
+			FromWalletNumber: tx.Penalty.FromWalletNumber,
+			ToWalletNumber:   tx.Penalty.ToWalletNumber,
+			Amount:           tx.Penalty.Amount,
+			CreatedAt:        tx.Penalty.CreatedAt,
+		}
+	}
+	return response
 }
 
 func (server *Server) createTransfer(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
@@ -118,15 +135,20 @@ func (server *Server) createTransfer(w http.ResponseWriter, r *http.Request, _ h
 		return
 	}
 
-	authPayload := r.Context().Value("authPayloadKey").(*token.Payload)
-
-	accountID, valid := server.validWallet(w, req.FromWalletNumber, req.Currency)
-	if !valid {
+	if req.PayeeID != 0 {
+		var valid bool
+		req.ToWalletNumber, valid = server.payeeWalletNumber(w, r, req.PayeeID)
+		if !valid {
+			return
+		}
+	}
+	if req.ToWalletNumber == 0 {
+		http.Error(w, "to_wallet_number or payee_id is required", (http.StatusBadRequest))
 		return
 	}
 
-	if *accountID != authPayload.AccountID {
-		http.Error(w, "from_account isn't authorization", http.StatusUnauthorized)
+	_, valid := server.validWallet(w, req.FromWalletNumber, req.Currency)
+	if !valid {
 		return
 	}
 
@@ -139,6 +161,24 @@ func (server *Server) createTransfer(w http.ResponseWriter, r *http.Request, _ h
 	wallet, err := server.store.GetWalletByNumber(server.ctx, req.FromWalletNumber)
 	if err != nil {
 		http.Error(w, "Failed to get the wallet", (http.StatusBadRequest))
+		return
+	}
+
+	// owner and spender of the 'from_wallet' can send money
+	if !server.authorizeWallet(w, r, wallet, services.WalletRoleSpender) {
+		return
+	}
+
+	toWallet, err := server.store.GetWalletByNumber(server.ctx, req.ToWalletNumber)
+	if err != nil {
+		http.Error(w, "Failed to get the wallet", (http.StatusBadRequest))
+		return
+	}
+	if !server.checkRecipient(w, r, wallet, toWallet, req.Amount) {
+		return
+	}
+	if !server.checkTransferTOTP(w, r, req.Amount, req.TOTPCode) {
+		return
 	}
 
 	arg := services.TransferTxParams{
@@ -151,10 +191,16 @@ func (server *Server) createTransfer(w http.ResponseWriter, r *http.Request, _ h
 
 	accounts, err := server.store.TransferTx(server.ctx, arg)
 	if err != nil {
+		if err == util.ErrWalletLocked || err == util.ErrWalletFrozen {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
 		http.Error(w, "Failed to tranfer", http.StatusInternalServerError)
 		json.NewEncoder(w).Encode(err.Error())
 		return
 	}
+	server.notifyGoalReached(r.Context(), accounts.ToWallet, accounts.GoalReached)
+	auditTarget(r, "transfer_id", accounts.Transfer.ID)
 
 	accountsResponse := newTransferTxResponse(accounts)
 
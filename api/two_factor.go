@@ -0,0 +1,428 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"simple-bank-system/db/pkg"
+	"simple-bank-system/db/services"
+	"simple-bank-system/token"
+	"simple-bank-system/util"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+// TOTP two-factor authentication, the login of an account with 2FA has 2 steps and large transfers need a code.
+
+const (
+	totpIssuer        = "Simple Bank"
+	recoveryCodeCount = 10
+	// wrong codes before the login challenge is refused
+	loginChallengeAttempts = 5
+)
+
+type enrollTOTPResponse struct {
+	Secret          string `json:"secret"`
+	ProvisioningURI string `json:"provisioning_uri"`
+}
+
+type totpCodeRequest struct {
+	Code string `json:"code" validate:"required"`
+}
+
+type recoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+type disableTOTPRequest struct {
+	Password string `json:"password" validate:"required"`
+	// TOTP or recovery code
+	Code string `json:"code" validate:"required"`
+}
+
+type loginChallengeResponse struct {
+	TwoFactorRequired  bool      `json:"two_factor_required"`
+	ChallengeToken     string    `json:"challenge_token"`
+	ChallengeExpiresAt time.Time `json:"challenge_expires_at"`
+}
+
+type loginTwoFactorRequest struct {
+	ChallengeToken string `json:"challenge_token" validate:"required"`
+	// TOTP or recovery code
+	Code string `json:"code" validate:"required"`
+}
+
+/*
+ * verifySecondFactor check a TOTP code of the enabled secret, a code is refused after it was used once.
+ * With 'allowRecovery' a recovery code is also accepted, it's used up.
+ */
+func (server *Server) verifySecondFactor(ctx context.Context, totp *pkg.AccountTOTP, code string, allowRecovery bool) error {
+	if step, ok := util.ValidateTOTP(totp.Secret, code, time.Now()); ok {
+		return server.store.UseTOTPStep(ctx, totp.AccountID, step)
+	}
+	if !allowRecovery || len(code) == util.TOTPDigits {
+		return util.ErrNotExist
+	}
+	return server.store.UseRecoveryCode(ctx, totp.AccountID, util.HashToken(util.NormalizeRecoveryCode(code)))
+}
+
+// enabledTOTP read the 2FA of the account, it return nil when 2FA isn't enabled.
+func (server *Server) enabledTOTP(w http.ResponseWriter, accountID int64) (*pkg.AccountTOTP, bool) {
+	totp, err := server.store.GetAccountTOTP(server.ctx, accountID)
+	if err != nil && err != util.ErrNotExist {
+		http.Error(w, "Can't get 2FA", (http.StatusInternalServerError))
+		json.NewEncoder(w).Encode(err.Error())
+		return nil, false
+	}
+	if totp == nil || !totp.EnabledAt.Valid {
+		return nil, true
+	}
+	return totp, true
+}
+
+// newRecoveryCodes return the codes for the user and their hashes for the database.
+func newRecoveryCodes() ([]string, [][]byte, error) {
+	codes, err := util.NewRecoveryCodes(recoveryCodeCount)
+	if err != nil {
+		return nil, nil, err
+	}
+	hashes := make([][]byte, 0, len(codes))
+	for _, code := range codes {
+		hashes = append(hashes, util.HashToken(code))
+	}
+	return codes, hashes, nil
+}
+
+// enrollTOTP create the secret of the authenticator app, 2FA is only enabled by 'confirmTOTP'.
+func (server *Server) enrollTOTP(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	account, ok := server.profileAccount(w, r)
+	if !ok {
+		return
+	}
+
+	secret, err := util.NewTOTPSecret()
+	if err != nil {
+		http.Error(w, "Failed to create TOTP secret", (http.StatusInternalServerError))
+		json.NewEncoder(w).Encode(err.Error())
+		return
+	}
+
+	_, err = server.store.CreateAccountTOTP(server.ctx, account.ID, secret)
+	if err != nil {
+		if err == util.ErrDuplicate {
+			http.Error(w, "2FA is already enabled", http.StatusConflict)
+			return
+		}
+		http.Error(w, "Failed to enroll TOTP", (http.StatusInternalServerError))
+		json.NewEncoder(w).Encode(err.Error())
+		return
+	}
+
+	w.Header().Add("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(enrollTOTPResponse{
+		Secret:          secret,
+		ProvisioningURI: util.TOTPURI(totpIssuer, account.Username, secret),
+	})
+}
+
+// confirmTOTP enable 2FA with the first code of the app, the response has the recovery codes.
+func (server *Server) confirmTOTP(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	var req totpCodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := validate.Struct(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+	authPayload := r.Context().Value("authPayloadKey").(*token.Payload)
+
+	totp, err := server.store.GetAccountTOTP(server.ctx, authPayload.AccountID)
+	if err != nil && err != util.ErrNotExist {
+		http.Error(w, "Can't get 2FA", (http.StatusInternalServerError))
+		json.NewEncoder(w).Encode(err.Error())
+		return
+	}
+	if totp == nil || totp.EnabledAt.Valid {
+		http.Error(w, "no TOTP enrollment is pending", http.StatusConflict)
+		return
+	}
+
+	step, ok := util.ValidateTOTP(totp.Secret, req.Code, time.Now())
+	if !ok {
+		http.Error(w, "code is wrong", http.StatusBadRequest)
+		return
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		http.Error(w, "Failed to create recovery codes", (http.StatusInternalServerError))
+		json.NewEncoder(w).Encode(err.Error())
+		return
+	}
+
+	err = server.store.EnableTOTPTx(server.ctx, services.EnableTOTPParams{
+		AccountID:          totp.AccountID,
+		Step:               step,
+		RecoveryCodeHashes: hashes,
+	})
+	if err != nil {
+		if err == util.ErrNotExist {
+			http.Error(w, "no TOTP enrollment is pending", http.StatusConflict)
+			return
+		}
+		http.Error(w, "Failed to enable 2FA", (http.StatusInternalServerError))
+		json.NewEncoder(w).Encode(err.Error())
+		return
+	}
+	auditChange(r, "two_factor", false, true)
+
+	w.Header().Add("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(recoveryCodesResponse{RecoveryCodes: codes})
+}
+
+// disableTOTP need the password and a code, so a stolen access token can't remove 2FA.
+func (server *Server) disableTOTP(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	var req disableTOTPRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := validate.Struct(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	account, ok := server.profileAccount(w, r)
+	if !ok {
+		return
+	}
+	if err := util.VerifyPassword(req.Password, account.HashedPassword); err != nil {
+		http.Error(w, "Your password is wrong", http.StatusForbidden)
+		return
+	}
+
+	totp, ok := server.enabledTOTP(w, account.ID)
+	if !ok {
+		return
+	}
+	if totp == nil {
+		http.Error(w, "2FA isn't enabled", http.StatusConflict)
+		return
+	}
+	if !server.checkSecondFactor(w, totp, req.Code, true) {
+		return
+	}
+
+	err := server.store.DisableTOTPTx(server.ctx, account.ID)
+	if err != nil && err != util.ErrNotExist {
+		http.Error(w, "Failed to disable 2FA", (http.StatusInternalServerError))
+		json.NewEncoder(w).Encode(err.Error())
+		return
+	}
+	auditChange(r, "two_factor", true, false)
+
+	w.Header().Add("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode("2FA disabled!")
+}
+
+// regenerateRecoveryCodes replace the recovery codes, it need a TOTP code.
+func (server *Server) regenerateRecoveryCodes(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	var req totpCodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := validate.Struct(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+	authPayload := r.Context().Value("authPayloadKey").(*token.Payload)
+
+	totp, ok := server.enabledTOTP(w, authPayload.AccountID)
+	if !ok {
+		return
+	}
+	if totp == nil {
+		http.Error(w, "2FA isn't enabled", http.StatusConflict)
+		return
+	}
+	if !server.checkSecondFactor(w, totp, req.Code, false) {
+		return
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		http.Error(w, "Failed to create recovery codes", (http.StatusInternalServerError))
+		json.NewEncoder(w).Encode(err.Error())
+		return
+	}
+	if err = server.store.ReplaceRecoveryCodesTx(server.ctx, totp.AccountID, hashes); err != nil {
+		http.Error(w, "Failed to save recovery codes", (http.StatusInternalServerError))
+		json.NewEncoder(w).Encode(err.Error())
+		return
+	}
+
+	w.Header().Add("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(recoveryCodesResponse{RecoveryCodes: codes})
+}
+
+// checkSecondFactor write the error of a wrong or reused code.
+func (server *Server) checkSecondFactor(w http.ResponseWriter, totp *pkg.AccountTOTP, code string, allowRecovery bool) bool {
+	err := server.verifySecondFactor(server.ctx, totp, code, allowRecovery)
+	switch err {
+	case nil:
+		return true
+	case util.ErrNotExist:
+		http.Error(w, "code is wrong", http.StatusForbidden)
+		return false
+	case util.ErrCodeReused:
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return false
+	}
+	http.Error(w, "Can't check code", (http.StatusInternalServerError))
+	json.NewEncoder(w).Encode(err.Error())
+	return false
+}
+
+// writeLoginChallenge is the answer of the first login step of an account with 2FA.
+func (server *Server) writeLoginChallenge(w http.ResponseWriter, r *http.Request, accountID int64) {
+	challengeToken, err := util.RandomToken(32)
+	if err != nil {
+		http.Error(w, "Failed to create challenge token", (http.StatusInternalServerError))
+		json.NewEncoder(w).Encode(err.Error())
+		return
+	}
+
+	challenge, err := server.store.CreateLoginChallenge(server.ctx, services.CreateLoginChallengeParams{
+		AccountID: accountID,
+		TokenHash: util.HashToken(challengeToken),
+		ExpiresAt: time.Now().Add(server.twoFactorChallengeDuration),
+	})
+	if err != nil {
+		http.Error(w, "Failed to create login challenge", (http.StatusInternalServerError))
+		json.NewEncoder(w).Encode(err.Error())
+		return
+	}
+	auditTarget(r, "login_challenge_id", challenge.ID)
+
+	w.Header().Add("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(loginChallengeResponse{
+		TwoFactorRequired:  true,
+		ChallengeToken:     challengeToken,
+		ChallengeExpiresAt: challenge.ExpiresAt,
+	})
+}
+
+// loginTwoFactor is the second login step, the challenge token and a TOTP or recovery code are exchanged for the tokens.
+func (server *Server) loginTwoFactor(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	var req loginTwoFactorRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := validate.Struct(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	challenge, err := server.store.GetLoginChallenge(server.ctx, util.HashToken(req.ChallengeToken))
+	if err != nil {
+		if err == util.ErrNotExist {
+			http.Error(w, "challenge token is wrong, login again", http.StatusUnauthorized)
+			return
+		}
+		http.Error(w, "Can't get login challenge", (http.StatusInternalServerError))
+		json.NewEncoder(w).Encode(err.Error())
+		return
+	}
+	auditTarget(r, "login_challenge_id", challenge.ID)
+	setAuditActor(r, challenge.AccountID)
+	if time.Now().After(challenge.ExpiresAt) || challenge.Attempts >= loginChallengeAttempts {
+		http.Error(w, "challenge token is expired, login again", http.StatusUnauthorized)
+		return
+	}
+
+	account, err := server.store.GetAccountProfile(server.ctx, challenge.AccountID)
+	if err != nil {
+		http.Error(w, "Can't get account", http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(err.Error())
+		return
+	}
+	// the wrong codes count as failed logins of the username, the lockout of the password also stop the codes
+	keys := loginThrottleKeys(account.Username, clientIP(r))
+	if !server.checkLoginLock(w, keys) {
+		return
+	}
+
+	totp, ok := server.enabledTOTP(w, challenge.AccountID)
+	if !ok {
+		return
+	}
+	// 2FA was disabled since the first step
+	if totp == nil {
+		http.Error(w, "challenge token is wrong, login again", http.StatusUnauthorized)
+		return
+	}
+
+	err = server.verifySecondFactor(server.ctx, totp, req.Code, true)
+	if err == util.ErrNotExist || err == util.ErrCodeReused {
+		if _, err := server.store.AddLoginChallengeAttempt(server.ctx, challenge.ID); err != nil {
+			http.Error(w, "Can't check code", (http.StatusInternalServerError))
+			json.NewEncoder(w).Encode(err.Error())
+			return
+		}
+		server.recordLoginFailure(w, r, keys, account)
+		http.Error(w, "code is wrong", http.StatusUnauthorized)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Can't check code", (http.StatusInternalServerError))
+		json.NewEncoder(w).Encode(err.Error())
+		return
+	}
+
+	if err = server.store.UseLoginChallenge(server.ctx, challenge.ID); err != nil {
+		if err == util.ErrNotExist {
+			http.Error(w, "challenge token is wrong, login again", http.StatusUnauthorized)
+			return
+		}
+		http.Error(w, "Can't use login challenge", (http.StatusInternalServerError))
+		json.NewEncoder(w).Encode(err.Error())
+		return
+	}
+	server.finishLogin(w, r, account)
+}
+
+/*
+ * checkTransferTOTP ask a TOTP code for the transfers from 'twoFactorTransferAmount' when the sender has 2FA,
+ * every handler that move money call it: transfers, payments of payment requests and payment files (with the
+ * total of the file). Recovery codes aren't accepted and a code that was already used (e.g. for the login) is refused.
+ */
+func (server *Server) checkTransferTOTP(w http.ResponseWriter, r *http.Request, amount int64, code string) bool {
+	if amount < server.twoFactorTransferAmount {
+		return true
+	}
+	authPayload := r.Context().Value("authPayloadKey").(*token.Payload)
+
+	totp, ok := server.enabledTOTP(w, authPayload.AccountID)
+	if !ok {
+		return false
+	}
+	if totp == nil {
+		return true
+	}
+	if code == "" {
+		http.Error(w, "totp_code is required for large transfers", http.StatusForbidden)
+		return false
+	}
+	return server.checkSecondFactor(w, totp, code, false)
+}
@@ -0,0 +1,113 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"simple-bank-system/iso20022"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Every handler that move money ask the TOTP code from 'TWO_FACTOR_TRANSFER_AMOUNT'.
+func TestLargeTransferTOTP(t *testing.T) {
+	amount := testConfig.TwoFactorTransferAmount
+
+	t.Run("Transfer", func(t *testing.T) {
+		sender := loginAccount(t)
+		recipient := loginAccount(t)
+		secret := enableTOTP(t, sender)
+
+		arg := transferRequest{
+			FromWalletNumber: sender.Account.AccountNumber,
+			ToWalletNumber:   recipient.Account.AccountNumber,
+			Amount:           amount,
+			Currency:         "IDR",
+		}
+		status, body := sendRequest(t, "POST", "/transfer", sender.AccessToken, arg)
+		require.Equal(t, http.StatusForbidden, status, string(body))
+		arg.TOTPCode = "000000"
+		status, body = sendRequest(t, "POST", "/transfer", sender.AccessToken, arg)
+		require.Equal(t, http.StatusForbidden, status, string(body))
+
+		// smaller transfers don't need it
+		status, body = transferMoney(t, sender.AccessToken, sender.Account.AccountNumber, recipient.Account.AccountNumber, amount-1)
+		require.Equal(t, http.StatusOK, status, string(body))
+
+		status, body = transferMoney(t, recipient.AccessToken, recipient.Account.AccountNumber, sender.Account.AccountNumber, amount-1)
+		require.Equal(t, http.StatusOK, status, string(body))
+		arg.TOTPCode = totpCode(t, secret)
+		status, body = sendRequest(t, "POST", "/transfer", sender.AccessToken, arg)
+		require.Equal(t, http.StatusOK, status, string(body))
+	})
+
+	t.Run("PaymentRequest", func(t *testing.T) {
+		requester := loginAccount(t)
+		payer := loginAccount(t)
+		secret := enableTOTP(t, payer)
+
+		status, body := sendRequest(t, "POST", "/payment-requests", requester.AccessToken, createPaymentRequestRequest{
+			WalletNumber: requester.Account.AccountNumber,
+			Amount:       amount,
+			ExpiresAt:    time.Now().AddDate(0, 0, 7).Format(time.DateOnly),
+			Payers:       []paymentPayerRequest{{AccountNumber: payer.Account.AccountNumber}},
+		})
+		require.Equal(t, http.StatusOK, status, string(body))
+		var request paymentRequestResponse
+		require.NoError(t, json.Unmarshal(body, &request))
+		path := "/payment-requests/" + strconv.FormatInt(request.ID, 10) + "/pay"
+
+		status, body = sendRequest(t, "POST", path, payer.AccessToken, payPaymentRequestRequest{WalletNumber: payer.Account.AccountNumber})
+		require.Equal(t, http.StatusForbidden, status, string(body))
+		status, body = sendRequest(t, "POST", path, payer.AccessToken, payPaymentRequestRequest{WalletNumber: payer.Account.AccountNumber, TOTPCode: "000000"})
+		require.Equal(t, http.StatusForbidden, status, string(body))
+
+		status, body = sendRequest(t, "POST", path, payer.AccessToken, payPaymentRequestRequest{WalletNumber: payer.Account.AccountNumber, TOTPCode: totpCode(t, secret)})
+		require.Equal(t, http.StatusOK, status, string(body))
+	})
+
+	t.Run("PaymentFile", func(t *testing.T) {
+		sender := loginAccount(t)
+		recipient := loginAccount(t)
+		secret := enableTOTP(t, sender)
+
+		// every transaction is under the amount, the total of the file isn't
+		half := strconv.FormatInt(amount/2+1, 10)
+		file := newPain001("MSG-"+strconv.FormatInt(sender.Account.AccountNumber, 10), []testPayment{{
+			debtor: sender.Account.AccountNumber,
+			transactions: []testCreditTransfer{
+				{endToEndID: "E2E-1", creditor: recipient.Account.AccountNumber, amount: half},
+				{endToEndID: "E2E-2", creditor: recipient.Account.AccountNumber, amount: half},
+			},
+		}})
+
+		status, body := uploadPaymentFile(t, sender.AccessToken, file)
+		require.Equal(t, http.StatusForbidden, status, string(body))
+		status, body = uploadPaymentFileWithCode(t, sender.AccessToken, "000000", file)
+		require.Equal(t, http.StatusForbidden, status, string(body))
+
+		// a huge amount can't overflow the total below the amount
+		overflow := newPain001("MSG-OVERFLOW-"+strconv.FormatInt(sender.Account.AccountNumber, 10), []testPayment{{
+			debtor: sender.Account.AccountNumber,
+			transactions: []testCreditTransfer{
+				{endToEndID: "E2E-1", creditor: recipient.Account.AccountNumber, amount: "9223372036854775807"},
+				{endToEndID: "E2E-2", creditor: recipient.Account.AccountNumber, amount: "10"},
+			},
+		}})
+		status, body = uploadPaymentFile(t, sender.AccessToken, overflow)
+		require.Equal(t, http.StatusForbidden, status, string(body))
+
+		// nothing was executed
+		wallet, err := testStore.GetWalletByNumber(testCtx, sender.Account.AccountNumber)
+		require.NoError(t, err)
+		assert.Equal(t, int64(1000000), wallet.Balance)
+
+		status, body = uploadPaymentFileWithCode(t, sender.AccessToken, totpCode(t, secret), file)
+		require.Equal(t, http.StatusOK, status, string(body))
+		assert.Equal(t, [2]string{iso20022.StatusSettled, ""}, transactionStatus(t, body)["E2E-1"])
+	})
+}
@@ -3,7 +3,6 @@ package api
 import (
 	"encoding/json"
 	"fmt"
-	"log"
 
 	"net/http"
 	"strconv"
@@ -37,6 +36,10 @@ func translateError(err error, trans ut.Translator) (errs []string) {
 type createWalletRequest struct {
 	Name     string `json:"name" validate:"required"`
 	Currency string `json:"currency" validate:"required,currency"`
+	// "regular" (default) or "savings", only savings wallet earn interest
+	Type string `json:"type" validate:"omitempty,oneof=regular savings"`
+	// Wallet with goal is always a savings wallet
+	Goal *savingsGoalRequest `json:"goal"`
 }
 
 type walletResponse struct {
@@ -44,7 +47,9 @@ type walletResponse struct {
 	WalletNumber int64
 	Balance      int64
 	Currency     string
+	Type         string
 	CreatedAt    time.Time
+	Goal         *savingsGoalResponse `json:",omitempty"`
 }
 
 func newWalletResponse(wallet *pkg.Wallet) walletResponse {
@@ -53,6 +58,7 @@ func newWalletResponse(wallet *pkg.Wallet) walletResponse {
 		WalletNumber: wallet.WalletNumber,
 		Balance:      wallet.Balance,
 		Currency:     wallet.Currency,
+		Type:         wallet.Type,
 		CreatedAt:    wallet.CreatedAt,
 	}
 }
@@ -121,10 +127,24 @@ func (server *Server) createWallet(w http.ResponseWriter, r *http.Request, _ htt
 		WalletNumber: sixDigitNumb * 10000,
 		Name:         req.Name,
 		Currency:     req.Currency,
+		Type:         req.Type,
 		Balance:      0,
 	}
 
-	wallet, err := server.store.CreateWallet(server.ctx, arg)
+	var wallet *pkg.Wallet
+	var goal *pkg.SavingsGoal
+	if req.Goal != nil {
+		var goalArg services.CreateSavingsGoalParams
+		goalArg, err = req.Goal.params()
+		if err != nil {
+			http.Error(w, "Format input data is wrong", (http.StatusBadRequest))
+			json.NewEncoder(w).Encode(err.Error())
+			return
+		}
+		wallet, goal, err = server.store.CreateSavingsGoalWalletTx(server.ctx, arg, goalArg)
+	} else {
+		wallet, err = server.store.CreateWalletTx(server.ctx, arg)
+	}
 	if err != nil {
 		switch err {
 		case util.ErrAccUser, util.ErrDuplicate:
@@ -136,7 +156,9 @@ func (server *Server) createWallet(w http.ResponseWriter, r *http.Request, _ htt
 		return
 	}
 
+	auditTarget(r, "wallet_number", wallet.WalletNumber)
 	response := newWalletResponse(wallet)
+	response.Goal = newSavingsGoalResponse(goal, wallet.Balance)
 	w.Header().Add("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(response)
@@ -167,17 +189,23 @@ func (server *Server) getWallet(w http.ResponseWriter, r *http.Request, ps httpr
 		return
 	}
 
-	// access authorization payload inside context
-	// 'ctx.Value' return general interface, so we should cast it to token.payload object
-	authPayload := r.Context().Value("authPayloadKey").(*token.Payload)
-	if wallet.AccountID != authPayload.AccountID {
-		http.Error(w, "wallet doesn't belong to you", (http.StatusUnauthorized))
+	// every member of the wallet can see it
+	if !server.authorizeWallet(w, r, wallet, services.WalletRoleViewer) {
 		return
 	}
 
+	response := newWalletResponse(wallet)
+	goal, err := server.store.GetSavingsGoal(server.ctx, wallet.ID)
+	if err != nil && err != util.ErrNotExist {
+		http.Error(w, "Can't get wallet savings goal", (http.StatusInternalServerError))
+		json.NewEncoder(w).Encode(err.Error())
+		return
+	}
+	response.Goal = newSavingsGoalResponse(goal, wallet.Balance)
+
 	w.Header().Add("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(wallet)
+	json.NewEncoder(w).Encode(response)
 }
 
 type listWalletsRequest struct {
@@ -205,9 +233,10 @@ func (server *Server) listWallets(w http.ResponseWriter, r *http.Request, _ http
 	authPayload := r.Context().Value("authPayloadKey").(*token.Payload)
 
 	arg := services.ListWalletParams{
-		AccountID: authPayload.AccountID,
-		Limit:     req.PageSize,
-		Offset:    (req.PageID - 1) * req.PageSize,
+		AccountID:     authPayload.AccountID,
+		WalletNumbers: authPayload.Wallets,
+		Limit:         req.PageSize,
+		Offset:        (req.PageID - 1) * req.PageSize,
 	}
 
 	validate := validator.New()
@@ -243,13 +272,14 @@ func (server *Server) listWallets(w http.ResponseWriter, r *http.Request, _ http
 	json.NewEncoder(w).Encode(wallets)
 }
 
-type updateWalletRequest struct {
-	WalletNumber int64 `validate:"required,min=1010000000,max=1019999999"`
-	Balance      int64 `json:"balance" validate:"required"`
+type updateWalletInfoRequest struct {
+	WalletNumber int64  `validate:"required,min=1010000000,max=1019999999"`
+	Name         string `json:"name" validate:"required"`
+	Currency     string `json:"currency" validate:"required"`
 }
 
-func (server *Server) updateWallet(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
-	var req updateWalletRequest
+func (server *Server) updateWalletInfo(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	var req updateWalletInfoRequest
 	var err error
 
 	req.WalletNumber, err = strconv.ParseInt(ps.ByName("number"), 10, 64)
@@ -272,69 +302,9 @@ func (server *Server) updateWallet(w http.ResponseWriter, r *http.Request, ps ht
 	wallet, err := server.store.GetWalletByNumber(server.ctx, req.WalletNumber)
 	if err != nil {
 		http.Error(w, "Failed to get the wallet", (http.StatusBadRequest))
-	}
-
-	arg := services.UpdateWalletParams{
-		ID:      wallet.ID,
-		Balance: req.Balance,
-	}
-
-	validate := validator.New()
-	err = validate.Struct(req)
-
-	//translate
-	english := en.New()
-	uni := ut.New(english, english)
-	trans, _ := uni.GetTranslator("en")
-	_ = en_translations.RegisterDefaultTranslations(validate, trans)
-
-	valErr := translateError(err, trans)
-	if valErr != nil {
-		http.Error(w, "Format input data is wrong", (http.StatusBadRequest))
-		json.NewEncoder(w).Encode(valErr)
-		return
-	}
-
-	err = server.store.UpdateWallet(server.ctx, arg)
-	if err != nil {
-		if err == util.ErrUpdateFailed {
-			http.Error(w, err.Error(), 403)
-			return
-		}
-		http.Error(w, "Failed parsing data into database", http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(err.Error())
-		return
-	}
-
-	w.Header().Add("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode("Data modified")
-}
-
-type updateWalletInfoRequest struct {
-	WalletNumber int64  `validate:"required,min=1010000000,max=1019999999"`
-	Name         string `json:"name" validate:"required"`
-	Currency     string `json:"currency" validate:"required"`
-}
-
-func (server *Server) updateWalletInfo(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
-	var req updateWalletInfoRequest
-	var err error
-
-	req.WalletNumber, err = strconv.ParseInt(ps.ByName("number"), 10, 64)
-	if err != nil {
-		http.Error(w, "Failed convert data to int or data is nill", http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(err.Error())
 		return
 	}
-
-	//fmt.Println("number: ", req.WalletNumber)
-
-	err = json.NewDecoder(r.Body).Decode(&req)
-	//fmt.Println("balance: ", req.Balance)
-	if err != nil {
-		http.Error(w, "Failed to get input data", http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(err.Error())
+	if !server.authorizeWallet(w, r, wallet, services.WalletRoleOwner) {
 		return
 	}
 
@@ -360,6 +330,8 @@ func (server *Server) updateWalletInfo(w http.ResponseWriter, r *http.Request, p
 		return
 	}
 
+	auditChange(r, "name", wallet.Name, req.Name)
+	auditChange(r, "currency", wallet.Currency, req.Currency)
 	err = server.store.UpdateWalletInformation(server.ctx, arg)
 	if err != nil {
 		if err == util.ErrUpdateFailed {
@@ -407,57 +379,38 @@ func (server *Server) deleteWallet(w http.ResponseWriter, r *http.Request, ps ht
 		json.NewEncoder(w).Encode(err.Error())
 		return
 	}
-
-	// Before delete the wallet, check the wallet balance.
-	// if the wallet balance > 0, we should transfer the balance first to primary wallet using account number.
-	_, isOK := server.transferWalletBalance(w, *wallet)
-	if isOK == false {
-		log.Println("---(error)transfer wallet balance")
-		// http.Error(w, "Failed to delete wallet because transfer balance from wallet that will be deleted", http.StatusBadRequest)
-		// json.NewEncoder(w).Encode(err.Error())
-		return
-	}
-
-	err = server.store.DeleteWallet(server.ctx, wallet.ID)
-	if err != nil {
-		http.Error(w, "Failed delete wallet from database", http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(err.Error())
+	if !server.authorizeWallet(w, r, wallet, services.WalletRoleOwner) {
 		return
 	}
 
-	w.Header().Add("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode("Wallet deleted!")
-}
-
-func (server *Server) transferWalletBalance(w http.ResponseWriter, wallet pkg.Wallet) (*services.TransferTXResult, bool) {
+	// the balance is moved to the primary wallet (its number is the account number) in the same transaction as the close
 	if wallet.Currency != "IDR" {
 		err := fmt.Errorf("wallet [%d] currency mismatch: %s - %s", wallet.ID, wallet.Currency, "IDR")
 		w.WriteHeader(http.StatusBadRequest)
 		json.NewEncoder(w).Encode(err.Error())
-		return nil, false
+		return
 	}
-
 	accountNumber, err := server.store.GetAccountByID(server.ctx, wallet.AccountID)
 	if err != nil {
-		//log.Printf("(output) account ID: %d <> %d (input) account ID\n", *accountID, wallet.AccountID)
 		http.Error(w, "failed to get account number to transfer wallet balance", http.StatusUnauthorized)
-		return nil, false
-	}
-
-	arg := services.TransferTxParams{
-		AccountID:        wallet.AccountID,
-		WalletID:         wallet.ID,
-		FromWalletNumber: wallet.WalletNumber,
-		ToWalletNumber:   *accountNumber,
-		Amount:           wallet.Balance,
+		return
 	}
 
-	accounts, err := server.store.TransferTx(server.ctx, arg)
+	_, err = server.store.CloseWalletTx(server.ctx, *wallet, *accountNumber)
 	if err != nil {
-		http.Error(w, "Failed to tranfer", http.StatusBadRequest)
-		json.NewEncoder(w).Encode(err.Error())
-		return nil, false
+		switch err {
+		case util.ErrWalletLocked, util.ErrWalletFrozen:
+			http.Error(w, err.Error(), http.StatusForbidden)
+		case util.ErrNotExist:
+			http.Error(w, "wallet is already closed", http.StatusNotFound)
+		default:
+			http.Error(w, "Failed delete wallet from database", http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(err.Error())
+		}
+		return
 	}
-	return accounts, true
+
+	w.Header().Add("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode("Wallet deleted!")
 }
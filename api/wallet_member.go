@@ -0,0 +1,415 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"simple-bank-system/authz"
+	"simple-bank-system/db/pkg"
+	"simple-bank-system/db/services"
+	"simple-bank-system/notification"
+	"simple-bank-system/token"
+	"simple-bank-system/util"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+type inviteMemberRequest struct {
+	AccountNumber int64  `json:"account_number" validate:"required,min=1010000000,max=1019999999"`
+	Role          string `json:"role" validate:"required,oneof=owner spender viewer"`
+}
+
+type updateMemberRequest struct {
+	Role string `json:"role" validate:"required,oneof=owner spender viewer"`
+}
+
+type walletMemberResponse struct {
+	WalletNumber  int64
+	AccountNumber int64
+	Role          string
+	Status        string
+	CreatedAt     time.Time
+	AcceptedAt    *time.Time `json:",omitempty"`
+}
+
+func newWalletMemberResponse(walletNumber int64, member pkg.WalletMember) walletMemberResponse {
+	res := walletMemberResponse{
+		WalletNumber:  walletNumber,
+		AccountNumber: member.AccountNumber,
+		Role:          member.Role,
+		Status:        member.Status,
+		CreatedAt:     member.CreatedAt,
+	}
+	if member.AcceptedAt.Valid {
+		res.AcceptedAt = &member.AcceptedAt.Time
+	}
+	return res
+}
+
+/*
+ * authorizeWallet check the role of the logged in account in the wallet, the wallet owner
+ * has every right, members need at least the 'required' role. Frozen wallets only allow viewing.
+ * It write the error response and return false when the account isn't allowed.
+ */
+func (server *Server) authorizeWallet(w http.ResponseWriter, r *http.Request, wallet *pkg.Wallet, required string) bool {
+	authPayload := r.Context().Value("authPayloadKey").(*token.Payload)
+	if !authPayload.AllowsWallet(wallet.WalletNumber) {
+		http.Error(w, "your token isn't allowed to use this wallet", http.StatusForbidden)
+		return false
+	}
+
+	role, err := server.store.GetWalletRole(server.ctx, *wallet, authPayload.AccountID)
+	if err != nil {
+		if err == util.ErrNotExist {
+			http.Error(w, "wallet doesn't belong to you", (http.StatusUnauthorized))
+			return false
+		}
+		http.Error(w, "Can't check wallet role", (http.StatusInternalServerError))
+		json.NewEncoder(w).Encode(err.Error())
+		return false
+	}
+
+	// only the actions of viewers need no freeze check, they are allowed in frozen wallets
+	frozen := false
+	if required != services.WalletRoleViewer {
+		frozen, err = server.store.IsWalletFrozen(server.ctx, wallet.ID)
+		if err != nil {
+			http.Error(w, "Can't check wallet freeze", (http.StatusInternalServerError))
+			json.NewEncoder(w).Encode(err.Error())
+			return false
+		}
+	}
+
+	if !authz.WalletAllows(role, required, frozen) {
+		if frozen {
+			http.Error(w, util.ErrWalletFrozen.Error(), http.StatusForbidden)
+			return false
+		}
+		http.Error(w, fmt.Sprintf("your role '%s' isn't allowed, need '%s'", role, required), http.StatusForbidden)
+		return false
+	}
+	return true
+}
+
+// walletByParam read the wallet of the 'number' url parameter.
+func (server *Server) walletByParam(w http.ResponseWriter, ps httprouter.Params) (*pkg.Wallet, bool) {
+	walletNumber, err := strconv.ParseInt(ps.ByName("number"), 10, 64)
+	if err != nil {
+		http.Error(w, "failed to convert url parameter to int", (http.StatusInternalServerError))
+		return nil, false
+	}
+
+	wallet, err := server.store.GetWalletByNumber(server.ctx, walletNumber)
+	if err != nil {
+		if err == util.ErrNotExist {
+			http.Error(w, err.Error(), 403)
+			return nil, false
+		}
+		http.Error(w, "Can't get wallet", (http.StatusInternalServerError))
+		json.NewEncoder(w).Encode(err.Error())
+		return nil, false
+	}
+	return wallet, true
+}
+
+// inviteWalletMember invite other account to the wallet, only the owners can invite.
+func (server *Server) inviteWalletMember(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	var req inviteMemberRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		http.Error(w, "failed to decode input data", (http.StatusInternalServerError))
+		json.NewEncoder(w).Encode(err.Error())
+		return
+	}
+
+	err = validate.Struct(req)
+	if err != nil {
+		http.Error(w, "Format input data is wrong", (http.StatusBadRequest))
+		json.NewEncoder(w).Encode(err.Error())
+		return
+	}
+
+	wallet, ok := server.walletByParam(w, ps)
+	if !ok {
+		return
+	}
+	if !server.authorizeWallet(w, r, wallet, services.WalletRoleOwner) {
+		return
+	}
+
+	account, err := server.store.GetAccountByNumber(server.ctx, req.AccountNumber)
+	if err != nil {
+		if err == util.ErrNotExist {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Can't get the invited account", (http.StatusInternalServerError))
+		json.NewEncoder(w).Encode(err.Error())
+		return
+	}
+	if account.ID == wallet.AccountID {
+		http.Error(w, "account already owns the wallet", http.StatusBadRequest)
+		return
+	}
+
+	authPayload := r.Context().Value("authPayloadKey").(*token.Payload)
+	member, err := server.store.InviteWalletMember(server.ctx, services.InviteWalletMemberParams{
+		WalletID:  wallet.ID,
+		AccountID: account.ID,
+		Role:      req.Role,
+		InvitedBy: authPayload.AccountID,
+	})
+	if err != nil {
+		switch err {
+		case util.ErrAccUser, util.ErrDuplicate:
+			http.Error(w, err.Error(), 403)
+			return
+		}
+		http.Error(w, "failed to pass data into database", (http.StatusInternalServerError))
+		json.NewEncoder(w).Encode(err.Error())
+		return
+	}
+
+	err = server.notifier.Notify(r.Context(), notification.Event{
+		AccountID: account.ID,
+		Type:      notification.EventWalletInvitation,
+		Message:   fmt.Sprintf("You're invited to the wallet %q as %s", wallet.Name, member.Role),
+		Data: map[string]interface{}{
+			"wallet_number": wallet.WalletNumber,
+			"role":          member.Role,
+		},
+		CreatedAt: member.CreatedAt,
+	})
+	if err != nil {
+		log.Println("--- (err) notify wallet invitation:", err)
+	}
+
+	w.Header().Add("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(newWalletMemberResponse(wallet.WalletNumber, *member))
+}
+
+// listWalletMembers return invited and active members of the wallet, every member can see them.
+func (server *Server) listWalletMembers(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	wallet, ok := server.walletByParam(w, ps)
+	if !ok {
+		return
+	}
+	if !server.authorizeWallet(w, r, wallet, services.WalletRoleViewer) {
+		return
+	}
+
+	members, err := server.store.ListWalletMembers(server.ctx, wallet.ID)
+	if err != nil {
+		http.Error(w, "Failed to get List", (http.StatusInternalServerError))
+		json.NewEncoder(w).Encode(err.Error())
+		return
+	}
+
+	response := []walletMemberResponse{}
+	for _, member := range members {
+		response = append(response, newWalletMemberResponse(wallet.WalletNumber, member))
+	}
+
+	w.Header().Add("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
+
+// updateWalletMember change the role of a member, only the owners can do it.
+func (server *Server) updateWalletMember(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	var req updateMemberRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		http.Error(w, "failed to decode input data", (http.StatusInternalServerError))
+		json.NewEncoder(w).Encode(err.Error())
+		return
+	}
+
+	err = validate.Struct(req)
+	if err != nil {
+		http.Error(w, "Format input data is wrong", (http.StatusBadRequest))
+		json.NewEncoder(w).Encode(err.Error())
+		return
+	}
+
+	wallet, ok := server.walletByParam(w, ps)
+	if !ok {
+		return
+	}
+	if !server.authorizeWallet(w, r, wallet, services.WalletRoleOwner) {
+		return
+	}
+
+	account, ok := server.memberAccountByParam(w, ps)
+	if !ok {
+		return
+	}
+
+	if member, err := server.store.GetWalletMember(server.ctx, wallet.ID, account.ID); err == nil {
+		auditChange(r, "role", member.Role, req.Role)
+	}
+	err = server.store.UpdateWalletMemberRole(server.ctx, services.UpdateWalletMemberRoleParams{
+		WalletID:  wallet.ID,
+		AccountID: account.ID,
+		Role:      req.Role,
+	})
+	if err != nil {
+		if err == util.ErrUpdateFailed {
+			http.Error(w, err.Error(), 403)
+			return
+		}
+		http.Error(w, "Failed parsing data into database", http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(err.Error())
+		return
+	}
+
+	w.Header().Add("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode("Data modified")
+}
+
+// removeWalletMember remove a member from the wallet. Owners can remove everyone, other members can only leave.
+func (server *Server) removeWalletMember(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	wallet, ok := server.walletByParam(w, ps)
+	if !ok {
+		return
+	}
+
+	account, ok := server.memberAccountByParam(w, ps)
+	if !ok {
+		return
+	}
+
+	authPayload := r.Context().Value("authPayloadKey").(*token.Payload)
+	if !authPayload.AllowsWallet(wallet.WalletNumber) {
+		http.Error(w, "your token isn't allowed to use this wallet", http.StatusForbidden)
+		return
+	}
+	if account.ID != authPayload.AccountID && !server.authorizeWallet(w, r, wallet, services.WalletRoleOwner) {
+		return
+	}
+
+	err := server.store.DeleteWalletMember(server.ctx, wallet.ID, account.ID)
+	if err != nil {
+		if err == util.ErrDeleteFailed {
+			http.Error(w, err.Error(), 403)
+			return
+		}
+		http.Error(w, "Failed delete member from database", http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(err.Error())
+		return
+	}
+
+	w.Header().Add("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode("Member removed!")
+}
+
+func (server *Server) memberAccountByParam(w http.ResponseWriter, ps httprouter.Params) (*pkg.Account, bool) {
+	accountNumber, err := strconv.ParseInt(ps.ByName("account_number"), 10, 64)
+	if err != nil {
+		http.Error(w, "failed to convert url parameter to int", (http.StatusInternalServerError))
+		return nil, false
+	}
+
+	account, err := server.store.GetAccountByNumber(server.ctx, accountNumber)
+	if err != nil {
+		if err == util.ErrNotExist {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return nil, false
+		}
+		http.Error(w, "Can't get the member account", (http.StatusInternalServerError))
+		json.NewEncoder(w).Encode(err.Error())
+		return nil, false
+	}
+	return account, true
+}
+
+// listInvitations return pending wallet invitations of the logged in account.
+func (server *Server) listInvitations(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authPayload := r.Context().Value("authPayloadKey").(*token.Payload)
+
+	invitations, err := server.store.ListWalletInvitations(server.ctx, authPayload.AccountID)
+	if err != nil {
+		http.Error(w, "Failed to get List", (http.StatusInternalServerError))
+		json.NewEncoder(w).Encode(err.Error())
+		return
+	}
+
+	response := []walletMemberResponse{}
+	for _, invitation := range invitations {
+		wallet, err := server.store.GetWallet(server.ctx, invitation.WalletID)
+		if err != nil {
+			http.Error(w, "Can't get wallet", (http.StatusInternalServerError))
+			json.NewEncoder(w).Encode(err.Error())
+			return
+		}
+		response = append(response, newWalletMemberResponse(wallet.WalletNumber, invitation))
+	}
+
+	w.Header().Add("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
+
+// acceptInvitation activate the membership of the logged in account in the wallet.
+func (server *Server) acceptInvitation(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	wallet, ok := server.walletByParam(w, ps)
+	if !ok {
+		return
+	}
+
+	authPayload := r.Context().Value("authPayloadKey").(*token.Payload)
+	err := server.store.AcceptWalletInvitation(server.ctx, wallet.ID, authPayload.AccountID)
+	if err != nil {
+		if err == util.ErrNotExist {
+			http.Error(w, "there's no invitation to the wallet", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed parsing data into database", http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(err.Error())
+		return
+	}
+
+	member, err := server.store.GetWalletMember(server.ctx, wallet.ID, authPayload.AccountID)
+	if err != nil {
+		http.Error(w, "Can't get wallet member", (http.StatusInternalServerError))
+		json.NewEncoder(w).Encode(err.Error())
+		return
+	}
+
+	w.Header().Add("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(newWalletMemberResponse(wallet.WalletNumber, *member))
+}
+
+// declineInvitation delete the pending invitation of the logged in account.
+func (server *Server) declineInvitation(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	wallet, ok := server.walletByParam(w, ps)
+	if !ok {
+		return
+	}
+
+	authPayload := r.Context().Value("authPayloadKey").(*token.Payload)
+	member, err := server.store.GetWalletMember(server.ctx, wallet.ID, authPayload.AccountID)
+	if err != nil || member.Status != services.MemberStatusInvited {
+		http.Error(w, "there's no invitation to the wallet", http.StatusNotFound)
+		return
+	}
+
+	err = server.store.DeleteWalletMember(server.ctx, wallet.ID, authPayload.AccountID)
+	if err != nil {
+		http.Error(w, "Failed delete invitation from database", http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(err.Error())
+		return
+	}
+
+	w.Header().Add("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode("Invitation declined")
+}
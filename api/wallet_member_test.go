@@ -0,0 +1,82 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWalletMemberRoles(t *testing.T) {
+	owner := loginAccount(t)
+	member := loginAccount(t)
+	stranger := loginAccount(t)
+
+	wallet := createWalletCurrency(t, owner.AccessToken, "IDR")
+	status, body := transferMoney(t, owner.AccessToken, owner.Account.AccountNumber, wallet.WalletNumber, 50000)
+	require.Equal(t, http.StatusOK, status, string(body))
+
+	walletPath := "/wallet/" + strconv.FormatInt(wallet.WalletNumber, 10)
+	memberPath := "/wallet/members/" + strconv.FormatInt(wallet.WalletNumber, 10) + "/" + strconv.FormatInt(member.Account.AccountNumber, 10)
+	invitationPath := "/invitations/" + strconv.FormatInt(wallet.WalletNumber, 10)
+
+	// only the owner can invite
+	status, _ = sendRequest(t, "POST", walletPath+"/members", stranger.AccessToken, inviteMemberRequest{
+		AccountNumber: member.Account.AccountNumber,
+		Role:          "viewer",
+	})
+	assert.Equal(t, http.StatusUnauthorized, status)
+
+	// the owner already owns the wallet
+	status, _ = sendRequest(t, "POST", walletPath+"/members", owner.AccessToken, inviteMemberRequest{
+		AccountNumber: owner.Account.AccountNumber,
+		Role:          "viewer",
+	})
+	assert.Equal(t, http.StatusBadRequest, status)
+
+	status, body = sendRequest(t, "POST", walletPath+"/members", owner.AccessToken, inviteMemberRequest{
+		AccountNumber: member.Account.AccountNumber,
+		Role:          "viewer",
+	})
+	require.Equal(t, http.StatusOK, status, string(body))
+
+	// the invited account isn't a member before accepting
+	status, _ = sendRequest(t, "GET", walletPath+"/members", member.AccessToken, nil)
+	assert.Equal(t, http.StatusUnauthorized, status)
+
+	status, _ = sendRequest(t, "POST", invitationPath+"/accept", stranger.AccessToken, nil)
+	assert.Equal(t, http.StatusNotFound, status)
+
+	status, body = sendRequest(t, "POST", invitationPath+"/accept", member.AccessToken, nil)
+	require.Equal(t, http.StatusOK, status, string(body))
+	var accepted walletMemberResponse
+	require.NoError(t, json.Unmarshal(body, &accepted))
+	assert.Equal(t, "viewer", accepted.Role)
+	assert.Equal(t, "active", accepted.Status)
+
+	status, _ = sendRequest(t, "GET", walletPath+"/members", member.AccessToken, nil)
+	assert.Equal(t, http.StatusOK, status)
+
+	// viewers can't spend or manage members
+	status, _ = transferMoney(t, member.AccessToken, wallet.WalletNumber, member.Account.AccountNumber, 1000)
+	assert.Equal(t, http.StatusForbidden, status)
+
+	status, _ = sendRequest(t, "PUT", memberPath, member.AccessToken, updateMemberRequest{Role: "owner"})
+	assert.Equal(t, http.StatusForbidden, status)
+
+	status, body = sendRequest(t, "PUT", memberPath, owner.AccessToken, updateMemberRequest{Role: "spender"})
+	require.Equal(t, http.StatusOK, status, string(body))
+
+	status, body = transferMoney(t, member.AccessToken, wallet.WalletNumber, member.Account.AccountNumber, 1000)
+	assert.Equal(t, http.StatusOK, status, string(body))
+
+	// members can leave the wallet, after that they lose access
+	status, body = sendRequest(t, "DELETE", memberPath, member.AccessToken, nil)
+	require.Equal(t, http.StatusOK, status, string(body))
+
+	status, _ = transferMoney(t, member.AccessToken, wallet.WalletNumber, member.Account.AccountNumber, 1000)
+	assert.Equal(t, http.StatusUnauthorized, status)
+}
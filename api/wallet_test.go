@@ -178,46 +178,17 @@ func TestListWallet(t *testing.T) {
 	}
 }
 
-func updateWalletTest(t *testing.T, token string, wallet walletResponse, balance int64) {
-	arg := updateWalletRequest{
-		WalletNumber: wallet.WalletNumber,
-		Balance:      balance,
-	}
-
-	argMarshaled, err := json.Marshal(arg)
-	require.NoError(t, err)
-	require.NotEmpty(t, argMarshaled)
-
-	newUrl := url + "/update/" + strconv.FormatInt(wallet.WalletNumber, 10)
-	req, err := http.NewRequest("PUT", newUrl, bytes.NewReader(argMarshaled))
-	require.NoError(t, err)
-	require.NotEmpty(t, req)
-
-	req.Header.Add("Content-Type", "application/json")
-	req.Header.Add("Authorization", "Bearer "+token)
-
-	client := http.Client{Timeout: 10 * time.Second}
-	require.NotEmpty(t, client)
-
-	res, err := client.Do(req)
-	require.NoError(t, err)
-	require.NotEmpty(t, res)
-	assert.True(t, res.StatusCode == 200)
-
-	defer res.Body.Close()
-
-	resBody, err := io.ReadAll(res.Body)
-	require.NoError(t, err)
-	require.NotEmpty(t, resBody)
-
-	resStr := string(resBody)
-	assert.Equal(t, "\"Data modified\"\n", resStr)
-}
-
 func TestUpdateWallet(t *testing.T) {
 	accRes := loginAccount(t)
 	walRes := createRandomWallet(t, accRes.AccessToken)
-	updateWalletTest(t, accRes.AccessToken, walRes, 100000)
+
+	// customers can't set a balance, it only change with transfers
+	status, _ := sendRequest(t, "PUT", "/wallet/update/"+strconv.FormatInt(walRes.WalletNumber, 10), accRes.AccessToken, map[string]int64{"balance": 100000})
+	require.Equal(t, http.StatusNotFound, status)
+
+	wallet, err := testStore.GetWalletByNumber(testCtx, walRes.WalletNumber)
+	require.NoError(t, err)
+	assert.Zero(t, wallet.Balance)
 }
 
 func TestUpdateinfoWallet(t *testing.T) {
@@ -311,7 +282,8 @@ func TestDeleteWallet(t *testing.T) {
 	walRes := createWalletCurrency(t, accRes.AccessToken, "IDR")
 	require.NotEmpty(t, walRes)
 
-	updateWalletTest(t, accRes.AccessToken, walRes, 350000)
+	status, body := transferMoney(t, accRes.AccessToken, accRes.Account.AccountNumber, walRes.WalletNumber, 350000)
+	require.Equal(t, http.StatusOK, status, string(body))
 	//wallet := getWalletTest(t, accRes.AccessToken, walRes)
 	//assert.Equal(t, int64(350000), wallet.Balance)
 
@@ -0,0 +1,336 @@
+package api
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"simple-bank-system/db/pkg"
+	"simple-bank-system/db/services"
+	"simple-bank-system/token"
+	"simple-bank-system/util"
+	"simple-bank-system/webhook"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+type createWebhookRequest struct {
+	URL        string   `json:"url" validate:"required,http_url,max=2048"`
+	EventTypes []string `json:"event_types" validate:"required,min=1,dive,required"`
+}
+
+type webhookResponse struct {
+	ID         int64
+	URL        string
+	EventTypes []string
+	// only returned when the webhook is created and when the secret is rotated
+	Secret          string `json:",omitempty"`
+	SecretRotatedAt *time.Time
+	CreatedAt       time.Time
+}
+
+type webhookDeliveryResponse struct {
+	ID            int64
+	EventID       int64
+	EventType     string
+	Status        string
+	Attempts      int64
+	NextAttemptAt *time.Time
+	DeliveredAt   *time.Time
+	CreatedAt     time.Time
+	Payload       json.RawMessage        `json:",omitempty"`
+	Log           []webhookAttemptResult `json:",omitempty"`
+}
+
+type webhookAttemptResult struct {
+	StatusCode int64
+	Error      string
+	DurationMs int64
+	CreatedAt  time.Time
+}
+
+func newWebhookResponse(subscription pkg.WebhookSubscription, withSecret bool) webhookResponse {
+	response := webhookResponse{
+		ID:         subscription.ID,
+		URL:        subscription.URL,
+		EventTypes: subscription.EventTypes,
+		CreatedAt:  subscription.CreatedAt,
+	}
+	if withSecret {
+		response.Secret = subscription.Secret
+	}
+	if subscription.SecretRotatedAt.Valid {
+		response.SecretRotatedAt = &subscription.SecretRotatedAt.Time
+	}
+	return response
+}
+
+func newWebhookDeliveryResponse(delivery pkg.WebhookDelivery) webhookDeliveryResponse {
+	response := webhookDeliveryResponse{
+		ID:        delivery.ID,
+		EventID:   delivery.EventID,
+		EventType: delivery.EventType,
+		Status:    delivery.Status,
+		Attempts:  delivery.Attempts,
+		CreatedAt: delivery.CreatedAt,
+	}
+	if delivery.Status == services.WebhookPending {
+		response.NextAttemptAt = &delivery.NextAttemptAt
+	}
+	if delivery.DeliveredAt.Valid {
+		response.DeliveredAt = &delivery.DeliveredAt.Time
+	}
+	return response
+}
+
+// webhookByParam return the webhook of the 'id' url parameter when it belongs to the account, otherwise it write the error.
+func (server *Server) webhookByParam(w http.ResponseWriter, r *http.Request, ps httprouter.Params) (*pkg.WebhookSubscription, bool) {
+	id, err := strconv.ParseInt(ps.ByName("id"), 10, 64)
+	if err != nil {
+		http.Error(w, "failed to convert url parameter to int", (http.StatusInternalServerError))
+		return nil, false
+	}
+
+	authPayload := r.Context().Value("authPayloadKey").(*token.Payload)
+	subscription, err := server.store.GetWebhook(server.ctx, id, authPayload.AccountID)
+	if err != nil {
+		if err == util.ErrNotExist {
+			http.Error(w, "webhook doesn't exist", http.StatusNotFound)
+			return nil, false
+		}
+		http.Error(w, "Can't get webhook", (http.StatusInternalServerError))
+		json.NewEncoder(w).Encode(err.Error())
+		return nil, false
+	}
+
+	return subscription, true
+}
+
+func (server *Server) createWebhook(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	var req createWebhookRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		http.Error(w, "failed to decode input data", (http.StatusInternalServerError))
+		json.NewEncoder(w).Encode(err.Error())
+		return
+	}
+
+	err = validate.Struct(req)
+	if err != nil {
+		http.Error(w, "Format input data is wrong", (http.StatusBadRequest))
+		json.NewEncoder(w).Encode(err.Error())
+		return
+	}
+	for _, eventType := range req.EventTypes {
+		if !webhook.ValidEventType(eventType) {
+			http.Error(w, "event type "+eventType+" doesn't exist", http.StatusBadRequest)
+			return
+		}
+	}
+	err = webhook.CheckURL(r.Context(), net.DefaultResolver, req.URL)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	secret, err := webhook.NewSecret()
+	if err != nil {
+		http.Error(w, "Can't create webhook secret", http.StatusInternalServerError)
+		return
+	}
+
+	authPayload := r.Context().Value("authPayloadKey").(*token.Payload)
+	subscription, err := server.store.CreateWebhook(server.ctx, services.CreateWebhookParams{
+		AccountID:  authPayload.AccountID,
+		URL:        req.URL,
+		EventTypes: req.EventTypes,
+		Secret:     secret,
+	})
+	if err != nil {
+		http.Error(w, "failed to pass data into database", (http.StatusInternalServerError))
+		json.NewEncoder(w).Encode(err.Error())
+		return
+	}
+
+	auditTarget(r, "webhook_id", subscription.ID)
+	w.Header().Add("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(newWebhookResponse(*subscription, true))
+}
+
+func (server *Server) listWebhooks(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authPayload := r.Context().Value("authPayloadKey").(*token.Payload)
+
+	subscriptions, err := server.store.ListWebhooks(server.ctx, authPayload.AccountID)
+	if err != nil {
+		http.Error(w, "Failed to get List", (http.StatusInternalServerError))
+		json.NewEncoder(w).Encode(err.Error())
+		return
+	}
+
+	response := []webhookResponse{}
+	for _, subscription := range subscriptions {
+		response = append(response, newWebhookResponse(subscription, false))
+	}
+
+	w.Header().Add("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
+
+func (server *Server) getWebhook(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	subscription, ok := server.webhookByParam(w, r, ps)
+	if !ok {
+		return
+	}
+
+	w.Header().Add("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(newWebhookResponse(*subscription, false))
+}
+
+func (server *Server) deleteWebhook(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	subscription, ok := server.webhookByParam(w, r, ps)
+	if !ok {
+		return
+	}
+
+	err := server.store.DeleteWebhook(server.ctx, subscription.ID, subscription.AccountID)
+	if err != nil {
+		http.Error(w, "Failed delete webhook from database", http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(err.Error())
+		return
+	}
+
+	w.Header().Add("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode("Webhook deleted!")
+}
+
+// rotateWebhookSecret return the new secret, the old secret also sign the deliveries during the grace period.
+func (server *Server) rotateWebhookSecret(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	subscription, ok := server.webhookByParam(w, r, ps)
+	if !ok {
+		return
+	}
+
+	secret, err := webhook.NewSecret()
+	if err != nil {
+		http.Error(w, "Can't create webhook secret", http.StatusInternalServerError)
+		return
+	}
+
+	subscription, err = server.store.RotateWebhookSecret(server.ctx, subscription.ID, subscription.AccountID, secret)
+	if err != nil {
+		http.Error(w, "Failed to rotate webhook secret", http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(err.Error())
+		return
+	}
+
+	w.Header().Add("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(newWebhookResponse(*subscription, true))
+}
+
+// listWebhookDeliveries return the last deliveries of the webhook, '?limit=' is 50 by default and max 200.
+func (server *Server) listWebhookDeliveries(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	subscription, ok := server.webhookByParam(w, r, ps)
+	if !ok {
+		return
+	}
+
+	limit := 50
+	if value := r.URL.Query().Get("limit"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 || n > 200 {
+			http.Error(w, "limit must be 1 to 200", http.StatusBadRequest)
+			return
+		}
+		limit = n
+	}
+
+	deliveries, err := server.store.ListWebhookDeliveries(server.ctx, subscription.ID, limit)
+	if err != nil {
+		http.Error(w, "Failed to get List", (http.StatusInternalServerError))
+		json.NewEncoder(w).Encode(err.Error())
+		return
+	}
+
+	response := []webhookDeliveryResponse{}
+	for _, delivery := range deliveries {
+		response = append(response, newWebhookDeliveryResponse(delivery))
+	}
+
+	w.Header().Add("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
+
+// getWebhookDelivery return the delivery with its body and every attempt.
+func (server *Server) getWebhookDelivery(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	subscription, ok := server.webhookByParam(w, r, ps)
+	if !ok {
+		return
+	}
+	deliveryID, err := strconv.ParseInt(ps.ByName("delivery_id"), 10, 64)
+	if err != nil {
+		http.Error(w, "failed to convert url parameter to int", (http.StatusInternalServerError))
+		return
+	}
+
+	delivery, err := server.store.GetWebhookDelivery(server.ctx, deliveryID, subscription.ID)
+	if err != nil {
+		if err == util.ErrNotExist {
+			http.Error(w, "delivery doesn't exist", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Can't get delivery", (http.StatusInternalServerError))
+		json.NewEncoder(w).Encode(err.Error())
+		return
+	}
+
+	response := newWebhookDeliveryResponse(*delivery)
+	response.Payload = delivery.Payload
+	for _, attempt := range delivery.Log {
+		response.Log = append(response.Log, webhookAttemptResult{
+			StatusCode: attempt.StatusCode,
+			Error:      attempt.Error,
+			DurationMs: attempt.DurationMs,
+			CreatedAt:  attempt.CreatedAt,
+		})
+	}
+
+	w.Header().Add("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
+
+// replayWebhookDelivery queue the delivery to be sent again with the same body.
+func (server *Server) replayWebhookDelivery(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	subscription, ok := server.webhookByParam(w, r, ps)
+	if !ok {
+		return
+	}
+	deliveryID, err := strconv.ParseInt(ps.ByName("delivery_id"), 10, 64)
+	if err != nil {
+		http.Error(w, "failed to convert url parameter to int", (http.StatusInternalServerError))
+		return
+	}
+
+	err = server.store.ReplayWebhookDelivery(server.ctx, deliveryID, subscription.ID)
+	if err != nil {
+		if err == util.ErrUpdateFailed {
+			http.Error(w, "delivery doesn't exist", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to replay delivery", http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(err.Error())
+		return
+	}
+
+	w.Header().Add("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode("Delivery is queued!")
+}
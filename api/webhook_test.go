@@ -0,0 +1,48 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"simple-bank-system/webhook"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateWebhook(t *testing.T) {
+	accRes := loginAccount(t)
+	eventTypes := []string{webhook.EventTransferIncoming}
+
+	testCases := []struct {
+		name   string
+		req    createWebhookRequest
+		status int
+	}{
+		{name: "HTTP", req: createWebhookRequest{URL: "http://93.184.215.14/hooks", EventTypes: eventTypes}, status: http.StatusBadRequest},
+		{name: "Loopback", req: createWebhookRequest{URL: "https://127.0.0.1/hooks", EventTypes: eventTypes}, status: http.StatusBadRequest},
+		{name: "Localhost", req: createWebhookRequest{URL: "https://localhost:8080/hooks", EventTypes: eventTypes}, status: http.StatusBadRequest},
+		{name: "Private", req: createWebhookRequest{URL: "https://10.0.0.1/hooks", EventTypes: eventTypes}, status: http.StatusBadRequest},
+		{name: "Metadata", req: createWebhookRequest{URL: "https://169.254.169.254/latest/meta-data", EventTypes: eventTypes}, status: http.StatusBadRequest},
+		{name: "IPv6Loopback", req: createWebhookRequest{URL: "https://[::1]/hooks", EventTypes: eventTypes}, status: http.StatusBadRequest},
+		{name: "UnknownEvent", req: createWebhookRequest{URL: "https://93.184.215.14/hooks", EventTypes: []string{"transfer.unknown"}}, status: http.StatusBadRequest},
+		{name: "OK", req: createWebhookRequest{URL: "https://93.184.215.14/hooks", EventTypes: eventTypes}, status: http.StatusOK},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			status, body := sendRequest(t, "POST", "/webhooks", accRes.AccessToken, tc.req)
+			require.Equal(t, tc.status, status, string(body))
+			if status != http.StatusOK {
+				return
+			}
+
+			var response webhookResponse
+			require.NoError(t, json.Unmarshal(body, &response))
+			assert.Equal(t, tc.req.URL, response.URL)
+			assert.NotEmpty(t, response.Secret)
+		})
+	}
+}
@@ -0,0 +1,164 @@
+/*
+ * Package authz is the authorization policy of the API. Every account has a role (a claim of its token)
+ * and every route need a permission. The roles are ordered, a role has the permissions of the roles
+ * before it: staff accounts can also use their own wallets like customers.
+ * Rights in 1 wallet (owner, spender, viewer) are checked by the handlers with 'WalletAllows'.
+ * Restricted tokens (minted by the account for other apps) also need the scope of the permission.
+ */
+package authz
+
+import (
+	"simple-bank-system/db/services"
+)
+
+// Roles of the accounts, from the least to the most rights
+const (
+	RoleCustomer = "customer"
+	RoleSupport  = "support"
+	RoleOperator = "operator"
+	RoleAdmin    = "admin"
+)
+
+var Roles = []string{RoleCustomer, RoleSupport, RoleOperator, RoleAdmin}
+
+var roleRank = map[string]int{
+	RoleCustomer: 1,
+	RoleSupport:  2,
+	RoleOperator: 3,
+	RoleAdmin:    4,
+}
+
+type Permission string
+
+// Permissions of the routes
+const (
+	// own wallets and the wallets shared with the account
+	WalletRead  Permission = "wallet:read"
+	WalletWrite Permission = "wallet:write"
+	// transfers, payment files and paying payment requests
+	TransferCreate       Permission = "transfer:create"
+	PayeeManage          Permission = "payee:manage"
+	PaymentRequestManage Permission = "payment_request:manage"
+	NotificationManage   Permission = "notification:manage"
+	WebhookManage        Permission = "webhook:manage"
+	ProfileManage        Permission = "profile:manage"
+	AdminAccountRead     Permission = "admin:account:read"
+	AdminAccountUnlock   Permission = "admin:account:unlock"
+	AdminWalletRead      Permission = "admin:wallet:read"
+	AdminLedgerRead      Permission = "admin:ledger:read"
+	AdminWalletFreeze    Permission = "admin:wallet:freeze"
+	AdminBalanceAdjust   Permission = "admin:balance:adjust"
+	AdminAuditRead       Permission = "admin:audit:read"
+	AdminRoleAssign      Permission = "admin:role:assign"
+	AdminInterestRateSet Permission = "admin:interest_rate:set"
+)
+
+// The least role that has the permission
+var policy = map[Permission]string{
+	WalletRead:           RoleCustomer,
+	WalletWrite:          RoleCustomer,
+	TransferCreate:       RoleCustomer,
+	PayeeManage:          RoleCustomer,
+	PaymentRequestManage: RoleCustomer,
+	NotificationManage:   RoleCustomer,
+	WebhookManage:        RoleCustomer,
+	ProfileManage:        RoleCustomer,
+	AdminAccountRead:     RoleSupport,
+	AdminAccountUnlock:   RoleSupport,
+	AdminWalletRead:      RoleSupport,
+	AdminLedgerRead:      RoleSupport,
+	AdminWalletFreeze:    RoleOperator,
+	AdminBalanceAdjust:   RoleOperator,
+	AdminAuditRead:       RoleOperator,
+	AdminRoleAssign:      RoleAdmin,
+	AdminInterestRateSet: RoleOperator,
+}
+
+// Scopes of the restricted tokens, from the least to the most rights
+const (
+	ScopeWalletsRead    = "wallets:read"
+	ScopeWalletsWrite   = "wallets:write"
+	ScopeTransfersWrite = "transfers:write"
+	ScopePayees         = "payees"
+	ScopeNotifications  = "notifications"
+	ScopeWebhooks       = "webhooks"
+	ScopeProfile        = "profile"
+	ScopeAdmin          = "admin"
+)
+
+var Scopes = []string{ScopeWalletsRead, ScopeWalletsWrite, ScopeTransfersWrite, ScopePayees, ScopeNotifications, ScopeWebhooks, ScopeProfile, ScopeAdmin}
+
+// The scope a restricted token need for the permission
+var scopes = map[Permission]string{
+	WalletRead:           ScopeWalletsRead,
+	WalletWrite:          ScopeWalletsWrite,
+	TransferCreate:       ScopeTransfersWrite,
+	PayeeManage:          ScopePayees,
+	PaymentRequestManage: ScopeTransfersWrite,
+	NotificationManage:   ScopeNotifications,
+	WebhookManage:        ScopeWebhooks,
+	ProfileManage:        ScopeProfile,
+	AdminAccountRead:     ScopeAdmin,
+	AdminAccountUnlock:   ScopeAdmin,
+	AdminWalletRead:      ScopeAdmin,
+	AdminLedgerRead:      ScopeAdmin,
+	AdminWalletFreeze:    ScopeAdmin,
+	AdminBalanceAdjust:   ScopeAdmin,
+	AdminAuditRead:       ScopeAdmin,
+	AdminRoleAssign:      ScopeAdmin,
+	AdminInterestRateSet: ScopeAdmin,
+}
+
+func ValidRole(role string) bool {
+	_, ok := roleRank[role]
+	return ok
+}
+
+// RoleOf return the role of the token claim, tokens without role (made before roles) are customers.
+func RoleOf(claim string) string {
+	if claim == "" {
+		return RoleCustomer
+	}
+	return claim
+}
+
+// Allows return true when the role has the permission, unknown roles and permissions have no rights.
+func Allows(role string, permission Permission) bool {
+	required, ok := policy[permission]
+	if !ok {
+		return false
+	}
+	rank, ok := roleRank[RoleOf(role)]
+	return ok && rank >= roleRank[required]
+}
+
+// ScopeOf return the scope needed by the permission, unknown permissions need an unknown scope.
+func ScopeOf(permission Permission) string {
+	if scope, ok := scopes[permission]; ok {
+		return scope
+	}
+	return string(permission)
+}
+
+// ScopeAllows return true when the role can use the scope: it has at least 1 permission of the scope,
+// customers can't mint 'admin' tokens.
+func ScopeAllows(role, scope string) bool {
+	for permission, s := range scopes {
+		if s == scope && Allows(role, permission) {
+			return true
+		}
+	}
+	return false
+}
+
+/*
+ * WalletAllows decide what the account can do in 1 wallet: 'walletRole' is its role in the wallet
+ * (owner, spender or viewer) and 'required' the least wallet role of the action.
+ * Frozen wallets only allow the actions of viewers, nobody can spend or change them.
+ */
+func WalletAllows(walletRole, required string, frozen bool) bool {
+	if frozen && required != services.WalletRoleViewer {
+		return false
+	}
+	return services.WalletRoleAllows(walletRole, required)
+}
@@ -0,0 +1,64 @@
+package authz
+
+import (
+	"testing"
+
+	"simple-bank-system/db/services"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAllows(t *testing.T) {
+	assert.True(t, Allows(RoleCustomer, TransferCreate))
+	assert.False(t, Allows(RoleCustomer, AdminAccountRead))
+	assert.True(t, Allows("", WalletRead), "tokens without role are customers")
+
+	assert.True(t, Allows(RoleSupport, AdminLedgerRead))
+	assert.True(t, Allows(RoleSupport, AdminAccountUnlock))
+	assert.True(t, Allows(RoleSupport, TransferCreate), "staff can use their own wallets")
+	assert.False(t, Allows(RoleSupport, AdminWalletFreeze))
+	assert.False(t, Allows(RoleSupport, AdminBalanceAdjust))
+	assert.False(t, Allows(RoleCustomer, AdminBalanceAdjust))
+
+	assert.True(t, Allows(RoleOperator, AdminWalletFreeze))
+	assert.True(t, Allows(RoleOperator, AdminAuditRead))
+	assert.True(t, Allows(RoleOperator, AdminBalanceAdjust))
+	assert.False(t, Allows(RoleOperator, AdminRoleAssign))
+	assert.True(t, Allows(RoleOperator, AdminInterestRateSet))
+	assert.False(t, Allows(RoleSupport, AdminInterestRateSet))
+
+	assert.True(t, Allows(RoleAdmin, AdminRoleAssign))
+	assert.False(t, Allows("root", WalletRead), "unknown role")
+	assert.False(t, Allows(RoleAdmin, Permission("unknown")), "unknown permission")
+
+	for _, role := range Roles {
+		assert.True(t, ValidRole(role))
+	}
+	assert.False(t, ValidRole(""))
+}
+
+func TestWalletAllows(t *testing.T) {
+	assert.True(t, WalletAllows(services.WalletRoleOwner, services.WalletRoleOwner, false))
+	assert.False(t, WalletAllows(services.WalletRoleViewer, services.WalletRoleSpender, false))
+
+	assert.True(t, WalletAllows(services.WalletRoleOwner, services.WalletRoleViewer, true))
+	assert.False(t, WalletAllows(services.WalletRoleOwner, services.WalletRoleSpender, true), "frozen wallet can't spend")
+	assert.False(t, WalletAllows(services.WalletRoleOwner, services.WalletRoleOwner, true), "frozen wallet can't change")
+}
+
+func TestScopes(t *testing.T) {
+	assert.Equal(t, ScopeWalletsRead, ScopeOf(WalletRead))
+	assert.Equal(t, ScopeTransfersWrite, ScopeOf(TransferCreate))
+	assert.Equal(t, ScopeAdmin, ScopeOf(AdminAuditRead))
+	assert.NotContains(t, Scopes, ScopeOf(Permission("unknown")), "unknown permission")
+
+	// every permission has a scope
+	for permission := range policy {
+		assert.Contains(t, Scopes, ScopeOf(permission), permission)
+	}
+
+	assert.True(t, ScopeAllows(RoleCustomer, ScopeWalletsRead))
+	assert.False(t, ScopeAllows(RoleCustomer, ScopeAdmin), "customers can't use admin tokens")
+	assert.True(t, ScopeAllows(RoleSupport, ScopeAdmin))
+	assert.False(t, ScopeAllows(RoleAdmin, "unknown"))
+}
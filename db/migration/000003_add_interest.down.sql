@@ -0,0 +1,23 @@
+--Order position is important
+-- the interest postings can't be deleted, the balances of the customer wallets wouldn't be the sum of their entries anymore
+DO $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM entries WHERE wallet_id IN (SELECT id FROM wallets WHERE wallet_type = 'system')) THEN
+        RAISE EXCEPTION 'interest postings exist, they can''t be removed from the ledger';
+    END IF;
+END;
+$$;
+
+DROP INDEX IF EXISTS ix_entries_walletId_createdAt;
+DROP TABLE IF EXISTS interest_accruals;
+DROP TABLE IF EXISTS interest_postings;
+DROP TABLE IF EXISTS interest_rates;
+
+DELETE FROM wallets WHERE wallet_type = 'system';
+DELETE FROM accounts WHERE account_number = 1010000000;
+DELETE FROM addresses WHERE street = 'Simple Bank Head Office';
+
+ALTER TABLE wallets DROP CONSTRAINT IF EXISTS ck_wallets_balance_minus;
+ALTER TABLE wallets ADD CONSTRAINT ck_wallets_balance_minus CHECK (balance >= 0);
+ALTER TABLE wallets DROP COLUMN IF EXISTS wallet_type;
+DROP TYPE IF EXISTS wallet_type;
@@ -0,0 +1,74 @@
+/*
+ * Wallet product type.
+ * 'savings' wallets earn interest, 'system' wallets belong to the bank itself
+ * and are the only wallets allowed to have negative balance (e.g. interest expense).
+ */
+CREATE TYPE wallet_type AS ENUM ('regular', 'savings', 'system');
+ALTER TABLE wallets ADD COLUMN wallet_type wallet_type DEFAULT 'regular' NOT NULL;
+
+ALTER TABLE wallets DROP CONSTRAINT ck_wallets_balance_minus;
+ALTER TABLE wallets ADD CONSTRAINT ck_wallets_balance_minus CHECK (balance >= 0 OR wallet_type = 'system');
+
+/*
+ * The bank owns an account (1010000000) that can't be used to login ('!' isn't a valid
+ * bcrypt hash). Its wallets are used as the counterparty of bank-initiated transfers,
+ * there is 1 wallet for every currency named "<purpose> <currency>".
+ */
+WITH bank_address AS (
+    INSERT INTO addresses(provinces, city, zip, street)
+    VALUES ('DKI Jakarta', 'Jakarta Pusat', 10110, 'Simple Bank Head Office')
+    RETURNING id
+)
+INSERT INTO accounts(account_number, username, hashed_password, full_name, date_of_birth, address, email)
+SELECT 1010000000, 'simplebank', '!', 'Simple Bank', '2023-01-01', id, 'system@simplebank.local' FROM bank_address;
+
+INSERT INTO wallets(account_id, wallet_number, name, balance, currency, wallet_type)
+SELECT accounts.id, wallet.number, 'Interest Expense ' || wallet.currency, 0, wallet.currency::valid_currency, 'system'
+FROM accounts, (VALUES (1010000001, 'IDR'), (1010000002, 'USD'), (1010000003, 'EUR'), (1010000004, 'YEN')) AS wallet(number, currency)
+WHERE accounts.account_number = 1010000000;
+
+-- Yearly interest rate for every wallet type in basis points (250 = 2.5%)
+CREATE TABLE interest_rates (
+    wallet_type wallet_type CONSTRAINT pk_interestRates_walletType PRIMARY KEY,
+    annual_rate_bps INT NOT NULL CONSTRAINT ck_interestRates_rate_minus CHECK (annual_rate_bps >= 0),
+    updated_at TIMESTAMPTZ DEFAULT NOW() NOT NULL
+);
+INSERT INTO interest_rates(wallet_type, annual_rate_bps) VALUES ('regular', 0), ('savings', 250), ('system', 0);
+
+/*
+ * Interest that is posted to the wallet as one transfer from the interest expense wallet.
+ * 'remainder' is the fraction that can't be posted as whole money, it's carried to the next posting.
+ */
+CREATE TABLE interest_postings (
+    id BIGSERIAL CONSTRAINT pk_interestPostings_id PRIMARY KEY,
+    wallet_id INT NOT NULL,
+        CONSTRAINT fk_interestPostings_walletId FOREIGN KEY (wallet_id) REFERENCES wallets(id),
+    period_end DATE NOT NULL,
+    amount BIGINT NOT NULL CONSTRAINT ck_interestPostings_amount_minus CHECK (amount >= 0),
+    remainder NUMERIC(24, 6) DEFAULT 0 NOT NULL,
+    transfer_id BIGINT,
+        CONSTRAINT fk_interestPostings_transferId FOREIGN KEY (transfer_id) REFERENCES transfers(id),
+    created_at TIMESTAMPTZ DEFAULT NOW() NOT NULL,
+    CONSTRAINT uq_interestPostings_wallet_period UNIQUE (wallet_id, period_end)
+);
+
+/*
+ * One row per wallet per day, the unique constraint make the daily accrual safe to re-run.
+ * 'balance' is the end-of-day balance of 'accrual_date'.
+ */
+CREATE TABLE interest_accruals (
+    id BIGSERIAL CONSTRAINT pk_interestAccruals_id PRIMARY KEY,
+    wallet_id INT NOT NULL,
+        CONSTRAINT fk_interestAccruals_walletId FOREIGN KEY (wallet_id) REFERENCES wallets(id),
+    accrual_date DATE NOT NULL,
+    balance BIGINT NOT NULL,
+    annual_rate_bps INT NOT NULL,
+    amount NUMERIC(24, 6) NOT NULL,
+    posting_id BIGINT,
+        CONSTRAINT fk_interestAccruals_postingId FOREIGN KEY (posting_id) REFERENCES interest_postings(id),
+    created_at TIMESTAMPTZ DEFAULT NOW() NOT NULL,
+    CONSTRAINT uq_interestAccruals_wallet_date UNIQUE (wallet_id, accrual_date)
+);
+
+CREATE INDEX ix_interestAccruals_unposted ON interest_accruals (wallet_id) WHERE posting_id IS NULL;
+CREATE INDEX ix_entries_walletId_createdAt ON entries (wallet_id, created_at);
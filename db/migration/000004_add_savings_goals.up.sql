@@ -0,0 +1,24 @@
+CREATE TYPE goal_lock_mode AS ENUM ('none', 'block', 'penalty');
+
+/*
+ * Savings goal of a wallet, 1 wallet can only have 1 goal.
+ * While the goal is locked, withdrawals from the wallet are blocked ('block') or charged
+ * 'penalty_bps' of the amount ('penalty') until 'target_date' or 'target_amount' is reached.
+ * 'reached_at' is set by the transfer that make the balance reach 'target_amount'.
+ */
+CREATE TABLE savings_goals (
+    wallet_id INT CONSTRAINT pk_savingsGoals_walletId PRIMARY KEY,
+        CONSTRAINT fk_savingsGoals_walletId FOREIGN KEY (wallet_id) REFERENCES wallets(id),
+    target_amount BIGINT NOT NULL CONSTRAINT ck_savingsGoals_targetAmount_zero CHECK (target_amount > 0),
+    target_date DATE NOT NULL,
+    lock_mode goal_lock_mode DEFAULT 'none' NOT NULL,
+    penalty_bps INT DEFAULT 0 NOT NULL CONSTRAINT ck_savingsGoals_penalty_range CHECK (penalty_bps >= 0 AND penalty_bps <= 10000),
+    reached_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ DEFAULT NOW() NOT NULL
+);
+
+-- Early withdrawal penalties are paid to these bank wallets
+INSERT INTO wallets(account_id, wallet_number, name, balance, currency, wallet_type)
+SELECT accounts.id, wallet.number, 'Penalty Income ' || wallet.currency, 0, wallet.currency::valid_currency, 'system'
+FROM accounts, (VALUES (1010000005, 'IDR'), (1010000006, 'USD'), (1010000007, 'EUR'), (1010000008, 'YEN')) AS wallet(number, currency)
+WHERE accounts.account_number = 1010000000;
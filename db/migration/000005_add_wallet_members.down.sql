@@ -0,0 +1,4 @@
+--Order position is important
+DROP TABLE IF EXISTS wallet_members;
+DROP TYPE IF EXISTS wallet_member_status;
+DROP TYPE IF EXISTS wallet_role;
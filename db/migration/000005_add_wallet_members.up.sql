@@ -0,0 +1,24 @@
+CREATE TYPE wallet_role AS ENUM ('owner', 'spender', 'viewer');
+CREATE TYPE wallet_member_status AS ENUM ('invited', 'active');
+
+/*
+ * Other accounts that share a wallet with its owner (wallets.account_id).
+ * The owner isn't stored here, a member is invited by an owner and has no access
+ * until the invitation is accepted ('active').
+ * 'owner' can do everything, 'spender' can see the wallet and transfer from it, 'viewer' can only see it.
+ */
+CREATE TABLE wallet_members (
+    wallet_id INT NOT NULL,
+        CONSTRAINT fk_walletMembers_walletId FOREIGN KEY (wallet_id) REFERENCES wallets(id),
+    account_id INT NOT NULL,
+        CONSTRAINT fk_walletMembers_accountId FOREIGN KEY (account_id) REFERENCES accounts(id),
+    role wallet_role NOT NULL,
+    status wallet_member_status DEFAULT 'invited' NOT NULL,
+    invited_by INT NOT NULL,
+        CONSTRAINT fk_walletMembers_invitedBy FOREIGN KEY (invited_by) REFERENCES accounts(id),
+    created_at TIMESTAMPTZ DEFAULT NOW() NOT NULL,
+    accepted_at TIMESTAMPTZ,
+    CONSTRAINT pk_walletMembers PRIMARY KEY (wallet_id, account_id)
+);
+
+CREATE INDEX ix_walletMembers_accountId ON wallet_members (account_id);
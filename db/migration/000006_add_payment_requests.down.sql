@@ -0,0 +1,4 @@
+--Order position is important
+DROP TABLE IF EXISTS payment_request_shares;
+DROP TABLE IF EXISTS payment_requests;
+DROP TYPE IF EXISTS payment_share_status;
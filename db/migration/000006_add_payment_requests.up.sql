@@ -0,0 +1,34 @@
+CREATE TYPE payment_share_status AS ENUM ('pending', 'paid', 'declined');
+
+/*
+ * Payment request, the requester ask 1 or more accounts (split bill) to pay into 'wallet_id'.
+ * 'amount' is the total of all shares in the currency of the wallet.
+ * Payers can pay or decline their share until 'expires_at', a share that's ignored stays 'pending'.
+ */
+CREATE TABLE payment_requests (
+    id BIGSERIAL CONSTRAINT pk_paymentRequests_id PRIMARY KEY,
+    requester_id INT NOT NULL,
+        CONSTRAINT fk_paymentRequests_requesterId FOREIGN KEY (requester_id) REFERENCES accounts(id),
+    wallet_id INT NOT NULL,
+        CONSTRAINT fk_paymentRequests_walletId FOREIGN KEY (wallet_id) REFERENCES wallets(id),
+    amount BIGINT NOT NULL CONSTRAINT ck_paymentRequests_amount_zero CHECK (amount > 0),
+    memo VARCHAR(255) DEFAULT '' NOT NULL,
+    expires_at DATE NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT NOW() NOT NULL
+);
+
+CREATE TABLE payment_request_shares (
+    request_id BIGINT NOT NULL,
+        CONSTRAINT fk_paymentRequestShares_requestId FOREIGN KEY (request_id) REFERENCES payment_requests(id),
+    payer_id INT NOT NULL,
+        CONSTRAINT fk_paymentRequestShares_payerId FOREIGN KEY (payer_id) REFERENCES accounts(id),
+    amount BIGINT NOT NULL CONSTRAINT ck_paymentRequestShares_amount_zero CHECK (amount > 0),
+    status payment_share_status DEFAULT 'pending' NOT NULL,
+    transfer_id BIGINT,
+        CONSTRAINT fk_paymentRequestShares_transferId FOREIGN KEY (transfer_id) REFERENCES transfers(id),
+    updated_at TIMESTAMPTZ DEFAULT NOW() NOT NULL,
+    CONSTRAINT pk_paymentRequestShares PRIMARY KEY (request_id, payer_id)
+);
+
+CREATE INDEX ix_paymentRequests_requesterId ON payment_requests (requester_id);
+CREATE INDEX ix_paymentRequestShares_payerId ON payment_request_shares (payer_id);
@@ -0,0 +1,17 @@
+/*
+ * Saved payees (address book) of an account.
+ * 'wallet_number' is the destination, the primary wallet number of an account is its account number.
+ * 'display_name' is the masked full name of the recipient when the payee is added, so the owner can
+ * confirm the recipient without exposing the name.
+ */
+CREATE TABLE payees (
+    id BIGSERIAL CONSTRAINT pk_payees_id PRIMARY KEY,
+    account_id INT NOT NULL,
+        CONSTRAINT fk_payees_accountId FOREIGN KEY (account_id) REFERENCES accounts(id),
+    nickname VARCHAR(50) NOT NULL CONSTRAINT ck_payees_nickname_empty CHECK (nickname <> ''),
+    wallet_number BIGINT NOT NULL,
+        CONSTRAINT fk_payees_walletNumber FOREIGN KEY (wallet_number) REFERENCES wallets(wallet_number),
+    display_name VARCHAR NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT NOW() NOT NULL,
+    CONSTRAINT uq_payees_account_wallet UNIQUE (account_id, wallet_number)
+);
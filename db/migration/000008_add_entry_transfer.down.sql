@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS ix_entries_transferId;
+ALTER TABLE entries DROP COLUMN IF EXISTS transfer_id;
@@ -0,0 +1,14 @@
+/*
+ * Link every entry to the transfer that created it, so statements can show the counterparty.
+ * Old entries are linked to the transfer of the same db transaction (same created_at) that moved
+ * the same amount from/to the wallet.
+ */
+ALTER TABLE entries ADD COLUMN transfer_id BIGINT;
+ALTER TABLE entries ADD CONSTRAINT fk_entries_transferId FOREIGN KEY (transfer_id) REFERENCES transfers(id);
+
+UPDATE entries e SET transfer_id = t.id
+FROM transfers t
+WHERE t.created_at = e.created_at
+AND ((t.from_wallet_number = e.wallet_number AND t.amount = -e.amount) OR (t.to_wallet_number = e.wallet_number AND t.amount = e.amount));
+
+CREATE INDEX ix_entries_transferId ON entries (transfer_id);
@@ -0,0 +1,14 @@
+/*
+ * Uploaded ISO 20022 pain.001 payment files. 'msg_id' is unique per account, so a file that's uploaded
+ * again isn't executed twice and get the same pain.002 status report.
+ * 'report' is empty while the file is executed.
+ */
+CREATE TABLE payment_files (
+    id BIGSERIAL CONSTRAINT pk_paymentFiles_id PRIMARY KEY,
+    account_id INT NOT NULL,
+        CONSTRAINT fk_paymentFiles_accountId FOREIGN KEY (account_id) REFERENCES accounts(id),
+    msg_id VARCHAR(35) NOT NULL,
+    report TEXT DEFAULT '' NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT NOW() NOT NULL,
+    CONSTRAINT uq_paymentFiles_account_msgId UNIQUE (account_id, msg_id)
+);
@@ -0,0 +1,13 @@
+/*
+ * End-of-day balance of every wallet, filled by the snapshot job.
+ * 'balance' is the balance at the end of 'snapshot_date' (before 'snapshot_date' + 1 00:00),
+ * point-in-time balance queries start from the nearest snapshot instead of replaying all entries.
+ */
+CREATE TABLE balance_snapshots (
+    wallet_id INT NOT NULL,
+        CONSTRAINT fk_balanceSnapshots_walletId FOREIGN KEY (wallet_id) REFERENCES wallets(id),
+    snapshot_date DATE NOT NULL,
+    balance BIGINT NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT NOW() NOT NULL,
+    CONSTRAINT pk_balanceSnapshots_wallet_date PRIMARY KEY (wallet_id, snapshot_date)
+);
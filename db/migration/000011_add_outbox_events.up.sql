@@ -0,0 +1,20 @@
+/*
+ * Transactional outbox of domain events. The events are written in the same db transaction as the change
+ * they describe, then the relay deliver them to the sinks and set 'delivered_at'.
+ * 'id' is the cursor of the consumers, it only increase within a wallet and the relay deliver the events
+ * of a wallet in 'id' order. Events that don't belong to a wallet (e.g. account created) have no 'wallet_id'.
+ */
+CREATE TABLE outbox_events (
+    id BIGSERIAL CONSTRAINT pk_outboxEvents_id PRIMARY KEY,
+    event_type VARCHAR(64) NOT NULL,
+    account_id INT NOT NULL,
+    wallet_id INT,
+    payload JSONB NOT NULL,
+    attempts INT DEFAULT 0 NOT NULL,
+    last_error TEXT,
+    created_at TIMESTAMPTZ DEFAULT NOW() NOT NULL,
+    delivered_at TIMESTAMPTZ
+);
+
+CREATE INDEX ix_outboxEvents_undelivered ON outbox_events (id) WHERE delivered_at IS NULL;
+CREATE INDEX ix_outboxEvents_wallet_undelivered ON outbox_events (wallet_id, id) WHERE delivered_at IS NULL;
@@ -0,0 +1,53 @@
+CREATE TYPE webhook_delivery_status AS ENUM ('pending', 'succeeded', 'failed');
+
+/*
+ * Webhook endpoint of an account for the chosen event types.
+ * Deliveries are signed with 'secret'. After the secret is rotated, 'previous_secret' is still
+ * used for a second signature until the grace period since 'secret_rotated_at' is over.
+ */
+CREATE TABLE webhook_subscriptions (
+    id BIGSERIAL CONSTRAINT pk_webhookSubscriptions_id PRIMARY KEY,
+    account_id INT NOT NULL,
+        CONSTRAINT fk_webhookSubscriptions_accountId FOREIGN KEY (account_id) REFERENCES accounts(id),
+    url VARCHAR(2048) NOT NULL,
+    event_types VARCHAR(64)[] NOT NULL,
+    secret VARCHAR(128) NOT NULL,
+    previous_secret VARCHAR(128),
+    secret_rotated_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ DEFAULT NOW() NOT NULL,
+    deleted_at TIMESTAMPTZ
+);
+CREATE INDEX ix_webhookSubscriptions_accountId ON webhook_subscriptions (account_id) WHERE deleted_at IS NULL;
+
+/*
+ * 1 delivery per subscription and outbox event, 'payload' is the exact body that is sent (and replayed).
+ * Failed attempts are retried at 'next_attempt_at' until the max attempts, then the delivery is 'failed'.
+ */
+CREATE TABLE webhook_deliveries (
+    id BIGSERIAL CONSTRAINT pk_webhookDeliveries_id PRIMARY KEY,
+    subscription_id BIGINT NOT NULL,
+        CONSTRAINT fk_webhookDeliveries_subscriptionId FOREIGN KEY (subscription_id) REFERENCES webhook_subscriptions(id),
+    event_id BIGINT NOT NULL,
+        CONSTRAINT fk_webhookDeliveries_eventId FOREIGN KEY (event_id) REFERENCES outbox_events(id),
+    event_type VARCHAR(64) NOT NULL,
+    payload JSONB NOT NULL,
+    status webhook_delivery_status DEFAULT 'pending' NOT NULL,
+    attempts INT DEFAULT 0 NOT NULL,
+    next_attempt_at TIMESTAMPTZ DEFAULT NOW() NOT NULL,
+    delivered_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ DEFAULT NOW() NOT NULL,
+    CONSTRAINT uq_webhookDeliveries_subscription_event UNIQUE (subscription_id, event_id)
+);
+CREATE INDEX ix_webhookDeliveries_due ON webhook_deliveries (next_attempt_at) WHERE status = 'pending';
+
+-- Every HTTP request of a delivery, it's the delivery log
+CREATE TABLE webhook_attempts (
+    id BIGSERIAL CONSTRAINT pk_webhookAttempts_id PRIMARY KEY,
+    delivery_id BIGINT NOT NULL,
+        CONSTRAINT fk_webhookAttempts_deliveryId FOREIGN KEY (delivery_id) REFERENCES webhook_deliveries(id),
+    status_code INT DEFAULT 0 NOT NULL,
+    error TEXT DEFAULT '' NOT NULL,
+    duration_ms INT DEFAULT 0 NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT NOW() NOT NULL
+);
+CREATE INDEX ix_webhookAttempts_deliveryId ON webhook_attempts (delivery_id);
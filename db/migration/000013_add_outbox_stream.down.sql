@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS ix_outboxEvents_wallet_id;
+DROP INDEX IF EXISTS ix_outboxEvents_xid_id;
+
+ALTER TABLE outbox_events DROP COLUMN IF EXISTS xid;
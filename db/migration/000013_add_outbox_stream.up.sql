@@ -0,0 +1,9 @@
+/*
+ * Transaction of the event, the wallet stream only send the events of transactions that are older than every
+ * running transaction, so an event with a smaller 'id' that is committed later can't be skipped.
+ * The stream cursor is ('xid', 'id').
+ */
+ALTER TABLE outbox_events ADD COLUMN xid xid8 DEFAULT pg_current_xact_id() NOT NULL;
+
+CREATE INDEX ix_outboxEvents_xid_id ON outbox_events (xid, id);
+CREATE INDEX ix_outboxEvents_wallet_id ON outbox_events (wallet_id);
@@ -0,0 +1,31 @@
+/*
+ * In-app inbox of the account, 'title' and 'body' are rendered from the template of 'event_type'
+ * when the notification is created. 'read_at' is NULL while the notification is unread.
+ */
+CREATE TABLE notifications (
+    id BIGSERIAL CONSTRAINT pk_notifications_id PRIMARY KEY,
+    account_id INT NOT NULL,
+        CONSTRAINT fk_notifications_accountId FOREIGN KEY (account_id) REFERENCES accounts(id),
+    event_type VARCHAR(64) NOT NULL,
+    title VARCHAR NOT NULL,
+    body TEXT NOT NULL,
+    data JSONB DEFAULT '{}' NOT NULL,
+    read_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ DEFAULT NOW() NOT NULL
+);
+CREATE INDEX ix_notifications_accountId_id ON notifications (account_id, id);
+CREATE INDEX ix_notifications_unread ON notifications (account_id) WHERE read_at IS NULL;
+
+/*
+ * Channels an account receive for an event type, event types without a row use the default
+ * preferences of the notification package.
+ */
+CREATE TABLE notification_preferences (
+    account_id INT NOT NULL,
+        CONSTRAINT fk_notificationPreferences_accountId FOREIGN KEY (account_id) REFERENCES accounts(id),
+    event_type VARCHAR(64) NOT NULL,
+    email BOOLEAN NOT NULL,
+    in_app BOOLEAN NOT NULL,
+    updated_at TIMESTAMPTZ DEFAULT NOW() NOT NULL,
+    CONSTRAINT pk_notificationPreferences PRIMARY KEY (account_id, event_type)
+);
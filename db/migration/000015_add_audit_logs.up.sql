@@ -0,0 +1,36 @@
+/*
+ * Append-only log of the state-changing API calls. 'actor_account_id' is NULL when the caller isn't
+ * logged in. 'targets' hold the ids of the changed objects (e.g. {"number": "1010000001"}) and 'changes'
+ * the changed fields (e.g. {"name": {"before": "a", "after": "b"}}).
+ * The triggers refuse every UPDATE, DELETE and TRUNCATE, so rows can't be changed through the application.
+ */
+CREATE TABLE audit_logs (
+    id BIGSERIAL CONSTRAINT pk_auditLogs_id PRIMARY KEY,
+    actor_account_id INT,
+    action VARCHAR(64) NOT NULL,
+    method VARCHAR(8) NOT NULL,
+    path VARCHAR NOT NULL,
+    status_code INT NOT NULL,
+    targets JSONB DEFAULT '{}' NOT NULL,
+    changes JSONB DEFAULT '{}' NOT NULL,
+    request_id VARCHAR(64) NOT NULL,
+    client_ip VARCHAR(64) NOT NULL,
+    user_agent VARCHAR NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT NOW() NOT NULL
+);
+CREATE INDEX ix_auditLogs_actorAccountId ON audit_logs (actor_account_id, id);
+CREATE INDEX ix_auditLogs_action ON audit_logs (action, id);
+CREATE INDEX ix_auditLogs_createdAt ON audit_logs (created_at);
+CREATE INDEX ix_auditLogs_requestId ON audit_logs (request_id);
+CREATE INDEX ix_auditLogs_targets ON audit_logs USING GIN (targets jsonb_path_ops);
+
+CREATE FUNCTION audit_logs_append_only() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'audit_logs is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER tg_auditLogs_noUpdate BEFORE UPDATE OR DELETE ON audit_logs
+    FOR EACH ROW EXECUTE FUNCTION audit_logs_append_only();
+CREATE TRIGGER tg_auditLogs_noTruncate BEFORE TRUNCATE ON audit_logs
+    FOR EACH STATEMENT EXECUTE FUNCTION audit_logs_append_only();
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS wallet_freezes;
+
+ALTER TABLE accounts DROP COLUMN IF EXISTS role;
+DROP TYPE IF EXISTS account_role;
@@ -0,0 +1,19 @@
+/*
+ * Role of the account, it's a claim of the token and the authz package decide what every role can do.
+ * Staff accounts (support, operator, admin) are also customers.
+ */
+CREATE TYPE account_role AS ENUM ('customer', 'support', 'operator', 'admin');
+ALTER TABLE accounts ADD COLUMN role account_role DEFAULT 'customer' NOT NULL;
+
+/*
+ * Frozen wallets can't send money until an operator unfreeze them, they still receive money.
+ * The freezes are removed when the wallet is unfrozen, the audit log keep the history.
+ */
+CREATE TABLE wallet_freezes (
+    wallet_id INT CONSTRAINT pk_walletFreezes_walletId PRIMARY KEY,
+        CONSTRAINT fk_walletFreezes_walletId FOREIGN KEY (wallet_id) REFERENCES wallets(id),
+    reason VARCHAR NOT NULL CONSTRAINT ck_walletFreezes_reason_empty CHECK (reason <> ''),
+    frozen_by INT NOT NULL,
+        CONSTRAINT fk_walletFreezes_frozenBy FOREIGN KEY (frozen_by) REFERENCES accounts(id),
+    created_at TIMESTAMPTZ DEFAULT NOW() NOT NULL
+);
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS email_changes;
+DROP TABLE IF EXISTS address_history;
+
+ALTER TABLE accounts ALTER COLUMN address TYPE SMALLINT;
@@ -0,0 +1,33 @@
+/*
+ * Address changes add a new row to 'addresses', 'accounts.address' is widened because every change use a new id.
+ * The replaced addresses are kept in 'address_history' with the time they were used.
+ */
+ALTER TABLE accounts ALTER COLUMN address TYPE INT;
+
+CREATE TABLE address_history (
+    id BIGSERIAL CONSTRAINT pk_addressHistory_id PRIMARY KEY,
+    account_id INT NOT NULL,
+        CONSTRAINT fk_addressHistory_accountId FOREIGN KEY (account_id) REFERENCES accounts(id),
+    address_id INT NOT NULL,
+        CONSTRAINT fk_addressHistory_addressId FOREIGN KEY (address_id) REFERENCES addresses(id),
+    valid_from TIMESTAMPTZ NOT NULL,
+    valid_to TIMESTAMPTZ DEFAULT NOW() NOT NULL
+);
+CREATE INDEX ix_addressHistory_accountId ON address_history (account_id, id);
+
+/*
+ * Email change waiting for the verification of the new address, the email of the account is
+ * only changed when the token sent to the new address is verified. Only the hash of the token is saved.
+ */
+CREATE TABLE email_changes (
+    id BIGSERIAL CONSTRAINT pk_emailChanges_id PRIMARY KEY,
+    account_id INT NOT NULL,
+        CONSTRAINT fk_emailChanges_accountId FOREIGN KEY (account_id) REFERENCES accounts(id),
+    email VARCHAR NOT NULL CONSTRAINT ck_emailChanges_email_empty CHECK (email <> ''),
+    token_hash BYTEA NOT NULL,
+    expires_at TIMESTAMPTZ NOT NULL,
+    verified_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ DEFAULT NOW() NOT NULL
+);
+-- 1 pending change per account, a new request replace the old one
+CREATE UNIQUE INDEX uq_emailChanges_pending ON email_changes (account_id) WHERE verified_at IS NULL;
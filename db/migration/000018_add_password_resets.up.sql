@@ -0,0 +1,14 @@
+/*
+ * Forgot password tokens, only the hash of the token that is emailed is saved.
+ * A token is used once ('used_at'), 'created_at' is also used to limit the requests of an account.
+ */
+CREATE TABLE password_resets (
+    id BIGSERIAL CONSTRAINT pk_passwordResets_id PRIMARY KEY,
+    account_id INT NOT NULL,
+        CONSTRAINT fk_passwordResets_accountId FOREIGN KEY (account_id) REFERENCES accounts(id),
+    token_hash BYTEA NOT NULL CONSTRAINT uq_passwordResets_tokenHash UNIQUE,
+    expires_at TIMESTAMPTZ NOT NULL,
+    used_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ DEFAULT NOW() NOT NULL
+);
+CREATE INDEX ix_passwordResets_accountId_createdAt ON password_resets (account_id, created_at);
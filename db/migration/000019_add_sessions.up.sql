@@ -0,0 +1,18 @@
+/*
+ * Login session of a device. The refresh token is '<session id>.<secret>' and only the hash of the current
+ * secret is saved: every refresh replace it, so an old secret that is used again means the token was stolen
+ * and the session is revoked.
+ */
+CREATE TABLE sessions (
+    id BIGSERIAL CONSTRAINT pk_sessions_id PRIMARY KEY,
+    account_id INT NOT NULL,
+        CONSTRAINT fk_sessions_accountId FOREIGN KEY (account_id) REFERENCES accounts(id),
+    refresh_token_hash BYTEA NOT NULL,
+    user_agent VARCHAR NOT NULL,
+    client_ip VARCHAR NOT NULL,
+    expires_at TIMESTAMPTZ NOT NULL,
+    revoked_at TIMESTAMPTZ,
+    last_used_at TIMESTAMPTZ DEFAULT NOW() NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT NOW() NOT NULL
+);
+CREATE INDEX ix_sessions_accountId ON sessions (account_id) WHERE revoked_at IS NULL;
@@ -0,0 +1,17 @@
+/*
+ * Revoked access tokens, they are checked by every authenticated request (through the in-memory cache of
+ * the 'revocation' package). A row revoke 1 token ('token_id', logout) or every token of the account that
+ * was issued before 'revoked_before' (logout everywhere).
+ * The rows are pruned after 'expires_at', when the revoked tokens are expired anyway.
+ */
+CREATE TABLE token_revocations (
+    id BIGSERIAL CONSTRAINT pk_tokenRevocations_id PRIMARY KEY,
+    token_id UUID CONSTRAINT uq_tokenRevocations_tokenId UNIQUE,
+    account_id INT NOT NULL,
+        CONSTRAINT fk_tokenRevocations_accountId FOREIGN KEY (account_id) REFERENCES accounts(id),
+    revoked_before TIMESTAMPTZ,
+    expires_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT NOW() NOT NULL,
+    CONSTRAINT ck_tokenRevocations_kind CHECK ((token_id IS NULL) <> (revoked_before IS NULL))
+);
+CREATE INDEX ix_tokenRevocations_expiresAt ON token_revocations (expires_at);
@@ -0,0 +1,39 @@
+/*
+ * TOTP (RFC 6238) second factor of the account. The secret is pending until the first code is confirmed
+ * ('enabled_at'). 'last_used_step' is the time step of the last accepted code, older or equal steps are
+ * refused so a code can't be used twice.
+ */
+CREATE TABLE account_totp (
+    account_id INT CONSTRAINT pk_accountTotp_accountId PRIMARY KEY,
+        CONSTRAINT fk_accountTotp_accountId FOREIGN KEY (account_id) REFERENCES accounts(id),
+    secret VARCHAR(64) NOT NULL,
+    enabled_at TIMESTAMPTZ,
+    last_used_step BIGINT,
+    created_at TIMESTAMPTZ DEFAULT NOW() NOT NULL
+);
+
+-- One-time recovery codes, used in place of a TOTP code when the phone is lost. Only the hash is saved.
+CREATE TABLE recovery_codes (
+    id BIGSERIAL CONSTRAINT pk_recoveryCodes_id PRIMARY KEY,
+    account_id INT NOT NULL,
+        CONSTRAINT fk_recoveryCodes_accountId FOREIGN KEY (account_id) REFERENCES accounts(id),
+    code_hash BYTEA NOT NULL,
+    used_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ DEFAULT NOW() NOT NULL,
+    CONSTRAINT uq_recoveryCodes_accountId_codeHash UNIQUE (account_id, code_hash)
+);
+
+/*
+ * First step of the login of an account with 2FA. The password was right, the challenge token is exchanged
+ * with a TOTP code for the access token. It's used once and refused after 'attempts' wrong codes.
+ */
+CREATE TABLE login_challenges (
+    id BIGSERIAL CONSTRAINT pk_loginChallenges_id PRIMARY KEY,
+    account_id INT NOT NULL,
+        CONSTRAINT fk_loginChallenges_accountId FOREIGN KEY (account_id) REFERENCES accounts(id),
+    token_hash BYTEA NOT NULL CONSTRAINT uq_loginChallenges_tokenHash UNIQUE,
+    expires_at TIMESTAMPTZ NOT NULL,
+    attempts INT DEFAULT 0 NOT NULL,
+    used_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ DEFAULT NOW() NOT NULL
+);
@@ -0,0 +1,14 @@
+/*
+ * Failed logins of a username ('scope' username) and of a client IP ('scope' ip). After too many failures
+ * in a row the key is locked until 'locked_until', every new lockout ('lockouts') double the duration.
+ * Unknown usernames are counted too, so the lockout doesn't tell if the username exists.
+ */
+CREATE TABLE login_throttles (
+    scope VARCHAR(16) NOT NULL,
+    key VARCHAR(255) NOT NULL,
+    failed_attempts INT DEFAULT 0 NOT NULL,
+    lockouts INT DEFAULT 0 NOT NULL,
+    locked_until TIMESTAMPTZ,
+    last_failed_at TIMESTAMPTZ,
+    CONSTRAINT pk_loginThrottles_scope_key PRIMARY KEY (scope, key)
+);
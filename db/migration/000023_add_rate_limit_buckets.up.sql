@@ -0,0 +1,10 @@
+/*
+ * Token buckets of the Postgres rate limiter backend, shared by every server. A new bucket is full
+ * (it's refilled since 'epoch'), the buckets that are idle for a day are pruned.
+ */
+CREATE TABLE rate_limit_buckets (
+    key VARCHAR(320) CONSTRAINT pk_rateLimitBuckets_key PRIMARY KEY,
+    tokens DOUBLE PRECISION NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL
+);
+CREATE INDEX ix_rateLimitBuckets_updatedAt ON rate_limit_buckets (updated_at);
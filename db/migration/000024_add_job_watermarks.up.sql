@@ -0,0 +1,9 @@
+/*
+ * Last day that a daily job (interest accrual, balance snapshot) has processed. The jobs process every
+ * day after it up to yesterday, so the days when the server was down are caught up by the next run.
+ */
+CREATE TABLE job_watermarks (
+    job VARCHAR(100) CONSTRAINT pk_jobWatermarks_job PRIMARY KEY,
+    last_day DATE NOT NULL,
+    updated_at TIMESTAMPTZ DEFAULT NOW() NOT NULL
+);
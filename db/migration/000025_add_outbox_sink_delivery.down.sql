@@ -0,0 +1,2 @@
+ALTER TABLE outbox_events DROP COLUMN IF EXISTS delivered_sinks;
+ALTER TABLE outbox_events DROP COLUMN IF EXISTS claimed_until;
@@ -0,0 +1,7 @@
+/*
+ * The relay claim the events for a while ('claimed_until') in a short db transaction and call the sinks
+ * after it's committed, a claim of a relay that stopped expire. 'delivered_sinks' are the names of the sinks
+ * that already have the event, so a failing sink doesn't make the others get the event again.
+ */
+ALTER TABLE outbox_events ADD COLUMN claimed_until TIMESTAMPTZ;
+ALTER TABLE outbox_events ADD COLUMN delivered_sinks TEXT[] DEFAULT '{}' NOT NULL;
@@ -0,0 +1,13 @@
+--Order position is important
+-- the adjustments can't be deleted, the balances of the customer wallets wouldn't be the sum of their entries anymore
+DO $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM transfers WHERE from_wallet_number IN (1010000009, 1010000010, 1010000011, 1010000012)
+        OR to_wallet_number IN (1010000009, 1010000010, 1010000011, 1010000012)) THEN
+        RAISE EXCEPTION 'balance adjustments exist, they can''t be removed from the ledger';
+    END IF;
+END;
+$$;
+
+DELETE FROM balance_snapshots WHERE wallet_id IN (SELECT id FROM wallets WHERE wallet_number IN (1010000009, 1010000010, 1010000011, 1010000012));
+DELETE FROM wallets WHERE wallet_number IN (1010000009, 1010000010, 1010000011, 1010000012);
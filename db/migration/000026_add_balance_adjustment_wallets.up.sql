@@ -0,0 +1,5 @@
+-- Corrections of a balance by the operators are transfers from (or to) these bank wallets, so they are in the ledger
+INSERT INTO wallets(account_id, wallet_number, name, balance, currency, wallet_type)
+SELECT accounts.id, wallet.number, 'Balance Adjustment ' || wallet.currency, 0, wallet.currency::valid_currency, 'system'
+FROM accounts, (VALUES (1010000009, 'IDR'), (1010000010, 'USD'), (1010000011, 'EUR'), (1010000012, 'YEN')) AS wallet(number, currency)
+WHERE accounts.account_number = 1010000000;
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS payment_file_transactions;
+
+ALTER TABLE payment_files DROP COLUMN IF EXISTS claimed_until;
+ALTER TABLE payment_files DROP COLUMN IF EXISTS file_hash;
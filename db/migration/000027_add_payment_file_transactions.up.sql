@@ -0,0 +1,23 @@
+/*
+ * The result of every transaction of a payment file is saved as it runs, in the db transaction of its transfer,
+ * so a file whose execution was interrupted is resumed by the next upload with the same message id: the saved
+ * transactions aren't executed again. 'claimed_until' is the lease of the execution, an upload while it's
+ * running get 409, after it the file can be resumed. 'file_hash' (SHA-256) make sure the same file is resumed.
+ */
+ALTER TABLE payment_files ADD COLUMN file_hash VARCHAR(64) DEFAULT '' NOT NULL;
+ALTER TABLE payment_files ADD COLUMN claimed_until TIMESTAMPTZ DEFAULT NOW() NOT NULL;
+
+CREATE TABLE payment_file_transactions (
+    file_id BIGINT NOT NULL,
+        CONSTRAINT fk_paymentFileTransactions_fileId FOREIGN KEY (file_id) REFERENCES payment_files(id),
+    -- position of the payment information in the file and of the transaction in it
+    payment_index INT NOT NULL,
+    transaction_index INT NOT NULL,
+    status VARCHAR(4) NOT NULL,
+    reason VARCHAR(4) DEFAULT '' NOT NULL,
+    info TEXT DEFAULT '' NOT NULL,
+    transfer_id BIGINT,
+        CONSTRAINT fk_paymentFileTransactions_transferId FOREIGN KEY (transfer_id) REFERENCES transfers(id),
+    created_at TIMESTAMPTZ DEFAULT NOW() NOT NULL,
+    CONSTRAINT pk_paymentFileTransactions PRIMARY KEY (file_id, payment_index, transaction_index)
+);
@@ -3,6 +3,8 @@ package pkg
 import (
 	"database/sql"
 	"time"
+
+	"github.com/google/uuid"
 )
 
 type Account struct {
@@ -17,6 +19,8 @@ type Account struct {
 	PasswordChangeAt time.Time
 	CreatedAt        time.Time
 	DeletedAt        sql.NullTime
+	// customer, support, operator or admin
+	Role string
 }
 
 type Addresses struct {
@@ -34,6 +38,7 @@ type Wallet struct {
 	WalletNumber int64
 	Balance      int64
 	Currency     string
+	Type         string
 	CreatedAt    time.Time
 	DeletedAt    sql.NullTime
 }
@@ -46,6 +51,7 @@ type Entry struct {
 	Amount       int64
 	CreatedAt    time.Time
 	DeletedAt    sql.NullTime
+	TransferID   sql.NullInt64
 }
 
 type Transfers struct {
@@ -58,3 +64,246 @@ type Transfers struct {
 	CreatedAt        time.Time
 	DeletedAt        sql.NullTime
 }
+
+type InterestRate struct {
+	WalletType    string
+	AnnualRateBps int64
+	UpdatedAt     time.Time
+}
+
+type InterestPosting struct {
+	ID         int64
+	WalletID   int64
+	PeriodEnd  time.Time
+	Amount     int64
+	TransferID sql.NullInt64
+	CreatedAt  time.Time
+}
+
+type SavingsGoal struct {
+	WalletID     int64
+	TargetAmount int64
+	TargetDate   time.Time
+	LockMode     string
+	PenaltyBps   int64
+	ReachedAt    sql.NullTime
+	CreatedAt    time.Time
+}
+
+type WalletMember struct {
+	WalletID      int64
+	AccountID     int64
+	AccountNumber int64
+	Role          string
+	Status        string
+	InvitedBy     int64
+	CreatedAt     time.Time
+	AcceptedAt    sql.NullTime
+}
+
+type PaymentRequest struct {
+	ID           int64
+	RequesterID  int64
+	WalletID     int64
+	WalletNumber int64
+	Amount       int64
+	Currency     string
+	Memo         string
+	ExpiresAt    time.Time
+	CreatedAt    time.Time
+	// total of the paid shares
+	PaidAmount int64
+	Shares     []PaymentRequestShare
+}
+
+type PaymentRequestShare struct {
+	RequestID     int64
+	PayerID       int64
+	AccountNumber int64
+	Amount        int64
+	Status        string
+	TransferID    sql.NullInt64
+	UpdatedAt     time.Time
+}
+
+type Payee struct {
+	ID           int64
+	AccountID    int64
+	Nickname     string
+	WalletNumber int64
+	DisplayName  string
+	CreatedAt    time.Time
+}
+
+// OutboxEvent is a domain event, 'Payload' is JSON and 'WalletID' is 0 when the event doesn't belong to a wallet.
+type OutboxEvent struct {
+	ID        int64
+	Type      string
+	AccountID int64
+	WalletID  int64
+	Payload   []byte
+	Attempts  int64
+	// names of the sinks that already have the event
+	DeliveredSinks []string
+	CreatedAt      time.Time
+}
+
+type WebhookSubscription struct {
+	ID              int64
+	AccountID       int64
+	URL             string
+	EventTypes      []string
+	Secret          string
+	PreviousSecret  sql.NullString
+	SecretRotatedAt sql.NullTime
+	CreatedAt       time.Time
+}
+
+type WebhookDelivery struct {
+	ID             int64
+	SubscriptionID int64
+	EventID        int64
+	EventType      string
+	Payload        []byte
+	Status         string
+	Attempts       int64
+	NextAttemptAt  time.Time
+	DeliveredAt    sql.NullTime
+	CreatedAt      time.Time
+	// filled by 'GetWebhookDelivery' only
+	Log []WebhookAttempt
+}
+
+type WebhookAttempt struct {
+	ID         int64
+	DeliveryID int64
+	StatusCode int64
+	Error      string
+	DurationMs int64
+	CreatedAt  time.Time
+}
+
+type Notification struct {
+	ID        int64
+	AccountID int64
+	Type      string
+	Title     string
+	Body      string
+	Data      []byte
+	ReadAt    sql.NullTime
+	CreatedAt time.Time
+}
+
+type NotificationPreference struct {
+	EventType string
+	Email     bool
+	InApp     bool
+}
+
+type AuditLog struct {
+	ID         int64
+	ActorID    sql.NullInt64
+	Action     string
+	Method     string
+	Path       string
+	StatusCode int64
+	Targets    []byte
+	Changes    []byte
+	RequestID  string
+	ClientIP   string
+	UserAgent  string
+	CreatedAt  time.Time
+}
+
+type WalletFreeze struct {
+	WalletID  int64
+	Reason    string
+	FrozenBy  int64
+	CreatedAt time.Time
+}
+
+// Address that the account used before, from 'ValidFrom' until 'ValidTo'
+type AddressHistory struct {
+	ID        int64
+	AccountID int64
+	Address   Addresses
+	ValidFrom time.Time
+	ValidTo   time.Time
+}
+
+type Session struct {
+	ID               int64
+	AccountID        int64
+	RefreshTokenHash []byte
+	UserAgent        string
+	ClientIP         string
+	ExpiresAt        time.Time
+	RevokedAt        sql.NullTime
+	LastUsedAt       time.Time
+	CreatedAt        time.Time
+}
+
+// Revocation of 1 access token ('TokenID') or of the tokens of the account issued before 'RevokedBefore'
+type TokenRevocation struct {
+	ID            int64
+	TokenID       uuid.NullUUID
+	AccountID     int64
+	RevokedBefore sql.NullTime
+	ExpiresAt     time.Time
+	CreatedAt     time.Time
+}
+
+// TOTP second factor, it's only used after the first code confirmed it ('EnabledAt')
+type AccountTOTP struct {
+	AccountID    int64
+	Secret       string
+	EnabledAt    sql.NullTime
+	LastUsedStep sql.NullInt64
+	CreatedAt    time.Time
+}
+
+type LoginChallenge struct {
+	ID        int64
+	AccountID int64
+	TokenHash []byte
+	ExpiresAt time.Time
+	Attempts  int
+	UsedAt    sql.NullTime
+	CreatedAt time.Time
+}
+
+// Failed logins and lockouts of a username or a client IP
+type LoginThrottle struct {
+	Scope          string
+	Key            string
+	FailedAttempts int
+	Lockouts       int
+	LockedUntil    sql.NullTime
+	LastFailedAt   sql.NullTime
+}
+
+// Token bucket of the rate limiter, 'Key' is the policy and the account or client IP
+type RateLimitBucket struct {
+	Key       string
+	Tokens    float64
+	UpdatedAt time.Time
+}
+
+type PasswordReset struct {
+	ID        int64
+	AccountID int64
+	TokenHash []byte
+	ExpiresAt time.Time
+	UsedAt    sql.NullTime
+	CreatedAt time.Time
+}
+
+type EmailChange struct {
+	ID         int64
+	AccountID  int64
+	Email      string
+	TokenHash  []byte
+	ExpiresAt  time.Time
+	VerifiedAt sql.NullTime
+	CreatedAt  time.Time
+}
@@ -45,9 +45,9 @@ func (r *DB) CreateAccount(ctx context.Context, account CreateAccountParams) (*p
 	query = `INSERT INTO accounts(account_number, username, hashed_password, full_name, date_of_birth, address, email
 		) VALUES(
 			1010000000+CAST(1000000 + floor(random() * 9000000) AS bigint), $1, $2, $3, $4, $5, $6
-		) RETURNING id, account_number, username, full_name, date_of_birth, email, password_change_at, created_at;`
+		) RETURNING id, account_number, username, full_name, date_of_birth, email, password_change_at, created_at, role;`
 	dbReturn = r.db.QueryRow(ctx, query, account.Username, hashedPass, account.FullName, account.DateOfBirth, res.Address.ID, account.Email)
-	err = dbReturn.Scan(&res.ID, &res.AccountNumber, &res.Username, &res.FullName, &res.DateOfBirth, &res.Email, &res.PasswordChangeAt, &res.CreatedAt)
+	err = dbReturn.Scan(&res.ID, &res.AccountNumber, &res.Username, &res.FullName, &res.DateOfBirth, &res.Email, &res.PasswordChangeAt, &res.CreatedAt, &res.Role)
 	if err != nil {
 		log.Println("--- (2)database")
 		err = accErrHandling(err)
@@ -57,6 +57,41 @@ func (r *DB) CreateAccount(ctx context.Context, account CreateAccountParams) (*p
 	return &res, nil
 }
 
+// CreateAccountTx() create the account together with its primary wallet, the wallet number is the account number.
+func (store *Store) CreateAccountTx(ctx context.Context, accountArg CreateAccountParams, walletArg CreateWalletParams) (*pkg.Account, *pkg.Wallet, error) {
+	var account *pkg.Account
+	var wallet *pkg.Wallet
+
+	err := store.execTx(ctx, func(q *DB) error {
+		var err error
+
+		account, err = q.CreateAccount(ctx, accountArg)
+		if err != nil {
+			return err
+		}
+
+		walletArg.AccountID = account.ID
+		walletArg.WalletNumber = account.AccountNumber
+		wallet, err = q.CreatePrimaryWallet(ctx, walletArg)
+		if err != nil {
+			return err
+		}
+
+		q.addEvent(EventAccountCreated, account.ID, 0, map[string]interface{}{
+			"account_number": account.AccountNumber,
+			"username":       account.Username,
+			"time":           account.CreatedAt,
+		})
+		q.addEvent(EventWalletCreated, account.ID, wallet.ID, newWalletEvent(wallet))
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return account, wallet, nil
+}
+
 func (r *DB) GetAccount(ctx context.Context, username string) (*pkg.Account, error) {
 	/*
 	 * Inner join using the WHERE clause.
@@ -69,7 +104,7 @@ func (r *DB) GetAccount(ctx context.Context, username string) (*pkg.Account, err
 
 	var account pkg.Account
 	err := row.Scan(&account.ID, &account.AccountNumber, &account.Username, &account.HashedPassword, &account.FullName, &account.DateOfBirth, &account.Address.ID, &account.Email, &account.PasswordChangeAt, &account.CreatedAt,
-		&account.DeletedAt, &account.Address.ID, &account.Address.Provinces, &account.Address.City, &account.Address.ZIP, &account.Address.Street)
+		&account.DeletedAt, &account.Role, &account.Address.ID, &account.Address.Provinces, &account.Address.City, &account.Address.ZIP, &account.Address.Street)
 	if err == pgx.ErrNoRows {
 		return nil, util.ErrNotExist
 	}
@@ -107,7 +142,7 @@ func (r *DB) GetAccountByNumber(ctx context.Context, accountNumber int64) (*pkg.
 
 	var account pkg.Account
 	err := row.Scan(&account.ID, &account.AccountNumber, &account.Username, &account.HashedPassword, &account.FullName, &account.DateOfBirth, &account.Address.ID, &account.Email, &account.PasswordChangeAt, &account.CreatedAt,
-		&account.DeletedAt, &account.Address.ID, &account.Address.Provinces, &account.Address.City, &account.Address.ZIP, &account.Address.Street)
+		&account.DeletedAt, &account.Role, &account.Address.ID, &account.Address.Provinces, &account.Address.City, &account.Address.ZIP, &account.Address.Street)
 	if err == pgx.ErrNoRows {
 		return nil, util.ErrNotExist
 	}
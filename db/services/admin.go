@@ -0,0 +1,166 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"simple-bank-system/db/pkg"
+	"simple-bank-system/util"
+
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
+)
+
+// SetAccountRole() change the role of the account, the tokens of the previous role are refused after it.
+func (r *DB) SetAccountRole(ctx context.Context, accountID int64, role string) error {
+	res, err := r.db.Exec(ctx, `UPDATE accounts SET role=$2 WHERE id=$1 AND deleted_at IS NULL;`, accountID, role)
+	if err != nil {
+		return err
+	}
+	if res.RowsAffected() == 0 {
+		return util.ErrUpdateFailed
+	}
+
+	return nil
+}
+
+// ListAccountWallets() return every open wallet that the account own, the primary wallet too.
+func (r *DB) ListAccountWallets(ctx context.Context, accountID int64) ([]pkg.Wallet, error) {
+	rows, err := r.db.Query(ctx, "SELECT * FROM wallets WHERE account_id=$1 AND deleted_at IS NULL ORDER BY id;", accountID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []pkg.Wallet
+	for rows.Next() {
+		var wallet pkg.Wallet
+		err = rows.Scan(&wallet.ID, &wallet.AccountID, &wallet.WalletNumber, &wallet.Name, &wallet.Balance, &wallet.Currency, &wallet.CreatedAt, &wallet.DeletedAt, &wallet.Type)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, wallet)
+	}
+
+	return list, rows.Err()
+}
+
+// GetWalletFreeze() return util.ErrNotExist when the wallet isn't frozen.
+func (r *DB) GetWalletFreeze(ctx context.Context, walletID int64) (*pkg.WalletFreeze, error) {
+	var res pkg.WalletFreeze
+	err := r.db.QueryRow(ctx, `SELECT wallet_id, reason, frozen_by, created_at FROM wallet_freezes WHERE wallet_id=$1;`, walletID).
+		Scan(&res.WalletID, &res.Reason, &res.FrozenBy, &res.CreatedAt)
+	if err == pgx.ErrNoRows {
+		return nil, util.ErrNotExist
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+func (r *DB) IsWalletFrozen(ctx context.Context, walletID int64) (bool, error) {
+	var frozen bool
+	err := r.db.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM wallet_freezes WHERE wallet_id=$1);`, walletID).Scan(&frozen)
+	return frozen, err
+}
+
+// Payload of 'wallet.frozen' and 'wallet.unfrozen'
+type WalletFreezeEvent struct {
+	WalletEvent
+	Reason string `json:"reason,omitempty"`
+}
+
+// FreezeWalletTx() freeze the wallet and write the 'wallet.frozen' event, a wallet that is frozen already return util.ErrDuplicate.
+func (store *Store) FreezeWalletTx(ctx context.Context, wallet pkg.Wallet, reason string, frozenBy int64) (*pkg.WalletFreeze, error) {
+	var freeze pkg.WalletFreeze
+
+	err := store.execTx(ctx, func(q *DB) error {
+		query := `INSERT INTO wallet_freezes(wallet_id, reason, frozen_by) VALUES ($1, $2, $3)
+		RETURNING wallet_id, reason, frozen_by, created_at;`
+		err := q.db.QueryRow(ctx, query, wallet.ID, reason, frozenBy).Scan(&freeze.WalletID, &freeze.Reason, &freeze.FrozenBy, &freeze.CreatedAt)
+		if err != nil {
+			var pgxError *pgconn.PgError
+			// 23505 (unique_violation) -> wallet is already frozen
+			if errors.As(err, &pgxError) && pgxError.Code == "23505" {
+				return util.ErrDuplicate
+			}
+			return err
+		}
+
+		event := WalletFreezeEvent{WalletEvent: newWalletEvent(&wallet), Reason: reason}
+		q.addEvent(EventWalletFrozen, wallet.AccountID, wallet.ID, event)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &freeze, nil
+}
+
+// UnfreezeWalletTx() remove the freeze and write the 'wallet.unfrozen' event, a wallet that isn't frozen return util.ErrDeleteFailed.
+func (store *Store) UnfreezeWalletTx(ctx context.Context, wallet pkg.Wallet) error {
+	return store.execTx(ctx, func(q *DB) error {
+		res, err := q.db.Exec(ctx, `DELETE FROM wallet_freezes WHERE wallet_id=$1;`, wallet.ID)
+		if err != nil {
+			return err
+		}
+		if res.RowsAffected() == 0 {
+			return util.ErrDeleteFailed
+		}
+
+		q.addEvent(EventWalletUnfrozen, wallet.AccountID, wallet.ID, WalletFreezeEvent{WalletEvent: newWalletEvent(&wallet)})
+		return nil
+	})
+}
+
+// ListLedgerParams are the filters of the ledger, zero values don't filter.
+type ListLedgerParams struct {
+	WalletNumber int64
+	From         time.Time
+	To           time.Time
+	// entries with a smaller id than 'BeforeID', for the next page
+	BeforeID int64
+	Limit    int
+}
+
+// ListLedgerEntries() return the entries of every wallet, newest first.
+func (r *DB) ListLedgerEntries(ctx context.Context, arg ListLedgerParams) ([]pkg.Entry, error) {
+	query := `SELECT id, account_id, wallet_id, wallet_number, amount, created_at, deleted_at, transfer_id
+	FROM entries
+	WHERE deleted_at IS NULL
+	AND ($1 = 0 OR wallet_number = $1)
+	AND ($2::timestamptz IS NULL OR created_at >= $2)
+	AND ($3::timestamptz IS NULL OR created_at < $3)
+	AND ($4 = 0 OR id < $4)
+	ORDER BY id DESC
+	LIMIT $5;`
+
+	var from, to interface{}
+	if !arg.From.IsZero() {
+		from = arg.From
+	}
+	if !arg.To.IsZero() {
+		to = arg.To
+	}
+
+	rows, err := r.db.Query(ctx, query, arg.WalletNumber, from, to, arg.BeforeID, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []pkg.Entry
+	for rows.Next() {
+		var entry pkg.Entry
+		err = rows.Scan(&entry.ID, &entry.AccountID, &entry.WalletID, &entry.WalletNumber, &entry.Amount, &entry.CreatedAt, &entry.DeletedAt, &entry.TransferID)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, entry)
+	}
+
+	return list, rows.Err()
+}
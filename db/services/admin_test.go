@@ -0,0 +1,97 @@
+package services
+
+import (
+	"testing"
+
+	"simple-bank-system/util"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAccountRole(t *testing.T) {
+	store := NewStore(dbpool)
+	account := createRandomAccount(t)
+	assert.Equal(t, "customer", account.Role)
+
+	require.NoError(t, store.SetAccountRole(ctx, account.ID, "support"))
+	updated, err := store.GetAccountByNumber(ctx, account.AccountNumber)
+	require.NoError(t, err)
+	assert.Equal(t, "support", updated.Role)
+
+	auth, err := store.GetAccountAuth(ctx, account.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "support", auth.Role)
+	assert.Equal(t, updated.PasswordChangeAt, auth.PasswordChangeAt)
+
+	_, err = store.GetAccountAuth(ctx, 0)
+	require.ErrorIs(t, err, util.ErrNotExist)
+
+	require.Error(t, store.SetAccountRole(ctx, account.ID, "root"), "role isn't in the enum")
+}
+
+func TestFreezeWallet(t *testing.T) {
+	store := NewStore(dbpool)
+	account := createRandomAccount(t)
+	staff := createRandomAccount(t)
+	wallet1, _ := createRandomWalletTransfer(t, account, "IDR")
+	wallet2, _ := createRandomWalletTransfer(t, account, "IDR")
+
+	_, err := store.GetWalletFreeze(ctx, wallet1.ID)
+	require.ErrorIs(t, err, util.ErrNotExist)
+
+	freeze, err := store.FreezeWalletTx(ctx, wallet1, "fraud check", staff.ID)
+	require.NoError(t, err)
+	assert.Equal(t, wallet1.ID, freeze.WalletID)
+	assert.Equal(t, "fraud check", freeze.Reason)
+	assert.Equal(t, staff.ID, freeze.FrozenBy)
+
+	_, err = store.FreezeWalletTx(ctx, wallet1, "again", staff.ID)
+	require.ErrorIs(t, err, util.ErrDuplicate)
+
+	// the frozen wallet can't send money, but can receive it
+	_, err = store.TransferTx(ctx, TransferTxParams{
+		AccountID:        account.ID,
+		WalletID:         wallet1.ID,
+		FromWalletNumber: wallet1.WalletNumber,
+		ToWalletNumber:   wallet2.WalletNumber,
+		Amount:           10,
+	})
+	require.ErrorIs(t, err, util.ErrWalletFrozen)
+
+	_, err = store.TransferTx(ctx, TransferTxParams{
+		AccountID:        account.ID,
+		WalletID:         wallet2.ID,
+		FromWalletNumber: wallet2.WalletNumber,
+		ToWalletNumber:   wallet1.WalletNumber,
+		Amount:           10,
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, store.UnfreezeWalletTx(ctx, wallet1))
+	require.ErrorIs(t, store.UnfreezeWalletTx(ctx, wallet1), util.ErrDeleteFailed)
+
+	frozen, err := store.IsWalletFrozen(ctx, wallet1.ID)
+	require.NoError(t, err)
+	assert.False(t, frozen)
+
+	wallets, err := store.ListAccountWallets(ctx, account.ID)
+	require.NoError(t, err)
+	assert.GreaterOrEqual(t, len(wallets), 2)
+}
+
+func TestListLedgerEntries(t *testing.T) {
+	account := createRandomAccount(t)
+	wallet, _ := createRandomWalletTransfer(t, account, "IDR")
+	createRandomEntries(t, account.ID, wallet)
+	createRandomEntries(t, account.ID, wallet)
+
+	entries, err := testQueries.ListLedgerEntries(ctx, ListLedgerParams{WalletNumber: wallet.WalletNumber, Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	assert.Greater(t, entries[0].ID, entries[1].ID, "newest first")
+
+	entries, err = testQueries.ListLedgerEntries(ctx, ListLedgerParams{WalletNumber: wallet.WalletNumber, BeforeID: entries[0].ID, Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+}
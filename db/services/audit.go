@@ -0,0 +1,101 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"simple-bank-system/db/pkg"
+)
+
+type CreateAuditLogParams struct {
+	// 0 when the caller isn't logged in
+	ActorID    int64
+	Action     string
+	Method     string
+	Path       string
+	StatusCode int
+	// JSON objects
+	Targets   []byte
+	Changes   []byte
+	RequestID string
+	ClientIP  string
+	UserAgent string
+}
+
+// CreateAuditLog() append the record, there are no functions to change or delete audit logs.
+func (r *DB) CreateAuditLog(ctx context.Context, arg CreateAuditLogParams) error {
+	query := `INSERT INTO audit_logs(actor_account_id, action, method, path, status_code, targets, changes, request_id, client_ip, user_agent
+	) VALUES (
+		NULLIF($1, 0), $2, $3, $4, $5, $6::jsonb, $7::jsonb, $8, $9, $10
+	);`
+
+	_, err := r.db.Exec(ctx, query, arg.ActorID, arg.Action, arg.Method, arg.Path, arg.StatusCode,
+		jsonObject(arg.Targets), jsonObject(arg.Changes), arg.RequestID, arg.ClientIP, arg.UserAgent)
+	return err
+}
+
+func jsonObject(data []byte) string {
+	if len(data) == 0 {
+		return "{}"
+	}
+	return string(data)
+}
+
+// ListAuditLogsParams are the filters of the audit logs, zero values don't filter.
+type ListAuditLogsParams struct {
+	ActorID int64
+	Action  string
+	// JSON object that the targets contain, e.g. {"number": "1010000001"}
+	Target    []byte
+	RequestID string
+	From      time.Time
+	To        time.Time
+	// logs with a smaller id than 'BeforeID', for the next page
+	BeforeID int64
+	Limit    int
+}
+
+// ListAuditLogs() return the logs that match every filter, newest first.
+func (r *DB) ListAuditLogs(ctx context.Context, arg ListAuditLogsParams) ([]pkg.AuditLog, error) {
+	query := `SELECT id, actor_account_id, action, method, path, status_code, targets, changes, request_id, client_ip, user_agent, created_at
+	FROM audit_logs
+	WHERE ($1 = 0 OR actor_account_id = $1)
+	AND ($2 = '' OR action = $2)
+	AND ($3::jsonb IS NULL OR targets @> $3::jsonb)
+	AND ($4 = '' OR request_id = $4)
+	AND ($5::timestamptz IS NULL OR created_at >= $5)
+	AND ($6::timestamptz IS NULL OR created_at < $6)
+	AND ($7 = 0 OR id < $7)
+	ORDER BY id DESC
+	LIMIT $8;`
+
+	var target, from, to interface{}
+	if len(arg.Target) > 0 {
+		target = string(arg.Target)
+	}
+	if !arg.From.IsZero() {
+		from = arg.From
+	}
+	if !arg.To.IsZero() {
+		to = arg.To
+	}
+
+	rows, err := r.db.Query(ctx, query, arg.ActorID, arg.Action, target, arg.RequestID, from, to, arg.BeforeID, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []pkg.AuditLog
+	for rows.Next() {
+		var log pkg.AuditLog
+		err = rows.Scan(&log.ID, &log.ActorID, &log.Action, &log.Method, &log.Path, &log.StatusCode, &log.Targets,
+			&log.Changes, &log.RequestID, &log.ClientIP, &log.UserAgent, &log.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, log)
+	}
+
+	return list, rows.Err()
+}
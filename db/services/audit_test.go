@@ -0,0 +1,72 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"simple-bank-system/util"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuditLog(t *testing.T) {
+	store := NewStore(dbpool)
+	account := createRandomAccount(t)
+	requestID := util.RandomString(32)
+	start := time.Now().Add(-time.Minute)
+
+	err := store.CreateAuditLog(ctx, CreateAuditLogParams{
+		ActorID:    account.ID,
+		Action:     "wallet.update_info",
+		Method:     "PUT",
+		Path:       "/wallet/updateInfo/1010000001",
+		StatusCode: 200,
+		Targets:    []byte(`{"number":"1010000001"}`),
+		Changes:    []byte(`{"name":{"before":"a","after":"b"}}`),
+		RequestID:  requestID,
+		ClientIP:   "127.0.0.1",
+		UserAgent:  "test",
+	})
+	require.NoError(t, err)
+	// request without actor
+	err = store.CreateAuditLog(ctx, CreateAuditLogParams{
+		Action:     "account.login",
+		Method:     "POST",
+		Path:       "/account/login",
+		StatusCode: 400,
+		RequestID:  requestID,
+		ClientIP:   "127.0.0.1",
+	})
+	require.NoError(t, err)
+
+	logs, err := store.ListAuditLogs(ctx, ListAuditLogsParams{RequestID: requestID, Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, logs, 2)
+	assert.Equal(t, "account.login", logs[0].Action)
+	assert.False(t, logs[0].ActorID.Valid)
+	assert.JSONEq(t, `{}`, string(logs[0].Targets))
+
+	logs, err = store.ListAuditLogs(ctx, ListAuditLogsParams{
+		ActorID: account.ID,
+		Action:  "wallet.update_info",
+		Target:  []byte(`{"number":"1010000001"}`),
+		From:    start,
+		To:      time.Now().Add(time.Minute),
+		Limit:   10,
+	})
+	require.NoError(t, err)
+	require.Len(t, logs, 1)
+	assert.Equal(t, account.ID, logs[0].ActorID.Int64)
+	assert.JSONEq(t, `{"name":{"before":"a","after":"b"}}`, string(logs[0].Changes))
+
+	logs, err = store.ListAuditLogs(ctx, ListAuditLogsParams{ActorID: account.ID, To: start, Limit: 10})
+	require.NoError(t, err)
+	assert.Empty(t, logs)
+
+	// audit logs are append-only
+	_, err = dbpool.Exec(ctx, `UPDATE audit_logs SET action='changed' WHERE request_id=$1`, requestID)
+	require.Error(t, err)
+	_, err = dbpool.Exec(ctx, `DELETE FROM audit_logs WHERE request_id=$1`, requestID)
+	require.Error(t, err)
+}
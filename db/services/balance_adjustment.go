@@ -0,0 +1,49 @@
+package services
+
+import (
+	"context"
+
+	"simple-bank-system/db/pkg"
+)
+
+type AdjustBalanceParams struct {
+	Wallet pkg.Wallet
+	// positive amounts are added to the wallet, negative ones are taken from it
+	Amount int64
+}
+
+/*
+ * AdjustBalanceTx() correct the balance of the wallet with a transfer from (or to) the balance adjustment
+ * wallet of the bank in its currency, so every correction is in the ledger like any other change of a balance.
+ * The transfer follow the rules of 'transferTx', a frozen or locked wallet can't be debited.
+ */
+func (store *Store) AdjustBalanceTx(ctx context.Context, arg AdjustBalanceParams) (*TransferTXResult, error) {
+	var result TransferTXResult
+
+	err := store.execTx(ctx, func(q *DB) error {
+		bankWallet, err := q.GetBankWallet(ctx, BankWalletBalanceAdjustment, arg.Wallet.Currency)
+		if err != nil {
+			return err
+		}
+
+		transfer := TransferTxParams{
+			AccountID:        bankWallet.AccountID,
+			WalletID:         bankWallet.ID,
+			FromWalletNumber: bankWallet.WalletNumber,
+			ToWalletNumber:   arg.Wallet.WalletNumber,
+			Amount:           arg.Amount,
+		}
+		if arg.Amount < 0 {
+			transfer = TransferTxParams{
+				AccountID:        arg.Wallet.AccountID,
+				WalletID:         arg.Wallet.ID,
+				FromWalletNumber: arg.Wallet.WalletNumber,
+				ToWalletNumber:   bankWallet.WalletNumber,
+				Amount:           -arg.Amount,
+			}
+		}
+		return transferTx(ctx, q, transfer, &result)
+	})
+
+	return &result, err
+}
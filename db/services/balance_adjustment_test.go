@@ -0,0 +1,42 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAdjustBalanceTx(t *testing.T) {
+	store := NewStore(dbpool)
+	account := createRandomAccount(t)
+	wallet := createRandomSavingsWallet(t, account, 1000)
+
+	bankWallet, err := testQueries.GetBankWallet(ctx, BankWalletBalanceAdjustment, wallet.Currency)
+	require.NoError(t, err)
+
+	// credit, from the bank wallet
+	result, err := store.AdjustBalanceTx(ctx, AdjustBalanceParams{Wallet: wallet, Amount: 500})
+	require.NoError(t, err)
+	assert.Equal(t, bankWallet.WalletNumber, result.Transfer.FromWalletNumber)
+	assert.Equal(t, wallet.WalletNumber, result.Transfer.ToWalletNumber)
+	assert.Equal(t, int64(1500), result.ToWallet.Balance)
+	assert.Equal(t, bankWallet.Balance-500, result.FromWallet.Balance)
+
+	// debit, to the bank wallet
+	result, err = store.AdjustBalanceTx(ctx, AdjustBalanceParams{Wallet: wallet, Amount: -300})
+	require.NoError(t, err)
+	assert.Equal(t, wallet.WalletNumber, result.Transfer.FromWalletNumber)
+	assert.Equal(t, bankWallet.WalletNumber, result.Transfer.ToWalletNumber)
+	assert.Equal(t, int64(300), result.Transfer.Amount)
+	assert.Equal(t, int64(1200), result.FromWallet.Balance)
+	assert.Equal(t, -int64(300), result.FromEntry.Amount)
+
+	// the balance of a customer wallet can't be negative
+	_, err = store.AdjustBalanceTx(ctx, AdjustBalanceParams{Wallet: wallet, Amount: -5000})
+	require.True(t, IsInsufficientFunds(err), err)
+
+	updated, err := testQueries.GetWallet(ctx, wallet.ID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1200), updated.Balance)
+}
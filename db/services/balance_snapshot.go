@@ -0,0 +1,78 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"simple-bank-system/db/pkg"
+
+	"github.com/jackc/pgx/v4"
+)
+
+/*
+ * SnapshotBalances() record the end-of-day balance of 'day' for every wallet that existed at the end of the day.
+ * End-of-day balance is the current balance minus all entries that were created after the day.
+ * Wallet that already has a snapshot of the day is skipped, so it's safe to re-run for the same day.
+ * Return the number of new snapshots.
+ */
+func (r *DB) SnapshotBalances(ctx context.Context, day time.Time) (int64, error) {
+	query := `INSERT INTO balance_snapshots(wallet_id, snapshot_date, balance)
+	SELECT w.id, $1::date, w.balance - COALESCE(SUM(e.amount), 0)
+	FROM wallets w
+	LEFT JOIN entries e ON e.wallet_id = w.id AND e.created_at >= $1::date + 1 AND e.deleted_at IS NULL
+	WHERE w.deleted_at IS NULL AND w.created_at < $1::date + 1
+	GROUP BY w.id, w.balance
+	ON CONFLICT (wallet_id, snapshot_date) DO NOTHING;`
+
+	res, err := r.db.Exec(ctx, query, day.Format(time.DateOnly))
+	if err != nil {
+		return 0, err
+	}
+
+	return res.RowsAffected(), nil
+}
+
+/*
+ * BalanceAsOf() return the balance of the wallet at 'at'.
+ * When there is a snapshot after 'at', the balance is the nearest snapshot before 'at' plus the entries
+ * between them. Otherwise 'at' is after the last snapshot, so only the few entries since 'at' are
+ * subtracted from the current balance, which make the answer equal to the live balance for 'at' = now.
+ * Wallet has no balance before it was created.
+ */
+func (r *DB) BalanceAsOf(ctx context.Context, wallet pkg.Wallet, at time.Time) (int64, error) {
+	if at.Before(wallet.CreatedAt) {
+		return 0, nil
+	}
+
+	// true when the wallet has no snapshot or 'at' is after the end of the last snapshot
+	var afterLast bool
+	query := `SELECT COALESCE($2::timestamptz >= MAX(snapshot_date) + 1, true) FROM balance_snapshots WHERE wallet_id=$1;`
+	err := r.db.QueryRow(ctx, query, wallet.ID, at).Scan(&afterLast)
+	if err != nil {
+		return 0, err
+	}
+	if afterLast {
+		return r.BalanceAt(ctx, wallet, at)
+	}
+
+	query = `SELECT s.balance + COALESCE((
+		SELECT SUM(e.amount) FROM entries e
+		WHERE e.wallet_id = s.wallet_id AND e.created_at >= s.snapshot_date + 1 AND e.created_at < $2 AND e.deleted_at IS NULL
+	), 0)
+	FROM balance_snapshots s
+	WHERE s.wallet_id=$1 AND s.snapshot_date <= $2::timestamptz::date - 1
+	ORDER BY s.snapshot_date DESC
+	LIMIT 1;`
+
+	var balance int64
+	err = r.db.QueryRow(ctx, query, wallet.ID, at).Scan(&balance)
+	if err == pgx.ErrNoRows {
+		// 'at' is before the first snapshot
+		return r.BalanceAt(ctx, wallet, at)
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	return balance, nil
+}
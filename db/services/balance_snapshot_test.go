@@ -0,0 +1,62 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBalanceAsOf(t *testing.T) {
+	store := NewStore(dbpool)
+	account := createRandomAccount(t)
+	recipient := createRandomAccount(t)
+	wallet := createRandomSavingsWallet(t, account, 1000)
+	toWallet := createRandomSavingsWallet(t, recipient, 0)
+
+	before := time.Now()
+	_, err := store.TransferTx(ctx, TransferTxParams{
+		AccountID:        account.ID,
+		WalletID:         wallet.ID,
+		FromWalletNumber: wallet.WalletNumber,
+		ToWalletNumber:   toWallet.WalletNumber,
+		Amount:           300,
+	})
+	require.NoError(t, err)
+
+	current, err := store.GetWallet(ctx, wallet.ID)
+	require.NoError(t, err)
+
+	// without snapshot
+	balance, err := store.BalanceAsOf(ctx, *current, time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, current.Balance, balance)
+	balance, err = store.BalanceAsOf(ctx, *current, before)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1000), balance)
+	balance, err = store.BalanceAsOf(ctx, *current, current.CreatedAt.Add(-time.Second))
+	require.NoError(t, err)
+	assert.Zero(t, balance)
+
+	// the snapshot of today include the transfer, it's re-run safe
+	n, err := store.SnapshotBalances(ctx, time.Now())
+	require.NoError(t, err)
+	assert.Greater(t, n, int64(0))
+	n, err = store.SnapshotBalances(ctx, time.Now())
+	require.NoError(t, err)
+	assert.Zero(t, n)
+
+	// 'at' is before the end of the snapshot, so the balance start from the live balance or an older snapshot
+	balance, err = store.BalanceAsOf(ctx, *current, time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, current.Balance, balance)
+	balance, err = store.BalanceAsOf(ctx, *current, before)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1000), balance)
+
+	// after the end of the snapshot
+	balance, err = store.BalanceAsOf(ctx, *current, time.Now().AddDate(0, 0, 2))
+	require.NoError(t, err)
+	assert.Equal(t, int64(700), balance)
+}
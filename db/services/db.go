@@ -15,6 +15,8 @@ type DBTX interface {
 
 type DB struct {
 	db DBTX
+	// domain events of the db transaction, see 'addEvent'
+	events []outboxEvent
 }
 
 func NewDB(db DBTX) *DB {
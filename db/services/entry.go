@@ -15,16 +15,17 @@ type CreateEntryParam struct {
 	walletID     int64
 	walletNumber int64
 	amount       int64
+	transferID   int64
 }
 
 func (c *DB) CreateEntry(ctx context.Context, arg CreateEntryParam) (*pkg.Entry, error) {
-	query := `INSERT INTO entries (account_id, wallet_id, wallet_number, amount
+	query := `INSERT INTO entries (account_id, wallet_id, wallet_number, amount, transfer_id
 	) VALUES (
-		$1, $2, $3, $4
-	) RETURNING id, account_id, wallet_id, wallet_number, amount, created_at;`
+		$1, $2, $3, $4, NULLIF($5, 0)
+	) RETURNING id, account_id, wallet_id, wallet_number, amount, created_at, transfer_id;`
 
 	var res pkg.Entry
-	err := c.db.QueryRow(ctx, query, arg.accountID, arg.walletID, arg.walletNumber, arg.amount).Scan(&res.ID, &res.AccountID, &res.WalletID, &res.WalletNumber, &res.Amount, &res.CreatedAt)
+	err := c.db.QueryRow(ctx, query, arg.accountID, arg.walletID, arg.walletNumber, arg.amount, arg.transferID).Scan(&res.ID, &res.AccountID, &res.WalletID, &res.WalletNumber, &res.Amount, &res.CreatedAt, &res.TransferID)
 	if err != nil {
 		return nil, err
 	}
@@ -53,7 +54,7 @@ func (c *DB) GetEntry(ctx context.Context, id int64, option string) (*pkg.Entry,
 	var err error
 	for _, dbQuery := range queries {
 		if dbQuery.option == option {
-			err = c.db.QueryRow(ctx, dbQuery.query, id).Scan(&res.ID, &res.AccountID, &res.WalletID, &res.WalletNumber, &res.Amount, &res.CreatedAt, &res.DeletedAt)
+			err = c.db.QueryRow(ctx, dbQuery.query, id).Scan(&res.ID, &res.AccountID, &res.WalletID, &res.WalletNumber, &res.Amount, &res.CreatedAt, &res.DeletedAt, &res.TransferID)
 		}
 	}
 
@@ -79,7 +80,7 @@ func (c *DB) ListEntry(ctx context.Context, arg listEntryParam) ([]pkg.Entry, er
 	var res []pkg.Entry
 	for row.Next() {
 		var temp pkg.Entry
-		if err = row.Scan(&temp.ID, &temp.AccountID, &temp.WalletID, &temp.WalletNumber, &temp.Amount, &temp.CreatedAt, &temp.DeletedAt); err != nil {
+		if err = row.Scan(&temp.ID, &temp.AccountID, &temp.WalletID, &temp.WalletNumber, &temp.Amount, &temp.CreatedAt, &temp.DeletedAt, &temp.TransferID); err != nil {
 			return nil, err
 		}
 		res = append(res, temp)
@@ -108,7 +109,7 @@ func (c *DB) ListEntryByID(ctx context.Context, ID int64, walOk bool) ([]pkg.Ent
 	var res []pkg.Entry
 	for row.Next() {
 		var temp pkg.Entry
-		if err = row.Scan(&temp.ID, &temp.AccountID, &temp.WalletID, &temp.WalletNumber, &temp.Amount, &temp.CreatedAt, &temp.DeletedAt); err != nil {
+		if err = row.Scan(&temp.ID, &temp.AccountID, &temp.WalletID, &temp.WalletNumber, &temp.Amount, &temp.CreatedAt, &temp.DeletedAt, &temp.TransferID); err != nil {
 			return nil, err
 		}
 		res = append(res, temp)
@@ -149,7 +150,7 @@ func (c *DB) ListEntryByDate(ctx context.Context, startDate, endDate string, ord
 	var res []pkg.Entry
 	for row.Next() {
 		var temp pkg.Entry
-		if err = row.Scan(&temp.ID, &temp.AccountID, &temp.WalletID, &temp.WalletNumber, &temp.Amount, &temp.CreatedAt, &temp.DeletedAt); err != nil {
+		if err = row.Scan(&temp.ID, &temp.AccountID, &temp.WalletID, &temp.WalletNumber, &temp.Amount, &temp.CreatedAt, &temp.DeletedAt, &temp.TransferID); err != nil {
 			return nil, err
 		}
 		res = append(res, temp)
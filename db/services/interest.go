@@ -0,0 +1,206 @@
+package services
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"simple-bank-system/db/pkg"
+	"simple-bank-system/util"
+
+	"github.com/jackc/pgx/v4"
+)
+
+func (r *DB) GetInterestRate(ctx context.Context, walletType string) (*pkg.InterestRate, error) {
+	query := `SELECT wallet_type, annual_rate_bps, updated_at FROM interest_rates WHERE wallet_type=$1;`
+
+	var res pkg.InterestRate
+	err := r.db.QueryRow(ctx, query, walletType).Scan(&res.WalletType, &res.AnnualRateBps, &res.UpdatedAt)
+	if err == pgx.ErrNoRows {
+		return nil, util.ErrNotExist
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &res, nil
+}
+
+type SetInterestRateParams struct {
+	WalletType    string
+	AnnualRateBps int64
+}
+
+// New rate is used from the next accrual, rate of days that already accrued isn't changed.
+func (r *DB) SetInterestRate(ctx context.Context, arg SetInterestRateParams) (*pkg.InterestRate, error) {
+	query := `INSERT INTO interest_rates(wallet_type, annual_rate_bps
+	) VALUES (
+		$1, $2
+	) ON CONFLICT (wallet_type) DO UPDATE SET annual_rate_bps=EXCLUDED.annual_rate_bps, updated_at=now()
+	RETURNING wallet_type, annual_rate_bps, updated_at;`
+
+	var res pkg.InterestRate
+	err := r.db.QueryRow(ctx, query, arg.WalletType, arg.AnnualRateBps).Scan(&res.WalletType, &res.AnnualRateBps, &res.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return &res, nil
+}
+
+/*
+ * AccrueInterest() record interest of 1 day for every wallet that has positive interest rate.
+ * End-of-day balance is the current balance minus all entries that were created after the day.
+ * Wallet that already accrued for the day is skipped, so it's safe to re-run for the same day.
+ * Return the number of new accrual rows.
+ */
+func (r *DB) AccrueInterest(ctx context.Context, day time.Time) (int64, error) {
+	query := `INSERT INTO interest_accruals(wallet_id, accrual_date, balance, annual_rate_bps, amount)
+	SELECT w.id, $1::date, eod.balance, rate.annual_rate_bps, eod.balance * rate.annual_rate_bps / 10000.0 / 365
+	FROM wallets w
+	JOIN interest_rates rate ON rate.wallet_type = w.wallet_type
+	CROSS JOIN LATERAL (
+		SELECT w.balance - COALESCE(SUM(e.amount), 0) AS balance
+		FROM entries e
+		WHERE e.wallet_id = w.id AND e.created_at >= $1::date + 1 AND e.deleted_at IS NULL
+	) eod
+	WHERE w.deleted_at IS NULL AND w.created_at < $1::date + 1 AND rate.annual_rate_bps > 0 AND eod.balance > 0
+	ON CONFLICT (wallet_id, accrual_date) DO NOTHING;`
+
+	res, err := r.db.Exec(ctx, query, day.Format(time.DateOnly))
+	if err != nil {
+		return 0, err
+	}
+
+	return res.RowsAffected(), nil
+}
+
+/*
+ * PostInterest() pay all unposted accruals up to 'periodEnd' (inclusive) as 1 transfer per wallet
+ * from the interest expense wallet. Each wallet is posted in its own db transaction, and a wallet can
+ * only be posted once for a period, so re-run for the same period doesn't double-post.
+ */
+func (store *Store) PostInterest(ctx context.Context, periodEnd time.Time) ([]pkg.InterestPosting, error) {
+	query := `SELECT wallet_id FROM interest_accruals WHERE posting_id IS NULL AND accrual_date <= $1 GROUP BY wallet_id ORDER BY wallet_id;`
+	rows, err := store.db.Query(ctx, query, periodEnd.Format(time.DateOnly))
+	if err != nil {
+		return nil, err
+	}
+
+	var walletIDs []int64
+	for rows.Next() {
+		var id int64
+		if err = rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		walletIDs = append(walletIDs, id)
+	}
+	rows.Close()
+
+	var list []pkg.InterestPosting
+	for _, walletID := range walletIDs {
+		posting, err := store.postWalletInterest(ctx, walletID, periodEnd)
+		if err != nil {
+			log.Printf("(PostInterest) wallet %d, err: %s\n", walletID, err)
+			return list, err
+		}
+		if posting != nil {
+			list = append(list, *posting)
+		}
+	}
+
+	return list, nil
+}
+
+func (store *Store) postWalletInterest(ctx context.Context, walletID int64, periodEnd time.Time) (*pkg.InterestPosting, error) {
+	var posting *pkg.InterestPosting
+
+	err := store.execTx(ctx, func(q *DB) error {
+		var err error
+		date := periodEnd.Format(time.DateOnly)
+
+		// Accruals and the remainder of the previous posting are summed in postgres to keep NUMERIC precision
+		query := `INSERT INTO interest_postings(wallet_id, period_end, amount, remainder)
+		SELECT $1::int, $2::date, floor(t.total), t.total - floor(t.total)
+		FROM (
+			SELECT COALESCE((SELECT SUM(amount) FROM interest_accruals WHERE wallet_id=$1 AND posting_id IS NULL AND accrual_date <= $2), 0)
+				+ COALESCE((SELECT remainder FROM interest_postings WHERE wallet_id=$1 ORDER BY period_end DESC LIMIT 1), 0) AS total
+		) t
+		ON CONFLICT (wallet_id, period_end) DO NOTHING
+		RETURNING id, wallet_id, period_end, amount, created_at;`
+
+		var res pkg.InterestPosting
+		err = q.db.QueryRow(ctx, query, walletID, date).Scan(&res.ID, &res.WalletID, &res.PeriodEnd, &res.Amount, &res.CreatedAt)
+		if err == pgx.ErrNoRows {
+			// already posted for this period
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		_, err = q.db.Exec(ctx, `UPDATE interest_accruals SET posting_id=$1 WHERE wallet_id=$2 AND posting_id IS NULL AND accrual_date <= $3`, res.ID, walletID, date)
+		if err != nil {
+			return err
+		}
+
+		if res.Amount > 0 {
+			wallet, err := q.GetWallet(ctx, walletID)
+			if err != nil {
+				return err
+			}
+			expense, err := q.GetBankWallet(ctx, BankWalletInterestExpense, wallet.Currency)
+			if err != nil {
+				return err
+			}
+
+			var result TransferTXResult
+			err = transferTx(ctx, q, TransferTxParams{
+				AccountID:        expense.AccountID,
+				WalletID:         expense.ID,
+				FromWalletNumber: expense.WalletNumber,
+				ToWalletNumber:   wallet.WalletNumber,
+				Amount:           res.Amount,
+			}, &result)
+			if err != nil {
+				return err
+			}
+
+			_, err = q.db.Exec(ctx, `UPDATE interest_postings SET transfer_id=$1 WHERE id=$2`, result.Transfer.ID, res.ID)
+			if err != nil {
+				return err
+			}
+			res.TransferID.Int64, res.TransferID.Valid = result.Transfer.ID, true
+		}
+
+		posting = &res
+		return nil
+	})
+
+	return posting, err
+}
+
+type WalletInterest struct {
+	WalletID      int64
+	AnnualRateBps int64
+	// Posted interest for periods that end in the current year
+	InterestYTD int64
+	// Interest that is accrued but not posted yet, can be a fraction
+	AccruedInterest float64
+}
+
+func (r *DB) GetWalletInterest(ctx context.Context, wallet pkg.Wallet) (*WalletInterest, error) {
+	query := `SELECT
+		COALESCE((SELECT annual_rate_bps FROM interest_rates WHERE wallet_type=$2), 0),
+		COALESCE((SELECT SUM(amount) FROM interest_postings WHERE wallet_id=$1 AND period_end >= date_trunc('year', now())::date), 0)::bigint,
+		COALESCE((SELECT SUM(amount) FROM interest_accruals WHERE wallet_id=$1 AND posting_id IS NULL), 0)::float8;`
+
+	res := WalletInterest{WalletID: wallet.ID}
+	err := r.db.QueryRow(ctx, query, wallet.ID, wallet.Type).Scan(&res.AnnualRateBps, &res.InterestYTD, &res.AccruedInterest)
+	if err != nil {
+		return nil, err
+	}
+
+	return &res, nil
+}
@@ -0,0 +1,89 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"simple-bank-system/db/pkg"
+	"simple-bank-system/util"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func createRandomSavingsWallet(t *testing.T, account pkg.Account, balance int64) pkg.Wallet {
+	arg := CreateWalletParams{
+		WalletNumber: 1015050000,
+		Name:         util.RandomOwner(),
+		AccountID:    account.ID,
+		Balance:      balance,
+		Currency:     "IDR",
+		Type:         WalletTypeSavings,
+	}
+
+	wallet, err := testQueries.CreateWallet(ctx, arg)
+	require.NoError(t, err)
+	require.NotNil(t, wallet)
+	assert.Equal(t, WalletTypeSavings, wallet.Type)
+
+	return *wallet
+}
+
+func TestGetInterestRate(t *testing.T) {
+	rate, err := testQueries.GetInterestRate(ctx, WalletTypeSavings)
+	require.NoError(t, err)
+	assert.Equal(t, WalletTypeSavings, rate.WalletType)
+	assert.Greater(t, rate.AnnualRateBps, int64(0))
+
+	rate, err = testQueries.GetInterestRate(ctx, WalletTypeRegular)
+	require.NoError(t, err)
+	assert.Zero(t, rate.AnnualRateBps)
+}
+
+func TestAccrueAndPostInterest(t *testing.T) {
+	store := NewStore(dbpool)
+
+	rate, err := store.GetInterestRate(ctx, WalletTypeSavings)
+	require.NoError(t, err)
+
+	account := createRandomAccount(t)
+	balance := int64(100000000)
+	wallet := createRandomSavingsWallet(t, account, balance)
+	today := time.Now()
+
+	// accrual is safe to re-run for the same day
+	for i := 0; i < 2; i++ {
+		_, err = store.AccrueInterest(ctx, today)
+		require.NoError(t, err)
+	}
+
+	daily := float64(balance) * float64(rate.AnnualRateBps) / 10000 / 365
+	interest, err := store.GetWalletInterest(ctx, wallet)
+	require.NoError(t, err)
+	assert.Equal(t, rate.AnnualRateBps, interest.AnnualRateBps)
+	assert.InDelta(t, daily, interest.AccruedInterest, 0.000001)
+
+	// posting is safe to re-run for the same period
+	var posted []pkg.InterestPosting
+	for i := 0; i < 2; i++ {
+		postings, err := store.PostInterest(ctx, today)
+		require.NoError(t, err)
+		for _, posting := range postings {
+			if posting.WalletID == wallet.ID {
+				posted = append(posted, posting)
+			}
+		}
+	}
+	require.Len(t, posted, 1)
+	assert.Equal(t, int64(daily), posted[0].Amount)
+	assert.True(t, posted[0].TransferID.Valid)
+
+	updated, err := store.GetWallet(ctx, wallet.ID)
+	require.NoError(t, err)
+	assert.Equal(t, balance+int64(daily), updated.Balance)
+
+	interest, err = store.GetWalletInterest(ctx, wallet)
+	require.NoError(t, err)
+	assert.Equal(t, int64(daily), interest.InterestYTD)
+	assert.Zero(t, interest.AccruedInterest)
+}
@@ -0,0 +1,45 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"simple-bank-system/util"
+
+	"github.com/jackc/pgx/v4"
+)
+
+// Daily jobs that keep a watermark
+const (
+	JobInterestAccrual = "interest_accrual"
+	JobBalanceSnapshot = "balance_snapshot"
+)
+
+/*
+ * JobWatermark() return the last day processed by the job. A job that never ran start the day before the
+ * first wallet was created. It return util.ErrNotExist when there is no wallet yet.
+ */
+func (r *DB) JobWatermark(ctx context.Context, job string) (time.Time, error) {
+	query := `SELECT COALESCE(
+		(SELECT last_day FROM job_watermarks WHERE job=$1),
+		(SELECT MIN(created_at)::date - 1 FROM wallets)
+	);`
+
+	var day *time.Time
+	err := r.db.QueryRow(ctx, query, job).Scan(&day)
+	if err == pgx.ErrNoRows || (err == nil && day == nil) {
+		return time.Time{}, util.ErrNotExist
+	}
+	if err != nil {
+		return time.Time{}, err
+	}
+	return *day, nil
+}
+
+// SetJobWatermark() record that the job processed 'day', the watermark never moves back.
+func (r *DB) SetJobWatermark(ctx context.Context, job string, day time.Time) error {
+	query := `INSERT INTO job_watermarks(job, last_day) VALUES ($1, $2::date)
+	ON CONFLICT (job) DO UPDATE SET last_day=GREATEST(job_watermarks.last_day, EXCLUDED.last_day), updated_at=NOW();`
+	_, err := r.db.Exec(ctx, query, job, day.Format(time.DateOnly))
+	return err
+}
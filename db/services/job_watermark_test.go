@@ -0,0 +1,32 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"simple-bank-system/util"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJobWatermark(t *testing.T) {
+	job := "test_" + util.RandomString(10)
+
+	// a job that never ran start before the first wallet
+	first, err := testQueries.JobWatermark(ctx, job)
+	require.NoError(t, err)
+	assert.True(t, first.Before(time.Now()))
+
+	day := time.Date(2024, 5, 10, 0, 0, 0, 0, time.UTC)
+	require.NoError(t, testQueries.SetJobWatermark(ctx, job, day))
+	last, err := testQueries.JobWatermark(ctx, job)
+	require.NoError(t, err)
+	assert.Equal(t, "2024-05-10", last.Format(time.DateOnly))
+
+	// the watermark never moves back
+	require.NoError(t, testQueries.SetJobWatermark(ctx, job, day.AddDate(0, 0, -3)))
+	last, err = testQueries.JobWatermark(ctx, job)
+	require.NoError(t, err)
+	assert.Equal(t, "2024-05-10", last.Format(time.DateOnly))
+}
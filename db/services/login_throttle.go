@@ -0,0 +1,143 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"simple-bank-system/db/pkg"
+	"simple-bank-system/util"
+
+	"github.com/jackc/pgx/v4"
+)
+
+// Scopes of the login throttles
+const (
+	LoginScopeUsername = "username"
+	LoginScopeIP       = "ip"
+)
+
+type LoginThrottleKey struct {
+	Scope string
+	Key   string
+}
+
+// GetLoginLock() return the latest 'locked_until' of the keys that are locked at 'now', it's zero when none is locked.
+func (r *DB) GetLoginLock(ctx context.Context, keys []LoginThrottleKey, now time.Time) (time.Time, error) {
+	var lockedUntil time.Time
+	for _, key := range keys {
+		var until time.Time
+		query := `SELECT locked_until FROM login_throttles WHERE scope=$1 AND key=$2 AND locked_until > $3;`
+		err := r.db.QueryRow(ctx, query, key.Scope, key.Key, now).Scan(&until)
+		if err == pgx.ErrNoRows {
+			continue
+		}
+		if err != nil {
+			return time.Time{}, err
+		}
+		if until.After(lockedUntil) {
+			lockedUntil = until
+		}
+	}
+	return lockedUntil, nil
+}
+
+type RecordLoginFailureParams struct {
+	LoginThrottleKey
+	// the key is locked after 'MaxAttempts' failures in a row
+	MaxAttempts int
+	// failures older than 'Window' aren't counted
+	Window time.Duration
+	// the first lockout last 'Lockout', every next one double it up to 'MaxLockout'
+	Lockout    time.Duration
+	MaxLockout time.Duration
+	Now        time.Time
+}
+
+/*
+ * RecordLoginFailureTx() count a failed login of the key. When the failure reach 'MaxAttempts' the key is
+ * locked, the returned throttle has the new 'LockedUntil' and 'locked' is true.
+ */
+func (store *Store) RecordLoginFailureTx(ctx context.Context, arg RecordLoginFailureParams) (*pkg.LoginThrottle, bool, error) {
+	var res pkg.LoginThrottle
+	var locked bool
+
+	err := store.execTx(ctx, func(q *DB) error {
+		_, err := q.db.Exec(ctx, `INSERT INTO login_throttles(scope, key) VALUES ($1, $2) ON CONFLICT DO NOTHING;`, arg.Scope, arg.Key)
+		if err != nil {
+			return err
+		}
+
+		query := `SELECT scope, key, failed_attempts, lockouts, locked_until, last_failed_at
+		FROM login_throttles WHERE scope=$1 AND key=$2 FOR UPDATE;`
+		err = q.db.QueryRow(ctx, query, arg.Scope, arg.Key).
+			Scan(&res.Scope, &res.Key, &res.FailedAttempts, &res.Lockouts, &res.LockedUntil, &res.LastFailedAt)
+		if err != nil {
+			return err
+		}
+
+		if res.LastFailedAt.Valid && arg.Now.Sub(res.LastFailedAt.Time) > arg.Window {
+			res.FailedAttempts = 0
+			// quiet for 'Window' after the last lock too, the backoff start again (see PruneLoginThrottles)
+			if !res.LockedUntil.Valid || arg.Now.Sub(res.LockedUntil.Time) > arg.Window {
+				res.Lockouts = 0
+			}
+		}
+		res.FailedAttempts++
+		res.LastFailedAt.Time, res.LastFailedAt.Valid = arg.Now, true
+
+		if res.FailedAttempts >= arg.MaxAttempts {
+			res.Lockouts++
+			res.FailedAttempts = 0
+			res.LockedUntil.Time, res.LockedUntil.Valid = arg.Now.Add(lockoutDuration(arg.Lockout, arg.MaxLockout, res.Lockouts)), true
+			locked = true
+		}
+
+		query = `UPDATE login_throttles SET failed_attempts=$3, lockouts=$4, locked_until=$5, last_failed_at=$6
+		WHERE scope=$1 AND key=$2;`
+		_, err = q.db.Exec(ctx, query, arg.Scope, arg.Key, res.FailedAttempts, res.Lockouts, res.LockedUntil, res.LastFailedAt)
+		return err
+	})
+	if err != nil {
+		return nil, false, err
+	}
+
+	return &res, locked, nil
+}
+
+// lockoutDuration double 'lockout' for every lockout after the first one.
+func lockoutDuration(lockout, maxLockout time.Duration, lockouts int) time.Duration {
+	duration := lockout
+	for i := 1; i < lockouts && duration < maxLockout; i++ {
+		duration *= 2
+	}
+	if duration > maxLockout {
+		duration = maxLockout
+	}
+	return duration
+}
+
+/*
+ * PruneLoginThrottles() delete the keys that are quiet since 'before' (now - the failure window): no failure
+ * and no lock since then. Their failures and lockouts are forgotten, like 'RecordLoginFailureTx' does
+ * for a key that wasn't pruned yet.
+ */
+func (r *DB) PruneLoginThrottles(ctx context.Context, before time.Time) (int64, error) {
+	query := `DELETE FROM login_throttles WHERE last_failed_at < $1 AND (locked_until IS NULL OR locked_until < $1);`
+	res, err := r.db.Exec(ctx, query, before)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected(), nil
+}
+
+// ClearLoginThrottle() forget the failures and lockouts of the key, it return util.ErrDeleteFailed when there are none.
+func (r *DB) ClearLoginThrottle(ctx context.Context, key LoginThrottleKey) error {
+	res, err := r.db.Exec(ctx, `DELETE FROM login_throttles WHERE scope=$1 AND key=$2;`, key.Scope, key.Key)
+	if err != nil {
+		return err
+	}
+	if res.RowsAffected() == 0 {
+		return util.ErrDeleteFailed
+	}
+	return nil
+}
@@ -0,0 +1,104 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"simple-bank-system/util"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoginThrottle(t *testing.T) {
+	store := NewStore(dbpool)
+	key := LoginThrottleKey{Scope: LoginScopeUsername, Key: util.RandomString(12)}
+	now := time.Now()
+	arg := RecordLoginFailureParams{
+		LoginThrottleKey: key,
+		MaxAttempts:      3,
+		Window:           15 * time.Minute,
+		Lockout:          time.Minute,
+		MaxLockout:       3 * time.Minute,
+		Now:              now,
+	}
+
+	for i := 1; i < 3; i++ {
+		throttle, locked, err := store.RecordLoginFailureTx(ctx, arg)
+		require.NoError(t, err)
+		assert.False(t, locked)
+		assert.Equal(t, i, throttle.FailedAttempts)
+	}
+	throttle, locked, err := store.RecordLoginFailureTx(ctx, arg)
+	require.NoError(t, err)
+	require.True(t, locked)
+	assert.Equal(t, 1, throttle.Lockouts)
+	assert.WithinDuration(t, now.Add(time.Minute), throttle.LockedUntil.Time, time.Millisecond)
+
+	lockedUntil, err := store.GetLoginLock(ctx, []LoginThrottleKey{key, {Scope: LoginScopeIP, Key: "192.0.2.1"}}, now)
+	require.NoError(t, err)
+	assert.WithinDuration(t, throttle.LockedUntil.Time, lockedUntil, time.Millisecond)
+
+	// the next lockout is twice longer
+	arg.Now = now.Add(2 * time.Minute)
+	for i := 0; i < 3; i++ {
+		throttle, locked, err = store.RecordLoginFailureTx(ctx, arg)
+		require.NoError(t, err)
+	}
+	require.True(t, locked)
+	assert.Equal(t, 2, throttle.Lockouts)
+	assert.WithinDuration(t, arg.Now.Add(2*time.Minute), throttle.LockedUntil.Time, time.Millisecond)
+
+	lockedUntil, err = store.GetLoginLock(ctx, []LoginThrottleKey{key}, arg.Now.Add(3*time.Minute))
+	require.NoError(t, err)
+	assert.True(t, lockedUntil.IsZero())
+
+	// quiet for the window after the last lock, the backoff start again
+	arg.Now = throttle.LockedUntil.Time.Add(16 * time.Minute)
+	throttle, locked, err = store.RecordLoginFailureTx(ctx, arg)
+	require.NoError(t, err)
+	assert.False(t, locked)
+	assert.Equal(t, 1, throttle.FailedAttempts)
+	assert.Equal(t, 0, throttle.Lockouts)
+
+	require.NoError(t, store.ClearLoginThrottle(ctx, key))
+	require.ErrorIs(t, store.ClearLoginThrottle(ctx, key), util.ErrDeleteFailed)
+}
+
+func TestPruneLoginThrottles(t *testing.T) {
+	store := NewStore(dbpool)
+	now := time.Now()
+	newArg := func(maxAttempts int, failedAt time.Time) RecordLoginFailureParams {
+		return RecordLoginFailureParams{
+			LoginThrottleKey: LoginThrottleKey{Scope: LoginScopeIP, Key: util.RandomString(12)},
+			MaxAttempts:      maxAttempts,
+			Window:           15 * time.Minute,
+			Lockout:          time.Hour,
+			MaxLockout:       time.Hour,
+			Now:              failedAt,
+		}
+	}
+
+	quiet := newArg(3, now.Add(-20*time.Minute))
+	locked := newArg(1, now.Add(-20*time.Minute))
+	recent := newArg(3, now)
+	for _, arg := range []RecordLoginFailureParams{quiet, locked, recent} {
+		_, _, err := store.RecordLoginFailureTx(ctx, arg)
+		require.NoError(t, err)
+	}
+
+	n, err := store.PruneLoginThrottles(ctx, now.Add(-15*time.Minute))
+	require.NoError(t, err)
+	require.Positive(t, n)
+
+	require.ErrorIs(t, store.ClearLoginThrottle(ctx, quiet.LoginThrottleKey), util.ErrDeleteFailed)
+	// the lock and the recent failure are kept
+	require.NoError(t, store.ClearLoginThrottle(ctx, locked.LoginThrottleKey))
+	require.NoError(t, store.ClearLoginThrottle(ctx, recent.LoginThrottleKey))
+}
+
+func TestLockoutDuration(t *testing.T) {
+	assert.Equal(t, time.Minute, lockoutDuration(time.Minute, time.Hour, 1))
+	assert.Equal(t, 4*time.Minute, lockoutDuration(time.Minute, time.Hour, 3))
+	assert.Equal(t, time.Hour, lockoutDuration(time.Minute, time.Hour, 40))
+}
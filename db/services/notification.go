@@ -0,0 +1,158 @@
+package services
+
+import (
+	"context"
+
+	"simple-bank-system/db/pkg"
+	"simple-bank-system/util"
+
+	"github.com/jackc/pgx/v4"
+)
+
+const notificationColumns = `id, account_id, event_type, title, body, data, read_at, created_at`
+
+func scanNotification(row pgx.Row) (*pkg.Notification, error) {
+	var res pkg.Notification
+	err := row.Scan(&res.ID, &res.AccountID, &res.Type, &res.Title, &res.Body, &res.Data, &res.ReadAt, &res.CreatedAt)
+	if err == pgx.ErrNoRows {
+		return nil, util.ErrNotExist
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+// NotificationRecipient is the part of the account that is used to notify it.
+type NotificationRecipient struct {
+	AccountID int64
+	FullName  string
+	Email     string
+}
+
+func (r *DB) GetNotificationRecipient(ctx context.Context, accountID int64) (*NotificationRecipient, error) {
+	res := NotificationRecipient{AccountID: accountID}
+	err := r.db.QueryRow(ctx, `SELECT full_name, email FROM accounts WHERE id=$1;`, accountID).Scan(&res.FullName, &res.Email)
+	if err == pgx.ErrNoRows {
+		return nil, util.ErrNotExist
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+type CreateNotificationParams struct {
+	AccountID int64
+	Type      string
+	Title     string
+	Body      string
+	// JSON object
+	Data []byte
+}
+
+func (r *DB) CreateNotification(ctx context.Context, arg CreateNotificationParams) (*pkg.Notification, error) {
+	data := "{}"
+	if len(arg.Data) > 0 {
+		data = string(arg.Data)
+	}
+
+	query := `INSERT INTO notifications(account_id, event_type, title, body, data
+	) VALUES (
+		$1, $2, $3, $4, $5::jsonb
+	) RETURNING ` + notificationColumns + `;`
+
+	return scanNotification(r.db.QueryRow(ctx, query, arg.AccountID, arg.Type, arg.Title, arg.Body, data))
+}
+
+type ListNotificationsParams struct {
+	AccountID  int64
+	UnreadOnly bool
+	// notifications with a smaller id than 'BeforeID' (0 is the newest), for the next page
+	BeforeID int64
+	Limit    int
+}
+
+// ListNotifications() return the notifications of the account, newest first.
+func (r *DB) ListNotifications(ctx context.Context, arg ListNotificationsParams) ([]pkg.Notification, error) {
+	query := `SELECT ` + notificationColumns + ` FROM notifications
+	WHERE account_id=$1 AND (NOT $2 OR read_at IS NULL) AND ($3 = 0 OR id < $3)
+	ORDER BY id DESC
+	LIMIT $4;`
+
+	rows, err := r.db.Query(ctx, query, arg.AccountID, arg.UnreadOnly, arg.BeforeID, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []pkg.Notification
+	for rows.Next() {
+		notification, err := scanNotification(rows)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, *notification)
+	}
+
+	return list, rows.Err()
+}
+
+func (r *DB) CountUnreadNotifications(ctx context.Context, accountID int64) (int64, error) {
+	var count int64
+	err := r.db.QueryRow(ctx, `SELECT COUNT(*) FROM notifications WHERE account_id=$1 AND read_at IS NULL;`, accountID).Scan(&count)
+	return count, err
+}
+
+// MarkNotificationsRead() mark the notifications of the account as read, all of them when 'ids' is empty.
+// Return the number of notifications that were unread.
+func (r *DB) MarkNotificationsRead(ctx context.Context, accountID int64, ids []int64) (int64, error) {
+	query := `UPDATE notifications SET read_at=now()
+	WHERE account_id=$1 AND read_at IS NULL AND (cardinality($2::bigint[]) = 0 OR id = ANY($2));`
+
+	if ids == nil {
+		ids = []int64{}
+	}
+	res, err := r.db.Exec(ctx, query, accountID, ids)
+	if err != nil {
+		return 0, err
+	}
+
+	return res.RowsAffected(), nil
+}
+
+// GetNotificationPreferences() return the preferences that the account changed.
+func (r *DB) GetNotificationPreferences(ctx context.Context, accountID int64) ([]pkg.NotificationPreference, error) {
+	rows, err := r.db.Query(ctx, `SELECT event_type, email, in_app FROM notification_preferences WHERE account_id=$1 ORDER BY event_type;`, accountID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []pkg.NotificationPreference
+	for rows.Next() {
+		var preference pkg.NotificationPreference
+		if err = rows.Scan(&preference.EventType, &preference.Email, &preference.InApp); err != nil {
+			return nil, err
+		}
+		list = append(list, preference)
+	}
+
+	return list, rows.Err()
+}
+
+// SetNotificationPreferences() save the preferences of the event types together.
+func (store *Store) SetNotificationPreferences(ctx context.Context, accountID int64, preferences []pkg.NotificationPreference) error {
+	query := `INSERT INTO notification_preferences(account_id, event_type, email, in_app) VALUES ($1, $2, $3, $4)
+	ON CONFLICT (account_id, event_type) DO UPDATE SET email=EXCLUDED.email, in_app=EXCLUDED.in_app, updated_at=now();`
+
+	return store.execTx(ctx, func(q *DB) error {
+		for _, preference := range preferences {
+			_, err := q.db.Exec(ctx, query, accountID, preference.EventType, preference.Email, preference.InApp)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,81 @@
+package services
+
+import (
+	"testing"
+
+	"simple-bank-system/db/pkg"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNotifications(t *testing.T) {
+	store := NewStore(dbpool)
+	account := createRandomAccount(t)
+
+	recipient, err := store.GetNotificationRecipient(ctx, account.ID)
+	require.NoError(t, err)
+	assert.Equal(t, account.Email, recipient.Email)
+	assert.Equal(t, account.FullName, recipient.FullName)
+
+	var ids []int64
+	for i := 0; i < 3; i++ {
+		notification, err := store.CreateNotification(ctx, CreateNotificationParams{
+			AccountID: account.ID,
+			Type:      "login",
+			Title:     "New login to your account",
+			Body:      "Hi",
+			Data:      []byte(`{"ip":"127.0.0.1"}`),
+		})
+		require.NoError(t, err)
+		assert.False(t, notification.ReadAt.Valid)
+		ids = append(ids, notification.ID)
+	}
+
+	list, err := store.ListNotifications(ctx, ListNotificationsParams{AccountID: account.ID, Limit: 2})
+	require.NoError(t, err)
+	require.Len(t, list, 2)
+	assert.Equal(t, ids[2], list[0].ID)
+	assert.JSONEq(t, `{"ip":"127.0.0.1"}`, string(list[0].Data))
+
+	// next page
+	list, err = store.ListNotifications(ctx, ListNotificationsParams{AccountID: account.ID, BeforeID: list[1].ID, Limit: 2})
+	require.NoError(t, err)
+	require.Len(t, list, 1)
+	assert.Equal(t, ids[0], list[0].ID)
+
+	n, err := store.MarkNotificationsRead(ctx, account.ID, []int64{ids[0]})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), n)
+	unread, err := store.CountUnreadNotifications(ctx, account.ID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), unread)
+
+	list, err = store.ListNotifications(ctx, ListNotificationsParams{AccountID: account.ID, UnreadOnly: true, Limit: 10})
+	require.NoError(t, err)
+	assert.Len(t, list, 2)
+
+	// every other notification
+	n, err = store.MarkNotificationsRead(ctx, account.ID, nil)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), n)
+
+	other := createRandomAccount(t)
+	n, err = store.MarkNotificationsRead(ctx, other.ID, nil)
+	require.NoError(t, err)
+	assert.Zero(t, n)
+
+	require.NoError(t, store.SetNotificationPreferences(ctx, account.ID, []pkg.NotificationPreference{
+		{EventType: "login", Email: false, InApp: true},
+	}))
+	require.NoError(t, store.SetNotificationPreferences(ctx, account.ID, []pkg.NotificationPreference{
+		{EventType: "login", Email: true, InApp: false},
+		{EventType: "money_received", Email: false, InApp: true},
+	}))
+	preferences, err := store.GetNotificationPreferences(ctx, account.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []pkg.NotificationPreference{
+		{EventType: "login", Email: true, InApp: false},
+		{EventType: "money_received", Email: false, InApp: true},
+	}, preferences)
+}
@@ -0,0 +1,182 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"time"
+
+	"simple-bank-system/db/pkg"
+)
+
+// Domain event types that are written to the outbox
+const (
+	EventAccountCreated     = "account.created"
+	EventWalletCreated      = "wallet.created"
+	EventWalletClosed       = "wallet.closed"
+	EventTransferCompleted  = "transfer.completed"
+	EventSavingsGoalReached = "savings_goal.reached"
+	EventWalletFrozen       = "wallet.frozen"
+	EventWalletUnfrozen     = "wallet.unfrozen"
+)
+
+type outboxEvent struct {
+	eventType string
+	accountID int64
+	walletID  int64
+	payload   interface{}
+}
+
+// Payload of the wallet events
+type WalletEvent struct {
+	WalletNumber int64     `json:"wallet_number"`
+	Name         string    `json:"name"`
+	Currency     string    `json:"currency"`
+	WalletType   string    `json:"wallet_type"`
+	Balance      int64     `json:"balance"`
+	Time         time.Time `json:"time"`
+}
+
+/*
+ * Payload of 'transfer.completed', the event is written for both wallets of the transfer
+ * so each wallet get it in order with its other events. 'Amount' is negative for the sender.
+ */
+type TransferEvent struct {
+	TransferID       int64     `json:"transfer_id"`
+	FromWalletNumber int64     `json:"from_wallet_number"`
+	ToWalletNumber   int64     `json:"to_wallet_number"`
+	Amount           int64     `json:"amount"`
+	Currency         string    `json:"currency"`
+	Balance          int64     `json:"balance"`
+	Time             time.Time `json:"time"`
+}
+
+// Payload of 'savings_goal.reached'
+type SavingsGoalEvent struct {
+	WalletNumber int64     `json:"wallet_number"`
+	TargetAmount int64     `json:"target_amount"`
+	TargetDate   string    `json:"target_date"`
+	Balance      int64     `json:"balance"`
+	Time         time.Time `json:"time"`
+}
+
+func newWalletEvent(wallet *pkg.Wallet) WalletEvent {
+	return WalletEvent{
+		WalletNumber: wallet.WalletNumber,
+		Name:         wallet.Name,
+		Currency:     wallet.Currency,
+		WalletType:   wallet.Type,
+		Balance:      wallet.Balance,
+		Time:         time.Now(),
+	}
+}
+
+// addEvent() queue the event, 'execTx' write it to the outbox in the same db transaction.
+// Events that are added outside 'execTx' are never written.
+func (r *DB) addEvent(eventType string, accountID, walletID int64, payload interface{}) {
+	r.events = append(r.events, outboxEvent{
+		eventType: eventType,
+		accountID: accountID,
+		walletID:  walletID,
+		payload:   payload,
+	})
+}
+
+// writeOutbox() insert the queued events in the order they were added and notify 'OutboxChannel'.
+func (r *DB) writeOutbox(ctx context.Context) error {
+	query := `INSERT INTO outbox_events(event_type, account_id, wallet_id, payload) VALUES ($1, $2, NULLIF($3, 0), $4::jsonb);`
+	for _, event := range r.events {
+		payload, err := json.Marshal(event.payload)
+		if err != nil {
+			return err
+		}
+
+		_, err = r.db.Exec(ctx, query, event.eventType, event.accountID, event.walletID, string(payload))
+		if err != nil {
+			return err
+		}
+	}
+	if len(r.events) > 0 {
+		// the listeners are notified when the transaction is committed
+		_, err := r.db.Exec(ctx, `SELECT pg_notify($1, '');`, OutboxChannel)
+		if err != nil {
+			return err
+		}
+	}
+	r.events = nil
+
+	return nil
+}
+
+/*
+ * ClaimOutboxEvents() claim up to 'limit' undelivered events for 'lease' and return them in 'id' order.
+ * The claim is 1 statement that's committed before the events are delivered, so no db transaction stay open
+ * while the sinks are called, and 'FOR UPDATE SKIP LOCKED' let several relays claim together. An event is
+ * skipped while an older event of the same wallet isn't delivered yet (it's claimed by another relay or
+ * failed before), that keep the events of a wallet in order.
+ * The claim of a relay that stop expire after 'lease', then the event is claimed again, so the delivery is
+ * at-least-once.
+ */
+func (r *DB) ClaimOutboxEvents(ctx context.Context, limit int, lease time.Duration) ([]pkg.OutboxEvent, error) {
+	query := `WITH batch AS (
+		SELECT id FROM outbox_events
+		WHERE delivered_at IS NULL AND (claimed_until IS NULL OR claimed_until < now())
+		ORDER BY id LIMIT $1 FOR UPDATE SKIP LOCKED
+	), ready AS (
+		SELECT o.id FROM outbox_events o
+		JOIN batch b ON b.id = o.id
+		WHERE NOT EXISTS (
+			SELECT 1 FROM outbox_events older
+			WHERE older.wallet_id = o.wallet_id AND older.delivered_at IS NULL AND older.id < o.id
+			AND older.id NOT IN (SELECT id FROM batch)
+		)
+	)
+	UPDATE outbox_events o SET claimed_until = now() + make_interval(secs => $2), attempts = o.attempts + 1
+	FROM ready WHERE o.id = ready.id
+	RETURNING o.id, o.event_type, o.account_id, COALESCE(o.wallet_id, 0), o.payload, o.attempts, o.delivered_sinks, o.created_at;`
+
+	rows, err := r.db.Query(ctx, query, limit, lease.Seconds())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []pkg.OutboxEvent
+	for rows.Next() {
+		var event pkg.OutboxEvent
+		err = rows.Scan(&event.ID, &event.Type, &event.AccountID, &event.WalletID, &event.Payload, &event.Attempts, &event.DeliveredSinks, &event.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// RETURNING has no order
+	sort.Slice(events, func(i, j int) bool { return events[i].ID < events[j].ID })
+	return events, nil
+}
+
+// MarkOutboxSinkDelivered() record that the sink has the event, it isn't sent to the sink again.
+func (r *DB) MarkOutboxSinkDelivered(ctx context.Context, id int64, sink string) error {
+	query := `UPDATE outbox_events SET delivered_sinks = array_append(delivered_sinks, $2)
+	WHERE id=$1 AND NOT ($2 = ANY(delivered_sinks));`
+	_, err := r.db.Exec(ctx, query, id, sink)
+	return err
+}
+
+// CompleteOutboxEvent() mark the event as delivered to every sink.
+func (r *DB) CompleteOutboxEvent(ctx context.Context, id int64) error {
+	_, err := r.db.Exec(ctx, `UPDATE outbox_events SET delivered_at=now(), claimed_until=NULL WHERE id=$1;`, id)
+	return err
+}
+
+// ReleaseOutboxEvent() end the claim of an event that isn't delivered, so the next run claim it again.
+// Empty 'lastError' keep the error of the previous attempt.
+func (r *DB) ReleaseOutboxEvent(ctx context.Context, id int64, lastError string) error {
+	query := `UPDATE outbox_events SET claimed_until=NULL, last_error=COALESCE(NULLIF($2, ''), last_error) WHERE id=$1;`
+	_, err := r.db.Exec(ctx, query, id, lastError)
+	return err
+}
@@ -0,0 +1,151 @@
+package services
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"simple-bank-system/db/pkg"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOutboxTransfer(t *testing.T) {
+	store := NewStore(dbpool)
+	account := createRandomAccount(t)
+	recipient := createRandomAccount(t)
+	wallet := createRandomSavingsWallet(t, account, 1000)
+	toWallet := createRandomSavingsWallet(t, recipient, 0)
+
+	for i := 0; i < 2; i++ {
+		_, err := store.TransferTx(ctx, TransferTxParams{
+			AccountID:        account.ID,
+			WalletID:         wallet.ID,
+			FromWalletNumber: wallet.WalletNumber,
+			ToWalletNumber:   toWallet.WalletNumber,
+			Amount:           100,
+		})
+		require.NoError(t, err)
+	}
+
+	// a failed transfer doesn't write events
+	_, err := store.TransferTx(ctx, TransferTxParams{
+		AccountID:        account.ID,
+		WalletID:         wallet.ID,
+		FromWalletNumber: wallet.WalletNumber,
+		ToWalletNumber:   toWallet.WalletNumber,
+		Amount:           5000,
+	})
+	require.Error(t, err)
+
+	events := map[int64][]pkg.OutboxEvent{}
+	for {
+		claimed, err := store.ClaimOutboxEvents(ctx, 50, time.Minute)
+		require.NoError(t, err)
+		if len(claimed) == 0 {
+			break
+		}
+		for _, event := range claimed {
+			if event.WalletID == wallet.ID || event.WalletID == toWallet.ID {
+				events[event.WalletID] = append(events[event.WalletID], event)
+			}
+			require.NoError(t, store.CompleteOutboxEvent(ctx, event.ID))
+		}
+	}
+
+	for _, w := range []pkg.Wallet{wallet, toWallet} {
+		require.Len(t, events[w.ID], 2)
+		assert.Less(t, events[w.ID][0].ID, events[w.ID][1].ID)
+
+		var payload TransferEvent
+		require.NoError(t, json.Unmarshal(events[w.ID][1].Payload, &payload))
+		assert.Equal(t, EventTransferCompleted, events[w.ID][1].Type)
+		assert.Equal(t, w.AccountID, events[w.ID][1].AccountID)
+		assert.Equal(t, wallet.WalletNumber, payload.FromWalletNumber)
+		assert.Equal(t, toWallet.WalletNumber, payload.ToWalletNumber)
+	}
+
+	var payload TransferEvent
+	require.NoError(t, json.Unmarshal(events[wallet.ID][1].Payload, &payload))
+	assert.Equal(t, int64(-100), payload.Amount)
+	assert.Equal(t, int64(800), payload.Balance)
+	require.NoError(t, json.Unmarshal(events[toWallet.ID][1].Payload, &payload))
+	assert.Equal(t, int64(100), payload.Amount)
+	assert.Equal(t, int64(200), payload.Balance)
+
+	// delivered events aren't claimed again
+	claimed, err := store.ClaimOutboxEvents(ctx, 50, time.Minute)
+	require.NoError(t, err)
+	for _, event := range claimed {
+		assert.NotEqual(t, wallet.ID, event.WalletID)
+		require.NoError(t, store.ReleaseOutboxEvent(ctx, event.ID, ""))
+	}
+}
+
+// claimWalletEvents claim events until it get the events of the wallet or there's nothing to claim.
+func claimWalletEvents(t *testing.T, store *Store, walletID int64) []pkg.OutboxEvent {
+	var res []pkg.OutboxEvent
+	var others []int64
+	for len(res) == 0 {
+		claimed, err := store.ClaimOutboxEvents(ctx, 50, time.Minute)
+		require.NoError(t, err)
+		if len(claimed) == 0 {
+			break
+		}
+		for _, event := range claimed {
+			if event.WalletID == walletID {
+				res = append(res, event)
+				continue
+			}
+			others = append(others, event.ID)
+		}
+	}
+	for _, id := range others {
+		require.NoError(t, store.ReleaseOutboxEvent(ctx, id, ""))
+	}
+	return res
+}
+
+func TestOutboxClaim(t *testing.T) {
+	store := NewStore(dbpool)
+	account := createRandomAccount(t)
+	recipient := createRandomAccount(t)
+	wallet := createRandomSavingsWallet(t, account, 1000)
+	toWallet := createRandomSavingsWallet(t, recipient, 0)
+
+	_, err := store.TransferTx(ctx, TransferTxParams{
+		AccountID:        account.ID,
+		WalletID:         wallet.ID,
+		FromWalletNumber: wallet.WalletNumber,
+		ToWalletNumber:   toWallet.WalletNumber,
+		Amount:           100,
+	})
+	require.NoError(t, err)
+
+	events := claimWalletEvents(t, store, toWallet.ID)
+	require.NotEmpty(t, events)
+	event := events[len(events)-1]
+	assert.Empty(t, event.DeliveredSinks)
+
+	// a claimed event isn't claimed by other relays
+	assert.Empty(t, claimWalletEvents(t, store, toWallet.ID))
+
+	require.NoError(t, store.MarkOutboxSinkDelivered(ctx, event.ID, "log"))
+	require.NoError(t, store.MarkOutboxSinkDelivered(ctx, event.ID, "log"))
+	for _, e := range events {
+		require.NoError(t, store.ReleaseOutboxEvent(ctx, e.ID, "http sink: down"))
+	}
+
+	// the released event keep the sinks that already have it
+	events = claimWalletEvents(t, store, toWallet.ID)
+	require.NotEmpty(t, events)
+	assert.Equal(t, event.ID, events[len(events)-1].ID)
+	assert.Equal(t, []string{"log"}, events[len(events)-1].DeliveredSinks)
+	assert.Greater(t, events[len(events)-1].Attempts, event.Attempts)
+
+	for _, e := range events {
+		require.NoError(t, store.CompleteOutboxEvent(ctx, e.ID))
+	}
+	assert.Empty(t, claimWalletEvents(t, store, toWallet.ID))
+}
@@ -0,0 +1,117 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"simple-bank-system/db/pkg"
+	"simple-bank-system/util"
+
+	"github.com/jackc/pgx/v4"
+)
+
+// GetAccountByEmail() return util.ErrNotExist when no open account use the email.
+func (r *DB) GetAccountByEmail(ctx context.Context, email string) (*pkg.Account, error) {
+	query := `SELECT * FROM accounts JOIN addresses ON accounts.address = addresses.id WHERE accounts.email=$1 AND deleted_at IS NULL;`
+	row := r.db.QueryRow(ctx, query, email)
+
+	var account pkg.Account
+	err := row.Scan(&account.ID, &account.AccountNumber, &account.Username, &account.HashedPassword, &account.FullName, &account.DateOfBirth, &account.Address.ID, &account.Email, &account.PasswordChangeAt, &account.CreatedAt,
+		&account.DeletedAt, &account.Role, &account.Address.ID, &account.Address.Provinces, &account.Address.City, &account.Address.ZIP, &account.Address.Street)
+	if err == pgx.ErrNoRows {
+		return nil, util.ErrNotExist
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &account, nil
+}
+
+type CreatePasswordResetParams struct {
+	AccountID int64
+	// util.HashToken of the token that is emailed
+	TokenHash []byte
+	ExpiresAt time.Time
+	// at most 'Limit' resets are created since 'Since'
+	Limit int
+	Since time.Time
+}
+
+// CreatePasswordResetTx() return util.ErrTooManyRequests when the account already requested 'Limit' resets.
+func (store *Store) CreatePasswordResetTx(ctx context.Context, arg CreatePasswordResetParams) (*pkg.PasswordReset, error) {
+	var res pkg.PasswordReset
+
+	err := store.execTx(ctx, func(q *DB) error {
+		// the account row serialize the requests of the account, so the limit can't be passed by parallel requests
+		_, err := q.db.Exec(ctx, `SELECT id FROM accounts WHERE id=$1 FOR UPDATE;`, arg.AccountID)
+		if err != nil {
+			return err
+		}
+
+		var count int
+		err = q.db.QueryRow(ctx, `SELECT COUNT(*) FROM password_resets WHERE account_id=$1 AND created_at >= $2;`, arg.AccountID, arg.Since).Scan(&count)
+		if err != nil {
+			return err
+		}
+		if count >= arg.Limit {
+			return util.ErrTooManyRequests
+		}
+
+		query := `INSERT INTO password_resets(account_id, token_hash, expires_at) VALUES ($1, $2, $3)
+		RETURNING id, account_id, token_hash, expires_at, used_at, created_at;`
+		return q.db.QueryRow(ctx, query, arg.AccountID, arg.TokenHash, arg.ExpiresAt).
+			Scan(&res.ID, &res.AccountID, &res.TokenHash, &res.ExpiresAt, &res.UsedAt, &res.CreatedAt)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &res, nil
+}
+
+type ResetPasswordParams struct {
+	TokenHash []byte
+	// plain new password
+	Password  string
+	ChangedAt time.Time
+}
+
+/*
+ * ResetPasswordTx() use the reset token and change the password of its account, it return the account id.
+ * Unknown or used tokens return util.ErrNotExist and expired ones util.ErrTokenExpired.
+ * The other tokens of the account can't be used anymore, and the access tokens are revoked like a password change.
+ */
+func (store *Store) ResetPasswordTx(ctx context.Context, arg ResetPasswordParams) (int64, error) {
+	var accountID int64
+
+	err := store.execTx(ctx, func(q *DB) error {
+		var expiresAt time.Time
+		query := `SELECT account_id, expires_at FROM password_resets WHERE token_hash=$1 AND used_at IS NULL FOR UPDATE;`
+		err := q.db.QueryRow(ctx, query, arg.TokenHash).Scan(&accountID, &expiresAt)
+		if err == pgx.ErrNoRows {
+			return util.ErrNotExist
+		}
+		if err != nil {
+			return err
+		}
+		if arg.ChangedAt.After(expiresAt) {
+			return util.ErrTokenExpired
+		}
+
+		_, err = q.db.Exec(ctx, `UPDATE password_resets SET used_at=$2 WHERE account_id=$1 AND used_at IS NULL;`, accountID, arg.ChangedAt)
+		if err != nil {
+			return err
+		}
+
+		return q.UpdatePassword(ctx, UpdatePasswordParams{
+			AccountID: accountID,
+			Password:  arg.Password,
+			ChangedAt: arg.ChangedAt,
+		})
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return accountID, nil
+}
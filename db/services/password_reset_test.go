@@ -0,0 +1,59 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"simple-bank-system/util"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPasswordReset(t *testing.T) {
+	store := NewStore(dbpool)
+	account := createRandomAccount(t)
+
+	found, err := store.GetAccountByEmail(ctx, account.Email)
+	require.NoError(t, err)
+	assert.Equal(t, account.ID, found.ID)
+	_, err = store.GetAccountByEmail(ctx, util.RandomEmail())
+	require.ErrorIs(t, err, util.ErrNotExist)
+
+	now := time.Now()
+	arg := CreatePasswordResetParams{
+		AccountID: account.ID,
+		ExpiresAt: now.Add(time.Hour),
+		Limit:     2,
+		Since:     now.Add(-time.Hour),
+	}
+	arg.TokenHash = util.HashToken(util.RandomString(32))
+	_, err = store.CreatePasswordResetTx(ctx, arg)
+	require.NoError(t, err)
+
+	token := util.RandomString(32)
+	arg.TokenHash = util.HashToken(token)
+	reset, err := store.CreatePasswordResetTx(ctx, arg)
+	require.NoError(t, err)
+	assert.False(t, reset.UsedAt.Valid)
+
+	arg.TokenHash = util.HashToken(util.RandomString(32))
+	_, err = store.CreatePasswordResetTx(ctx, arg)
+	require.ErrorIs(t, err, util.ErrTooManyRequests)
+
+	_, err = store.ResetPasswordTx(ctx, ResetPasswordParams{TokenHash: util.HashToken(token), Password: "newsecret", ChangedAt: now.Add(2 * time.Hour)})
+	require.ErrorIs(t, err, util.ErrTokenExpired)
+
+	accountID, err := store.ResetPasswordTx(ctx, ResetPasswordParams{TokenHash: util.HashToken(token), Password: "newsecret", ChangedAt: time.Now()})
+	require.NoError(t, err)
+	assert.Equal(t, account.ID, accountID)
+
+	profile, err := store.GetAccountProfile(ctx, account.ID)
+	require.NoError(t, err)
+	require.NoError(t, util.VerifyPassword("newsecret", profile.HashedPassword))
+	assert.True(t, profile.PasswordChangeAt.After(account.CreatedAt))
+
+	// single use
+	_, err = store.ResetPasswordTx(ctx, ResetPasswordParams{TokenHash: util.HashToken(token), Password: "again1", ChangedAt: time.Now()})
+	require.ErrorIs(t, err, util.ErrNotExist)
+}
@@ -0,0 +1,144 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"simple-bank-system/db/pkg"
+	"simple-bank-system/util"
+
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
+)
+
+// MaskName() keep the first letter of every word in 'name' and mask the others, "Budi Santoso" -> "B*** S******".
+func MaskName(name string) string {
+	words := strings.Fields(name)
+	for i, word := range words {
+		letters := []rune(word)
+		words[i] = string(letters[0]) + strings.Repeat("*", len(letters)-1)
+	}
+	return strings.Join(words, " ")
+}
+
+// PayeeInCoolingOff() is true while the payee is younger than 'period'.
+func PayeeInCoolingOff(payee pkg.Payee, period time.Duration, now time.Time) bool {
+	return now.Before(payee.CreatedAt.Add(period))
+}
+
+// GetRecipientName() return the masked full name of the owner of the wallet.
+func (r *DB) GetRecipientName(ctx context.Context, walletNumber int64) (string, error) {
+	query := `SELECT accounts.full_name FROM wallets JOIN accounts ON accounts.id = wallets.account_id
+	WHERE wallets.wallet_number=$1 AND wallets.deleted_at IS NULL AND wallets.wallet_type != 'system';`
+
+	var name string
+	err := r.db.QueryRow(ctx, query, walletNumber).Scan(&name)
+	if err == pgx.ErrNoRows {
+		return "", util.ErrNotExist
+	}
+	if err != nil {
+		return "", err
+	}
+
+	return MaskName(name), nil
+}
+
+type CreatePayeeParams struct {
+	AccountID    int64
+	Nickname     string
+	WalletNumber int64
+	DisplayName  string
+}
+
+func (r *DB) CreatePayee(ctx context.Context, arg CreatePayeeParams) (*pkg.Payee, error) {
+	query := `INSERT INTO payees(account_id, nickname, wallet_number, display_name
+	) VALUES (
+		$1, $2, $3, $4
+	) RETURNING id, account_id, nickname, wallet_number, display_name, created_at;`
+
+	var res pkg.Payee
+	err := r.db.QueryRow(ctx, query, arg.AccountID, arg.Nickname, arg.WalletNumber, arg.DisplayName).Scan(&res.ID, &res.AccountID, &res.Nickname, &res.WalletNumber, &res.DisplayName, &res.CreatedAt)
+	if err != nil {
+		var pgxError *pgconn.PgError
+		if errors.As(err, &pgxError) {
+			// 23503 (foreign_key_violation) -> wallet doesn't exist
+			if pgxError.Code == "23503" {
+				return nil, util.ErrNotExist
+			}
+			// 23505 (unique_violation) -> wallet is already saved as payee
+			if pgxError.Code == "23505" {
+				return nil, util.ErrDuplicate
+			}
+		}
+		return nil, err
+	}
+
+	return &res, nil
+}
+
+// GetPayee() return the payee only when it belongs to the account.
+func (r *DB) GetPayee(ctx context.Context, id, accountID int64) (*pkg.Payee, error) {
+	query := `SELECT id, account_id, nickname, wallet_number, display_name, created_at FROM payees WHERE id=$1 AND account_id=$2;`
+
+	var res pkg.Payee
+	err := r.db.QueryRow(ctx, query, id, accountID).Scan(&res.ID, &res.AccountID, &res.Nickname, &res.WalletNumber, &res.DisplayName, &res.CreatedAt)
+	if err == pgx.ErrNoRows {
+		return nil, util.ErrNotExist
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &res, nil
+}
+
+// GetPayeeByWallet() return the payee of the account with the wallet number.
+func (r *DB) GetPayeeByWallet(ctx context.Context, accountID, walletNumber int64) (*pkg.Payee, error) {
+	query := `SELECT id, account_id, nickname, wallet_number, display_name, created_at FROM payees WHERE account_id=$1 AND wallet_number=$2;`
+
+	var res pkg.Payee
+	err := r.db.QueryRow(ctx, query, accountID, walletNumber).Scan(&res.ID, &res.AccountID, &res.Nickname, &res.WalletNumber, &res.DisplayName, &res.CreatedAt)
+	if err == pgx.ErrNoRows {
+		return nil, util.ErrNotExist
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &res, nil
+}
+
+func (r *DB) ListPayees(ctx context.Context, accountID int64) ([]pkg.Payee, error) {
+	query := `SELECT id, account_id, nickname, wallet_number, display_name, created_at FROM payees WHERE account_id=$1 ORDER BY nickname;`
+	rows, err := r.db.Query(ctx, query, accountID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []pkg.Payee
+	for rows.Next() {
+		var payee pkg.Payee
+		err = rows.Scan(&payee.ID, &payee.AccountID, &payee.Nickname, &payee.WalletNumber, &payee.DisplayName, &payee.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, payee)
+	}
+
+	return list, rows.Err()
+}
+
+func (r *DB) DeletePayee(ctx context.Context, id, accountID int64) error {
+	res, err := r.db.Exec(ctx, `DELETE FROM payees WHERE id=$1 AND account_id=$2`, id, accountID)
+	if err != nil {
+		return err
+	}
+	if res.RowsAffected() == 0 {
+		return util.ErrDeleteFailed
+	}
+
+	return nil
+}
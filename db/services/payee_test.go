@@ -0,0 +1,71 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"simple-bank-system/db/pkg"
+	"simple-bank-system/util"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMaskName(t *testing.T) {
+	assert.Equal(t, "B*** S******", MaskName("Budi Santoso"))
+	assert.Equal(t, "A", MaskName("A"))
+	assert.Equal(t, "J*** D**", MaskName("  John   Doe "))
+}
+
+func TestPayeeInCoolingOff(t *testing.T) {
+	now := time.Now()
+	payee := pkg.Payee{CreatedAt: now.Add(-time.Hour)}
+	assert.True(t, PayeeInCoolingOff(payee, 24*time.Hour, now))
+	assert.False(t, PayeeInCoolingOff(payee, time.Hour, now))
+	assert.False(t, PayeeInCoolingOff(payee, 0, now))
+}
+
+func TestPayee(t *testing.T) {
+	account := createRandomAccount(t)
+	recipient := createRandomAccount(t)
+	wallet, _ := createRandomWallet(t, recipient)
+
+	name, err := testQueries.GetRecipientName(ctx, wallet.WalletNumber)
+	require.NoError(t, err)
+	assert.Equal(t, MaskName(recipient.FullName), name)
+
+	arg := CreatePayeeParams{
+		AccountID:    account.ID,
+		Nickname:     util.RandomOwner(),
+		WalletNumber: wallet.WalletNumber,
+		DisplayName:  name,
+	}
+	payee, err := testQueries.CreatePayee(ctx, arg)
+	require.NoError(t, err)
+	assert.Equal(t, arg.Nickname, payee.Nickname)
+	assert.Equal(t, arg.WalletNumber, payee.WalletNumber)
+	assert.Equal(t, name, payee.DisplayName)
+
+	_, err = testQueries.CreatePayee(ctx, arg)
+	require.ErrorIs(t, err, util.ErrDuplicate)
+
+	// payee of other account can't be read
+	_, err = testQueries.GetPayee(ctx, payee.ID, recipient.ID)
+	require.ErrorIs(t, err, util.ErrNotExist)
+
+	byWallet, err := testQueries.GetPayeeByWallet(ctx, account.ID, wallet.WalletNumber)
+	require.NoError(t, err)
+	assert.Equal(t, payee.ID, byWallet.ID)
+	_, err = testQueries.GetPayeeByWallet(ctx, recipient.ID, wallet.WalletNumber)
+	require.ErrorIs(t, err, util.ErrNotExist)
+
+	payees, err := testQueries.ListPayees(ctx, account.ID)
+	require.NoError(t, err)
+	require.Len(t, payees, 1)
+	assert.Equal(t, payee.ID, payees[0].ID)
+
+	err = testQueries.DeletePayee(ctx, payee.ID, account.ID)
+	require.NoError(t, err)
+	_, err = testQueries.GetPayee(ctx, payee.ID, account.ID)
+	require.ErrorIs(t, err, util.ErrNotExist)
+}
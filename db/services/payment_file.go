@@ -0,0 +1,203 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"simple-bank-system/util"
+
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
+)
+
+type CreatePaymentFileParams struct {
+	AccountID int64
+	MsgID     string
+	// SHA-256 of the file, hex
+	FileHash     string
+	ClaimedUntil time.Time
+}
+
+// CreatePaymentFile() claim the message id of the uploaded file before it's executed.
+// It return util.ErrDuplicate when the account already uploaded a file with the same message id.
+func (r *DB) CreatePaymentFile(ctx context.Context, arg CreatePaymentFileParams) (int64, error) {
+	var id int64
+	err := r.db.QueryRow(ctx, `INSERT INTO payment_files(account_id, msg_id, file_hash, claimed_until) VALUES ($1, $2, $3, $4) RETURNING id;`,
+		arg.AccountID, arg.MsgID, arg.FileHash, arg.ClaimedUntil).Scan(&id)
+	if err != nil {
+		var pgxError *pgconn.PgError
+		if errors.As(err, &pgxError) && pgxError.Code == "23505" {
+			return 0, util.ErrDuplicate
+		}
+		return 0, err
+	}
+
+	return id, nil
+}
+
+type ClaimPaymentFileParams struct {
+	AccountID    int64
+	MsgID        string
+	FileHash     string
+	Now          time.Time
+	ClaimedUntil time.Time
+}
+
+type ClaimPaymentFileResult struct {
+	ID int64
+	// report of a file that was completely executed, it's empty when the file is claimed to be resumed
+	Report string
+}
+
+/*
+ * ClaimPaymentFileTx() is called when the message id was already uploaded. It return the report of a file
+ * that was completely executed, or claim an interrupted file until 'ClaimedUntil' so it can be resumed.
+ * It return util.ErrPaymentFileRunning while the claim of another upload is running and
+ * util.ErrPaymentFileChanged when the file isn't the one that was uploaded with the message id.
+ */
+func (store *Store) ClaimPaymentFileTx(ctx context.Context, arg ClaimPaymentFileParams) (*ClaimPaymentFileResult, error) {
+	var result ClaimPaymentFileResult
+
+	err := store.execTx(ctx, func(q *DB) error {
+		var fileHash string
+		var claimedUntil time.Time
+		err := q.db.QueryRow(ctx, `SELECT id, report, file_hash, claimed_until FROM payment_files WHERE account_id=$1 AND msg_id=$2 FOR UPDATE;`,
+			arg.AccountID, arg.MsgID).Scan(&result.ID, &result.Report, &fileHash, &claimedUntil)
+		if err == pgx.ErrNoRows {
+			return util.ErrNotExist
+		}
+		if err != nil {
+			return err
+		}
+
+		if result.Report != "" {
+			return nil
+		}
+		// the files uploaded before the hash was saved have none
+		if fileHash != "" && fileHash != arg.FileHash {
+			return util.ErrPaymentFileChanged
+		}
+		if claimedUntil.After(arg.Now) {
+			return util.ErrPaymentFileRunning
+		}
+
+		_, err = q.db.Exec(ctx, `UPDATE payment_files SET file_hash=$2, claimed_until=$3 WHERE id=$1;`, result.ID, arg.FileHash, arg.ClaimedUntil)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// ExtendPaymentFileClaim() keep the claim of the file while it's executed.
+func (r *DB) ExtendPaymentFileClaim(ctx context.Context, id int64, claimedUntil time.Time) error {
+	_, err := r.db.Exec(ctx, `UPDATE payment_files SET claimed_until=$2 WHERE id=$1 AND report='';`, id, claimedUntil)
+	return err
+}
+
+// PaymentFileTransaction is the saved result of 1 transaction of a payment file.
+type PaymentFileTransaction struct {
+	FileID           int64
+	PaymentIndex     int
+	TransactionIndex int
+	Status           string
+	Reason           string
+	Info             string
+}
+
+// ListPaymentFileTransactions() return the saved results of the file in the order of the file.
+func (r *DB) ListPaymentFileTransactions(ctx context.Context, fileID int64) ([]PaymentFileTransaction, error) {
+	rows, err := r.db.Query(ctx, `SELECT file_id, payment_index, transaction_index, status, reason, info FROM payment_file_transactions
+	WHERE file_id=$1 ORDER BY payment_index, transaction_index;`, fileID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []PaymentFileTransaction
+	for rows.Next() {
+		var res PaymentFileTransaction
+		err = rows.Scan(&res.FileID, &res.PaymentIndex, &res.TransactionIndex, &res.Status, &res.Reason, &res.Info)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, res)
+	}
+
+	return list, rows.Err()
+}
+
+// SavePaymentFileTransaction() save the result of a rejected transaction, a result that's already saved is kept.
+func (r *DB) SavePaymentFileTransaction(ctx context.Context, arg PaymentFileTransaction) error {
+	_, err := r.db.Exec(ctx, `INSERT INTO payment_file_transactions(file_id, payment_index, transaction_index, status, reason, info)
+	VALUES ($1, $2, $3, $4, $5, $6) ON CONFLICT DO NOTHING;`,
+		arg.FileID, arg.PaymentIndex, arg.TransactionIndex, arg.Status, arg.Reason, arg.Info)
+	return err
+}
+
+/*
+ * PaymentFileTransferTx() is the 'TransferTx' of 1 transaction of a payment file, its settled result is saved
+ * in the same db transaction. So an interrupted file never run a transaction twice when it's resumed, it
+ * return util.ErrDuplicate when the transaction already has a result.
+ */
+func (store *Store) PaymentFileTransferTx(ctx context.Context, arg TransferTxParams, record PaymentFileTransaction) (*TransferTXResult, error) {
+	var result TransferTXResult
+
+	err := store.execTx(ctx, func(q *DB) error {
+		res, err := q.db.Exec(ctx, `INSERT INTO payment_file_transactions(file_id, payment_index, transaction_index, status)
+		VALUES ($1, $2, $3, $4) ON CONFLICT DO NOTHING;`, record.FileID, record.PaymentIndex, record.TransactionIndex, record.Status)
+		if err != nil {
+			return err
+		}
+		if res.RowsAffected() == 0 {
+			return util.ErrDuplicate
+		}
+
+		err = transferTx(ctx, q, arg, &result)
+		if err != nil {
+			return err
+		}
+
+		_, err = q.db.Exec(ctx, `UPDATE payment_file_transactions SET transfer_id=$4 WHERE file_id=$1 AND payment_index=$2 AND transaction_index=$3;`,
+			record.FileID, record.PaymentIndex, record.TransactionIndex, result.Transfer.ID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// GetPaymentFileReport() return the status report of the file, it's empty while the file is executed.
+func (r *DB) GetPaymentFileReport(ctx context.Context, accountID int64, msgID string) (string, error) {
+	var report string
+	err := r.db.QueryRow(ctx, `SELECT report FROM payment_files WHERE account_id=$1 AND msg_id=$2;`, accountID, msgID).Scan(&report)
+	if err == pgx.ErrNoRows {
+		return "", util.ErrNotExist
+	}
+	if err != nil {
+		return "", err
+	}
+
+	return report, nil
+}
+
+func (r *DB) SetPaymentFileReport(ctx context.Context, id int64, report string) error {
+	res, err := r.db.Exec(ctx, `UPDATE payment_files SET report=$1 WHERE id=$2`, report, id)
+	if err != nil {
+		return err
+	}
+	if res.RowsAffected() == 0 {
+		return util.ErrUpdateFailed
+	}
+
+	return nil
+}
+
+// IsInsufficientFunds() is true when the transfer failed because the balance of the wallet would be negative.
+func IsInsufficientFunds(err error) bool {
+	var pgxError *pgconn.PgError
+	return errors.As(err, &pgxError) && pgxError.ConstraintName == "ck_wallets_balance_minus"
+}
@@ -0,0 +1,117 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"simple-bank-system/util"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPaymentFile(t *testing.T) {
+	account := createRandomAccount(t)
+	msgID := util.RandomOwner()
+	arg := CreatePaymentFileParams{AccountID: account.ID, MsgID: msgID, FileHash: "hash", ClaimedUntil: time.Now().Add(time.Minute)}
+
+	id, err := testQueries.CreatePaymentFile(ctx, arg)
+	require.NoError(t, err)
+	require.NotZero(t, id)
+
+	_, err = testQueries.CreatePaymentFile(ctx, arg)
+	require.ErrorIs(t, err, util.ErrDuplicate)
+
+	report, err := testQueries.GetPaymentFileReport(ctx, account.ID, msgID)
+	require.NoError(t, err)
+	assert.Empty(t, report)
+
+	require.NoError(t, testQueries.SetPaymentFileReport(ctx, id, "<Document/>"))
+	report, err = testQueries.GetPaymentFileReport(ctx, account.ID, msgID)
+	require.NoError(t, err)
+	assert.Equal(t, "<Document/>", report)
+
+	_, err = testQueries.GetPaymentFileReport(ctx, account.ID, "unknown")
+	require.ErrorIs(t, err, util.ErrNotExist)
+}
+
+func TestClaimPaymentFileTx(t *testing.T) {
+	store := NewStore(dbpool)
+	account := createRandomAccount(t)
+	now := time.Now()
+	id, err := store.CreatePaymentFile(ctx, CreatePaymentFileParams{AccountID: account.ID, MsgID: "claim", FileHash: "hash", ClaimedUntil: now.Add(time.Minute)})
+	require.NoError(t, err)
+
+	arg := ClaimPaymentFileParams{AccountID: account.ID, MsgID: "claim", FileHash: "hash", Now: now, ClaimedUntil: now.Add(time.Minute)}
+	_, err = store.ClaimPaymentFileTx(ctx, arg)
+	require.ErrorIs(t, err, util.ErrPaymentFileRunning)
+
+	// the claim of the interrupted upload expired
+	arg.Now = now.Add(2 * time.Minute)
+	arg.ClaimedUntil = arg.Now.Add(time.Minute)
+	arg.FileHash = "other"
+	_, err = store.ClaimPaymentFileTx(ctx, arg)
+	require.ErrorIs(t, err, util.ErrPaymentFileChanged)
+
+	arg.FileHash = "hash"
+	claim, err := store.ClaimPaymentFileTx(ctx, arg)
+	require.NoError(t, err)
+	assert.Equal(t, id, claim.ID)
+	assert.Empty(t, claim.Report)
+
+	// the report of a completed file is returned
+	require.NoError(t, store.SetPaymentFileReport(ctx, id, "<Document/>"))
+	arg.Now = arg.Now.Add(2 * time.Minute)
+	claim, err = store.ClaimPaymentFileTx(ctx, arg)
+	require.NoError(t, err)
+	assert.Equal(t, "<Document/>", claim.Report)
+
+	arg.MsgID = "unknown"
+	_, err = store.ClaimPaymentFileTx(ctx, arg)
+	require.ErrorIs(t, err, util.ErrNotExist)
+}
+
+func TestPaymentFileTransferTx(t *testing.T) {
+	store := NewStore(dbpool)
+	account := createRandomAccount(t)
+	recipient := createRandomAccount(t)
+	wallet := createRandomSavingsWallet(t, account, 1000)
+	toWallet := createRandomSavingsWallet(t, recipient, 0)
+	id, err := store.CreatePaymentFile(ctx, CreatePaymentFileParams{AccountID: account.ID, MsgID: "transfer", ClaimedUntil: time.Now()})
+	require.NoError(t, err)
+
+	arg := TransferTxParams{
+		AccountID:        account.ID,
+		WalletID:         wallet.ID,
+		FromWalletNumber: wallet.WalletNumber,
+		ToWalletNumber:   toWallet.WalletNumber,
+		Amount:           100,
+	}
+	record := PaymentFileTransaction{FileID: id, PaymentIndex: 0, TransactionIndex: 0, Status: "ACSC"}
+	result, err := store.PaymentFileTransferTx(ctx, arg, record)
+	require.NoError(t, err)
+	assert.Equal(t, int64(900), result.FromWallet.Balance)
+
+	// the transaction isn't executed twice
+	_, err = store.PaymentFileTransferTx(ctx, arg, record)
+	require.ErrorIs(t, err, util.ErrDuplicate)
+	current, err := store.GetWallet(ctx, wallet.ID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(900), current.Balance)
+
+	// a failed transfer doesn't save a result
+	arg.Amount = 10000
+	record.TransactionIndex = 1
+	_, err = store.PaymentFileTransferTx(ctx, arg, record)
+	require.True(t, IsInsufficientFunds(err))
+
+	record.Status, record.Reason, record.Info = "RJCT", "AM04", "insufficient funds"
+	require.NoError(t, store.SavePaymentFileTransaction(ctx, record))
+	require.NoError(t, store.SavePaymentFileTransaction(ctx, record), "a saved result is kept")
+
+	results, err := store.ListPaymentFileTransactions(ctx, id)
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+	assert.Equal(t, "ACSC", results[0].Status)
+	assert.Equal(t, "AM04", results[1].Reason)
+}
@@ -0,0 +1,291 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"simple-bank-system/db/pkg"
+	"simple-bank-system/util"
+
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
+)
+
+// Status of a payer share in a payment request
+const (
+	ShareStatusPending  = "pending"
+	ShareStatusPaid     = "paid"
+	ShareStatusDeclined = "declined"
+)
+
+// SplitAmount() split 'total' evenly into 'n' shares, the remainder is spread 1 by 1 to the first shares.
+func SplitAmount(total int64, n int) []int64 {
+	if n <= 0 {
+		return nil
+	}
+
+	shares := make([]int64, n)
+	for i := range shares {
+		shares[i] = total / int64(n)
+		if int64(i) < total%int64(n) {
+			shares[i]++
+		}
+	}
+	return shares
+}
+
+// PaymentRequestExpired() is true after the day of 'expires_at'.
+func PaymentRequestExpired(request pkg.PaymentRequest, now time.Time) bool {
+	y, m, d := request.ExpiresAt.Date()
+	return !now.Before(time.Date(y, m, d+1, 0, 0, 0, 0, now.Location()))
+}
+
+type PaymentShareParams struct {
+	PayerID int64
+	Amount  int64
+}
+
+type CreatePaymentRequestParams struct {
+	RequesterID int64
+	WalletID    int64
+	Memo        string
+	ExpiresAt   time.Time
+	Shares      []PaymentShareParams
+}
+
+// CreatePaymentRequestTx() create the request together with the share of every payer,
+// the amount of the request is the total of the shares.
+func (store *Store) CreatePaymentRequestTx(ctx context.Context, arg CreatePaymentRequestParams) (*pkg.PaymentRequest, error) {
+	var request *pkg.PaymentRequest
+
+	var amount int64
+	for _, share := range arg.Shares {
+		amount += share.Amount
+	}
+
+	err := store.execTx(ctx, func(q *DB) error {
+		var id int64
+		query := `INSERT INTO payment_requests(requester_id, wallet_id, amount, memo, expires_at
+		) VALUES (
+			$1, $2, $3, $4, $5
+		) RETURNING id;`
+		err := q.db.QueryRow(ctx, query, arg.RequesterID, arg.WalletID, amount, arg.Memo, arg.ExpiresAt).Scan(&id)
+		if err != nil {
+			return err
+		}
+
+		for _, share := range arg.Shares {
+			_, err = q.db.Exec(ctx, `INSERT INTO payment_request_shares(request_id, payer_id, amount) VALUES ($1, $2, $3)`, id, share.PayerID, share.Amount)
+			if err != nil {
+				var pgxError *pgconn.PgError
+				if errors.As(err, &pgxError) {
+					// 23503 (foreign_key_violation) -> payer account doesn't exist
+					if pgxError.Code == "23503" {
+						return util.ErrAccUser
+					}
+					// 23505 (unique_violation) -> same payer twice in 1 request
+					if pgxError.Code == "23505" {
+						return util.ErrDuplicate
+					}
+				}
+				return err
+			}
+		}
+
+		request, err = q.GetPaymentRequest(ctx, id)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return request, nil
+}
+
+const paymentRequestColumns = `payment_requests.id, payment_requests.requester_id, payment_requests.wallet_id, wallets.wallet_number,
+	payment_requests.amount, wallets.currency, payment_requests.memo, payment_requests.expires_at, payment_requests.created_at,
+	(SELECT COALESCE(SUM(amount), 0) FROM payment_request_shares WHERE request_id = payment_requests.id AND status = 'paid')`
+
+func scanPaymentRequest(row pgx.Row) (*pkg.PaymentRequest, error) {
+	var res pkg.PaymentRequest
+	err := row.Scan(&res.ID, &res.RequesterID, &res.WalletID, &res.WalletNumber, &res.Amount, &res.Currency, &res.Memo, &res.ExpiresAt, &res.CreatedAt, &res.PaidAmount)
+	if err == pgx.ErrNoRows {
+		return nil, util.ErrNotExist
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+// GetPaymentRequest() return the request with the share of every payer.
+func (r *DB) GetPaymentRequest(ctx context.Context, id int64) (*pkg.PaymentRequest, error) {
+	query := `SELECT ` + paymentRequestColumns + ` FROM payment_requests JOIN wallets ON wallets.id = payment_requests.wallet_id
+	WHERE payment_requests.id=$1;`
+
+	request, err := scanPaymentRequest(r.db.QueryRow(ctx, query, id))
+	if err != nil {
+		return nil, err
+	}
+
+	request.Shares, err = r.listPaymentRequestShares(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return request, nil
+}
+
+func (r *DB) listPaymentRequestShares(ctx context.Context, requestID int64) ([]pkg.PaymentRequestShare, error) {
+	query := `SELECT payment_request_shares.request_id, payment_request_shares.payer_id, accounts.account_number, payment_request_shares.amount,
+	payment_request_shares.status, payment_request_shares.transfer_id, payment_request_shares.updated_at
+	FROM payment_request_shares JOIN accounts ON accounts.id = payment_request_shares.payer_id
+	WHERE payment_request_shares.request_id=$1 ORDER BY payment_request_shares.payer_id;`
+	rows, err := r.db.Query(ctx, query, requestID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []pkg.PaymentRequestShare
+	for rows.Next() {
+		var share pkg.PaymentRequestShare
+		err = rows.Scan(&share.RequestID, &share.PayerID, &share.AccountNumber, &share.Amount, &share.Status, &share.TransferID, &share.UpdatedAt)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, share)
+	}
+
+	return list, rows.Err()
+}
+
+type ListPaymentRequestsParams struct {
+	AccountID int64
+	// true -> requests that wait for the account to pay, false -> requests made by the account
+	Incoming bool
+	Limit    int
+	Offset   int
+}
+
+func (r *DB) ListPaymentRequests(ctx context.Context, arg ListPaymentRequestsParams) ([]pkg.PaymentRequest, error) {
+	query := `SELECT ` + paymentRequestColumns + ` FROM payment_requests JOIN wallets ON wallets.id = payment_requests.wallet_id
+	WHERE payment_requests.requester_id=$1 ORDER BY payment_requests.id DESC LIMIT $2 OFFSET $3;`
+	if arg.Incoming {
+		query = `SELECT ` + paymentRequestColumns + ` FROM payment_requests JOIN wallets ON wallets.id = payment_requests.wallet_id
+		WHERE payment_requests.id IN (SELECT request_id FROM payment_request_shares WHERE payer_id=$1 AND status='pending')
+		AND payment_requests.expires_at >= CURRENT_DATE ORDER BY payment_requests.id DESC LIMIT $2 OFFSET $3;`
+	}
+
+	rows, err := r.db.Query(ctx, query, arg.AccountID, arg.Limit, arg.Offset)
+	if err != nil {
+		return nil, err
+	}
+
+	var list []pkg.PaymentRequest
+	for rows.Next() {
+		request, err := scanPaymentRequest(rows)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		list = append(list, *request)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for i := range list {
+		list[i].Shares, err = r.listPaymentRequestShares(ctx, list[i].ID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return list, nil
+}
+
+// DeclinePaymentShare() decline the pending share of the payer.
+func (r *DB) DeclinePaymentShare(ctx context.Context, requestID, payerID int64) error {
+	query := `UPDATE payment_request_shares SET status='declined', updated_at=now() WHERE request_id=$1 AND payer_id=$2 AND status='pending'`
+	res, err := r.db.Exec(ctx, query, requestID, payerID)
+	if err != nil {
+		return err
+	}
+	if res.RowsAffected() == 0 {
+		return util.ErrNotExist
+	}
+
+	return nil
+}
+
+type PayPaymentRequestParams struct {
+	RequestID int64
+	PayerID   int64
+	// wallet of the payer that pays the share
+	FromWallet pkg.Wallet
+}
+
+type PayPaymentRequestResult struct {
+	Request  *pkg.PaymentRequest
+	Transfer TransferTXResult
+}
+
+/*
+ * PayPaymentRequestTx() pay the pending share of the payer. The share is locked, then the money
+ * is moved with 'transferTx' to the wallet of the request and the transfer is linked to the share.
+ */
+func (store *Store) PayPaymentRequestTx(ctx context.Context, arg PayPaymentRequestParams) (*PayPaymentRequestResult, error) {
+	var result PayPaymentRequestResult
+
+	err := store.execTx(ctx, func(q *DB) error {
+		var amount int64
+		query := `SELECT amount FROM payment_request_shares WHERE request_id=$1 AND payer_id=$2 AND status='pending' FOR UPDATE;`
+		err := q.db.QueryRow(ctx, query, arg.RequestID, arg.PayerID).Scan(&amount)
+		if err == pgx.ErrNoRows {
+			return util.ErrNotExist
+		}
+		if err != nil {
+			return err
+		}
+
+		request, err := q.GetPaymentRequest(ctx, arg.RequestID)
+		if err != nil {
+			return err
+		}
+		if PaymentRequestExpired(*request, time.Now()) {
+			return util.ErrRequestExpired
+		}
+		if arg.FromWallet.Currency != request.Currency {
+			return fmt.Errorf("wallet [%d] currency mismatch: %s - %s", arg.FromWallet.ID, arg.FromWallet.Currency, request.Currency)
+		}
+
+		err = transferTx(ctx, q, TransferTxParams{
+			AccountID:        arg.FromWallet.AccountID,
+			WalletID:         arg.FromWallet.ID,
+			FromWalletNumber: arg.FromWallet.WalletNumber,
+			ToWalletNumber:   request.WalletNumber,
+			Amount:           amount,
+		}, &result.Transfer)
+		if err != nil {
+			return err
+		}
+
+		query = `UPDATE payment_request_shares SET status='paid', transfer_id=$1, updated_at=now() WHERE request_id=$2 AND payer_id=$3`
+		_, err = q.db.Exec(ctx, query, result.Transfer.Transfer.ID, arg.RequestID, arg.PayerID)
+		if err != nil {
+			return err
+		}
+
+		result.Request, err = q.GetPaymentRequest(ctx, arg.RequestID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
@@ -0,0 +1,94 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"simple-bank-system/db/pkg"
+	"simple-bank-system/util"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplitAmount(t *testing.T) {
+	assert.Equal(t, []int64{34, 33, 33}, SplitAmount(100, 3))
+	assert.Equal(t, []int64{50, 50}, SplitAmount(100, 2))
+	assert.Equal(t, []int64{1, 1, 0}, SplitAmount(2, 3))
+	assert.Nil(t, SplitAmount(100, 0))
+}
+
+func TestPaymentRequestExpired(t *testing.T) {
+	now := time.Now()
+	request := pkg.PaymentRequest{ExpiresAt: now}
+	assert.False(t, PaymentRequestExpired(request, now), "request is valid until the end of the day")
+	assert.True(t, PaymentRequestExpired(request, now.AddDate(0, 0, 1)))
+}
+
+func TestSplitPaymentRequest(t *testing.T) {
+	store := NewStore(dbpool)
+	requester := createRandomAccount(t)
+	payer1 := createRandomAccount(t)
+	payer2 := createRandomAccount(t)
+	wallet := createRandomSavingsWallet(t, requester, 0)
+
+	shares := SplitAmount(1001, 2)
+	request, err := store.CreatePaymentRequestTx(ctx, CreatePaymentRequestParams{
+		RequesterID: requester.ID,
+		WalletID:    wallet.ID,
+		Memo:        "dinner",
+		ExpiresAt:   time.Now().AddDate(0, 0, 7),
+		Shares: []PaymentShareParams{
+			{PayerID: payer1.ID, Amount: shares[0]},
+			{PayerID: payer2.ID, Amount: shares[1]},
+		},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1001), request.Amount)
+	assert.Equal(t, wallet.WalletNumber, request.WalletNumber)
+	assert.Zero(t, request.PaidAmount)
+	require.Len(t, request.Shares, 2)
+
+	incoming, err := store.ListPaymentRequests(ctx, ListPaymentRequestsParams{AccountID: payer1.ID, Incoming: true, Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, incoming, 1)
+	assert.Equal(t, request.ID, incoming[0].ID)
+
+	payerWallet := createRandomSavingsWallet(t, payer1, 10000)
+	result, err := store.PayPaymentRequestTx(ctx, PayPaymentRequestParams{
+		RequestID:  request.ID,
+		PayerID:    payer1.ID,
+		FromWallet: payerWallet,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, shares[0], result.Request.PaidAmount)
+	assert.Equal(t, payerWallet.Balance-shares[0], result.Transfer.FromWallet.Balance)
+	assert.Equal(t, shares[0], result.Transfer.ToWallet.Balance)
+
+	// share can only be paid once
+	_, err = store.PayPaymentRequestTx(ctx, PayPaymentRequestParams{
+		RequestID:  request.ID,
+		PayerID:    payer1.ID,
+		FromWallet: payerWallet,
+	})
+	require.ErrorIs(t, err, util.ErrNotExist)
+
+	err = store.DeclinePaymentShare(ctx, request.ID, payer2.ID)
+	require.NoError(t, err)
+
+	saved, err := store.GetPaymentRequest(ctx, request.ID)
+	require.NoError(t, err)
+	for _, share := range saved.Shares {
+		if share.PayerID == payer1.ID {
+			assert.Equal(t, ShareStatusPaid, share.Status)
+			assert.Equal(t, result.Transfer.Transfer.ID, share.TransferID.Int64)
+		} else {
+			assert.Equal(t, ShareStatusDeclined, share.Status)
+		}
+	}
+
+	outgoing, err := store.ListPaymentRequests(ctx, ListPaymentRequestsParams{AccountID: requester.ID, Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, outgoing, 1)
+	assert.Equal(t, shares[0], outgoing[0].PaidAmount)
+}
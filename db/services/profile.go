@@ -0,0 +1,264 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"simple-bank-system/db/pkg"
+	"simple-bank-system/util"
+
+	"github.com/jackc/pgx/v4"
+)
+
+// GetAccountProfile() return the account with its current address, it's the account of the access token.
+func (r *DB) GetAccountProfile(ctx context.Context, accountID int64) (*pkg.Account, error) {
+	query := `SELECT * FROM accounts JOIN addresses ON accounts.address = addresses.id WHERE accounts.id=$1 AND deleted_at IS NULL;`
+	row := r.db.QueryRow(ctx, query, accountID)
+
+	var account pkg.Account
+	err := row.Scan(&account.ID, &account.AccountNumber, &account.Username, &account.HashedPassword, &account.FullName, &account.DateOfBirth, &account.Address.ID, &account.Email, &account.PasswordChangeAt, &account.CreatedAt,
+		&account.DeletedAt, &account.Role, &account.Address.ID, &account.Address.Provinces, &account.Address.City, &account.Address.ZIP, &account.Address.Street)
+	if err == pgx.ErrNoRows {
+		return nil, util.ErrNotExist
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &account, nil
+}
+
+func (r *DB) UpdateAccountFullName(ctx context.Context, accountID int64, fullName string) error {
+	res, err := r.db.Exec(ctx, `UPDATE accounts SET full_name=$2 WHERE id=$1 AND deleted_at IS NULL;`, accountID, fullName)
+	if err != nil {
+		return accErrHandling(err)
+	}
+	if res.RowsAffected() == 0 {
+		return util.ErrUpdateFailed
+	}
+	return nil
+}
+
+type UpdatePasswordParams struct {
+	AccountID int64
+	// plain password, it's hashed like in 'CreateAccount'
+	Password  string
+	ChangedAt time.Time
+}
+
+// UpdatePassword() save the new password, the access tokens issued before 'ChangedAt' aren't accepted anymore.
+func (r *DB) UpdatePassword(ctx context.Context, arg UpdatePasswordParams) error {
+	hashedPass, err := util.HashingPassword(arg.Password)
+	if err != nil {
+		return err
+	}
+
+	res, err := r.db.Exec(ctx, `UPDATE accounts SET hashed_password=$2, password_change_at=$3 WHERE id=$1 AND deleted_at IS NULL;`,
+		arg.AccountID, hashedPass, arg.ChangedAt)
+	if err != nil {
+		return accErrHandling(err)
+	}
+	if res.RowsAffected() == 0 {
+		return util.ErrUpdateFailed
+	}
+	return nil
+}
+
+// GetPasswordChangeAt() return util.ErrNotExist for deleted accounts.
+func (r *DB) GetPasswordChangeAt(ctx context.Context, accountID int64) (time.Time, error) {
+	var changedAt time.Time
+	err := r.db.QueryRow(ctx, `SELECT password_change_at FROM accounts WHERE id=$1 AND deleted_at IS NULL;`, accountID).Scan(&changedAt)
+	if err == pgx.ErrNoRows {
+		return changedAt, util.ErrNotExist
+	}
+	return changedAt, err
+}
+
+// AccountAuth is what the access tokens of the account are checked against.
+type AccountAuth struct {
+	Role             string
+	PasswordChangeAt time.Time
+}
+
+// GetAccountAuth() is read for every authenticated request, it return util.ErrNotExist for deleted accounts.
+func (r *DB) GetAccountAuth(ctx context.Context, accountID int64) (*AccountAuth, error) {
+	var res AccountAuth
+	err := r.db.QueryRow(ctx, `SELECT role, password_change_at FROM accounts WHERE id=$1 AND deleted_at IS NULL;`, accountID).
+		Scan(&res.Role, &res.PasswordChangeAt)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, util.ErrNotExist
+		}
+		return nil, err
+	}
+	return &res, nil
+}
+
+/*
+ * UpdateAddressTx() save the new address as a new row and move the current one to the history,
+ * addresses are never updated in place so the history keep what the account used.
+ * The history row start when the previous change ended (or when the account was created).
+ */
+func (store *Store) UpdateAddressTx(ctx context.Context, accountID int64, address pkg.Addresses) (*pkg.Addresses, error) {
+	var res pkg.Addresses
+
+	err := store.execTx(ctx, func(q *DB) error {
+		var oldAddressID int64
+		var createdAt time.Time
+		err := q.db.QueryRow(ctx, `SELECT address, created_at FROM accounts WHERE id=$1 AND deleted_at IS NULL FOR UPDATE;`, accountID).
+			Scan(&oldAddressID, &createdAt)
+		if err == pgx.ErrNoRows {
+			return util.ErrNotExist
+		}
+		if err != nil {
+			return err
+		}
+
+		query := `INSERT INTO addresses(provinces, city, zip, street) VALUES($1, $2, $3, $4)
+		RETURNING id, provinces, city, zip, street;`
+		err = q.db.QueryRow(ctx, query, address.Provinces, address.City, address.ZIP, address.Street).
+			Scan(&res.ID, &res.Provinces, &res.City, &res.ZIP, &res.Street)
+		if err != nil {
+			return addressErrHandling(err)
+		}
+
+		query = `INSERT INTO address_history(account_id, address_id, valid_from)
+		VALUES ($1, $2, COALESCE((SELECT MAX(valid_to) FROM address_history WHERE account_id=$1), $3));`
+		_, err = q.db.Exec(ctx, query, accountID, oldAddressID, createdAt)
+		if err != nil {
+			return err
+		}
+
+		_, err = q.db.Exec(ctx, `UPDATE accounts SET address=$2 WHERE id=$1;`, accountID, res.ID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &res, nil
+}
+
+// ListAddressHistory() return the previous addresses of the account, the newest first.
+func (r *DB) ListAddressHistory(ctx context.Context, accountID int64) ([]pkg.AddressHistory, error) {
+	query := `SELECT h.id, h.account_id, a.id, a.provinces, a.city, a.zip, a.street, h.valid_from, h.valid_to
+	FROM address_history h JOIN addresses a ON h.address_id = a.id
+	WHERE h.account_id=$1 ORDER BY h.id DESC;`
+
+	rows, err := r.db.Query(ctx, query, accountID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []pkg.AddressHistory
+	for rows.Next() {
+		var item pkg.AddressHistory
+		err = rows.Scan(&item.ID, &item.AccountID, &item.Address.ID, &item.Address.Provinces, &item.Address.City, &item.Address.ZIP, &item.Address.Street,
+			&item.ValidFrom, &item.ValidTo)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, item)
+	}
+
+	return list, rows.Err()
+}
+
+type CreateEmailChangeParams struct {
+	AccountID int64
+	Email     string
+	// util.HashToken of the token that is sent to the new email
+	TokenHash []byte
+	ExpiresAt time.Time
+}
+
+// CreateEmailChangeTx() replace the pending email change of the account, an email that is used by
+// any account return util.ErrEmailExists.
+func (store *Store) CreateEmailChangeTx(ctx context.Context, arg CreateEmailChangeParams) (*pkg.EmailChange, error) {
+	var res pkg.EmailChange
+
+	err := store.execTx(ctx, func(q *DB) error {
+		var used bool
+		err := q.db.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM accounts WHERE email=$1);`, arg.Email).Scan(&used)
+		if err != nil {
+			return err
+		}
+		if used {
+			return util.ErrEmailExists
+		}
+
+		_, err = q.db.Exec(ctx, `DELETE FROM email_changes WHERE account_id=$1 AND verified_at IS NULL;`, arg.AccountID)
+		if err != nil {
+			return err
+		}
+
+		query := `INSERT INTO email_changes(account_id, email, token_hash, expires_at) VALUES ($1, $2, $3, $4)
+		RETURNING id, account_id, email, token_hash, expires_at, verified_at, created_at;`
+		return q.db.QueryRow(ctx, query, arg.AccountID, arg.Email, arg.TokenHash, arg.ExpiresAt).
+			Scan(&res.ID, &res.AccountID, &res.Email, &res.TokenHash, &res.ExpiresAt, &res.VerifiedAt, &res.CreatedAt)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &res, nil
+}
+
+func scanEmailChange(row pgx.Row) (*pkg.EmailChange, error) {
+	var res pkg.EmailChange
+	err := row.Scan(&res.ID, &res.AccountID, &res.Email, &res.TokenHash, &res.ExpiresAt, &res.VerifiedAt, &res.CreatedAt)
+	if err == pgx.ErrNoRows {
+		return nil, util.ErrNotExist
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+// GetPendingEmailChange() return util.ErrNotExist when the account has no email change to verify.
+func (r *DB) GetPendingEmailChange(ctx context.Context, accountID int64) (*pkg.EmailChange, error) {
+	query := `SELECT id, account_id, email, token_hash, expires_at, verified_at, created_at
+	FROM email_changes WHERE account_id=$1 AND verified_at IS NULL;`
+	return scanEmailChange(r.db.QueryRow(ctx, query, accountID))
+}
+
+/*
+ * VerifyEmailChangeTx() change the email of the account to the pending email when 'tokenHash' match.
+ * It return util.ErrNotExist when the token doesn't match and util.ErrTokenExpired when it's too late,
+ * the account has to request the change again.
+ */
+func (store *Store) VerifyEmailChangeTx(ctx context.Context, accountID int64, tokenHash []byte) (*pkg.EmailChange, error) {
+	var res *pkg.EmailChange
+
+	err := store.execTx(ctx, func(q *DB) error {
+		query := `SELECT id, account_id, email, token_hash, expires_at, verified_at, created_at
+		FROM email_changes WHERE account_id=$1 AND token_hash=$2 AND verified_at IS NULL FOR UPDATE;`
+		change, err := scanEmailChange(q.db.QueryRow(ctx, query, accountID, tokenHash))
+		if err != nil {
+			return err
+		}
+		if time.Now().After(change.ExpiresAt) {
+			return util.ErrTokenExpired
+		}
+
+		_, err = q.db.Exec(ctx, `UPDATE accounts SET email=$2 WHERE id=$1;`, accountID, change.Email)
+		if err != nil {
+			return accErrHandling(err)
+		}
+
+		err = q.db.QueryRow(ctx, `UPDATE email_changes SET verified_at=NOW() WHERE id=$1 RETURNING verified_at;`, change.ID).
+			Scan(&change.VerifiedAt)
+		if err != nil {
+			return err
+		}
+
+		res = change
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
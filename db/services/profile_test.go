@@ -0,0 +1,130 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"simple-bank-system/db/pkg"
+	"simple-bank-system/util"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUpdateProfile(t *testing.T) {
+	store := NewStore(dbpool)
+	account := createRandomAccount(t)
+
+	require.NoError(t, store.UpdateAccountFullName(ctx, account.ID, "New Name"))
+
+	history, err := store.ListAddressHistory(ctx, account.ID)
+	require.NoError(t, err)
+	require.Empty(t, history)
+
+	newAddress := pkg.Addresses{Provinces: "Jawa Barat", City: "Bandung", ZIP: 40111, Street: "Jl. Asia Afrika"}
+	address, err := store.UpdateAddressTx(ctx, account.ID, newAddress)
+	require.NoError(t, err)
+	assert.NotEqual(t, account.Address.ID, address.ID)
+
+	_, err = store.UpdateAddressTx(ctx, account.ID, pkg.Addresses{Provinces: "Bali", City: "Denpasar", ZIP: 80111, Street: "Jl. Gajah Mada"})
+	require.NoError(t, err)
+
+	profile, err := store.GetAccountProfile(ctx, account.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "New Name", profile.FullName)
+	assert.Equal(t, "Bali", profile.Address.Provinces)
+
+	// newest first, the first address start when the account was created
+	history, err = store.ListAddressHistory(ctx, account.ID)
+	require.NoError(t, err)
+	require.Len(t, history, 2)
+	assert.Equal(t, address.ID, history[0].Address.ID)
+	assert.Equal(t, account.Address.ID, history[1].Address.ID)
+	assert.WithinDuration(t, account.CreatedAt, history[1].ValidFrom, time.Second)
+	assert.True(t, history[1].ValidTo.Equal(history[0].ValidFrom))
+
+	_, err = store.UpdateAddressTx(ctx, account.ID, pkg.Addresses{Provinces: "", City: "x", ZIP: 1, Street: "x"})
+	require.ErrorIs(t, err, util.ErrAddressEmpty)
+}
+
+func TestEmailChange(t *testing.T) {
+	store := NewStore(dbpool)
+	account := createRandomAccount(t)
+	other := createRandomAccount(t)
+
+	_, err := store.CreateEmailChangeTx(ctx, CreateEmailChangeParams{
+		AccountID: account.ID,
+		Email:     other.Email,
+		TokenHash: util.HashToken("used"),
+		ExpiresAt: time.Now().Add(time.Hour),
+	})
+	require.ErrorIs(t, err, util.ErrEmailExists)
+
+	// the second request replace the first one
+	newEmail := util.RandomEmail()
+	_, err = store.CreateEmailChangeTx(ctx, CreateEmailChangeParams{
+		AccountID: account.ID,
+		Email:     util.RandomEmail(),
+		TokenHash: util.HashToken("first"),
+		ExpiresAt: time.Now().Add(time.Hour),
+	})
+	require.NoError(t, err)
+	_, err = store.CreateEmailChangeTx(ctx, CreateEmailChangeParams{
+		AccountID: account.ID,
+		Email:     newEmail,
+		TokenHash: util.HashToken("second"),
+		ExpiresAt: time.Now().Add(time.Hour),
+	})
+	require.NoError(t, err)
+
+	pending, err := store.GetPendingEmailChange(ctx, account.ID)
+	require.NoError(t, err)
+	assert.Equal(t, newEmail, pending.Email)
+
+	_, err = store.VerifyEmailChangeTx(ctx, account.ID, util.HashToken("first"))
+	require.ErrorIs(t, err, util.ErrNotExist)
+
+	change, err := store.VerifyEmailChangeTx(ctx, account.ID, util.HashToken("second"))
+	require.NoError(t, err)
+	assert.True(t, change.VerifiedAt.Valid)
+
+	profile, err := store.GetAccountProfile(ctx, account.ID)
+	require.NoError(t, err)
+	assert.Equal(t, newEmail, profile.Email)
+
+	_, err = store.GetPendingEmailChange(ctx, account.ID)
+	require.ErrorIs(t, err, util.ErrNotExist)
+
+	// expired token
+	_, err = store.CreateEmailChangeTx(ctx, CreateEmailChangeParams{
+		AccountID: account.ID,
+		Email:     util.RandomEmail(),
+		TokenHash: util.HashToken("late"),
+		ExpiresAt: time.Now().Add(-time.Minute),
+	})
+	require.NoError(t, err)
+	_, err = store.VerifyEmailChangeTx(ctx, account.ID, util.HashToken("late"))
+	require.ErrorIs(t, err, util.ErrTokenExpired)
+}
+
+func TestUpdatePassword(t *testing.T) {
+	account := createRandomAccount(t)
+
+	changedAt, err := testQueries.GetPasswordChangeAt(ctx, account.ID)
+	require.NoError(t, err)
+	assert.True(t, changedAt.Before(account.CreatedAt), "password was never changed")
+
+	now := time.Now()
+	require.NoError(t, testQueries.UpdatePassword(ctx, UpdatePasswordParams{AccountID: account.ID, Password: "newsecret", ChangedAt: now}))
+
+	changedAt, err = testQueries.GetPasswordChangeAt(ctx, account.ID)
+	require.NoError(t, err)
+	assert.WithinDuration(t, now, changedAt, time.Millisecond)
+
+	profile, err := testQueries.GetAccountProfile(ctx, account.ID)
+	require.NoError(t, err)
+	require.NoError(t, util.VerifyPassword("newsecret", profile.HashedPassword))
+
+	_, err = testQueries.GetPasswordChangeAt(ctx, 0)
+	require.ErrorIs(t, err, util.ErrNotExist)
+}
@@ -0,0 +1,39 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"simple-bank-system/db/pkg"
+)
+
+// UpdateRateLimitBucketTx() lock the bucket of the key, a new bucket is empty since 'epoch' (so it's full once refilled).
+func (store *Store) UpdateRateLimitBucketTx(ctx context.Context, key string, update func(bucket *pkg.RateLimitBucket)) error {
+	return store.execTx(ctx, func(q *DB) error {
+		_, err := q.db.Exec(ctx, `INSERT INTO rate_limit_buckets(key, tokens, updated_at) VALUES ($1, 0, 'epoch') ON CONFLICT DO NOTHING;`, key)
+		if err != nil {
+			return err
+		}
+
+		var bucket pkg.RateLimitBucket
+		err = q.db.QueryRow(ctx, `SELECT key, tokens, updated_at FROM rate_limit_buckets WHERE key=$1 FOR UPDATE;`, key).
+			Scan(&bucket.Key, &bucket.Tokens, &bucket.UpdatedAt)
+		if err != nil {
+			return err
+		}
+
+		update(&bucket)
+
+		_, err = q.db.Exec(ctx, `UPDATE rate_limit_buckets SET tokens=$2, updated_at=$3 WHERE key=$1;`, key, bucket.Tokens, bucket.UpdatedAt)
+		return err
+	})
+}
+
+// PruneRateLimitBuckets() delete the buckets that weren't used since 'before'.
+func (r *DB) PruneRateLimitBuckets(ctx context.Context, before time.Time) (int64, error) {
+	res, err := r.db.Exec(ctx, `DELETE FROM rate_limit_buckets WHERE updated_at < $1;`, before)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected(), nil
+}
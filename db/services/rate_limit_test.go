@@ -0,0 +1,40 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"simple-bank-system/db/pkg"
+	"simple-bank-system/util"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUpdateRateLimitBucket(t *testing.T) {
+	store := NewStore(dbpool)
+	key := "test:ip:" + util.RandomString(12)
+	now := time.Now().UTC().Truncate(time.Microsecond)
+
+	// a new bucket is empty since epoch
+	err := store.UpdateRateLimitBucketTx(ctx, key, func(bucket *pkg.RateLimitBucket) {
+		assert.Equal(t, key, bucket.Key)
+		assert.Zero(t, bucket.Tokens)
+		assert.True(t, bucket.UpdatedAt.Before(now.Add(-time.Hour)))
+		bucket.Tokens, bucket.UpdatedAt = 4, now
+	})
+	require.NoError(t, err)
+
+	err = store.UpdateRateLimitBucketTx(ctx, key, func(bucket *pkg.RateLimitBucket) {
+		assert.Equal(t, 4.0, bucket.Tokens)
+		assert.True(t, now.Equal(bucket.UpdatedAt))
+	})
+	require.NoError(t, err)
+
+	_, err = store.PruneRateLimitBuckets(ctx, now.Add(time.Second))
+	require.NoError(t, err)
+	err = store.UpdateRateLimitBucketTx(ctx, key, func(bucket *pkg.RateLimitBucket) {
+		assert.Zero(t, bucket.Tokens, "the bucket was pruned")
+	})
+	require.NoError(t, err)
+}
@@ -0,0 +1,93 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"simple-bank-system/db/pkg"
+	"simple-bank-system/util"
+
+	"github.com/google/uuid"
+)
+
+type RevokeTokenParams struct {
+	TokenID   uuid.UUID
+	AccountID int64
+	// expiry of the token, the revocation is pruned after it
+	ExpiresAt time.Time
+}
+
+// RevokeToken() revoke 1 access token, revoking it again does nothing.
+func (r *DB) RevokeToken(ctx context.Context, arg RevokeTokenParams) error {
+	query := `INSERT INTO token_revocations(token_id, account_id, expires_at) VALUES ($1, $2, $3)
+	ON CONFLICT (token_id) DO NOTHING;`
+	_, err := r.db.Exec(ctx, query, arg.TokenID, arg.AccountID, arg.ExpiresAt)
+	return err
+}
+
+type RevokeAccountTokensParams struct {
+	AccountID int64
+	// tokens issued before 'RevokedBefore' are revoked
+	RevokedBefore time.Time
+	// when the last of these tokens expire
+	ExpiresAt time.Time
+}
+
+// RevokeAccountTokensTx() revoke every access token of the account and every session, so they can't be refreshed.
+func (store *Store) RevokeAccountTokensTx(ctx context.Context, arg RevokeAccountTokensParams) error {
+	return store.execTx(ctx, func(q *DB) error {
+		query := `INSERT INTO token_revocations(account_id, revoked_before, expires_at) VALUES ($1, $2, $3);`
+		_, err := q.db.Exec(ctx, query, arg.AccountID, arg.RevokedBefore, arg.ExpiresAt)
+		if err != nil {
+			return err
+		}
+
+		_, err = q.db.Exec(ctx, `UPDATE sessions SET revoked_at=NOW() WHERE account_id=$1 AND revoked_at IS NULL;`, arg.AccountID)
+		return err
+	})
+}
+
+// RevokeSession() revoke the session of the account, it return util.ErrNotExist when the account has no such session.
+func (r *DB) RevokeSession(ctx context.Context, sessionID, accountID int64) error {
+	res, err := r.db.Exec(ctx, `UPDATE sessions SET revoked_at=COALESCE(revoked_at, NOW()) WHERE id=$1 AND account_id=$2;`, sessionID, accountID)
+	if err != nil {
+		return err
+	}
+	if res.RowsAffected() == 0 {
+		return util.ErrNotExist
+	}
+	return nil
+}
+
+// ListTokenRevocations() return the revocations that are still needed at 'now'.
+func (r *DB) ListTokenRevocations(ctx context.Context, now time.Time) ([]pkg.TokenRevocation, error) {
+	query := `SELECT id, token_id, account_id, revoked_before, expires_at, created_at
+	FROM token_revocations WHERE expires_at > $1 ORDER BY id;`
+
+	rows, err := r.db.Query(ctx, query, now)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []pkg.TokenRevocation
+	for rows.Next() {
+		var item pkg.TokenRevocation
+		err = rows.Scan(&item.ID, &item.TokenID, &item.AccountID, &item.RevokedBefore, &item.ExpiresAt, &item.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, item)
+	}
+
+	return list, rows.Err()
+}
+
+// PruneTokenRevocations() delete the revocations of the tokens that are expired at 'now'.
+func (r *DB) PruneTokenRevocations(ctx context.Context, now time.Time) (int64, error) {
+	res, err := r.db.Exec(ctx, `DELETE FROM token_revocations WHERE expires_at <= $1;`, now)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected(), nil
+}
@@ -0,0 +1,80 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"simple-bank-system/util"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTokenRevocations(t *testing.T) {
+	store := NewStore(dbpool)
+	account := createRandomAccount(t)
+	now := time.Now()
+
+	tokenID := uuid.New()
+	arg := RevokeTokenParams{TokenID: tokenID, AccountID: account.ID, ExpiresAt: now.Add(time.Minute)}
+	require.NoError(t, store.RevokeToken(ctx, arg))
+	// revoking again does nothing
+	require.NoError(t, store.RevokeToken(ctx, arg))
+
+	session, err := store.CreateSession(ctx, newSessionParams(account.ID, util.RandomString(20)))
+	require.NoError(t, err)
+	require.NoError(t, store.RevokeAccountTokensTx(ctx, RevokeAccountTokensParams{
+		AccountID:     account.ID,
+		RevokedBefore: now,
+		ExpiresAt:     now.Add(time.Hour),
+	}))
+
+	revoked, err := store.GetSession(ctx, session.ID)
+	require.NoError(t, err)
+	assert.True(t, revoked.RevokedAt.Valid)
+
+	list, err := store.ListTokenRevocations(ctx, now)
+	require.NoError(t, err)
+	var tokens, cutoffs int
+	for _, item := range list {
+		if item.AccountID != account.ID {
+			continue
+		}
+		if item.TokenID.Valid {
+			assert.Equal(t, tokenID, item.TokenID.UUID)
+			tokens++
+		} else {
+			assert.WithinDuration(t, now, item.RevokedBefore.Time, time.Millisecond)
+			cutoffs++
+		}
+	}
+	assert.Equal(t, 1, tokens)
+	assert.Equal(t, 1, cutoffs)
+
+	// the revocation of the token is pruned after the token expire
+	_, err = store.PruneTokenRevocations(ctx, now.Add(2*time.Minute))
+	require.NoError(t, err)
+	list, err = store.ListTokenRevocations(ctx, now)
+	require.NoError(t, err)
+	for _, item := range list {
+		assert.False(t, item.TokenID.Valid && item.TokenID.UUID == tokenID)
+	}
+}
+
+func TestRevokeSession(t *testing.T) {
+	store := NewStore(dbpool)
+	account := createRandomAccount(t)
+	other := createRandomAccount(t)
+
+	session, err := store.CreateSession(ctx, newSessionParams(account.ID, util.RandomString(20)))
+	require.NoError(t, err)
+
+	// the session of another account can't be revoked
+	require.ErrorIs(t, store.RevokeSession(ctx, session.ID, other.ID), util.ErrNotExist)
+	require.NoError(t, store.RevokeSession(ctx, session.ID, account.ID))
+
+	revoked, err := store.GetSession(ctx, session.ID)
+	require.NoError(t, err)
+	assert.True(t, revoked.RevokedAt.Valid)
+}
@@ -0,0 +1,137 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"simple-bank-system/db/pkg"
+	"simple-bank-system/util"
+
+	"github.com/jackc/pgx/v4"
+)
+
+// Lock modes of a savings goal
+const (
+	GoalLockNone    = "none"
+	GoalLockBlock   = "block"
+	GoalLockPenalty = "penalty"
+)
+
+const BankWalletPenaltyIncome = "Penalty Income"
+
+type CreateSavingsGoalParams struct {
+	WalletID     int64
+	TargetAmount int64
+	TargetDate   time.Time
+	LockMode     string
+	PenaltyBps   int64
+}
+
+func (r *DB) CreateSavingsGoal(ctx context.Context, arg CreateSavingsGoalParams) (*pkg.SavingsGoal, error) {
+	if arg.LockMode == "" {
+		arg.LockMode = GoalLockNone
+	}
+
+	query := `INSERT INTO savings_goals(wallet_id, target_amount, target_date, lock_mode, penalty_bps
+	) VALUES (
+		$1, $2, $3, $4, $5
+	) RETURNING wallet_id, target_amount, target_date, lock_mode, penalty_bps, reached_at, created_at;`
+
+	var res pkg.SavingsGoal
+	err := r.db.QueryRow(ctx, query, arg.WalletID, arg.TargetAmount, arg.TargetDate, arg.LockMode, arg.PenaltyBps).Scan(&res.WalletID, &res.TargetAmount, &res.TargetDate, &res.LockMode, &res.PenaltyBps, &res.ReachedAt, &res.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return &res, nil
+}
+
+func (r *DB) GetSavingsGoal(ctx context.Context, walletID int64) (*pkg.SavingsGoal, error) {
+	query := `SELECT wallet_id, target_amount, target_date, lock_mode, penalty_bps, reached_at, created_at FROM savings_goals WHERE wallet_id=$1;`
+
+	var res pkg.SavingsGoal
+	err := r.db.QueryRow(ctx, query, walletID).Scan(&res.WalletID, &res.TargetAmount, &res.TargetDate, &res.LockMode, &res.PenaltyBps, &res.ReachedAt, &res.CreatedAt)
+	if err == pgx.ErrNoRows {
+		return nil, util.ErrNotExist
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &res, nil
+}
+
+// markSavingsGoalReached() set 'reached_at' when 'balance' reach the target for the first time.
+// It return the goal only when it's reached by this call, otherwise it return nil.
+func (r *DB) markSavingsGoalReached(ctx context.Context, walletID, balance int64) (*pkg.SavingsGoal, error) {
+	query := `UPDATE savings_goals SET reached_at=now() WHERE wallet_id=$1 AND reached_at IS NULL AND target_amount <= $2
+	RETURNING wallet_id, target_amount, target_date, lock_mode, penalty_bps, reached_at, created_at;`
+
+	var res pkg.SavingsGoal
+	err := r.db.QueryRow(ctx, query, walletID, balance).Scan(&res.WalletID, &res.TargetAmount, &res.TargetDate, &res.LockMode, &res.PenaltyBps, &res.ReachedAt, &res.CreatedAt)
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &res, nil
+}
+
+// GoalLocked() is true while withdrawals from the wallet are restricted by its goal.
+func GoalLocked(goal pkg.SavingsGoal, now time.Time) bool {
+	if goal.LockMode == GoalLockNone || goal.ReachedAt.Valid {
+		return false
+	}
+	return now.Before(goal.TargetDate)
+}
+
+// GoalProgress() return how much of the target is saved in percent, it's never more than 100.
+func GoalProgress(goal pkg.SavingsGoal, balance int64) float64 {
+	if balance >= goal.TargetAmount {
+		return 100
+	}
+	return float64(balance) * 100 / float64(goal.TargetAmount)
+}
+
+// Penalty that is charged on top of the withdrawn amount.
+func GoalPenalty(goal pkg.SavingsGoal, amount int64) int64 {
+	return amount * goal.PenaltyBps / 10000
+}
+
+// GoalPenaltyIncluded() return the penalty part of 'total' when the penalty is paid from it, so the withdrawn
+// amount plus its penalty is exactly 'total' (the rounding goes to the penalty).
+func GoalPenaltyIncluded(goal pkg.SavingsGoal, total int64) int64 {
+	return total - total*10000/(10000+goal.PenaltyBps)
+}
+
+// CreateSavingsGoalWalletTx() create a savings wallet together with its goal.
+func (store *Store) CreateSavingsGoalWalletTx(ctx context.Context, walletArg CreateWalletParams, goalArg CreateSavingsGoalParams) (*pkg.Wallet, *pkg.SavingsGoal, error) {
+	var wallet *pkg.Wallet
+	var goal *pkg.SavingsGoal
+
+	err := store.execTx(ctx, func(q *DB) error {
+		var err error
+
+		walletArg.Type = WalletTypeSavings
+		wallet, err = q.CreateWallet(ctx, walletArg)
+		if err != nil {
+			return err
+		}
+
+		goalArg.WalletID = wallet.ID
+		goal, err = q.CreateSavingsGoal(ctx, goalArg)
+		if err != nil {
+			return err
+		}
+
+		q.addEvent(EventWalletCreated, wallet.AccountID, wallet.ID, newWalletEvent(wallet))
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return wallet, goal, nil
+}
@@ -0,0 +1,174 @@
+package services
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"simple-bank-system/db/pkg"
+	"simple-bank-system/util"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func createRandomGoalWallet(t *testing.T, store *Store, account pkg.Account, balance int64, lockMode string) (pkg.Wallet, pkg.SavingsGoal) {
+	walletArg := CreateWalletParams{
+		WalletNumber: 1015050000,
+		Name:         util.RandomOwner(),
+		AccountID:    account.ID,
+		Balance:      balance,
+		Currency:     "IDR",
+	}
+	goalArg := CreateSavingsGoalParams{
+		TargetAmount: 1000,
+		TargetDate:   time.Now().AddDate(0, 6, 0),
+		LockMode:     lockMode,
+		PenaltyBps:   500,
+	}
+
+	wallet, goal, err := store.CreateSavingsGoalWalletTx(ctx, walletArg, goalArg)
+	require.NoError(t, err)
+	require.NotNil(t, wallet)
+	require.NotNil(t, goal)
+
+	assert.Equal(t, WalletTypeSavings, wallet.Type)
+	assert.Equal(t, wallet.ID, goal.WalletID)
+	assert.Equal(t, goalArg.TargetAmount, goal.TargetAmount)
+	assert.Equal(t, goalArg.TargetDate.Format(time.DateOnly), goal.TargetDate.Format(time.DateOnly))
+	assert.Equal(t, lockMode, goal.LockMode)
+	assert.False(t, goal.ReachedAt.Valid)
+
+	return *wallet, *goal
+}
+
+func TestGoalRules(t *testing.T) {
+	now := time.Now()
+	goal := pkg.SavingsGoal{
+		TargetAmount: 1000,
+		TargetDate:   now.AddDate(0, 1, 0),
+		LockMode:     GoalLockBlock,
+		PenaltyBps:   250,
+	}
+
+	assert.True(t, GoalLocked(goal, now))
+	assert.False(t, GoalLocked(goal, now.AddDate(0, 2, 0)), "goal is unlocked after target date")
+	assert.Equal(t, float64(25), GoalProgress(goal, 250))
+	assert.Equal(t, float64(100), GoalProgress(goal, 5000))
+	assert.Equal(t, int64(25), GoalPenalty(goal, 1000))
+	// 1025 = 1000 withdrawn + 25 penalty, the rounding goes to the penalty
+	assert.Equal(t, int64(25), GoalPenaltyIncluded(goal, 1025))
+	assert.Equal(t, int64(25), GoalPenaltyIncluded(goal, 1024))
+
+	goal.ReachedAt = sql.NullTime{Time: now, Valid: true}
+	assert.False(t, GoalLocked(goal, now), "goal is unlocked after target is reached")
+
+	goal.ReachedAt = sql.NullTime{}
+	goal.LockMode = GoalLockNone
+	assert.False(t, GoalLocked(goal, now))
+}
+
+func TestTransferFromBlockedGoalWallet(t *testing.T) {
+	store := NewStore(dbpool)
+	account := createRandomAccount(t)
+	goalWallet, _ := createRandomGoalWallet(t, store, account, 500, GoalLockBlock)
+	wallet, _ := createRandomWalletTransfer(t, account, "IDR")
+
+	_, err := store.TransferTx(ctx, TransferTxParams{
+		AccountID:        account.ID,
+		WalletID:         goalWallet.ID,
+		FromWalletNumber: goalWallet.WalletNumber,
+		ToWalletNumber:   wallet.WalletNumber,
+		Amount:           100,
+	})
+	require.ErrorIs(t, err, util.ErrWalletLocked)
+
+	unchanged, err := store.GetWallet(ctx, goalWallet.ID)
+	require.NoError(t, err)
+	assert.Equal(t, goalWallet.Balance, unchanged.Balance)
+}
+
+func TestTransferFromPenaltyGoalWallet(t *testing.T) {
+	store := NewStore(dbpool)
+	account := createRandomAccount(t)
+	goalWallet, goal := createRandomGoalWallet(t, store, account, 500, GoalLockPenalty)
+	wallet, _ := createRandomWalletTransfer(t, account, "IDR")
+
+	amount := int64(200)
+	result, err := store.TransferTx(ctx, TransferTxParams{
+		AccountID:        account.ID,
+		WalletID:         goalWallet.ID,
+		FromWalletNumber: goalWallet.WalletNumber,
+		ToWalletNumber:   wallet.WalletNumber,
+		Amount:           amount,
+	})
+	require.NoError(t, err)
+
+	penalty := GoalPenalty(goal, amount)
+	require.NotNil(t, result.Penalty)
+	assert.Equal(t, penalty, result.Penalty.Amount)
+	assert.Equal(t, goalWallet.WalletNumber, result.Penalty.FromWalletNumber)
+	assert.Equal(t, goalWallet.Balance-amount-penalty, result.FromWallet.Balance)
+	assert.Equal(t, wallet.Balance+amount, result.ToWallet.Balance)
+}
+
+func TestTransferWholePenaltyGoalWallet(t *testing.T) {
+	// closing a wallet move all its balance, the penalty is paid from it
+	store := NewStore(dbpool)
+	account := createRandomAccount(t)
+	goalWallet, goal := createRandomGoalWallet(t, store, account, 500, GoalLockPenalty)
+	wallet, _ := createRandomWalletTransfer(t, account, "IDR")
+
+	result, err := store.TransferTx(ctx, TransferTxParams{
+		AccountID:        account.ID,
+		WalletID:         goalWallet.ID,
+		FromWalletNumber: goalWallet.WalletNumber,
+		ToWalletNumber:   wallet.WalletNumber,
+		Amount:           goalWallet.Balance,
+		PenaltyIncluded:  true,
+	})
+	require.NoError(t, err)
+
+	penalty := GoalPenaltyIncluded(goal, goalWallet.Balance)
+	require.NotNil(t, result.Penalty)
+	assert.Equal(t, penalty, result.Penalty.Amount)
+	assert.Zero(t, result.FromWallet.Balance)
+	assert.Equal(t, wallet.Balance+goalWallet.Balance-penalty, result.ToWallet.Balance)
+}
+
+func TestTransferReachGoal(t *testing.T) {
+	store := NewStore(dbpool)
+	account := createRandomAccount(t)
+	goalWallet, goal := createRandomGoalWallet(t, store, account, 0, GoalLockBlock)
+	wallet := createRandomSavingsWallet(t, account, 100000)
+
+	arg := TransferTxParams{
+		AccountID:        account.ID,
+		WalletID:         wallet.ID,
+		FromWalletNumber: wallet.WalletNumber,
+		ToWalletNumber:   goalWallet.WalletNumber,
+		Amount:           goal.TargetAmount / 2,
+	}
+	result, err := store.TransferTx(ctx, arg)
+	require.NoError(t, err)
+	assert.Nil(t, result.GoalReached)
+
+	result, err = store.TransferTx(ctx, arg)
+	require.NoError(t, err)
+	require.NotNil(t, result.GoalReached)
+	assert.True(t, result.GoalReached.ReachedAt.Valid)
+
+	// reached goal doesn't lock the wallet anymore
+	saved, err := store.GetSavingsGoal(ctx, goalWallet.ID)
+	require.NoError(t, err)
+	assert.False(t, GoalLocked(*saved, time.Now()))
+
+	_, err = store.TransferTx(ctx, TransferTxParams{
+		AccountID:        account.ID,
+		WalletID:         goalWallet.ID,
+		FromWalletNumber: goalWallet.WalletNumber,
+		ToWalletNumber:   wallet.WalletNumber,
+		Amount:           goal.TargetAmount,
+	})
+	require.NoError(t, err)
+}
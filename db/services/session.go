@@ -0,0 +1,111 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"time"
+
+	"simple-bank-system/db/pkg"
+	"simple-bank-system/util"
+
+	"github.com/jackc/pgx/v4"
+)
+
+const sessionColumns = `id, account_id, refresh_token_hash, user_agent, client_ip, expires_at, revoked_at, last_used_at, created_at`
+
+func scanSession(row pgx.Row) (*pkg.Session, error) {
+	var res pkg.Session
+	err := row.Scan(&res.ID, &res.AccountID, &res.RefreshTokenHash, &res.UserAgent, &res.ClientIP, &res.ExpiresAt, &res.RevokedAt, &res.LastUsedAt, &res.CreatedAt)
+	if err == pgx.ErrNoRows {
+		return nil, util.ErrNotExist
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+type CreateSessionParams struct {
+	AccountID int64
+	// util.HashToken of the secret of the refresh token
+	RefreshTokenHash []byte
+	UserAgent        string
+	ClientIP         string
+	ExpiresAt        time.Time
+}
+
+// CreateSession() use the clock of the server for 'created_at', it's compared with 'password_change_at' that is also set by the server.
+func (r *DB) CreateSession(ctx context.Context, arg CreateSessionParams) (*pkg.Session, error) {
+	query := `INSERT INTO sessions(account_id, refresh_token_hash, user_agent, client_ip, expires_at, last_used_at, created_at)
+	VALUES ($1, $2, $3, $4, $5, $6, $6)
+	RETURNING ` + sessionColumns + `;`
+	return scanSession(r.db.QueryRow(ctx, query, arg.AccountID, arg.RefreshTokenHash, arg.UserAgent, arg.ClientIP, arg.ExpiresAt, time.Now()))
+}
+
+func (r *DB) GetSession(ctx context.Context, id int64) (*pkg.Session, error) {
+	return scanSession(r.db.QueryRow(ctx, `SELECT `+sessionColumns+` FROM sessions WHERE id=$1;`, id))
+}
+
+type RotateSessionParams struct {
+	ID int64
+	// hash of the secret that the client sent, and of the secret that replace it
+	RefreshTokenHash    []byte
+	NewRefreshTokenHash []byte
+	UserAgent           string
+	ClientIP            string
+}
+
+/*
+ * RotateSessionTx() replace the refresh token of the session when the client sent the current one.
+ * An old refresh token (already rotated) revoke the session and return util.ErrTokenReused, because
+ * someone else may have the token. Sessions that were created before the last password change are
+ * revoked too. Revoked sessions return util.ErrSessionRevoked and expired ones util.ErrTokenExpired.
+ */
+func (store *Store) RotateSessionTx(ctx context.Context, arg RotateSessionParams) (*pkg.Session, error) {
+	var res *pkg.Session
+	var revokeErr error
+
+	err := store.execTx(ctx, func(q *DB) error {
+		query := `SELECT ` + sessionColumns + ` FROM sessions WHERE id=$1 FOR UPDATE;`
+		session, err := scanSession(q.db.QueryRow(ctx, query, arg.ID))
+		if err != nil {
+			return err
+		}
+		if session.RevokedAt.Valid {
+			return util.ErrSessionRevoked
+		}
+		if time.Now().After(session.ExpiresAt) {
+			return util.ErrTokenExpired
+		}
+
+		passwordChangeAt, err := q.GetPasswordChangeAt(ctx, session.AccountID)
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case !bytes.Equal(session.RefreshTokenHash, arg.RefreshTokenHash):
+			revokeErr = util.ErrTokenReused
+		case session.CreatedAt.Before(passwordChangeAt):
+			revokeErr = util.ErrSessionRevoked
+		}
+		if revokeErr != nil {
+			// the revocation is committed, the error is returned after the transaction
+			_, err = q.db.Exec(ctx, `UPDATE sessions SET revoked_at=NOW() WHERE id=$1;`, session.ID)
+			return err
+		}
+
+		query = `UPDATE sessions SET refresh_token_hash=$2, user_agent=$3, client_ip=$4, last_used_at=NOW() WHERE id=$1
+		RETURNING ` + sessionColumns + `;`
+		res, err = scanSession(q.db.QueryRow(ctx, query, session.ID, arg.NewRefreshTokenHash, arg.UserAgent, arg.ClientIP))
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if revokeErr != nil {
+		return nil, revokeErr
+	}
+
+	return res, nil
+}
@@ -0,0 +1,80 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"simple-bank-system/util"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newSessionParams(accountID int64, secret string) CreateSessionParams {
+	return CreateSessionParams{
+		AccountID:        accountID,
+		RefreshTokenHash: util.HashToken(secret),
+		UserAgent:        "test-agent",
+		ClientIP:         "127.0.0.1",
+		ExpiresAt:        time.Now().Add(time.Hour),
+	}
+}
+
+func TestRotateSession(t *testing.T) {
+	store := NewStore(dbpool)
+	account := createRandomAccount(t)
+
+	session, err := store.CreateSession(ctx, newSessionParams(account.ID, "first"))
+	require.NoError(t, err)
+	assert.Equal(t, account.ID, session.AccountID)
+	assert.Equal(t, "test-agent", session.UserAgent)
+	assert.False(t, session.RevokedAt.Valid)
+
+	rotated, err := store.RotateSessionTx(ctx, RotateSessionParams{
+		ID:                  session.ID,
+		RefreshTokenHash:    util.HashToken("first"),
+		NewRefreshTokenHash: util.HashToken("second"),
+		UserAgent:           "new-agent",
+		ClientIP:            "10.0.0.1",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, util.HashToken("second"), rotated.RefreshTokenHash)
+	assert.Equal(t, "10.0.0.1", rotated.ClientIP)
+
+	// the first token is used again, the session is revoked for everyone
+	_, err = store.RotateSessionTx(ctx, RotateSessionParams{ID: session.ID, RefreshTokenHash: util.HashToken("first"), NewRefreshTokenHash: util.HashToken("third")})
+	require.ErrorIs(t, err, util.ErrTokenReused)
+
+	_, err = store.RotateSessionTx(ctx, RotateSessionParams{ID: session.ID, RefreshTokenHash: util.HashToken("second"), NewRefreshTokenHash: util.HashToken("third")})
+	require.ErrorIs(t, err, util.ErrSessionRevoked)
+
+	revoked, err := store.GetSession(ctx, session.ID)
+	require.NoError(t, err)
+	assert.True(t, revoked.RevokedAt.Valid)
+}
+
+func TestRotateSessionAfterPasswordChange(t *testing.T) {
+	store := NewStore(dbpool)
+	account := createRandomAccount(t)
+
+	session, err := store.CreateSession(ctx, newSessionParams(account.ID, "first"))
+	require.NoError(t, err)
+
+	require.NoError(t, store.UpdatePassword(ctx, UpdatePasswordParams{AccountID: account.ID, Password: "newsecret", ChangedAt: time.Now()}))
+
+	_, err = store.RotateSessionTx(ctx, RotateSessionParams{ID: session.ID, RefreshTokenHash: util.HashToken("first"), NewRefreshTokenHash: util.HashToken("second")})
+	require.ErrorIs(t, err, util.ErrSessionRevoked)
+
+	// sessions of the new password can be refreshed
+	session, err = store.CreateSession(ctx, newSessionParams(account.ID, "new"))
+	require.NoError(t, err)
+	_, err = store.RotateSessionTx(ctx, RotateSessionParams{ID: session.ID, RefreshTokenHash: util.HashToken("new"), NewRefreshTokenHash: util.HashToken("second")})
+	require.NoError(t, err)
+
+	arg := newSessionParams(account.ID, "expired")
+	arg.ExpiresAt = time.Now().Add(-time.Minute)
+	session, err = store.CreateSession(ctx, arg)
+	require.NoError(t, err)
+	_, err = store.RotateSessionTx(ctx, RotateSessionParams{ID: session.ID, RefreshTokenHash: util.HashToken("expired"), NewRefreshTokenHash: util.HashToken("second")})
+	require.ErrorIs(t, err, util.ErrTokenExpired)
+}
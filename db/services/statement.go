@@ -0,0 +1,167 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"simple-bank-system/db/pkg"
+	"simple-bank-system/statement"
+
+	"github.com/jackc/pgx/v4"
+)
+
+// BalanceAt() return the balance of the wallet at 't', it's the current balance minus all entries created since 't'.
+func (r *DB) BalanceAt(ctx context.Context, wallet pkg.Wallet, t time.Time) (int64, error) {
+	query := `SELECT COALESCE(SUM(amount), 0) FROM entries WHERE wallet_id=$1 AND created_at >= $2 AND deleted_at IS NULL;`
+
+	var since int64
+	err := r.db.QueryRow(ctx, query, wallet.ID, t).Scan(&since)
+	if err != nil {
+		return 0, err
+	}
+
+	return wallet.Balance - since, nil
+}
+
+// statementPageSize is the number of entries read by each query of 'StreamStatement'.
+var statementPageSize = 500
+
+type StreamStatementParams struct {
+	WalletID int64
+	// 'From' inclusive, 'To' exclusive
+	From time.Time
+	To   time.Time
+	// the entries created after the snapshot of the balances are left out, 0 keep every entry
+	MaxEntryID     int64
+	OpeningBalance int64
+}
+
+/*
+ * StreamStatement() call 'fn' for every entry of the wallet from 'from' (inclusive) until 'to' (exclusive)
+ * in the order they were created, together with the counterparty of its transfer and the balance after it.
+ * The entries are read by pages of 'statementPageSize' (keyset on created_at and id), so large ranges aren't
+ * loaded into memory, and 'fn' is only called between the queries so no query stay open while it write.
+ */
+func (r *DB) StreamStatement(ctx context.Context, arg StreamStatementParams, fn func(statement.Line) error) error {
+	query := `SELECT e.id, e.amount, e.created_at, t.id, counterparty.wallet_number, a.full_name
+	FROM entries e
+	LEFT JOIN transfers t ON t.id = e.transfer_id
+	LEFT JOIN wallets counterparty ON counterparty.wallet_number = CASE WHEN e.amount < 0 THEN t.to_wallet_number ELSE t.from_wallet_number END
+	LEFT JOIN accounts a ON a.id = counterparty.account_id
+	WHERE e.wallet_id=$1 AND e.created_at >= $2 AND e.created_at < $3 AND e.deleted_at IS NULL
+	AND ($4::bigint = 0 OR e.id <= $4) AND (e.created_at, e.id) > ($5, $6)
+	ORDER BY e.created_at, e.id
+	LIMIT $7;`
+
+	balance := arg.OpeningBalance
+	// the entry ids start at 1, the first page has every entry of 'From'
+	afterCreatedAt, afterID := arg.From, int64(0)
+	for {
+		lines, err := r.statementPage(ctx, query, arg, afterCreatedAt, afterID)
+		if err != nil {
+			return err
+		}
+
+		for _, line := range lines {
+			balance += line.Amount
+			line.Balance = balance
+			if err = fn(line); err != nil {
+				return err
+			}
+		}
+		if len(lines) < statementPageSize {
+			return nil
+		}
+		last := lines[len(lines)-1]
+		afterCreatedAt, afterID = last.Date, last.EntryID
+	}
+}
+
+// statementPage read 1 page of 'StreamStatement', the rows are closed before the lines are written.
+func (r *DB) statementPage(ctx context.Context, query string, arg StreamStatementParams, afterCreatedAt time.Time, afterID int64) ([]statement.Line, error) {
+	rows, err := r.db.Query(ctx, query, arg.WalletID, arg.From, arg.To, arg.MaxEntryID, afterCreatedAt, afterID, statementPageSize)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var lines []statement.Line
+	for rows.Next() {
+		var line statement.Line
+		var transferID, counterpartyNumber sql.NullInt64
+		var counterpartyName sql.NullString
+		err = rows.Scan(&line.EntryID, &line.Amount, &line.Date, &transferID, &counterpartyNumber, &counterpartyName)
+		if err != nil {
+			return nil, err
+		}
+
+		line.TransferID = transferID.Int64
+		line.CounterpartyWalletNumber = counterpartyNumber.Int64
+		if counterpartyName.Valid {
+			line.CounterpartyName = MaskName(counterpartyName.String)
+		}
+		lines = append(lines, line)
+	}
+
+	return lines, rows.Err()
+}
+
+type StatementTxParams struct {
+	WalletID int64
+	// 'From' inclusive, 'To' exclusive
+	From time.Time
+	To   time.Time
+	// called with both balances before the first line
+	WriteHeader func(openingBalance, closingBalance int64) error
+	WriteLine   func(statement.Line) error
+}
+
+/*
+ * StatementTx() read both balances and the last entry id in 1 REPEATABLE READ transaction, then the
+ * transaction end and the entries are streamed by pages up to that id. So the closing balance is the
+ * opening balance plus the streamed entries, a transfer committed meanwhile isn't in the statement, and
+ * a slow client never keep a transaction open.
+ */
+func (store *Store) StatementTx(ctx context.Context, arg StatementTxParams) error {
+	tx, err := store.db.BeginTx(ctx, pgx.TxOptions{IsoLevel: pgx.RepeatableRead, AccessMode: pgx.ReadOnly})
+	if err != nil {
+		return err
+	}
+	// nothing to commit
+	defer tx.Rollback(ctx)
+
+	q := NewDB(tx)
+	wallet, err := q.GetWallet(ctx, arg.WalletID)
+	if err != nil {
+		return err
+	}
+	opening, err := q.BalanceAt(ctx, *wallet, arg.From)
+	if err != nil {
+		return err
+	}
+	closing, err := q.BalanceAt(ctx, *wallet, arg.To)
+	if err != nil {
+		return err
+	}
+	var maxEntryID int64
+	err = tx.QueryRow(ctx, `SELECT COALESCE(MAX(id), 0) FROM entries;`).Scan(&maxEntryID)
+	if err != nil {
+		return err
+	}
+	if err = tx.Rollback(ctx); err != nil {
+		return err
+	}
+
+	err = arg.WriteHeader(opening, closing)
+	if err != nil {
+		return err
+	}
+	return store.StreamStatement(ctx, StreamStatementParams{
+		WalletID:       wallet.ID,
+		From:           arg.From,
+		To:             arg.To,
+		MaxEntryID:     maxEntryID,
+		OpeningBalance: opening,
+	}, arg.WriteLine)
+}
@@ -0,0 +1,115 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"simple-bank-system/statement"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStreamStatement(t *testing.T) {
+	store := NewStore(dbpool)
+	account := createRandomAccount(t)
+	recipient := createRandomAccount(t)
+	wallet := createRandomSavingsWallet(t, account, 1000)
+	toWallet := createRandomSavingsWallet(t, recipient, 0)
+
+	from := time.Now().Add(-time.Minute)
+	for i := 0; i < 3; i++ {
+		_, err := store.TransferTx(ctx, TransferTxParams{
+			AccountID:        account.ID,
+			WalletID:         wallet.ID,
+			FromWalletNumber: wallet.WalletNumber,
+			ToWalletNumber:   toWallet.WalletNumber,
+			Amount:           100,
+		})
+		require.NoError(t, err)
+	}
+	to := time.Now().Add(time.Minute)
+
+	current, err := store.GetWallet(ctx, wallet.ID)
+	require.NoError(t, err)
+
+	opening, err := store.BalanceAt(ctx, *current, from)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1000), opening)
+	closing, err := store.BalanceAt(ctx, *current, to)
+	require.NoError(t, err)
+	assert.Equal(t, int64(700), closing)
+
+	// 2 pages
+	statementPageSize = 2
+	defer func() { statementPageSize = 500 }()
+
+	var lines []statement.Line
+	err = store.StreamStatement(ctx, StreamStatementParams{WalletID: wallet.ID, From: from, To: to, OpeningBalance: opening}, func(line statement.Line) error {
+		lines = append(lines, line)
+		return nil
+	})
+	require.NoError(t, err)
+	require.Len(t, lines, 3)
+
+	for i, line := range lines {
+		assert.Equal(t, int64(-100), line.Amount)
+		assert.Equal(t, opening-int64(100*(i+1)), line.Balance)
+		assert.NotZero(t, line.TransferID)
+		assert.Equal(t, toWallet.WalletNumber, line.CounterpartyWalletNumber)
+		assert.Equal(t, MaskName(recipient.FullName), line.CounterpartyName)
+	}
+	assert.Equal(t, closing, lines[2].Balance)
+}
+
+func TestStatementTx(t *testing.T) {
+	store := NewStore(dbpool)
+	account := createRandomAccount(t)
+	recipient := createRandomAccount(t)
+	wallet := createRandomSavingsWallet(t, account, 1000)
+	toWallet := createRandomSavingsWallet(t, recipient, 0)
+
+	from := time.Now().Add(-time.Minute)
+	for i := 0; i < 2; i++ {
+		_, err := store.TransferTx(ctx, TransferTxParams{
+			AccountID:        account.ID,
+			WalletID:         wallet.ID,
+			FromWalletNumber: wallet.WalletNumber,
+			ToWalletNumber:   toWallet.WalletNumber,
+			Amount:           100,
+		})
+		require.NoError(t, err)
+	}
+	to := time.Now().Add(time.Minute)
+
+	// a transfer between every page
+	statementPageSize = 1
+	defer func() { statementPageSize = 500 }()
+
+	var opening, closing, sum int64
+	err := store.StatementTx(ctx, StatementTxParams{
+		WalletID: wallet.ID,
+		From:     from,
+		To:       to,
+		WriteHeader: func(openingBalance, closingBalance int64) error {
+			opening, closing = openingBalance, closingBalance
+			return nil
+		},
+		WriteLine: func(line statement.Line) error {
+			sum += line.Amount
+			// a transfer that commit while the statement is read isn't in its snapshot
+			_, err := store.TransferTx(ctx, TransferTxParams{
+				AccountID:        account.ID,
+				WalletID:         wallet.ID,
+				FromWalletNumber: wallet.WalletNumber,
+				ToWalletNumber:   toWallet.WalletNumber,
+				Amount:           10,
+			})
+			return err
+		},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1000), opening)
+	assert.Equal(t, int64(800), closing)
+	assert.Equal(t, closing, opening+sum)
+}
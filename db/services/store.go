@@ -11,8 +11,10 @@ package services
 import (
 	"context"
 	"fmt"
-	"log"
+	"time"
+
 	"simple-bank-system/db/pkg"
+	"simple-bank-system/util"
 
 	"github.com/jackc/pgx/v4"
 	"github.com/jackc/pgx/v4/pgxpool"
@@ -46,6 +48,10 @@ func (store *Store) execTx(ctx context.Context, fn func(*DB) error) error {
 
 	q := NewDB(tx)
 	err = fn(q)
+	if err == nil {
+		// the events are only committed together with the change they describe
+		err = q.writeOutbox(ctx)
+	}
 	if err != nil {
 		if rbErr := tx.Rollback(ctx); rbErr != nil {
 			return fmt.Errorf("tx rr = %s, and rb err = %s", err, rbErr)
@@ -62,6 +68,9 @@ type TransferTxParams struct {
 	FromWalletNumber int64
 	ToWalletNumber   int64
 	Amount           int64
+	// 'Amount' already include the early withdrawal penalty (closing a wallet move its whole balance),
+	// the penalty is taken from it instead of being charged on top
+	PenaltyIncluded bool
 }
 type TransferTXResult struct {
 	Transfer   *pkg.Transfers
@@ -69,6 +78,10 @@ type TransferTXResult struct {
 	ToWallet   *pkg.Wallet
 	FromEntry  *pkg.Entry
 	ToEntry    *pkg.Entry
+	// Transfer of the early withdrawal penalty when 'FromWallet' has a goal that is locked with penalty
+	Penalty *pkg.Transfers
+	// Goal of 'ToWallet' when this transfer make it reached
+	GoalReached *pkg.SavingsGoal
 }
 
 //var txKey = struct{}{}
@@ -77,82 +90,162 @@ func (store *Store) TransferTx(ctx context.Context, arg TransferTxParams) (*Tran
 	var result TransferTXResult
 
 	err := store.execTx(ctx, func(q *DB) error {
-		var err error
+		return transferTx(ctx, q, arg, &result)
+	})
 
-		//txName := ctx.Value(txKey)
+	return &result, err
+}
 
-		//fmt.Println(txName, "create transfer")
-		//fmt.Println("(input) Transfer Tx Params:", arg)
-		result.Transfer, err = q.CreateTransfer(ctx, CreateTransferParam{
+// transferTx is the body of 'TransferTx', it's separated so other transactions that move money
+// (e.g. interest posting) can create the transfer record, entries and balance update inside their own
+// db transaction. It also apply the savings goal rules of both wallets.
+func transferTx(ctx context.Context, q *DB, arg TransferTxParams, result *TransferTXResult) error {
+	var penalty int64
+
+	// a frozen wallet can still receive money, but can't send it
+	frozen, err := q.IsWalletFrozen(ctx, arg.WalletID)
+	if err != nil {
+		return err
+	}
+	if frozen {
+		return util.ErrWalletFrozen
+	}
+
+	goal, err := q.GetSavingsGoal(ctx, arg.WalletID)
+	if err != nil && err != util.ErrNotExist {
+		return err
+	}
+	if goal != nil && GoalLocked(*goal, time.Now()) {
+		if goal.LockMode == GoalLockBlock {
+			return util.ErrWalletLocked
+		}
+		if arg.PenaltyIncluded {
+			penalty = GoalPenaltyIncluded(*goal, arg.Amount)
+			arg.Amount -= penalty
+		} else {
+			penalty = GoalPenalty(*goal, arg.Amount)
+		}
+	}
+
+	err = moveMoney(ctx, q, arg, result)
+	if err != nil {
+		return err
+	}
+
+	if penalty > 0 {
+		penaltyWallet, err := q.GetBankWallet(ctx, BankWalletPenaltyIncome, result.FromWallet.Currency)
+		if err != nil {
+			return err
+		}
+
+		var penaltyResult TransferTXResult
+		err = moveMoney(ctx, q, TransferTxParams{
 			AccountID:        arg.AccountID,
 			WalletID:         arg.WalletID,
 			FromWalletNumber: arg.FromWalletNumber,
-			ToWalletNumber:   arg.ToWalletNumber,
-			Amount:           arg.Amount,
-		})
+			ToWalletNumber:   penaltyWallet.WalletNumber,
+			Amount:           penalty,
+		}, &penaltyResult)
 		if err != nil {
-			log.Println("--(err) 1")
 			return err
 		}
+		result.Penalty = penaltyResult.Transfer
+		result.FromWallet = penaltyResult.FromWallet
+	}
 
-		//fmt.Println(txName, "create entry 1")
-		result.FromEntry, err = q.CreateEntry(ctx, CreateEntryParam{
-			accountID:    arg.AccountID,
-			walletID:     arg.WalletID,
-			walletNumber: arg.FromWalletNumber,
-			amount:       -arg.Amount,
+	result.GoalReached, err = q.markSavingsGoalReached(ctx, result.ToWallet.ID, result.ToWallet.Balance)
+	if err != nil {
+		return err
+	}
+	if result.GoalReached != nil {
+		q.addEvent(EventSavingsGoalReached, result.ToWallet.AccountID, result.ToWallet.ID, SavingsGoalEvent{
+			WalletNumber: result.ToWallet.WalletNumber,
+			TargetAmount: result.GoalReached.TargetAmount,
+			TargetDate:   result.GoalReached.TargetDate.Format(time.DateOnly),
+			Balance:      result.ToWallet.Balance,
+			Time:         result.GoalReached.ReachedAt.Time,
 		})
-		if err != nil {
-			log.Println("--(err) 2")
-			return err
-		}
+	}
+
+	return nil
+}
+
+// moveMoney create the transfer record and both entries, then update balance of both wallets.
+func moveMoney(ctx context.Context, q *DB, arg TransferTxParams, result *TransferTXResult) error {
+	var err error
 
-		toWallet, err := q.GetWalletByNumber(ctx, arg.ToWalletNumber)
+	result.Transfer, err = q.CreateTransfer(ctx, CreateTransferParam{
+		AccountID:        arg.AccountID,
+		WalletID:         arg.WalletID,
+		FromWalletNumber: arg.FromWalletNumber,
+		ToWalletNumber:   arg.ToWalletNumber,
+		Amount:           arg.Amount,
+	})
+	if err != nil {
+		return err
+	}
+
+	result.FromEntry, err = q.CreateEntry(ctx, CreateEntryParam{
+		accountID:    arg.AccountID,
+		walletID:     arg.WalletID,
+		walletNumber: arg.FromWalletNumber,
+		amount:       -arg.Amount,
+		transferID:   result.Transfer.ID,
+	})
+	if err != nil {
+		return err
+	}
+
+	toWallet, err := q.GetWalletByNumber(ctx, arg.ToWalletNumber)
+	if err != nil {
+		return err
+	}
+	result.ToEntry, err = q.CreateEntry(ctx, CreateEntryParam{
+		accountID:    toWallet.AccountID,
+		walletID:     toWallet.ID,
+		walletNumber: arg.ToWalletNumber,
+		amount:       arg.Amount,
+		transferID:   result.Transfer.ID,
+	})
+	if err != nil {
+		return err
+	}
+
+	// From Account
+	// 1. GetAccount to get the balance, 2. Update the balance
+	// 3. GetAccount again to get updated balance
+	// account1, err := q.GetAccountForUpdate(ctx, arg.FromWalletID)
+
+	/*
+	 * To avoid deadlock, I make the wallet with the smaller wallet_number to update first
+	 */
+
+	if arg.FromWalletNumber < arg.ToWalletNumber {
+		result.FromWallet, result.ToWallet, err = AddMoney(ctx, q, arg.FromWalletNumber, -arg.Amount, arg.ToWalletNumber, arg.Amount)
 		if err != nil {
-			log.Println("--(err) 3")
 			return err
 		}
-		//fmt.Println(txName, "create entry 2")
-		result.ToEntry, err = q.CreateEntry(ctx, CreateEntryParam{
-			accountID:    toWallet.AccountID,
-			walletID:     toWallet.ID,
-			walletNumber: arg.ToWalletNumber,
-			amount:       arg.Amount,
-		})
+	} else {
+		result.ToWallet, result.FromWallet, err = AddMoney(ctx, q, arg.ToWalletNumber, arg.Amount, arg.FromWalletNumber, -arg.Amount)
 		if err != nil {
-			log.Println("--(err) 4")
 			return err
 		}
+	}
 
-		// From Account
-		// 1. GetAccount to get the balance, 2. Update the balance
-		// 3. GetAccount again to get updated balance
-		// account1, err := q.GetAccountForUpdate(ctx, arg.FromWalletID)
-
-		/*
-		 * To avoid deadlock, I make the wallet with the smaller wallet_number to update first
-		 */
-
-		if arg.FromWalletNumber < arg.ToWalletNumber {
-			//fmt.Println(txName, "update account 1")
-			result.FromWallet, result.ToWallet, err = AddMoney(ctx, q, arg.FromWalletNumber, -arg.Amount, arg.ToWalletNumber, arg.Amount)
-			if err != nil {
-				log.Println("--(err) 5:", err)
-				return err
-			}
-		} else {
-			//fmt.Println(txName, "update account 2")
-			result.ToWallet, result.FromWallet, err = AddMoney(ctx, q, arg.ToWalletNumber, arg.Amount, arg.FromWalletNumber, -arg.Amount)
-			if err != nil {
-				log.Println("--(err) 6:", err)
-				return err
-			}
-		}
-
-		return nil
-	})
+	event := TransferEvent{
+		TransferID:       result.Transfer.ID,
+		FromWalletNumber: arg.FromWalletNumber,
+		ToWalletNumber:   arg.ToWalletNumber,
+		Amount:           -arg.Amount,
+		Currency:         result.FromWallet.Currency,
+		Balance:          result.FromWallet.Balance,
+		Time:             result.Transfer.CreatedAt,
+	}
+	q.addEvent(EventTransferCompleted, result.FromWallet.AccountID, result.FromWallet.ID, event)
+	event.Amount, event.Balance = arg.Amount, result.ToWallet.Balance
+	q.addEvent(EventTransferCompleted, result.ToWallet.AccountID, result.ToWallet.ID, event)
 
-	return &result, err
+	return nil
 }
 
 func AddMoney(ctx context.Context, q *DB, walletNumb1, amount1, walletNumb2, amount2 int64) (wallet1 *pkg.Wallet, wallet2 *pkg.Wallet, err error) {
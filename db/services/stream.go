@@ -0,0 +1,139 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"simple-bank-system/db/pkg"
+
+	"github.com/jackc/pgx/v4"
+)
+
+// Postgres channel that is notified when outbox events are committed
+const OutboxChannel = "outbox_events"
+
+// Outbox events that are sent on the wallet stream
+var walletStreamEvents = []string{EventTransferCompleted, EventWalletCreated, EventWalletClosed, EventSavingsGoalReached, EventWalletFrozen, EventWalletUnfrozen}
+
+// WalletStreamEvent is an outbox event of a wallet that the account can see.
+type WalletStreamEvent struct {
+	ID           int64
+	Type         string
+	WalletNumber int64
+	Payload      []byte
+	CreatedAt    time.Time
+}
+
+// Wallets of the account: owned wallets and wallets where it's an active member
+const streamWalletsCondition = `(w.account_id = $1 OR EXISTS (
+	SELECT 1 FROM wallet_members m WHERE m.wallet_id = w.id AND m.account_id = $1 AND m.status = 'active'
+))`
+
+// Events of transactions that can still be running are not sent yet, see migration 000013
+const streamCommittedCondition = `o.xid < pg_snapshot_xmin(pg_current_snapshot())`
+
+/*
+ * ListWalletStreamEvents() return up to 'limit' events of the account's wallets after the event 'afterID'
+ * (0 is the start) in stream order. An event is only returned once all transactions that started before it
+ * are finished, so the events that are returned later are always after the returned ones.
+ */
+func (r *DB) ListWalletStreamEvents(ctx context.Context, accountID, afterID int64, limit int) ([]WalletStreamEvent, error) {
+	query := `SELECT o.id, o.event_type, w.wallet_number, o.payload, o.created_at
+	FROM outbox_events o
+	JOIN wallets w ON w.id = o.wallet_id
+	WHERE ` + streamWalletsCondition + ` AND o.event_type = ANY($2) AND ` + streamCommittedCondition + `
+	AND ($3 = 0 OR (o.xid, o.id) > (SELECT xid, id FROM outbox_events WHERE id = $3))
+	ORDER BY o.xid, o.id
+	LIMIT $4;`
+
+	rows, err := r.db.Query(ctx, query, accountID, walletStreamEvents, afterID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []WalletStreamEvent
+	for rows.Next() {
+		var event WalletStreamEvent
+		err = rows.Scan(&event.ID, &event.Type, &event.WalletNumber, &event.Payload, &event.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, event)
+	}
+
+	return list, rows.Err()
+}
+
+// LastWalletStreamEventID() return the last event of the account's wallets that can be sent, 0 when there are none.
+func (r *DB) LastWalletStreamEventID(ctx context.Context, accountID int64) (int64, error) {
+	query := `SELECT o.id
+	FROM outbox_events o
+	JOIN wallets w ON w.id = o.wallet_id
+	WHERE ` + streamWalletsCondition + ` AND o.event_type = ANY($2) AND ` + streamCommittedCondition + `
+	ORDER BY o.xid DESC, o.id DESC
+	LIMIT 1;`
+
+	var id int64
+	err := r.db.QueryRow(ctx, query, accountID, walletStreamEvents).Scan(&id)
+	if err == pgx.ErrNoRows {
+		return 0, nil
+	}
+	return id, err
+}
+
+// OutboxEventExists() is used to check the event id that a stream resume from.
+func (r *DB) OutboxEventExists(ctx context.Context, id int64) (bool, error) {
+	var exists bool
+	err := r.db.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM outbox_events WHERE id=$1);`, id).Scan(&exists)
+	return exists, err
+}
+
+// ListStreamWallets() return the open wallets that the account can see, it's the first state of a new stream.
+func (r *DB) ListStreamWallets(ctx context.Context, accountID int64) ([]pkg.Wallet, error) {
+	query := `SELECT w.id, w.name, w.account_id, w.wallet_number, w.balance, w.currency, w.wallet_type, w.created_at, w.deleted_at
+	FROM wallets w
+	WHERE ` + streamWalletsCondition + ` AND w.deleted_at IS NULL
+	ORDER BY w.id;`
+
+	rows, err := r.db.Query(ctx, query, accountID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []pkg.Wallet
+	for rows.Next() {
+		var wallet pkg.Wallet
+		err = rows.Scan(&wallet.ID, &wallet.Name, &wallet.AccountID, &wallet.WalletNumber, &wallet.Balance, &wallet.Currency, &wallet.Type, &wallet.CreatedAt, &wallet.DeletedAt)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, wallet)
+	}
+
+	return list, rows.Err()
+}
+
+// ListenOutbox() hold 1 connection of the pool that LISTEN on 'OutboxChannel' and call 'notify' for every
+// notification, it return when the context is done or the connection fails.
+func (store *Store) ListenOutbox(ctx context.Context, notify func()) error {
+	conn, err := store.db.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	_, err = conn.Exec(ctx, `LISTEN `+OutboxChannel+`;`)
+	if err != nil {
+		return err
+	}
+
+	for {
+		_, err = conn.Conn().WaitForNotification(ctx)
+		if err != nil {
+			return err
+		}
+		notify()
+	}
+}
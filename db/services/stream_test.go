@@ -0,0 +1,67 @@
+package services
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWalletStreamEvents(t *testing.T) {
+	store := NewStore(dbpool)
+	account := createRandomAccount(t)
+	recipient := createRandomAccount(t)
+	wallet := createRandomSavingsWallet(t, account, 1000)
+	toWallet := createRandomSavingsWallet(t, recipient, 0)
+
+	start, err := store.LastWalletStreamEventID(ctx, account.ID)
+	require.NoError(t, err)
+
+	for i := 0; i < 3; i++ {
+		_, err := store.TransferTx(ctx, TransferTxParams{
+			AccountID:        account.ID,
+			WalletID:         wallet.ID,
+			FromWalletNumber: wallet.WalletNumber,
+			ToWalletNumber:   toWallet.WalletNumber,
+			Amount:           100,
+		})
+		require.NoError(t, err)
+	}
+
+	// the account only get the events of its wallet
+	events, err := store.ListWalletStreamEvents(ctx, account.ID, start, 10)
+	require.NoError(t, err)
+	require.Len(t, events, 3)
+	for i, event := range events {
+		assert.Equal(t, EventTransferCompleted, event.Type)
+		assert.Equal(t, wallet.WalletNumber, event.WalletNumber)
+
+		var payload TransferEvent
+		require.NoError(t, json.Unmarshal(event.Payload, &payload))
+		assert.Equal(t, int64(900-100*i), payload.Balance)
+	}
+
+	last, err := store.LastWalletStreamEventID(ctx, account.ID)
+	require.NoError(t, err)
+	assert.Equal(t, events[2].ID, last)
+
+	// resume after the first event
+	resumed, err := store.ListWalletStreamEvents(ctx, account.ID, events[0].ID, 10)
+	require.NoError(t, err)
+	require.Len(t, resumed, 2)
+	assert.Equal(t, events[1:], resumed)
+
+	exists, err := store.OutboxEventExists(ctx, last)
+	require.NoError(t, err)
+	assert.True(t, exists)
+
+	wallets, err := store.ListStreamWallets(ctx, recipient.ID)
+	require.NoError(t, err)
+	require.NotEmpty(t, wallets)
+	for _, w := range wallets {
+		if w.ID == toWallet.ID {
+			assert.Equal(t, int64(300), w.Balance)
+		}
+	}
+}
@@ -0,0 +1,195 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"simple-bank-system/db/pkg"
+	"simple-bank-system/util"
+
+	"github.com/jackc/pgx/v4"
+)
+
+const accountTOTPColumns = `account_id, secret, enabled_at, last_used_step, created_at`
+
+// GetAccountTOTP() return util.ErrNotExist when the account never enrolled.
+func (r *DB) GetAccountTOTP(ctx context.Context, accountID int64) (*pkg.AccountTOTP, error) {
+	var res pkg.AccountTOTP
+	err := r.db.QueryRow(ctx, `SELECT `+accountTOTPColumns+` FROM account_totp WHERE account_id=$1;`, accountID).
+		Scan(&res.AccountID, &res.Secret, &res.EnabledAt, &res.LastUsedStep, &res.CreatedAt)
+	if err == pgx.ErrNoRows {
+		return nil, util.ErrNotExist
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+// CreateAccountTOTP() save a pending secret, it replace the pending one. It return util.ErrDuplicate when 2FA is already enabled.
+func (r *DB) CreateAccountTOTP(ctx context.Context, accountID int64, secret string) (*pkg.AccountTOTP, error) {
+	query := `INSERT INTO account_totp(account_id, secret) VALUES ($1, $2)
+	ON CONFLICT (account_id) DO UPDATE SET secret=EXCLUDED.secret, last_used_step=NULL, created_at=NOW()
+	WHERE account_totp.enabled_at IS NULL
+	RETURNING ` + accountTOTPColumns + `;`
+
+	var res pkg.AccountTOTP
+	err := r.db.QueryRow(ctx, query, accountID, secret).
+		Scan(&res.AccountID, &res.Secret, &res.EnabledAt, &res.LastUsedStep, &res.CreatedAt)
+	if err == pgx.ErrNoRows {
+		return nil, util.ErrDuplicate
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+// UseTOTPStep() save the step of an accepted code, it return util.ErrCodeReused when a code of this step or a newer one was used.
+func (r *DB) UseTOTPStep(ctx context.Context, accountID, step int64) error {
+	query := `UPDATE account_totp SET last_used_step=$2
+	WHERE account_id=$1 AND (last_used_step IS NULL OR last_used_step < $2);`
+	res, err := r.db.Exec(ctx, query, accountID, step)
+	if err != nil {
+		return err
+	}
+	if res.RowsAffected() == 0 {
+		return util.ErrCodeReused
+	}
+	return nil
+}
+
+type EnableTOTPParams struct {
+	AccountID int64
+	// step of the confirmation code
+	Step int64
+	// util.HashToken of the recovery codes
+	RecoveryCodeHashes [][]byte
+}
+
+// EnableTOTPTx() enable the pending secret and save the recovery codes, it return util.ErrNotExist when no secret is pending.
+func (store *Store) EnableTOTPTx(ctx context.Context, arg EnableTOTPParams) error {
+	return store.execTx(ctx, func(q *DB) error {
+		query := `UPDATE account_totp SET enabled_at=NOW(), last_used_step=$2
+		WHERE account_id=$1 AND enabled_at IS NULL;`
+		res, err := q.db.Exec(ctx, query, arg.AccountID, arg.Step)
+		if err != nil {
+			return err
+		}
+		if res.RowsAffected() == 0 {
+			return util.ErrNotExist
+		}
+
+		return q.replaceRecoveryCodes(ctx, arg.AccountID, arg.RecoveryCodeHashes)
+	})
+}
+
+// DisableTOTPTx() delete the secret and the recovery codes, it return util.ErrNotExist when 2FA isn't enabled.
+func (store *Store) DisableTOTPTx(ctx context.Context, accountID int64) error {
+	return store.execTx(ctx, func(q *DB) error {
+		res, err := q.db.Exec(ctx, `DELETE FROM account_totp WHERE account_id=$1 AND enabled_at IS NOT NULL;`, accountID)
+		if err != nil {
+			return err
+		}
+		if res.RowsAffected() == 0 {
+			return util.ErrNotExist
+		}
+
+		_, err = q.db.Exec(ctx, `DELETE FROM recovery_codes WHERE account_id=$1;`, accountID)
+		return err
+	})
+}
+
+// ReplaceRecoveryCodesTx() replace every recovery code of the account, the old ones can't be used anymore.
+func (store *Store) ReplaceRecoveryCodesTx(ctx context.Context, accountID int64, codeHashes [][]byte) error {
+	return store.execTx(ctx, func(q *DB) error {
+		return q.replaceRecoveryCodes(ctx, accountID, codeHashes)
+	})
+}
+
+func (r *DB) replaceRecoveryCodes(ctx context.Context, accountID int64, codeHashes [][]byte) error {
+	_, err := r.db.Exec(ctx, `DELETE FROM recovery_codes WHERE account_id=$1;`, accountID)
+	if err != nil {
+		return err
+	}
+
+	for _, hash := range codeHashes {
+		_, err = r.db.Exec(ctx, `INSERT INTO recovery_codes(account_id, code_hash) VALUES ($1, $2);`, accountID, hash)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// UseRecoveryCode() use 1 recovery code, it return util.ErrNotExist when the code is wrong or already used.
+func (r *DB) UseRecoveryCode(ctx context.Context, accountID int64, codeHash []byte) error {
+	query := `UPDATE recovery_codes SET used_at=NOW() WHERE account_id=$1 AND code_hash=$2 AND used_at IS NULL;`
+	res, err := r.db.Exec(ctx, query, accountID, codeHash)
+	if err != nil {
+		return err
+	}
+	if res.RowsAffected() == 0 {
+		return util.ErrNotExist
+	}
+	return nil
+}
+
+// CountRecoveryCodes() return the number of recovery codes that aren't used.
+func (r *DB) CountRecoveryCodes(ctx context.Context, accountID int64) (int, error) {
+	var count int
+	err := r.db.QueryRow(ctx, `SELECT COUNT(*) FROM recovery_codes WHERE account_id=$1 AND used_at IS NULL;`, accountID).Scan(&count)
+	return count, err
+}
+
+type CreateLoginChallengeParams struct {
+	AccountID int64
+	// util.HashToken of the challenge token
+	TokenHash []byte
+	ExpiresAt time.Time
+}
+
+const loginChallengeColumns = `id, account_id, token_hash, expires_at, attempts, used_at, created_at`
+
+func scanLoginChallenge(row pgx.Row) (*pkg.LoginChallenge, error) {
+	var res pkg.LoginChallenge
+	err := row.Scan(&res.ID, &res.AccountID, &res.TokenHash, &res.ExpiresAt, &res.Attempts, &res.UsedAt, &res.CreatedAt)
+	if err == pgx.ErrNoRows {
+		return nil, util.ErrNotExist
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+func (r *DB) CreateLoginChallenge(ctx context.Context, arg CreateLoginChallengeParams) (*pkg.LoginChallenge, error) {
+	query := `INSERT INTO login_challenges(account_id, token_hash, expires_at) VALUES ($1, $2, $3)
+	RETURNING ` + loginChallengeColumns + `;`
+	return scanLoginChallenge(r.db.QueryRow(ctx, query, arg.AccountID, arg.TokenHash, arg.ExpiresAt))
+}
+
+// GetLoginChallenge() return util.ErrNotExist when the token is wrong or already used.
+func (r *DB) GetLoginChallenge(ctx context.Context, tokenHash []byte) (*pkg.LoginChallenge, error) {
+	query := `SELECT ` + loginChallengeColumns + ` FROM login_challenges WHERE token_hash=$1 AND used_at IS NULL;`
+	return scanLoginChallenge(r.db.QueryRow(ctx, query, tokenHash))
+}
+
+// AddLoginChallengeAttempt() count a wrong code, it return the number of attempts.
+func (r *DB) AddLoginChallengeAttempt(ctx context.Context, id int64) (int, error) {
+	var attempts int
+	err := r.db.QueryRow(ctx, `UPDATE login_challenges SET attempts=attempts+1 WHERE id=$1 RETURNING attempts;`, id).Scan(&attempts)
+	return attempts, err
+}
+
+// UseLoginChallenge() return util.ErrNotExist when the challenge was already used (by a parallel request).
+func (r *DB) UseLoginChallenge(ctx context.Context, id int64) error {
+	res, err := r.db.Exec(ctx, `UPDATE login_challenges SET used_at=NOW() WHERE id=$1 AND used_at IS NULL;`, id)
+	if err != nil {
+		return err
+	}
+	if res.RowsAffected() == 0 {
+		return util.ErrNotExist
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"simple-bank-system/util"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTOTPEnrollment(t *testing.T) {
+	store := NewStore(dbpool)
+	account := createRandomAccount(t)
+
+	_, err := store.GetAccountTOTP(ctx, account.ID)
+	require.ErrorIs(t, err, util.ErrNotExist)
+
+	secret, err := util.NewTOTPSecret()
+	require.NoError(t, err)
+	totp, err := store.CreateAccountTOTP(ctx, account.ID, secret)
+	require.NoError(t, err)
+	assert.Equal(t, secret, totp.Secret)
+	assert.False(t, totp.EnabledAt.Valid)
+
+	// a new enrollment replace the pending secret
+	secret, err = util.NewTOTPSecret()
+	require.NoError(t, err)
+	totp, err = store.CreateAccountTOTP(ctx, account.ID, secret)
+	require.NoError(t, err)
+	assert.Equal(t, secret, totp.Secret)
+
+	step := util.TOTPStep(time.Now())
+	require.NoError(t, store.EnableTOTPTx(ctx, EnableTOTPParams{
+		AccountID:          account.ID,
+		Step:               step,
+		RecoveryCodeHashes: [][]byte{util.HashToken("aaaaa-aaaaa"), util.HashToken("bbbbb-bbbbb")},
+	}))
+	require.ErrorIs(t, store.EnableTOTPTx(ctx, EnableTOTPParams{AccountID: account.ID, Step: step}), util.ErrNotExist)
+
+	// the secret can't be replaced after it's enabled
+	_, err = store.CreateAccountTOTP(ctx, account.ID, secret)
+	require.ErrorIs(t, err, util.ErrDuplicate)
+
+	// the code of the confirmation can't be used again
+	require.ErrorIs(t, store.UseTOTPStep(ctx, account.ID, step), util.ErrCodeReused)
+	require.NoError(t, store.UseTOTPStep(ctx, account.ID, step+1))
+
+	require.NoError(t, store.UseRecoveryCode(ctx, account.ID, util.HashToken("aaaaa-aaaaa")))
+	require.ErrorIs(t, store.UseRecoveryCode(ctx, account.ID, util.HashToken("aaaaa-aaaaa")), util.ErrNotExist)
+	count, err := store.CountRecoveryCodes(ctx, account.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+
+	require.NoError(t, store.DisableTOTPTx(ctx, account.ID))
+	require.ErrorIs(t, store.DisableTOTPTx(ctx, account.ID), util.ErrNotExist)
+	count, err = store.CountRecoveryCodes(ctx, account.ID)
+	require.NoError(t, err)
+	assert.Zero(t, count)
+}
+
+func TestLoginChallenge(t *testing.T) {
+	store := NewStore(dbpool)
+	account := createRandomAccount(t)
+	challengeToken := util.RandomString(32)
+
+	challenge, err := store.CreateLoginChallenge(ctx, CreateLoginChallengeParams{
+		AccountID: account.ID,
+		TokenHash: util.HashToken(challengeToken),
+		ExpiresAt: time.Now().Add(5 * time.Minute),
+	})
+	require.NoError(t, err)
+
+	attempts, err := store.AddLoginChallengeAttempt(ctx, challenge.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 1, attempts)
+
+	got, err := store.GetLoginChallenge(ctx, util.HashToken(challengeToken))
+	require.NoError(t, err)
+	assert.Equal(t, challenge.ID, got.ID)
+	assert.Equal(t, 1, got.Attempts)
+
+	// the challenge is used once
+	require.NoError(t, store.UseLoginChallenge(ctx, challenge.ID))
+	require.ErrorIs(t, store.UseLoginChallenge(ctx, challenge.ID), util.ErrNotExist)
+	_, err = store.GetLoginChallenge(ctx, util.HashToken(challengeToken))
+	require.ErrorIs(t, err, util.ErrNotExist)
+}
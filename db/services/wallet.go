@@ -12,22 +12,34 @@ import (
 	"github.com/jackc/pgx/v4"
 )
 
+// Wallet types, 'savings' wallet earn interest and 'system' wallet belong to the bank.
+const (
+	WalletTypeRegular = "regular"
+	WalletTypeSavings = "savings"
+	WalletTypeSystem  = "system"
+)
+
 type CreateWalletParams struct {
 	WalletNumber int64
 	Name         string
 	AccountID    int64
 	Balance      int64
 	Currency     string
+	Type         string
 }
 
 func (r *DB) CreateWallet(ctx context.Context, wallet CreateWalletParams) (*pkg.Wallet, error) {
 	var res pkg.Wallet
 
-	query := `INSERT INTO wallets(wallet_number, name, account_id, balance, currency
+	if wallet.Type == "" {
+		wallet.Type = WalletTypeRegular
+	}
+
+	query := `INSERT INTO wallets(wallet_number, name, account_id, balance, currency, wallet_type
 		) VALUES(
-			$1+CAST(1000 + floor(random() * 9000) AS bigint), $2, $3, $4, $5
-		) RETURNING id, name, account_id, wallet_number, balance, currency, created_at;`
-	err := r.db.QueryRow(ctx, query, wallet.WalletNumber, wallet.Name, wallet.AccountID, wallet.Balance, wallet.Currency).Scan(&res.ID, &res.Name, &res.AccountID, &res.WalletNumber, &res.Balance, &res.Currency, &res.CreatedAt)
+			$1+CAST(1000 + floor(random() * 9000) AS bigint), $2, $3, $4, $5, $6
+		) RETURNING id, name, account_id, wallet_number, balance, currency, wallet_type, created_at;`
+	err := r.db.QueryRow(ctx, query, wallet.WalletNumber, wallet.Name, wallet.AccountID, wallet.Balance, wallet.Currency, wallet.Type).Scan(&res.ID, &res.Name, &res.AccountID, &res.WalletNumber, &res.Balance, &res.Currency, &res.Type, &res.CreatedAt)
 	if err != nil {
 		var pgxError *pgconn.PgError
 		if errors.As(err, &pgxError) {
@@ -36,7 +48,7 @@ func (r *DB) CreateWallet(ctx context.Context, wallet CreateWalletParams) (*pkg.
 			}
 			if pgxError.ConstraintName == "wallets_wallet_number_key" {
 				for pgxError.ConstraintName == "wallets_wallet_number_key" {
-					err = r.db.QueryRow(ctx, query, wallet.WalletNumber, wallet.Name, wallet.AccountID, wallet.Balance, wallet.Currency).Scan(&res.ID, &res.Name, &res.AccountID, &res.WalletNumber, &res.Balance, &res.Currency, &res.CreatedAt)
+					err = r.db.QueryRow(ctx, query, wallet.WalletNumber, wallet.Name, wallet.AccountID, wallet.Balance, wallet.Currency, wallet.Type).Scan(&res.ID, &res.Name, &res.AccountID, &res.WalletNumber, &res.Balance, &res.Currency, &res.Type, &res.CreatedAt)
 					errors.As(err, &pgxError)
 				}
 			}
@@ -61,8 +73,8 @@ func (r *DB) CreatePrimaryWallet(ctx context.Context, wallet CreateWalletParams)
 	query := `INSERT INTO wallets(wallet_number, name, account_id, balance, currency
 		) VALUES(
 			$1, $2, $3, $4, $5
-		) RETURNING id, name, account_id, wallet_number, balance, currency, created_at;`
-	err := r.db.QueryRow(ctx, query, wallet.WalletNumber, wallet.Name, wallet.AccountID, wallet.Balance, wallet.Currency).Scan(&res.ID, &res.Name, &res.AccountID, &res.WalletNumber, &res.Balance, &res.Currency, &res.CreatedAt)
+		) RETURNING id, name, account_id, wallet_number, balance, currency, wallet_type, created_at;`
+	err := r.db.QueryRow(ctx, query, wallet.WalletNumber, wallet.Name, wallet.AccountID, wallet.Balance, wallet.Currency).Scan(&res.ID, &res.Name, &res.AccountID, &res.WalletNumber, &res.Balance, &res.Currency, &res.Type, &res.CreatedAt)
 	if err != nil {
 		var pgxError *pgconn.PgError
 		if errors.As(err, &pgxError) {
@@ -71,7 +83,7 @@ func (r *DB) CreatePrimaryWallet(ctx context.Context, wallet CreateWalletParams)
 			}
 			if pgxError.ConstraintName == "wallets_wallet_number_key" {
 				for pgxError.ConstraintName == "wallets_wallet_number_key" {
-					err = r.db.QueryRow(ctx, query, wallet.WalletNumber, wallet.Name, wallet.AccountID, wallet.Balance, wallet.Currency).Scan(&res.ID, &res.Name, &res.AccountID, &res.WalletNumber, &res.Balance, &res.Currency, &res.CreatedAt)
+					err = r.db.QueryRow(ctx, query, wallet.WalletNumber, wallet.Name, wallet.AccountID, wallet.Balance, wallet.Currency).Scan(&res.ID, &res.Name, &res.AccountID, &res.WalletNumber, &res.Balance, &res.Currency, &res.Type, &res.CreatedAt)
 					errors.As(err, &pgxError)
 				}
 			}
@@ -94,7 +106,7 @@ func (r *DB) GetWallet(ctx context.Context, id int64) (*pkg.Wallet, error) {
 	row := r.db.QueryRow(ctx, "SELECT * FROM wallets WHERE id=$1 AND deleted_at IS NULL;", &id)
 
 	var wallet pkg.Wallet
-	err := row.Scan(&wallet.ID, &wallet.AccountID, &wallet.WalletNumber, &wallet.Name, &wallet.Balance, &wallet.Currency, &wallet.CreatedAt, &wallet.DeletedAt)
+	err := row.Scan(&wallet.ID, &wallet.AccountID, &wallet.WalletNumber, &wallet.Name, &wallet.Balance, &wallet.Currency, &wallet.CreatedAt, &wallet.DeletedAt, &wallet.Type)
 	if err == pgx.ErrNoRows {
 		return nil, pgx.ErrNoRows
 	}
@@ -109,7 +121,7 @@ func (r *DB) GetWalletByNumber(ctx context.Context, number int64) (*pkg.Wallet,
 	row := r.db.QueryRow(ctx, "SELECT * FROM wallets WHERE wallet_number=$1 AND deleted_at IS NULL;", &number)
 
 	var wallet pkg.Wallet
-	err := row.Scan(&wallet.ID, &wallet.AccountID, &wallet.WalletNumber, &wallet.Name, &wallet.Balance, &wallet.Currency, &wallet.CreatedAt, &wallet.DeletedAt)
+	err := row.Scan(&wallet.ID, &wallet.AccountID, &wallet.WalletNumber, &wallet.Name, &wallet.Balance, &wallet.Currency, &wallet.CreatedAt, &wallet.DeletedAt, &wallet.Type)
 	if err == pgx.ErrNoRows {
 		return nil, util.ErrNotExist
 	}
@@ -122,14 +134,19 @@ func (r *DB) GetWalletByNumber(ctx context.Context, number int64) (*pkg.Wallet,
 
 type ListWalletParams struct {
 	AccountID int64
-	Limit     int
-	Offset    int
+	// only list these wallet numbers (restricted tokens), nil list every wallet
+	WalletNumbers []int64
+	Limit         int
+	Offset        int
 }
 
 func (r *DB) ListWallet(ctx context.Context, arg ListWalletParams) ([]pkg.Wallet, error) {
 	//log.Printf("account Id: %d - limit: %d - offset: %d\n", arg.AccountID, arg.Limit, arg.Offset)
-	query := `SELECT * FROM wallets WHERE account_id=$1 AND deleted_at IS NULL AND name!='Primary Wallet' ORDER BY id LIMIT $2 OFFSET $3;`
-	res, err := r.db.Query(ctx, query, arg.AccountID, arg.Limit, arg.Offset)
+	// wallets shared with the account (active member) are listed together with its own wallets
+	query := `SELECT * FROM wallets WHERE (account_id=$1 OR id IN (SELECT wallet_id FROM wallet_members WHERE account_id=$1 AND status='active'))
+	AND deleted_at IS NULL AND name!='Primary Wallet' AND ($4::BIGINT[] IS NULL OR wallet_number = ANY($4))
+	ORDER BY id LIMIT $2 OFFSET $3;`
+	res, err := r.db.Query(ctx, query, arg.AccountID, arg.Limit, arg.Offset, arg.WalletNumbers)
 
 	if err != nil {
 		log.Println("err 1")
@@ -140,7 +157,7 @@ func (r *DB) ListWallet(ctx context.Context, arg ListWalletParams) ([]pkg.Wallet
 	for res.Next() {
 		var temp pkg.Wallet
 		//log.Println("pass 1")
-		if err := res.Scan(&temp.ID, &temp.AccountID, &temp.WalletNumber, &temp.Name, &temp.Balance, &temp.Currency, &temp.CreatedAt, &temp.DeletedAt); err != nil {
+		if err := res.Scan(&temp.ID, &temp.AccountID, &temp.WalletNumber, &temp.Name, &temp.Balance, &temp.Currency, &temp.CreatedAt, &temp.DeletedAt, &temp.Type); err != nil {
 			//log.Println("err 2")
 			if err == pgx.ErrNoRows {
 				return nil, util.ErrNotExist
@@ -228,13 +245,100 @@ func (r *DB) DeleteWallet(ctx context.Context, id int64) error {
 	return nil
 }
 
+// CreateWalletTx() create the wallet and write the 'wallet.created' event.
+func (store *Store) CreateWalletTx(ctx context.Context, arg CreateWalletParams) (*pkg.Wallet, error) {
+	var wallet *pkg.Wallet
+
+	err := store.execTx(ctx, func(q *DB) error {
+		var err error
+
+		wallet, err = q.CreateWallet(ctx, arg)
+		if err != nil {
+			return err
+		}
+
+		q.addEvent(EventWalletCreated, wallet.AccountID, wallet.ID, newWalletEvent(wallet))
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return wallet, nil
+}
+
+/*
+ * CloseWalletTx() move the whole balance of the wallet to 'toWalletNumber' (the early withdrawal penalty is
+ * taken from it), delete the wallet and write the 'wallet.closed' event in 1 transaction. Both wallets are
+ * locked first in wallet number order, so a transfer to the wallet wait and then fail on the deleted wallet,
+ * and the balance is checked again before the delete. The transfer is nil when the wallet was empty.
+ */
+func (store *Store) CloseWalletTx(ctx context.Context, wallet pkg.Wallet, toWalletNumber int64) (*TransferTXResult, error) {
+	var result *TransferTXResult
+
+	err := store.execTx(ctx, func(q *DB) error {
+		rows, err := q.db.Query(ctx, `SELECT wallet_number FROM wallets WHERE wallet_number = ANY($1) AND deleted_at IS NULL
+		ORDER BY wallet_number FOR UPDATE;`, []int64{wallet.WalletNumber, toWalletNumber})
+		if err != nil {
+			return err
+		}
+		rows.Close()
+		if err = rows.Err(); err != nil {
+			return err
+		}
+
+		locked, err := q.GetWallet(ctx, wallet.ID)
+		if err != nil {
+			if err == pgx.ErrNoRows {
+				return util.ErrNotExist
+			}
+			return err
+		}
+		if locked.Balance > 0 {
+			result = &TransferTXResult{}
+			err = transferTx(ctx, q, TransferTxParams{
+				AccountID:        locked.AccountID,
+				WalletID:         locked.ID,
+				FromWalletNumber: locked.WalletNumber,
+				ToWalletNumber:   toWalletNumber,
+				Amount:           locked.Balance,
+				PenaltyIncluded:  true,
+			}, result)
+			if err != nil {
+				return err
+			}
+		}
+
+		var balance int64
+		err = q.db.QueryRow(ctx, `SELECT balance FROM wallets WHERE id=$1;`, wallet.ID).Scan(&balance)
+		if err != nil {
+			return err
+		}
+		if balance != 0 {
+			return util.ErrWalletNotEmpty
+		}
+
+		err = q.DeleteWallet(ctx, wallet.ID)
+		if err != nil {
+			return err
+		}
+
+		q.addEvent(EventWalletClosed, locked.AccountID, locked.ID, newWalletEvent(locked))
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
 func (r *DB) GetWalletForUpdate(ctx context.Context, id int64) (*pkg.Wallet, error) {
 	query := `SELECT * FROM wallets WHERE id=$1 AND deleted_at IS NULL FOR NO KEY UPDATE`
 	row := r.db.QueryRow(ctx, query, id)
 
 	var wallet pkg.Wallet
 	var err error
-	if err = row.Scan(&wallet.ID, &wallet.AccountID, &wallet.WalletNumber, &wallet.Name, &wallet.Balance, &wallet.Currency, &wallet.CreatedAt, &wallet.DeletedAt); err != nil {
+	if err = row.Scan(&wallet.ID, &wallet.AccountID, &wallet.WalletNumber, &wallet.Name, &wallet.Balance, &wallet.Currency, &wallet.CreatedAt, &wallet.DeletedAt, &wallet.Type); err != nil {
 		return nil, err
 	}
 
@@ -248,12 +352,38 @@ type AddWalletBalanceParams struct {
 
 func (r *DB) AddWalletBalance(ctx context.Context, arg AddWalletBalanceParams) (*pkg.Wallet, error) {
 	var res pkg.Wallet
-	query := `UPDATE wallets SET balance=balance+$1 WHERE wallet_number=$2
-	RETURNING id, account_id, wallet_number, name, balance, currency, created_at`
-	err := r.db.QueryRow(ctx, query, arg.Amount, arg.WalletNumber).Scan(&res.ID, &res.AccountID, &res.WalletNumber, &res.Name, &res.Balance, &res.Currency, &res.CreatedAt)
+	// a closed wallet can't get money anymore, the transfers that waited for its lock fail
+	query := `UPDATE wallets SET balance=balance+$1 WHERE wallet_number=$2 AND deleted_at IS NULL
+	RETURNING id, account_id, wallet_number, name, balance, currency, wallet_type, created_at`
+	err := r.db.QueryRow(ctx, query, arg.Amount, arg.WalletNumber).Scan(&res.ID, &res.AccountID, &res.WalletNumber, &res.Name, &res.Balance, &res.Currency, &res.Type, &res.CreatedAt)
 	if err != nil {
 		return nil, err
 	}
 
 	return &res, err
 }
+
+// Purpose of the bank wallets, the wallet name is "<purpose> <currency>".
+const (
+	BankAccountNumber           int64 = 1010000000
+	BankWalletInterestExpense         = "Interest Expense"
+	BankWalletBalanceAdjustment       = "Balance Adjustment"
+)
+
+// GetBankWallet() return the wallet of the bank account that is used for 'purpose' in 'currency'.
+func (r *DB) GetBankWallet(ctx context.Context, purpose, currency string) (*pkg.Wallet, error) {
+	query := `SELECT wallets.* FROM wallets JOIN accounts ON accounts.id = wallets.account_id
+	WHERE accounts.account_number=$1 AND wallets.name=$2 AND wallets.wallet_type='system' AND wallets.deleted_at IS NULL;`
+	row := r.db.QueryRow(ctx, query, BankAccountNumber, purpose+" "+currency)
+
+	var wallet pkg.Wallet
+	err := row.Scan(&wallet.ID, &wallet.AccountID, &wallet.WalletNumber, &wallet.Name, &wallet.Balance, &wallet.Currency, &wallet.CreatedAt, &wallet.DeletedAt, &wallet.Type)
+	if err == pgx.ErrNoRows {
+		return nil, util.ErrNotExist
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &wallet, nil
+}
@@ -0,0 +1,207 @@
+package services
+
+import (
+	"context"
+	"errors"
+
+	"simple-bank-system/db/pkg"
+	"simple-bank-system/util"
+
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
+)
+
+// Roles of an account in a wallet, from the most to the least rights.
+const (
+	WalletRoleOwner   = "owner"
+	WalletRoleSpender = "spender"
+	WalletRoleViewer  = "viewer"
+)
+
+const (
+	MemberStatusInvited = "invited"
+	MemberStatusActive  = "active"
+)
+
+var walletRoleRank = map[string]int{
+	WalletRoleViewer:  1,
+	WalletRoleSpender: 2,
+	WalletRoleOwner:   3,
+}
+
+// WalletRoleAllows() is true when 'role' has at least the rights of 'required'.
+func WalletRoleAllows(role, required string) bool {
+	rank, ok := walletRoleRank[role]
+	if !ok {
+		return false
+	}
+	return rank >= walletRoleRank[required]
+}
+
+const walletMemberColumns = `wallet_members.wallet_id, wallet_members.account_id, accounts.account_number, wallet_members.role,
+	wallet_members.status, wallet_members.invited_by, wallet_members.created_at, wallet_members.accepted_at`
+
+func scanWalletMember(row pgx.Row) (*pkg.WalletMember, error) {
+	var res pkg.WalletMember
+	err := row.Scan(&res.WalletID, &res.AccountID, &res.AccountNumber, &res.Role, &res.Status, &res.InvitedBy, &res.CreatedAt, &res.AcceptedAt)
+	if err == pgx.ErrNoRows {
+		return nil, util.ErrNotExist
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+type InviteWalletMemberParams struct {
+	WalletID  int64
+	AccountID int64
+	Role      string
+	InvitedBy int64
+}
+
+func (r *DB) InviteWalletMember(ctx context.Context, arg InviteWalletMemberParams) (*pkg.WalletMember, error) {
+	query := `WITH member AS (
+		INSERT INTO wallet_members(wallet_id, account_id, role, invited_by
+		) VALUES (
+			$1, $2, $3, $4
+		) RETURNING *
+	)
+	SELECT ` + walletMemberColumns + ` FROM member AS wallet_members JOIN accounts ON accounts.id = wallet_members.account_id;`
+
+	res, err := scanWalletMember(r.db.QueryRow(ctx, query, arg.WalletID, arg.AccountID, arg.Role, arg.InvitedBy))
+	if err != nil {
+		var pgxError *pgconn.PgError
+		if errors.As(err, &pgxError) {
+			// 23503 (foreign_key_violation) -> invite account that doesn't exist
+			if pgxError.Code == "23503" {
+				return nil, util.ErrAccUser
+			}
+			// 23505 (unique_violation) -> account is already invited or a member
+			if pgxError.Code == "23505" {
+				return nil, util.ErrDuplicate
+			}
+		}
+		return nil, err
+	}
+
+	return res, nil
+}
+
+func (r *DB) GetWalletMember(ctx context.Context, walletID, accountID int64) (*pkg.WalletMember, error) {
+	query := `SELECT ` + walletMemberColumns + ` FROM wallet_members JOIN accounts ON accounts.id = wallet_members.account_id
+	WHERE wallet_members.wallet_id=$1 AND wallet_members.account_id=$2;`
+
+	return scanWalletMember(r.db.QueryRow(ctx, query, walletID, accountID))
+}
+
+// AcceptWalletInvitation() activate the membership, it fails when there's no pending invitation.
+func (r *DB) AcceptWalletInvitation(ctx context.Context, walletID, accountID int64) error {
+	query := `UPDATE wallet_members SET status='active', accepted_at=now() WHERE wallet_id=$1 AND account_id=$2 AND status='invited'`
+	res, err := r.db.Exec(ctx, query, walletID, accountID)
+	if err != nil {
+		return err
+	}
+	if res.RowsAffected() == 0 {
+		return util.ErrNotExist
+	}
+
+	return nil
+}
+
+type UpdateWalletMemberRoleParams struct {
+	WalletID  int64
+	AccountID int64
+	Role      string
+}
+
+func (r *DB) UpdateWalletMemberRole(ctx context.Context, arg UpdateWalletMemberRoleParams) error {
+	res, err := r.db.Exec(ctx, `UPDATE wallet_members SET role=$1 WHERE wallet_id=$2 AND account_id=$3`, arg.Role, arg.WalletID, arg.AccountID)
+	if err != nil {
+		return err
+	}
+	if res.RowsAffected() == 0 {
+		return util.ErrUpdateFailed
+	}
+
+	return nil
+}
+
+// DeleteWalletMember() is used to decline an invitation, leave a wallet or remove a member.
+func (r *DB) DeleteWalletMember(ctx context.Context, walletID, accountID int64) error {
+	res, err := r.db.Exec(ctx, `DELETE FROM wallet_members WHERE wallet_id=$1 AND account_id=$2`, walletID, accountID)
+	if err != nil {
+		return err
+	}
+	if res.RowsAffected() == 0 {
+		return util.ErrDeleteFailed
+	}
+
+	return nil
+}
+
+func (r *DB) ListWalletMembers(ctx context.Context, walletID int64) ([]pkg.WalletMember, error) {
+	query := `SELECT ` + walletMemberColumns + ` FROM wallet_members JOIN accounts ON accounts.id = wallet_members.account_id
+	WHERE wallet_members.wallet_id=$1 ORDER BY wallet_members.created_at;`
+	rows, err := r.db.Query(ctx, query, walletID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []pkg.WalletMember
+	for rows.Next() {
+		member, err := scanWalletMember(rows)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, *member)
+	}
+
+	return list, rows.Err()
+}
+
+// ListWalletInvitations() return pending invitations of the account.
+func (r *DB) ListWalletInvitations(ctx context.Context, accountID int64) ([]pkg.WalletMember, error) {
+	query := `SELECT ` + walletMemberColumns + ` FROM wallet_members JOIN accounts ON accounts.id = wallet_members.account_id
+	WHERE wallet_members.account_id=$1 AND wallet_members.status='invited' ORDER BY wallet_members.created_at;`
+	rows, err := r.db.Query(ctx, query, accountID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []pkg.WalletMember
+	for rows.Next() {
+		member, err := scanWalletMember(rows)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, *member)
+	}
+
+	return list, rows.Err()
+}
+
+/*
+ * GetWalletRole() return the role of the account in the wallet.
+ * The owner of the wallet (wallets.account_id) is always "owner", other accounts need an active membership.
+ * It return util.ErrNotExist when the account has no access to the wallet.
+ */
+func (r *DB) GetWalletRole(ctx context.Context, wallet pkg.Wallet, accountID int64) (string, error) {
+	if wallet.AccountID == accountID {
+		return WalletRoleOwner, nil
+	}
+
+	var role string
+	query := `SELECT role FROM wallet_members WHERE wallet_id=$1 AND account_id=$2 AND status='active';`
+	err := r.db.QueryRow(ctx, query, wallet.ID, accountID).Scan(&role)
+	if err == pgx.ErrNoRows {
+		return "", util.ErrNotExist
+	}
+	if err != nil {
+		return "", err
+	}
+
+	return role, nil
+}
@@ -0,0 +1,86 @@
+package services
+
+import (
+	"testing"
+
+	"simple-bank-system/util"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWalletRoleAllows(t *testing.T) {
+	assert.True(t, WalletRoleAllows(WalletRoleOwner, WalletRoleSpender))
+	assert.True(t, WalletRoleAllows(WalletRoleSpender, WalletRoleSpender))
+	assert.True(t, WalletRoleAllows(WalletRoleViewer, WalletRoleViewer))
+	assert.False(t, WalletRoleAllows(WalletRoleViewer, WalletRoleSpender))
+	assert.False(t, WalletRoleAllows(WalletRoleSpender, WalletRoleOwner))
+	assert.False(t, WalletRoleAllows("", WalletRoleViewer))
+}
+
+func TestWalletMemberInvitation(t *testing.T) {
+	owner := createRandomAccount(t)
+	member := createRandomAccount(t)
+	wallet, _ := createRandomWallet(t, owner)
+
+	role, err := testQueries.GetWalletRole(ctx, wallet, owner.ID)
+	require.NoError(t, err)
+	assert.Equal(t, WalletRoleOwner, role)
+
+	invited, err := testQueries.InviteWalletMember(ctx, InviteWalletMemberParams{
+		WalletID:  wallet.ID,
+		AccountID: member.ID,
+		Role:      WalletRoleSpender,
+		InvitedBy: owner.ID,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, member.AccountNumber, invited.AccountNumber)
+	assert.Equal(t, MemberStatusInvited, invited.Status)
+	assert.False(t, invited.AcceptedAt.Valid)
+
+	_, err = testQueries.InviteWalletMember(ctx, InviteWalletMemberParams{
+		WalletID:  wallet.ID,
+		AccountID: member.ID,
+		Role:      WalletRoleViewer,
+		InvitedBy: owner.ID,
+	})
+	require.ErrorIs(t, err, util.ErrDuplicate)
+
+	// invited account has no access until it accept the invitation
+	_, err = testQueries.GetWalletRole(ctx, wallet, member.ID)
+	require.ErrorIs(t, err, util.ErrNotExist)
+
+	invitations, err := testQueries.ListWalletInvitations(ctx, member.ID)
+	require.NoError(t, err)
+	require.Len(t, invitations, 1)
+	assert.Equal(t, wallet.ID, invitations[0].WalletID)
+
+	err = testQueries.AcceptWalletInvitation(ctx, wallet.ID, member.ID)
+	require.NoError(t, err)
+	err = testQueries.AcceptWalletInvitation(ctx, wallet.ID, member.ID)
+	require.ErrorIs(t, err, util.ErrNotExist)
+
+	role, err = testQueries.GetWalletRole(ctx, wallet, member.ID)
+	require.NoError(t, err)
+	assert.Equal(t, WalletRoleSpender, role)
+
+	shared, err := testQueries.ListWallet(ctx, ListWalletParams{AccountID: member.ID, Limit: 10, Offset: 0})
+	require.NoError(t, err)
+	require.Len(t, shared, 1)
+	assert.Equal(t, wallet.ID, shared[0].ID)
+
+	err = testQueries.UpdateWalletMemberRole(ctx, UpdateWalletMemberRoleParams{WalletID: wallet.ID, AccountID: member.ID, Role: WalletRoleViewer})
+	require.NoError(t, err)
+
+	members, err := testQueries.ListWalletMembers(ctx, wallet.ID)
+	require.NoError(t, err)
+	require.Len(t, members, 1)
+	assert.Equal(t, WalletRoleViewer, members[0].Role)
+	assert.Equal(t, MemberStatusActive, members[0].Status)
+	assert.True(t, members[0].AcceptedAt.Valid)
+
+	err = testQueries.DeleteWalletMember(ctx, wallet.ID, member.ID)
+	require.NoError(t, err)
+	_, err = testQueries.GetWalletRole(ctx, wallet, member.ID)
+	require.ErrorIs(t, err, util.ErrNotExist)
+}
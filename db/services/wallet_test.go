@@ -215,3 +215,54 @@ func TestListWallet(t *testing.T) {
 		assert.Equal(t, lastWallet.AccountID, wallet.AccountID)
 	}
 }
+
+func TestListWalletNumbers(t *testing.T) {
+	account := createRandomAccount(t)
+	first, _ := createRandomWalletList(t, account, "IDR")
+	createRandomWalletList(t, account, "USD")
+
+	wallets, err := testQueries.ListWallet(ctx, ListWalletParams{
+		AccountID:     account.ID,
+		WalletNumbers: []int64{first.WalletNumber},
+		Limit:         10,
+	})
+	require.NoError(t, err)
+	require.Len(t, wallets, 1)
+	assert.Equal(t, first.WalletNumber, wallets[0].WalletNumber)
+}
+
+func TestCloseWalletTx(t *testing.T) {
+	store := NewStore(dbpool)
+	account := createRandomAccount(t)
+	wallet := createRandomSavingsWallet(t, account, 1000)
+	toWallet := createRandomSavingsWallet(t, account, 0)
+
+	result, err := store.CloseWalletTx(ctx, wallet, toWallet.WalletNumber)
+	require.NoError(t, err)
+	require.NotNil(t, result)
+	assert.Equal(t, int64(1000), result.Transfer.Amount)
+	assert.Equal(t, int64(0), result.FromWallet.Balance)
+	assert.Equal(t, int64(1000), result.ToWallet.Balance)
+
+	_, err = store.GetWallet(ctx, wallet.ID)
+	require.Error(t, err, "wallet is closed")
+
+	// the closed wallet can't get money anymore
+	_, err = store.TransferTx(ctx, TransferTxParams{
+		AccountID:        account.ID,
+		WalletID:         toWallet.ID,
+		FromWalletNumber: toWallet.WalletNumber,
+		ToWalletNumber:   wallet.WalletNumber,
+		Amount:           10,
+	})
+	require.Error(t, err)
+
+	_, err = store.CloseWalletTx(ctx, wallet, toWallet.WalletNumber)
+	require.ErrorIs(t, err, util.ErrNotExist)
+
+	// an empty wallet is closed without transfer
+	empty := createRandomSavingsWallet(t, account, 0)
+	result, err = store.CloseWalletTx(ctx, empty, toWallet.WalletNumber)
+	require.NoError(t, err)
+	assert.Nil(t, result)
+}
@@ -0,0 +1,293 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"simple-bank-system/db/pkg"
+	"simple-bank-system/util"
+
+	"github.com/jackc/pgx/v4"
+)
+
+// Status of webhook deliveries
+const (
+	WebhookPending   = "pending"
+	WebhookSucceeded = "succeeded"
+	WebhookFailed    = "failed"
+)
+
+const webhookColumns = `id, account_id, url, event_types, secret, previous_secret, secret_rotated_at, created_at`
+
+const deliveryColumns = `id, subscription_id, event_id, event_type, payload, status, attempts, next_attempt_at, delivered_at, created_at`
+
+func scanWebhook(row pgx.Row) (*pkg.WebhookSubscription, error) {
+	var res pkg.WebhookSubscription
+	err := row.Scan(&res.ID, &res.AccountID, &res.URL, &res.EventTypes, &res.Secret, &res.PreviousSecret, &res.SecretRotatedAt, &res.CreatedAt)
+	if err == pgx.ErrNoRows {
+		return nil, util.ErrNotExist
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+func scanDelivery(row pgx.Row) (*pkg.WebhookDelivery, error) {
+	var res pkg.WebhookDelivery
+	err := row.Scan(&res.ID, &res.SubscriptionID, &res.EventID, &res.EventType, &res.Payload, &res.Status, &res.Attempts, &res.NextAttemptAt, &res.DeliveredAt, &res.CreatedAt)
+	if err == pgx.ErrNoRows {
+		return nil, util.ErrNotExist
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+type CreateWebhookParams struct {
+	AccountID  int64
+	URL        string
+	EventTypes []string
+	Secret     string
+}
+
+func (r *DB) CreateWebhook(ctx context.Context, arg CreateWebhookParams) (*pkg.WebhookSubscription, error) {
+	query := `INSERT INTO webhook_subscriptions(account_id, url, event_types, secret
+	) VALUES (
+		$1, $2, $3, $4
+	) RETURNING ` + webhookColumns + `;`
+
+	return scanWebhook(r.db.QueryRow(ctx, query, arg.AccountID, arg.URL, arg.EventTypes, arg.Secret))
+}
+
+// GetWebhook() return the subscription only when it belongs to the account.
+func (r *DB) GetWebhook(ctx context.Context, id, accountID int64) (*pkg.WebhookSubscription, error) {
+	query := `SELECT ` + webhookColumns + ` FROM webhook_subscriptions WHERE id=$1 AND account_id=$2 AND deleted_at IS NULL;`
+	return scanWebhook(r.db.QueryRow(ctx, query, id, accountID))
+}
+
+func (r *DB) ListWebhooks(ctx context.Context, accountID int64) ([]pkg.WebhookSubscription, error) {
+	query := `SELECT ` + webhookColumns + ` FROM webhook_subscriptions WHERE account_id=$1 AND deleted_at IS NULL ORDER BY id;`
+	rows, err := r.db.Query(ctx, query, accountID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []pkg.WebhookSubscription
+	for rows.Next() {
+		webhook, err := scanWebhook(rows)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, *webhook)
+	}
+
+	return list, rows.Err()
+}
+
+// DeleteWebhook() stop the deliveries of the subscription, the delivery log is kept.
+func (r *DB) DeleteWebhook(ctx context.Context, id, accountID int64) error {
+	res, err := r.db.Exec(ctx, `UPDATE webhook_subscriptions SET deleted_at=now() WHERE id=$1 AND account_id=$2 AND deleted_at IS NULL`, id, accountID)
+	if err != nil {
+		return err
+	}
+	if res.RowsAffected() == 0 {
+		return util.ErrDeleteFailed
+	}
+
+	return nil
+}
+
+// RotateWebhookSecret() replace the secret, the old secret become 'previous_secret' for the grace period.
+func (r *DB) RotateWebhookSecret(ctx context.Context, id, accountID int64, secret string) (*pkg.WebhookSubscription, error) {
+	query := `UPDATE webhook_subscriptions SET previous_secret=secret, secret=$3, secret_rotated_at=now()
+	WHERE id=$1 AND account_id=$2 AND deleted_at IS NULL
+	RETURNING ` + webhookColumns + `;`
+
+	return scanWebhook(r.db.QueryRow(ctx, query, id, accountID, secret))
+}
+
+/*
+ * EnqueueWebhookDeliveries() create 1 delivery of the event for every subscription of the account that
+ * chose 'eventType'. The relay deliver outbox events at least once, so an event that is enqueued again
+ * doesn't create a second delivery. Return the number of new deliveries.
+ */
+func (r *DB) EnqueueWebhookDeliveries(ctx context.Context, eventID, accountID int64, eventType string, payload []byte) (int64, error) {
+	query := `INSERT INTO webhook_deliveries(subscription_id, event_id, event_type, payload)
+	SELECT id, $1, $3, $4::jsonb FROM webhook_subscriptions
+	WHERE account_id=$2 AND $3 = ANY(event_types) AND deleted_at IS NULL
+	ON CONFLICT (subscription_id, event_id) DO NOTHING;`
+
+	res, err := r.db.Exec(ctx, query, eventID, accountID, eventType, string(payload))
+	if err != nil {
+		return 0, err
+	}
+
+	return res.RowsAffected(), nil
+}
+
+// ListWebhookDeliveries() return the last 'limit' deliveries of the subscription, newest first.
+func (r *DB) ListWebhookDeliveries(ctx context.Context, subscriptionID int64, limit int) ([]pkg.WebhookDelivery, error) {
+	query := `SELECT ` + deliveryColumns + ` FROM webhook_deliveries WHERE subscription_id=$1 ORDER BY id DESC LIMIT $2;`
+	rows, err := r.db.Query(ctx, query, subscriptionID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []pkg.WebhookDelivery
+	for rows.Next() {
+		delivery, err := scanDelivery(rows)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, *delivery)
+	}
+
+	return list, rows.Err()
+}
+
+// GetWebhookDelivery() return the delivery of the subscription together with all its attempts.
+func (r *DB) GetWebhookDelivery(ctx context.Context, id, subscriptionID int64) (*pkg.WebhookDelivery, error) {
+	query := `SELECT ` + deliveryColumns + ` FROM webhook_deliveries WHERE id=$1 AND subscription_id=$2;`
+	delivery, err := scanDelivery(r.db.QueryRow(ctx, query, id, subscriptionID))
+	if err != nil {
+		return nil, err
+	}
+
+	query = `SELECT id, delivery_id, status_code, error, duration_ms, created_at FROM webhook_attempts WHERE delivery_id=$1 ORDER BY id;`
+	rows, err := r.db.Query(ctx, query, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var attempt pkg.WebhookAttempt
+		err = rows.Scan(&attempt.ID, &attempt.DeliveryID, &attempt.StatusCode, &attempt.Error, &attempt.DurationMs, &attempt.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		delivery.Log = append(delivery.Log, attempt)
+	}
+
+	return delivery, rows.Err()
+}
+
+// ReplayWebhookDelivery() send the delivery again as soon as possible with a new set of retries, the old attempts stay in the log.
+func (r *DB) ReplayWebhookDelivery(ctx context.Context, id, subscriptionID int64) error {
+	query := `UPDATE webhook_deliveries SET status='pending', attempts=0, next_attempt_at=now(), delivered_at=NULL
+	WHERE id=$1 AND subscription_id=$2;`
+	res, err := r.db.Exec(ctx, query, id, subscriptionID)
+	if err != nil {
+		return err
+	}
+	if res.RowsAffected() == 0 {
+		return util.ErrUpdateFailed
+	}
+
+	return nil
+}
+
+// WebhookAttemptResult is the result of 1 HTTP request of a delivery.
+type WebhookAttemptResult struct {
+	StatusCode int64
+	Error      string
+	Duration   time.Duration
+	Succeeded  bool
+	// when the failed delivery is retried, zero when there are no retries left
+	NextAttemptAt time.Time
+}
+
+// The claimed deliveries of a dispatcher that stopped are sent again after it
+const webhookClaimLease = 5 * time.Minute
+
+/*
+ * DispatchWebhookDeliveries() claim up to 'limit' pending deliveries that are due, with 'FOR UPDATE SKIP LOCKED'
+ * and moving their 'next_attempt_at' after the lease, and call 'send' for each of them after the claim is
+ * committed, so no db transaction stay open while the endpoints are called. Then the attempt and the new
+ * status of every delivery are recorded.
+ * Return the number of deliveries that were sent.
+ */
+func (store *Store) DispatchWebhookDeliveries(ctx context.Context, limit int, send func(pkg.WebhookDelivery, pkg.WebhookSubscription) WebhookAttemptResult) (int, error) {
+	query := `WITH due AS (
+		SELECT d.id FROM webhook_deliveries d
+		JOIN webhook_subscriptions s ON s.id = d.subscription_id AND s.deleted_at IS NULL
+		WHERE d.status = 'pending' AND d.next_attempt_at <= now()
+		ORDER BY d.next_attempt_at, d.id
+		LIMIT $1
+		FOR UPDATE OF d SKIP LOCKED
+	)
+	UPDATE webhook_deliveries d SET next_attempt_at = now() + make_interval(secs => $2)
+	FROM due, webhook_subscriptions s
+	WHERE d.id = due.id AND s.id = d.subscription_id
+	RETURNING d.id, d.subscription_id, d.event_id, d.event_type, d.payload, d.status, d.attempts, d.next_attempt_at, d.delivered_at, d.created_at,
+		s.id, s.account_id, s.url, s.event_types, s.secret, s.previous_secret, s.secret_rotated_at, s.created_at;`
+
+	rows, err := store.db.Query(ctx, query, limit, webhookClaimLease.Seconds())
+	if err != nil {
+		return 0, err
+	}
+
+	var deliveries []pkg.WebhookDelivery
+	var webhooks []pkg.WebhookSubscription
+	for rows.Next() {
+		var d pkg.WebhookDelivery
+		var s pkg.WebhookSubscription
+		err = rows.Scan(&d.ID, &d.SubscriptionID, &d.EventID, &d.EventType, &d.Payload, &d.Status, &d.Attempts, &d.NextAttemptAt, &d.DeliveredAt, &d.CreatedAt,
+			&s.ID, &s.AccountID, &s.URL, &s.EventTypes, &s.Secret, &s.PreviousSecret, &s.SecretRotatedAt, &s.CreatedAt)
+		if err != nil {
+			rows.Close()
+			return 0, err
+		}
+		deliveries = append(deliveries, d)
+		webhooks = append(webhooks, s)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return 0, err
+	}
+
+	var sent int
+	for i, delivery := range deliveries {
+		result := send(delivery, webhooks[i])
+		sent++
+
+		err = store.recordWebhookAttempt(ctx, delivery.ID, result)
+		if err != nil {
+			return sent, err
+		}
+	}
+
+	return sent, nil
+}
+
+// recordWebhookAttempt() save the attempt and the new status of the delivery together.
+func (store *Store) recordWebhookAttempt(ctx context.Context, deliveryID int64, result WebhookAttemptResult) error {
+	return store.execTx(ctx, func(q *DB) error {
+		_, err := q.db.Exec(ctx, `INSERT INTO webhook_attempts(delivery_id, status_code, error, duration_ms) VALUES ($1, $2, $3, $4);`,
+			deliveryID, result.StatusCode, result.Error, result.Duration.Milliseconds())
+		if err != nil {
+			return err
+		}
+
+		status := WebhookPending
+		switch {
+		case result.Succeeded:
+			status = WebhookSucceeded
+		case result.NextAttemptAt.IsZero():
+			status = WebhookFailed
+		}
+		query := `UPDATE webhook_deliveries SET status=$2, attempts=attempts+1, next_attempt_at=COALESCE($3, next_attempt_at),
+			delivered_at=CASE WHEN $2 = 'succeeded' THEN now() END
+		WHERE id=$1;`
+		var next *time.Time
+		if status == WebhookPending {
+			next = &result.NextAttemptAt
+		}
+		_, err = q.db.Exec(ctx, query, deliveryID, status, next)
+		return err
+	})
+}
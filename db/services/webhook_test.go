@@ -0,0 +1,96 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"simple-bank-system/db/pkg"
+	"simple-bank-system/util"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWebhook(t *testing.T) {
+	store := NewStore(dbpool)
+	account := createRandomAccount(t)
+
+	webhook, err := store.CreateWebhook(ctx, CreateWebhookParams{
+		AccountID:  account.ID,
+		URL:        "https://example.com/hook",
+		EventTypes: []string{"transfer.incoming"},
+		Secret:     "secret-1",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"transfer.incoming"}, webhook.EventTypes)
+	assert.False(t, webhook.PreviousSecret.Valid)
+
+	rotated, err := store.RotateWebhookSecret(ctx, webhook.ID, account.ID, "secret-2")
+	require.NoError(t, err)
+	assert.Equal(t, "secret-2", rotated.Secret)
+	assert.Equal(t, "secret-1", rotated.PreviousSecret.String)
+	assert.True(t, rotated.SecretRotatedAt.Valid)
+
+	_, err = store.GetWebhook(ctx, webhook.ID, account.ID+1)
+	require.ErrorIs(t, err, util.ErrNotExist)
+
+	// the outbox event of the delivery
+	err = store.execTx(ctx, func(q *DB) error {
+		q.addEvent(EventTransferCompleted, account.ID, 0, map[string]int64{"amount": 100})
+		return nil
+	})
+	require.NoError(t, err)
+	var eventID int64
+	require.NoError(t, dbpool.QueryRow(ctx, `SELECT MAX(id) FROM outbox_events WHERE account_id=$1`, account.ID).Scan(&eventID))
+
+	n, err := store.EnqueueWebhookDeliveries(ctx, eventID, account.ID, "transfer.incoming", []byte(`{"id":1}`))
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), n)
+	// redelivery of the same event
+	n, err = store.EnqueueWebhookDeliveries(ctx, eventID, account.ID, "transfer.incoming", []byte(`{"id":1}`))
+	require.NoError(t, err)
+	assert.Zero(t, n)
+	// not subscribed
+	n, err = store.EnqueueWebhookDeliveries(ctx, eventID, account.ID, "transfer.outgoing", []byte(`{"id":1}`))
+	require.NoError(t, err)
+	assert.Zero(t, n)
+
+	deliveries, err := store.ListWebhookDeliveries(ctx, webhook.ID, 10)
+	require.NoError(t, err)
+	require.Len(t, deliveries, 1)
+	delivery := deliveries[0]
+	assert.Equal(t, WebhookPending, delivery.Status)
+
+	// dispatch until our delivery is sent, other tests may have due deliveries too
+	for i := 0; i < 100; i++ {
+		sent, err := store.DispatchWebhookDeliveries(ctx, 50, func(d pkg.WebhookDelivery, s pkg.WebhookSubscription) WebhookAttemptResult {
+			if d.ID != delivery.ID {
+				return WebhookAttemptResult{Succeeded: true}
+			}
+			assert.Equal(t, "secret-2", s.Secret)
+			return WebhookAttemptResult{StatusCode: 500, Error: "endpoint answered 500", NextAttemptAt: time.Now().Add(time.Hour)}
+		})
+		require.NoError(t, err)
+		if sent == 0 {
+			break
+		}
+	}
+
+	got, err := store.GetWebhookDelivery(ctx, delivery.ID, webhook.ID)
+	require.NoError(t, err)
+	assert.Equal(t, WebhookPending, got.Status)
+	assert.Equal(t, int64(1), got.Attempts)
+	assert.True(t, got.NextAttemptAt.After(time.Now()))
+	require.Len(t, got.Log, 1)
+	assert.Equal(t, int64(500), got.Log[0].StatusCode)
+
+	require.NoError(t, store.ReplayWebhookDelivery(ctx, delivery.ID, webhook.ID))
+	got, err = store.GetWebhookDelivery(ctx, delivery.ID, webhook.ID)
+	require.NoError(t, err)
+	assert.Zero(t, got.Attempts)
+	assert.False(t, got.NextAttemptAt.After(time.Now()))
+	require.Len(t, got.Log, 1)
+
+	require.NoError(t, store.DeleteWebhook(ctx, webhook.ID, account.ID))
+	require.ErrorIs(t, store.DeleteWebhook(ctx, webhook.ID, account.ID), util.ErrDeleteFailed)
+}
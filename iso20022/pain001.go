@@ -0,0 +1,237 @@
+/*
+ * Package iso20022 read ISO 20022 customer credit transfer initiation (pain.001.001.03) files
+ * and write the payment status report (pain.002.001.03) of them.
+ * The Go standard library has no XSD validator, so 'Validate' check the constraints of the
+ * schema that the bank rely on (namespace, mandatory elements, lengths, patterns and totals).
+ */
+package iso20022
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const Pain001Namespace = "urn:iso:std:iso:20022:tech:xsd:pain.001.001.03"
+
+// ISO 20022 date time without fraction, e.g. 2024-03-01T10:00:00Z
+const isoDateTime = "2006-01-02T15:04:05Z07:00"
+
+var (
+	currencyPattern = regexp.MustCompile(`^[A-Z]{3}$`)
+	countPattern    = regexp.MustCompile(`^[0-9]{1,15}$`)
+	// DecimalNumber with max 18 digits and 17 fraction digits
+	decimalPattern = regexp.MustCompile(`^[0-9]{1,18}(\.[0-9]{1,17})?$`)
+	// ActiveOrHistoricCurrencyAndAmount with max 18 digits and 5 fraction digits
+	amountPattern = regexp.MustCompile(`^[0-9]{1,18}(\.[0-9]{1,5})?$`)
+)
+
+// WalletCurrency return the wallet currency of the ISO 4217 code, the wallets use YEN for JPY.
+func WalletCurrency(ccy string) string {
+	if ccy == "JPY" {
+		return "YEN"
+	}
+	return ccy
+}
+
+type Amount struct {
+	Ccy   string `xml:"Ccy,attr"`
+	Value string `xml:",chardata"`
+}
+
+type Account struct {
+	IBAN string `xml:"Id>IBAN"`
+	ID   string `xml:"Id>Othr>Id"`
+	Ccy  string `xml:"Ccy"`
+}
+
+// Number return the wallet number of the account, the bank only use the 'Othr' identification.
+func (a Account) Number() (int64, error) {
+	if a.ID == "" {
+		return 0, fmt.Errorf("account must be identified by Id/Othr/Id")
+	}
+	return strconv.ParseInt(strings.TrimSpace(a.ID), 10, 64)
+}
+
+type CreditTransfer struct {
+	InstrID    string  `xml:"PmtId>InstrId"`
+	EndToEndID string  `xml:"PmtId>EndToEndId"`
+	Amount     Amount  `xml:"Amt>InstdAmt"`
+	Creditor   string  `xml:"Cdtr>Nm"`
+	CdtrAcct   Account `xml:"CdtrAcct"`
+	Remittance string  `xml:"RmtInf>Ustrd"`
+}
+
+// WholeAmount return the amount in whole units of the currency, the wallets don't have fractions.
+func (c CreditTransfer) WholeAmount() (int64, error) {
+	value := strings.TrimSpace(c.Amount.Value)
+	whole, fraction, _ := strings.Cut(value, ".")
+	if strings.Trim(fraction, "0") != "" {
+		return 0, fmt.Errorf("amount %s has fraction", value)
+	}
+	return strconv.ParseInt(whole, 10, 64)
+}
+
+type PaymentInformation struct {
+	PmtInfID     string           `xml:"PmtInfId"`
+	PmtMtd       string           `xml:"PmtMtd"`
+	NbOfTxs      string           `xml:"NbOfTxs"`
+	CtrlSum      string           `xml:"CtrlSum"`
+	ReqdExctnDt  string           `xml:"ReqdExctnDt"`
+	Debtor       string           `xml:"Dbtr>Nm"`
+	DbtrAcct     Account          `xml:"DbtrAcct"`
+	Transactions []CreditTransfer `xml:"CdtTrfTxInf"`
+}
+
+type GroupHeader struct {
+	MsgID          string `xml:"MsgId"`
+	CreDtTm        string `xml:"CreDtTm"`
+	NbOfTxs        string `xml:"NbOfTxs"`
+	CtrlSum        string `xml:"CtrlSum"`
+	InitiatingName string `xml:"InitgPty>Nm"`
+}
+
+// Pain001 is the customer credit transfer initiation message.
+type Pain001 struct {
+	XMLName  xml.Name             `xml:"Document"`
+	GrpHdr   GroupHeader          `xml:"CstmrCdtTrfInitn>GrpHdr"`
+	Payments []PaymentInformation `xml:"CstmrCdtTrfInitn>PmtInf"`
+}
+
+// ParsePain001 decode and validate the pain.001 file.
+func ParsePain001(r io.Reader) (*Pain001, error) {
+	var doc Pain001
+	err := xml.NewDecoder(r).Decode(&doc)
+	if err != nil {
+		return nil, fmt.Errorf("pain.001 isn't valid XML: %w", err)
+	}
+
+	err = doc.Validate()
+	if err != nil {
+		return nil, err
+	}
+	return &doc, nil
+}
+
+// validDateTime is true for ISODateTime with or without time zone, the fraction of seconds is optional.
+func validDateTime(value string) bool {
+	for _, layout := range []string{time.RFC3339, "2006-01-02T15:04:05"} {
+		if _, err := time.Parse(layout, value); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+func checkText(name, value string, max int) error {
+	length := len([]rune(value))
+	if length == 0 || length > max {
+		return fmt.Errorf("%s must have 1 to %d characters", name, max)
+	}
+	return nil
+}
+
+// checkTotals compare 'NbOfTxs' and 'CtrlSum' with the transactions.
+func checkTotals(name, nbOfTxs, ctrlSum string, txs []CreditTransfer) error {
+	if !countPattern.MatchString(nbOfTxs) {
+		return fmt.Errorf("%s/NbOfTxs %q isn't valid", name, nbOfTxs)
+	}
+	if n, _ := strconv.Atoi(nbOfTxs); n != len(txs) {
+		return fmt.Errorf("%s/NbOfTxs is %s but there are %d transactions", name, nbOfTxs, len(txs))
+	}
+
+	if ctrlSum == "" {
+		return nil
+	}
+	if !decimalPattern.MatchString(ctrlSum) {
+		return fmt.Errorf("%s/CtrlSum %q isn't valid", name, ctrlSum)
+	}
+	sum, _ := strconv.ParseFloat(ctrlSum, 64)
+	var total float64
+	for _, tx := range txs {
+		amount, _ := strconv.ParseFloat(tx.Amount.Value, 64)
+		total += amount
+	}
+	if fmt.Sprintf("%.5f", sum) != fmt.Sprintf("%.5f", total) {
+		return fmt.Errorf("%s/CtrlSum is %s but the total of the transactions is %s", name, ctrlSum, strconv.FormatFloat(total, 'f', -1, 64))
+	}
+	return nil
+}
+
+// Validate check the pain.001.001.03 schema constraints of the message.
+func (doc *Pain001) Validate() error {
+	if doc.XMLName.Space != Pain001Namespace {
+		return fmt.Errorf("document namespace must be %s", Pain001Namespace)
+	}
+
+	if err := checkText("GrpHdr/MsgId", doc.GrpHdr.MsgID, 35); err != nil {
+		return err
+	}
+	if !validDateTime(doc.GrpHdr.CreDtTm) {
+		return fmt.Errorf("GrpHdr/CreDtTm %q isn't ISO date time", doc.GrpHdr.CreDtTm)
+	}
+	if len(doc.Payments) == 0 {
+		return fmt.Errorf("CstmrCdtTrfInitn must have at least 1 PmtInf")
+	}
+
+	var all []CreditTransfer
+	for i, pmt := range doc.Payments {
+		name := fmt.Sprintf("PmtInf[%d]", i+1)
+		if err := checkText(name+"/PmtInfId", pmt.PmtInfID, 35); err != nil {
+			return err
+		}
+		if pmt.PmtMtd != "TRF" && pmt.PmtMtd != "TRA" && pmt.PmtMtd != "CHK" {
+			return fmt.Errorf("%s/PmtMtd %q isn't valid", name, pmt.PmtMtd)
+		}
+		if pmt.PmtMtd != "TRF" {
+			return fmt.Errorf("%s/PmtMtd %s isn't supported, only TRF", name, pmt.PmtMtd)
+		}
+		if _, err := time.Parse(time.DateOnly, pmt.ReqdExctnDt); err != nil {
+			return fmt.Errorf("%s/ReqdExctnDt %q isn't ISO date", name, pmt.ReqdExctnDt)
+		}
+		if pmt.DbtrAcct.ID == "" && pmt.DbtrAcct.IBAN == "" {
+			return fmt.Errorf("%s/DbtrAcct/Id is required", name)
+		}
+		if pmt.DbtrAcct.Ccy != "" && !currencyPattern.MatchString(pmt.DbtrAcct.Ccy) {
+			return fmt.Errorf("%s/DbtrAcct/Ccy %q isn't valid", name, pmt.DbtrAcct.Ccy)
+		}
+		if len(pmt.Transactions) == 0 {
+			return fmt.Errorf("%s must have at least 1 CdtTrfTxInf", name)
+		}
+
+		for j, tx := range pmt.Transactions {
+			txName := fmt.Sprintf("%s/CdtTrfTxInf[%d]", name, j+1)
+			if err := checkText(txName+"/PmtId/EndToEndId", tx.EndToEndID, 35); err != nil {
+				return err
+			}
+			if tx.InstrID != "" {
+				if err := checkText(txName+"/PmtId/InstrId", tx.InstrID, 35); err != nil {
+					return err
+				}
+			}
+			if !amountPattern.MatchString(tx.Amount.Value) {
+				return fmt.Errorf("%s/Amt/InstdAmt %q isn't valid", txName, tx.Amount.Value)
+			}
+			if !currencyPattern.MatchString(tx.Amount.Ccy) {
+				return fmt.Errorf("%s/Amt/InstdAmt/@Ccy %q isn't valid", txName, tx.Amount.Ccy)
+			}
+			if tx.CdtrAcct.ID == "" && tx.CdtrAcct.IBAN == "" {
+				return fmt.Errorf("%s/CdtrAcct/Id is required", txName)
+			}
+			if len([]rune(tx.Remittance)) > 140 {
+				return fmt.Errorf("%s/RmtInf/Ustrd must have max 140 characters", txName)
+			}
+		}
+
+		if err := checkTotals(name, pmt.NbOfTxs, pmt.CtrlSum, pmt.Transactions); err != nil {
+			return err
+		}
+		all = append(all, pmt.Transactions...)
+	}
+
+	return checkTotals("GrpHdr", doc.GrpHdr.NbOfTxs, doc.GrpHdr.CtrlSum, all)
+}
@@ -0,0 +1,90 @@
+package iso20022
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func parseFile(t *testing.T, name string) (*Pain001, error) {
+	file, err := os.Open("testdata/" + name)
+	require.NoError(t, err)
+	defer file.Close()
+
+	return ParsePain001(file)
+}
+
+func TestParsePain001(t *testing.T) {
+	doc, err := parseFile(t, "pain001.xml")
+	require.NoError(t, err)
+
+	assert.Equal(t, "PAYROLL-2024-03", doc.GrpHdr.MsgID)
+	assert.Equal(t, "3", doc.GrpHdr.NbOfTxs)
+	require.Len(t, doc.Payments, 2)
+
+	pmt := doc.Payments[0]
+	assert.Equal(t, "PAYROLL-A", pmt.PmtInfID)
+	number, err := pmt.DbtrAcct.Number()
+	require.NoError(t, err)
+	assert.Equal(t, int64(1020300001), number)
+	assert.Equal(t, "YEN", WalletCurrency(pmt.DbtrAcct.Ccy))
+	require.Len(t, pmt.Transactions, 2)
+
+	tx := pmt.Transactions[0]
+	assert.Equal(t, "A-1", tx.InstrID)
+	assert.Equal(t, "SALARY-0001", tx.EndToEndID)
+	assert.Equal(t, "Salary March 2024", tx.Remittance)
+	number, err = tx.CdtrAcct.Number()
+	require.NoError(t, err)
+	assert.Equal(t, int64(1010000001), number)
+
+	amount, err := pmt.Transactions[1].WholeAmount()
+	require.NoError(t, err)
+	assert.Equal(t, int64(2000), amount)
+}
+
+func TestParsePain001Invalid(t *testing.T) {
+	tests := []struct {
+		file string
+		err  string
+	}{
+		{"pain001_namespace.xml", "namespace"},
+		{"pain001_nboftxs.xml", "GrpHdr/NbOfTxs"},
+		{"pain001_ctrlsum.xml", "PmtInf[1]/CtrlSum"},
+		{"pain001_amount.xml", "PmtInf[2]/CdtTrfTxInf[1]/Amt/InstdAmt"},
+	}
+	for _, test := range tests {
+		t.Run(test.file, func(t *testing.T) {
+			_, err := parseFile(t, test.file)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), test.err)
+		})
+	}
+
+	_, err := ParsePain001(strings.NewReader("<Document>"))
+	require.Error(t, err)
+}
+
+func TestWholeAmount(t *testing.T) {
+	tests := []struct {
+		value  string
+		amount int64
+		valid  bool
+	}{
+		{"1000", 1000, true},
+		{"1000.000", 1000, true},
+		{"1000.50", 0, false},
+	}
+	for _, test := range tests {
+		amount, err := CreditTransfer{Amount: Amount{Ccy: "JPY", Value: test.value}}.WholeAmount()
+		if !test.valid {
+			require.Error(t, err, test.value)
+			continue
+		}
+		require.NoError(t, err, test.value)
+		assert.Equal(t, test.amount, amount)
+	}
+}
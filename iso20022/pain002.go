@@ -0,0 +1,155 @@
+package iso20022
+
+import (
+	"encoding/xml"
+	"io"
+	"strconv"
+	"time"
+)
+
+const Pain002Namespace = "urn:iso:std:iso:20022:tech:xsd:pain.002.001.03"
+
+// Transaction status
+const (
+	// AcceptedSettlementCompleted, the money is on the creditor wallet
+	StatusSettled  = "ACSC"
+	StatusRejected = "RJCT"
+	// group and payment information status when only some transactions are settled
+	StatusPartial = "PART"
+)
+
+// Reason codes of rejected transactions (ExternalStatusReason1Code)
+const (
+	ReasonIncorrectAccount  = "AC01"
+	ReasonInsufficientFunds = "AM04"
+	ReasonInvalidAmount     = "AM12"
+	ReasonWrongCurrency     = "AM03"
+	ReasonForbidden         = "AG01"
+	ReasonNarrative         = "NARR"
+)
+
+// TransactionStatus is the result of 1 'CreditTransfer'.
+type TransactionStatus struct {
+	InstrID    string
+	EndToEndID string
+	Status     string
+	Reason     string
+	Info       string
+}
+
+// PaymentStatus is the result of 1 'PaymentInformation', in the same order as the transactions.
+type PaymentStatus struct {
+	PmtInfID     string
+	Transactions []TransactionStatus
+}
+
+type statusReason struct {
+	Code     string `xml:"Rsn>Cd"`
+	AddtlInf string `xml:"AddtlInf,omitempty"`
+}
+
+type txInfAndSts struct {
+	StsID           string        `xml:"StsId"`
+	OrgnlInstrID    string        `xml:"OrgnlInstrId,omitempty"`
+	OrgnlEndToEndID string        `xml:"OrgnlEndToEndId"`
+	TxSts           string        `xml:"TxSts"`
+	StsRsnInf       *statusReason `xml:"StsRsnInf,omitempty"`
+}
+
+type orgnlPmtInfAndSts struct {
+	OrgnlPmtInfID string        `xml:"OrgnlPmtInfId"`
+	PmtInfSts     string        `xml:"PmtInfSts"`
+	TxInfAndSts   []txInfAndSts `xml:"TxInfAndSts"`
+}
+
+// Pain002 is the customer payment status report message.
+type Pain002 struct {
+	XMLName xml.Name `xml:"Document"`
+	Xmlns   string   `xml:"xmlns,attr"`
+	GrpHdr  struct {
+		MsgID   string `xml:"MsgId"`
+		CreDtTm string `xml:"CreDtTm"`
+	} `xml:"CstmrPmtStsRpt>GrpHdr"`
+	OrgnlGrpInfAndSts struct {
+		OrgnlMsgID   string `xml:"OrgnlMsgId"`
+		OrgnlMsgNmID string `xml:"OrgnlMsgNmId"`
+		OrgnlNbOfTxs string `xml:"OrgnlNbOfTxs"`
+		OrgnlCtrlSum string `xml:"OrgnlCtrlSum,omitempty"`
+		GrpSts       string `xml:"GrpSts"`
+	} `xml:"CstmrPmtStsRpt>OrgnlGrpInfAndSts"`
+	OrgnlPmtInfAndSts []orgnlPmtInfAndSts `xml:"CstmrPmtStsRpt>OrgnlPmtInfAndSts"`
+}
+
+// groupStatus is ACSC when all transactions are settled, RJCT when none is settled, otherwise PART.
+func groupStatus(settled, total int) string {
+	switch settled {
+	case total:
+		return StatusSettled
+	case 0:
+		return StatusRejected
+	}
+	return StatusPartial
+}
+
+// NewPain002 build the status report of the 'original' pain.001 message.
+func NewPain002(msgID string, original *Pain001, payments []PaymentStatus, now time.Time) *Pain002 {
+	report := &Pain002{Xmlns: Pain002Namespace}
+	report.GrpHdr.MsgID = msgID
+	report.GrpHdr.CreDtTm = now.Format(isoDateTime)
+	report.OrgnlGrpInfAndSts.OrgnlMsgID = original.GrpHdr.MsgID
+	report.OrgnlGrpInfAndSts.OrgnlMsgNmID = "pain.001.001.03"
+	report.OrgnlGrpInfAndSts.OrgnlNbOfTxs = original.GrpHdr.NbOfTxs
+	report.OrgnlGrpInfAndSts.OrgnlCtrlSum = original.GrpHdr.CtrlSum
+
+	var settled, total int
+	for _, payment := range payments {
+		var paymentSettled int
+		info := orgnlPmtInfAndSts{OrgnlPmtInfID: payment.PmtInfID}
+		for _, tx := range payment.Transactions {
+			total++
+			status := txInfAndSts{
+				StsID:           msgID + "-" + strconv.Itoa(total),
+				OrgnlInstrID:    tx.InstrID,
+				OrgnlEndToEndID: tx.EndToEndID,
+				TxSts:           tx.Status,
+			}
+			if tx.Status == StatusSettled {
+				paymentSettled++
+			} else {
+				status.StsRsnInf = &statusReason{Code: tx.Reason, AddtlInf: truncate(tx.Info, 105)}
+			}
+			info.TxInfAndSts = append(info.TxInfAndSts, status)
+		}
+		info.PmtInfSts = groupStatus(paymentSettled, len(payment.Transactions))
+		settled += paymentSettled
+		report.OrgnlPmtInfAndSts = append(report.OrgnlPmtInfAndSts, info)
+	}
+	report.OrgnlGrpInfAndSts.GrpSts = groupStatus(settled, total)
+
+	return report
+}
+
+// Write encode the report as XML document.
+func (report *Pain002) Write(w io.Writer) error {
+	_, err := io.WriteString(w, xml.Header)
+	if err != nil {
+		return err
+	}
+
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	err = enc.Encode(report)
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(w, "\n")
+	return err
+}
+
+func truncate(s string, max int) string {
+	runes := []rune(s)
+	if len(runes) > max {
+		return string(runes[:max])
+	}
+	return s
+}
@@ -0,0 +1,64 @@
+package iso20022
+
+import (
+	"bytes"
+	"encoding/xml"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewPain002(t *testing.T) {
+	doc, err := parseFile(t, "pain001.xml")
+	require.NoError(t, err)
+
+	payments := []PaymentStatus{
+		{PmtInfID: "PAYROLL-A", Transactions: []TransactionStatus{
+			{InstrID: "A-1", EndToEndID: "SALARY-0001", Status: StatusSettled},
+			{EndToEndID: "SALARY-0002", Status: StatusRejected, Reason: ReasonInsufficientFunds, Info: "insufficient funds"},
+		}},
+		{PmtInfID: "PAYROLL-B", Transactions: []TransactionStatus{
+			{EndToEndID: "SALARY-0003", Status: StatusRejected, Reason: ReasonIncorrectAccount},
+		}},
+	}
+	now := time.Date(2024, 3, 25, 10, 0, 0, 0, time.UTC)
+
+	var buf bytes.Buffer
+	require.NoError(t, NewPain002("PSR1", doc, payments, now).Write(&buf))
+
+	var report Pain002
+	require.NoError(t, xml.Unmarshal(buf.Bytes(), &report))
+
+	assert.Equal(t, Pain002Namespace, report.XMLName.Space)
+	assert.Equal(t, "PSR1", report.GrpHdr.MsgID)
+	assert.Equal(t, "2024-03-25T10:00:00Z", report.GrpHdr.CreDtTm)
+	assert.Equal(t, "PAYROLL-2024-03", report.OrgnlGrpInfAndSts.OrgnlMsgID)
+	assert.Equal(t, "3", report.OrgnlGrpInfAndSts.OrgnlNbOfTxs)
+	assert.Equal(t, StatusPartial, report.OrgnlGrpInfAndSts.GrpSts)
+
+	require.Len(t, report.OrgnlPmtInfAndSts, 2)
+	a := report.OrgnlPmtInfAndSts[0]
+	assert.Equal(t, StatusPartial, a.PmtInfSts)
+	require.Len(t, a.TxInfAndSts, 2)
+	assert.Equal(t, "PSR1-1", a.TxInfAndSts[0].StsID)
+	assert.Equal(t, "A-1", a.TxInfAndSts[0].OrgnlInstrID)
+	assert.Equal(t, StatusSettled, a.TxInfAndSts[0].TxSts)
+	assert.Nil(t, a.TxInfAndSts[0].StsRsnInf)
+	assert.Equal(t, StatusRejected, a.TxInfAndSts[1].TxSts)
+	require.NotNil(t, a.TxInfAndSts[1].StsRsnInf)
+	assert.Equal(t, ReasonInsufficientFunds, a.TxInfAndSts[1].StsRsnInf.Code)
+
+	b := report.OrgnlPmtInfAndSts[1]
+	assert.Equal(t, StatusRejected, b.PmtInfSts)
+	require.Len(t, b.TxInfAndSts, 1)
+	assert.Equal(t, "PSR1-3", b.TxInfAndSts[0].StsID)
+	assert.Equal(t, ReasonIncorrectAccount, b.TxInfAndSts[0].StsRsnInf.Code)
+}
+
+func TestGroupStatus(t *testing.T) {
+	assert.Equal(t, StatusSettled, groupStatus(3, 3))
+	assert.Equal(t, StatusRejected, groupStatus(0, 3))
+	assert.Equal(t, StatusPartial, groupStatus(1, 3))
+}
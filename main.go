@@ -7,7 +7,11 @@ import (
 
 	"simple-bank-system/api"
 	"simple-bank-system/db/services"
+	"simple-bank-system/notification"
+	"simple-bank-system/outbox"
 	"simple-bank-system/util"
+	"simple-bank-system/webhook"
+	"simple-bank-system/worker"
 
 	"github.com/jackc/pgx/v4/pgxpool"
 )
@@ -29,6 +33,35 @@ func main() {
 	defer cancel()
 
 	store := services.NewStore(dbpool)
+
+	sinks := []outbox.Sink{
+		outbox.LogSink{},
+		webhook.NewSink(store),
+		notification.NewTransferSink(notification.NewService(store, config), config.LargeTransferAmount),
+	}
+	if config.OutboxFile != "" {
+		fileSink, err := outbox.NewFileSink(config.OutboxFile)
+		if err != nil {
+			log.Fatal("Can't open outbox file: ", err)
+		}
+		defer fileSink.Close()
+		sinks = append(sinks, fileSink)
+	}
+	if config.OutboxURL != "" {
+		sinks = append(sinks, outbox.NewHTTPSink(config.OutboxURL, 10*time.Second))
+	}
+
+	// background jobs live until the server is stopped
+	workerCtx, stopWorker := context.WithCancel(context.Background())
+	defer stopWorker()
+	worker.Start(workerCtx,
+		worker.InterestJob(store, config.InterestJobInterval),
+		worker.BalanceSnapshotJob(store, config.SnapshotJobInterval),
+		worker.OutboxRelayJob(outbox.NewRelay(store, sinks...), config.OutboxRelayInterval),
+		worker.WebhookJob(webhook.NewDispatcher(store, 10*time.Second), config.WebhookJobInterval),
+		worker.LoginThrottleCleanupJob(store, config.LoginFailureWindow, config.LoginThrottleCleanupInterval),
+	)
+
 	server, err := api.NewServer(store, ctx, config)
 	if err != nil {
 		log.Fatal("Can't create server, \nerr: ", err)
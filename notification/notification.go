@@ -0,0 +1,64 @@
+/*
+ * Package notification deliver events about account activity to the account owner.
+ * The API only depends on the 'Notifier' interface, so the way of delivery can be changed
+ * without touching the handlers. 'Service' render the template of the event and send it with
+ * every 'Sender' (email, in-app inbox) that the account's preferences allow.
+ */
+package notification
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// Event types
+const (
+	EventSavingsGoalReached = "savings_goal_reached"
+	EventWalletInvitation   = "wallet_invitation"
+	EventPaymentRequest     = "payment_request"
+	EventPaymentRequestPaid = "payment_request_paid"
+	EventLogin              = "login"
+	EventLargeTransfer      = "large_transfer"
+	EventMoneyReceived      = "money_received"
+	// security events are only sent by email to 'Event.Email', they have no preferences
+	EventEmailVerification = "email_verification"
+	EventPasswordReset     = "password_reset"
+)
+
+var EventTypes = []string{
+	EventLogin, EventLargeTransfer, EventMoneyReceived, EventSavingsGoalReached,
+	EventWalletInvitation, EventPaymentRequest, EventPaymentRequestPaid,
+}
+
+func ValidEventType(eventType string) bool {
+	for _, t := range EventTypes {
+		if t == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+type Event struct {
+	AccountID int64
+	Type      string
+	Message   string
+	Data      map[string]interface{}
+	CreatedAt time.Time
+	// Email is set for the security events, the event is only emailed to this address
+	// whatever the preferences are (e.g. the verification of a new email)
+	Email string
+}
+
+type Notifier interface {
+	Notify(ctx context.Context, event Event) error
+}
+
+// LogNotifier only write the event to the server log.
+type LogNotifier struct{}
+
+func (LogNotifier) Notify(ctx context.Context, event Event) error {
+	log.Printf("--- (notification) account %d, %s: %s\n", event.AccountID, event.Type, event.Message)
+	return nil
+}
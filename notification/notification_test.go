@@ -0,0 +1,326 @@
+package notification
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"simple-bank-system/db/pkg"
+	"simple-bank-system/db/services"
+	"simple-bank-system/outbox"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var recipient = services.NotificationRecipient{AccountID: 7, FullName: "Jane Doe", Email: "jane@example.com"}
+
+func TestRender(t *testing.T) {
+	content, err := Render(recipient, Event{
+		Type: EventMoneyReceived,
+		Data: map[string]interface{}{
+			"amount":             int64(250),
+			"currency":           "USD",
+			"from_wallet_number": int64(1010000001),
+			"to_wallet_number":   int64(1010000002),
+			"balance":            int64(900),
+		},
+		CreatedAt: time.Date(2024, 3, 1, 10, 30, 0, 0, time.UTC),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "You received 250 USD", content.Subject)
+	assert.Equal(t, "Hi Jane Doe,\n\nYour wallet 1010000002 received 250 USD from the wallet 1010000001 on 2024-03-01 10:30 UTC. The balance is 900 USD.", content.Body)
+
+	// every event type has its own template
+	for _, eventType := range EventTypes {
+		assert.Contains(t, templates, eventType)
+	}
+
+	content, err = Render(recipient, Event{Type: "unknown", Message: "Something happened"})
+	require.NoError(t, err)
+	assert.Equal(t, "Account activity", content.Subject)
+	assert.Contains(t, content.Body, "Something happened")
+}
+
+// fakeSMTPServer accept 1 mail and send its envelope and data to the channel.
+type fakeMail struct {
+	From string
+	To   string
+	Data string
+}
+
+func fakeSMTPServer(t *testing.T) (string, <-chan fakeMail) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { listener.Close() })
+
+	mails := make(chan fakeMail, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		reader := bufio.NewReader(conn)
+		reply := func(line string) { conn.Write([]byte(line + "\r\n")) }
+		reply("220 localhost fake SMTP")
+
+		var mail fakeMail
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				return
+			}
+			line = strings.TrimRight(line, "\r\n")
+			command := strings.ToUpper(line)
+			switch {
+			case strings.HasPrefix(command, "EHLO"), strings.HasPrefix(command, "HELO"):
+				reply("250 localhost")
+			case strings.HasPrefix(command, "MAIL FROM:"):
+				mail.From = strings.Trim(line[len("MAIL FROM:"):], "<>")
+				reply("250 OK")
+			case strings.HasPrefix(command, "RCPT TO:"):
+				mail.To = strings.Trim(line[len("RCPT TO:"):], "<>")
+				reply("250 OK")
+			case command == "DATA":
+				reply("354 End data with <CR><LF>.<CR><LF>")
+				var data strings.Builder
+				for {
+					dataLine, err := reader.ReadString('\n')
+					if err != nil {
+						return
+					}
+					if dataLine == ".\r\n" {
+						break
+					}
+					data.WriteString(dataLine)
+				}
+				mail.Data = data.String()
+				reply("250 OK")
+				mails <- mail
+			case command == "QUIT":
+				reply("221 Bye")
+				return
+			default:
+				reply("502 Command not implemented")
+			}
+		}
+	}()
+
+	return listener.Addr().String(), mails
+}
+
+func TestSMTPSender(t *testing.T) {
+	addr, mails := fakeSMTPServer(t)
+	sender := NewSMTPSender(addr, "no-reply@simplebank.local", "", "", time.Second)
+
+	err := sender.Send(context.Background(), recipient, Event{Type: EventLogin}, Content{
+		Subject: "New login\r\nBcc: evil@example.com",
+		Body:    "Hi Jane,\n.\nbye",
+	})
+	require.NoError(t, err)
+
+	select {
+	case mail := <-mails:
+		assert.Equal(t, "no-reply@simplebank.local", mail.From)
+		assert.Equal(t, recipient.Email, mail.To)
+		assert.Contains(t, mail.Data, "To: jane@example.com\r\n")
+		// a line break in the subject can't add a header
+		assert.Contains(t, mail.Data, "Subject: New login  Bcc: evil@example.com\r\n")
+		assert.NotContains(t, mail.Data, "\r\nBcc:")
+		// the dot line of the body is escaped
+		assert.Contains(t, mail.Data, "\r\n\r\nHi Jane,\r\n..\r\nbye\r\n")
+	case <-time.After(time.Second):
+		t.Fatal("mail isn't received")
+	}
+}
+
+func TestSMTPSenderUnreachable(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := listener.Addr().String()
+	listener.Close()
+
+	sender := NewSMTPSender(addr, "no-reply@simplebank.local", "", "", time.Second)
+	require.Error(t, sender.Send(context.Background(), recipient, Event{Type: EventLogin}, Content{Subject: "s", Body: "b"}))
+}
+
+type fakeStore struct {
+	preferences   []pkg.NotificationPreference
+	notifications []services.CreateNotificationParams
+}
+
+func (store *fakeStore) GetNotificationRecipient(ctx context.Context, accountID int64) (*services.NotificationRecipient, error) {
+	r := recipient
+	r.AccountID = accountID
+	return &r, nil
+}
+
+func (store *fakeStore) GetNotificationPreferences(ctx context.Context, accountID int64) ([]pkg.NotificationPreference, error) {
+	return store.preferences, nil
+}
+
+func (store *fakeStore) CreateNotification(ctx context.Context, arg services.CreateNotificationParams) (*pkg.Notification, error) {
+	store.notifications = append(store.notifications, arg)
+	return &pkg.Notification{AccountID: arg.AccountID, Type: arg.Type}, nil
+}
+
+type fakeSender struct {
+	channel string
+	sent    []Event
+	err     error
+}
+
+func (sender *fakeSender) Channel() string { return sender.channel }
+
+func (sender *fakeSender) Send(ctx context.Context, recipient services.NotificationRecipient, event Event, content Content) error {
+	sender.sent = append(sender.sent, event)
+	return sender.err
+}
+
+func TestServicePreferences(t *testing.T) {
+	store := &fakeStore{preferences: []pkg.NotificationPreference{
+		{EventType: EventLogin, Email: false, InApp: true},
+	}}
+	email := &fakeSender{channel: ChannelEmail}
+	service := &Service{store: store, senders: []Sender{NewInboxSender(store), email}}
+
+	// login is only in the inbox
+	require.NoError(t, service.Notify(context.Background(), Event{AccountID: 7, Type: EventLogin, Data: map[string]interface{}{"ip": "127.0.0.1"}}))
+	require.Len(t, store.notifications, 1)
+	assert.Equal(t, "New login to your account", store.notifications[0].Title)
+	assert.JSONEq(t, `{"ip":"127.0.0.1"}`, string(store.notifications[0].Data))
+	assert.Empty(t, email.sent)
+
+	// other event types use the default, every channel
+	require.NoError(t, service.Notify(context.Background(), Event{AccountID: 7, Type: EventWalletInvitation, Message: "invited"}))
+	assert.Len(t, store.notifications, 2)
+	assert.Len(t, email.sent, 1)
+
+	// a failed sender doesn't stop the others
+	email.err = errors.New("mail server is down")
+	err := service.Notify(context.Background(), Event{AccountID: 7, Type: EventPaymentRequest, Message: "pay"})
+	require.ErrorIs(t, err, email.err)
+	assert.Len(t, store.notifications, 3)
+
+	preferences := Preferences(store.preferences)
+	require.Len(t, preferences, len(EventTypes))
+	for _, preference := range preferences {
+		assert.Equal(t, preference.EventType != EventLogin, preference.Email)
+		assert.True(t, preference.InApp)
+	}
+}
+
+func TestServiceSecurityEmail(t *testing.T) {
+	store := &fakeStore{preferences: []pkg.NotificationPreference{
+		{EventType: EventEmailVerification, Email: false, InApp: true},
+	}}
+	email := &fakeSender{channel: ChannelEmail}
+	service := &Service{store: store, senders: []Sender{NewInboxSender(store), email}}
+
+	// the verification is only emailed, the preferences aren't used
+	event := Event{AccountID: 7, Type: EventEmailVerification, Email: "new@email.com", Data: map[string]interface{}{"token": "abc"}}
+	require.NoError(t, service.Notify(context.Background(), event))
+	assert.Empty(t, store.notifications)
+	require.Len(t, email.sent, 1)
+	assert.Equal(t, "new@email.com", email.sent[0].Email)
+
+	service = &Service{store: store, senders: []Sender{NewInboxSender(store)}}
+	require.ErrorIs(t, service.Notify(context.Background(), event), ErrNoEmailSender)
+	assert.Empty(t, store.notifications)
+}
+
+type fakeNotifier struct {
+	events []Event
+}
+
+func (notifier *fakeNotifier) Notify(ctx context.Context, event Event) error {
+	notifier.events = append(notifier.events, event)
+	return errors.New("ignored")
+}
+
+func TestTransferSink(t *testing.T) {
+	notifier := &fakeNotifier{}
+	sink := NewTransferSink(notifier, 1000)
+
+	message := func(amount int64) outbox.Message {
+		payload, err := json.Marshal(services.TransferEvent{
+			FromWalletNumber: 1010000001,
+			ToWalletNumber:   1010000002,
+			Amount:           amount,
+			Currency:         "USD",
+		})
+		require.NoError(t, err)
+		return outbox.Message{Type: services.EventTransferCompleted, AccountID: 3, Payload: payload}
+	}
+
+	// notifier errors don't fail the delivery
+	require.NoError(t, sink.Deliver(context.Background(), message(500)))
+	require.NoError(t, sink.Deliver(context.Background(), message(-500)))
+	require.NoError(t, sink.Deliver(context.Background(), message(-1000)))
+	require.NoError(t, sink.Deliver(context.Background(), outbox.Message{Type: services.EventWalletCreated, Payload: []byte(`{}`)}))
+
+	require.Len(t, notifier.events, 2)
+	assert.Equal(t, EventMoneyReceived, notifier.events[0].Type)
+	assert.Equal(t, int64(500), notifier.events[0].Data["amount"])
+	assert.Equal(t, EventLargeTransfer, notifier.events[1].Type)
+	assert.Equal(t, int64(1000), notifier.events[1].Data["amount"])
+	assert.Equal(t, int64(3), notifier.events[1].AccountID)
+}
+
+// blockingNotifier send the events to a channel, it wait until the test read them.
+type blockingNotifier struct {
+	events chan Event
+}
+
+func (notifier *blockingNotifier) Notify(ctx context.Context, event Event) error {
+	select {
+	case notifier.events <- event:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func TestQueue(t *testing.T) {
+	next := &blockingNotifier{events: make(chan Event)}
+	queue := NewQueue(next, 1, time.Second)
+
+	// the handler doesn't wait for the sender
+	require.NoError(t, queue.Notify(context.Background(), Event{AccountID: 1, Type: EventLogin}))
+	require.ErrorIs(t, queue.Notify(context.Background(), Event{AccountID: 2, Type: EventLogin}), ErrQueueFull)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		queue.Run(ctx)
+		close(done)
+	}()
+
+	select {
+	case event := <-next.events:
+		assert.Equal(t, int64(1), event.AccountID)
+	case <-time.After(time.Second):
+		t.Fatal("the queued event isn't sent")
+	}
+	require.NoError(t, queue.Notify(context.Background(), Event{AccountID: 3, Type: EventLogin}))
+	select {
+	case event := <-next.events:
+		assert.Equal(t, int64(3), event.AccountID)
+	case <-time.After(time.Second):
+		t.Fatal("the queued event isn't sent")
+	}
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Run doesn't stop")
+	}
+}
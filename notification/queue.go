@@ -0,0 +1,53 @@
+package notification
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+)
+
+// ErrQueueFull is returned when the events are queued faster than they are sent.
+var ErrQueueFull = errors.New("notification queue is full")
+
+/*
+ * Queue is the 'Notifier' of the handlers: Notify() only queue the event and 'Run' send it with the next
+ * notifier in the background, so a slow mail server never hold a request. The queue is in memory, the
+ * events that are still queued when the process stop are lost, notifications are best effort.
+ */
+type Queue struct {
+	next    Notifier
+	events  chan Event
+	timeout time.Duration
+}
+
+// NewQueue return a queue of 'size' events, every event has 'timeout' to be sent.
+func NewQueue(next Notifier, size int, timeout time.Duration) *Queue {
+	return &Queue{next: next, events: make(chan Event, size), timeout: timeout}
+}
+
+// Notify() never wait, it return ErrQueueFull instead.
+func (queue *Queue) Notify(ctx context.Context, event Event) error {
+	select {
+	case queue.events <- event:
+		return nil
+	default:
+		return ErrQueueFull
+	}
+}
+
+// Run() send the queued events until the context is canceled, the errors are logged.
+func (queue *Queue) Run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event := <-queue.events:
+			sendCtx, cancel := context.WithTimeout(ctx, queue.timeout)
+			if err := queue.next.Notify(sendCtx, event); err != nil {
+				log.Printf("--- (err) notify account %d, %s: %v\n", event.AccountID, event.Type, err)
+			}
+			cancel()
+		}
+	}
+}
@@ -0,0 +1,154 @@
+package notification
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"mime"
+	"net"
+	"net/smtp"
+	"strings"
+	"time"
+
+	"simple-bank-system/db/pkg"
+	"simple-bank-system/db/services"
+)
+
+// Channels of the senders, they are the fields of the preferences
+const (
+	ChannelEmail = "email"
+	ChannelInApp = "in_app"
+)
+
+// Sender deliver the rendered notification on 1 channel.
+type Sender interface {
+	Channel() string
+	Send(ctx context.Context, recipient services.NotificationRecipient, event Event, content Content) error
+}
+
+// InboxStore is the part of services.Store that is used by the inbox.
+type InboxStore interface {
+	CreateNotification(ctx context.Context, arg services.CreateNotificationParams) (*pkg.Notification, error)
+}
+
+// InboxSender save the notification in the in-app inbox of the account ('GET /notifications').
+type InboxSender struct {
+	store InboxStore
+}
+
+func NewInboxSender(store InboxStore) *InboxSender {
+	return &InboxSender{store: store}
+}
+
+func (sender *InboxSender) Channel() string { return ChannelInApp }
+
+func (sender *InboxSender) Send(ctx context.Context, recipient services.NotificationRecipient, event Event, content Content) error {
+	var data []byte
+	if event.Data != nil {
+		var err error
+		if data, err = json.Marshal(event.Data); err != nil {
+			return err
+		}
+	}
+
+	_, err := sender.store.CreateNotification(ctx, services.CreateNotificationParams{
+		AccountID: recipient.AccountID,
+		Type:      event.Type,
+		Title:     content.Subject,
+		Body:      content.Body,
+		Data:      data,
+	})
+	return err
+}
+
+// SMTPSender email the notification to the email of the account.
+type SMTPSender struct {
+	addr    string
+	host    string
+	from    string
+	auth    smtp.Auth
+	timeout time.Duration
+}
+
+// NewSMTPSender return the sender for the server 'addr' (host:port), there's no authentication when 'username' is empty.
+// The connection is upgraded with STARTTLS when the server support it.
+func NewSMTPSender(addr, from, username, password string, timeout time.Duration) *SMTPSender {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		host = addr
+	}
+
+	sender := &SMTPSender{addr: addr, host: host, from: from, timeout: timeout}
+	if username != "" {
+		sender.auth = smtp.PlainAuth("", username, password, host)
+	}
+	return sender
+}
+
+func (sender *SMTPSender) Channel() string { return ChannelEmail }
+
+func (sender *SMTPSender) Send(ctx context.Context, recipient services.NotificationRecipient, event Event, content Content) error {
+	dialer := net.Dialer{Timeout: sender.timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", sender.addr)
+	if err != nil {
+		return err
+	}
+	conn.SetDeadline(time.Now().Add(sender.timeout))
+
+	client, err := smtp.NewClient(conn, sender.host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err = client.StartTLS(&tls.Config{ServerName: sender.host}); err != nil {
+			return err
+		}
+	}
+	if sender.auth != nil {
+		if err = client.Auth(sender.auth); err != nil {
+			return err
+		}
+	}
+
+	if err = client.Mail(sender.from); err != nil {
+		return err
+	}
+	if err = client.Rcpt(recipient.Email); err != nil {
+		return err
+	}
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err = w.Write(sender.message(recipient, content)); err != nil {
+		return err
+	}
+	if err = w.Close(); err != nil {
+		return err
+	}
+
+	return client.Quit()
+}
+
+// message return the email, the data writer of the smtp client add the CRLF and dot-stuffing.
+func (sender *SMTPSender) message(recipient services.NotificationRecipient, content Content) []byte {
+	// the subject can contain names that the users chose, a line break would add headers
+	subject := strings.NewReplacer("\r", " ", "\n", " ").Replace(content.Subject)
+
+	var buf bytes.Buffer
+	buf.WriteString("From: " + sender.from + "\n")
+	buf.WriteString("To: " + recipient.Email + "\n")
+	buf.WriteString("Subject: " + mime.QEncoding.Encode("utf-8", subject) + "\n")
+	buf.WriteString("Date: " + time.Now().Format(time.RFC1123Z) + "\n")
+	buf.WriteString("MIME-Version: 1.0\n")
+	buf.WriteString("Content-Type: text/plain; charset=UTF-8\n")
+	buf.WriteString("Content-Transfer-Encoding: 8bit\n")
+	buf.WriteString("\n")
+	buf.WriteString(content.Body)
+	buf.WriteString("\n")
+	return buf.Bytes()
+}
@@ -0,0 +1,188 @@
+package notification
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"simple-bank-system/db/pkg"
+	"simple-bank-system/db/services"
+	"simple-bank-system/outbox"
+	"simple-bank-system/util"
+)
+
+// Store is the part of services.Store that is used by the service.
+type Store interface {
+	InboxStore
+	GetNotificationRecipient(ctx context.Context, accountID int64) (*services.NotificationRecipient, error)
+	GetNotificationPreferences(ctx context.Context, accountID int64) ([]pkg.NotificationPreference, error)
+}
+
+var _ Store = (*services.Store)(nil)
+
+// DefaultPreference is used for the event types that the account didn't change, every channel is on.
+func DefaultPreference(eventType string) pkg.NotificationPreference {
+	return pkg.NotificationPreference{EventType: eventType, Email: true, InApp: true}
+}
+
+// Preferences return the preference of every event type, the saved ones or the default.
+func Preferences(saved []pkg.NotificationPreference) []pkg.NotificationPreference {
+	byType := map[string]pkg.NotificationPreference{}
+	for _, preference := range saved {
+		byType[preference.EventType] = preference
+	}
+
+	var list []pkg.NotificationPreference
+	for _, eventType := range EventTypes {
+		preference, ok := byType[eventType]
+		if !ok {
+			preference = DefaultPreference(eventType)
+		}
+		list = append(list, preference)
+	}
+	return list
+}
+
+func enabled(preference pkg.NotificationPreference, channel string) bool {
+	switch channel {
+	case ChannelEmail:
+		return preference.Email
+	case ChannelInApp:
+		return preference.InApp
+	}
+	return true
+}
+
+// Service is the 'Notifier' that send the events with the senders the account's preferences allow.
+type Service struct {
+	store   Store
+	senders []Sender
+}
+
+// NewService return the service with the in-app inbox, and the email when 'SMTP_ADDR' is set.
+func NewService(store Store, config util.Config) *Service {
+	senders := []Sender{NewInboxSender(store)}
+	if config.SMTPAddr != "" {
+		senders = append(senders, NewSMTPSender(config.SMTPAddr, config.SMTPFrom, config.SMTPUsername, config.SMTPPassword, 10*time.Second))
+	}
+	return &Service{store: store, senders: senders}
+}
+
+// ErrNoEmailSender is returned for the events that must be emailed when SMTP isn't configured.
+var ErrNoEmailSender = errors.New("email isn't configured")
+
+// Notify() try every sender, the error is the first sender error.
+func (service *Service) Notify(ctx context.Context, event Event) error {
+	recipient, err := service.store.GetNotificationRecipient(ctx, event.AccountID)
+	if err != nil {
+		return err
+	}
+	if event.Email != "" {
+		return service.email(ctx, *recipient, event)
+	}
+
+	preference := DefaultPreference(event.Type)
+	saved, err := service.store.GetNotificationPreferences(ctx, event.AccountID)
+	if err != nil {
+		return err
+	}
+	for _, p := range saved {
+		if p.EventType == event.Type {
+			preference = p
+		}
+	}
+
+	content, err := Render(*recipient, event)
+	if err != nil {
+		return err
+	}
+
+	var sendErr error
+	for _, sender := range service.senders {
+		if !enabled(preference, sender.Channel()) {
+			continue
+		}
+		if err = sender.Send(ctx, *recipient, event, content); err != nil && sendErr == nil {
+			sendErr = fmt.Errorf("%s: %w", sender.Channel(), err)
+		}
+	}
+	return sendErr
+}
+
+// email() send the security event only to 'event.Email', it's never saved in the inbox.
+func (service *Service) email(ctx context.Context, recipient services.NotificationRecipient, event Event) error {
+	recipient.Email = event.Email
+	content, err := Render(recipient, event)
+	if err != nil {
+		return err
+	}
+
+	for _, sender := range service.senders {
+		if sender.Channel() == ChannelEmail {
+			return sender.Send(ctx, recipient, event, content)
+		}
+	}
+	return ErrNoEmailSender
+}
+
+/*
+ * TransferSink is the outbox sink that notify the transfers, so every kind of transfer (API, payment files,
+ * payment requests, interest) is notified. The receiver get 'money_received' and the sender get
+ * 'large_transfer' when the amount is at least 'largeAmount'.
+ * Notifications are best effort: a failed notification is logged and the event is still delivered, so a
+ * mail server that is down doesn't hold the outbox.
+ */
+type TransferSink struct {
+	notifier    Notifier
+	largeAmount int64
+}
+
+func NewTransferSink(notifier Notifier, largeAmount int64) *TransferSink {
+	return &TransferSink{notifier: notifier, largeAmount: largeAmount}
+}
+
+func (sink *TransferSink) Name() string { return "notification" }
+
+func (sink *TransferSink) Deliver(ctx context.Context, msg outbox.Message) error {
+	if msg.Type != services.EventTransferCompleted {
+		return nil
+	}
+
+	var transfer services.TransferEvent
+	if err := json.Unmarshal(msg.Payload, &transfer); err != nil {
+		log.Println("--- (err) notify transfer, payload:", err)
+		return nil
+	}
+
+	event := Event{
+		AccountID: msg.AccountID,
+		Data: map[string]interface{}{
+			"transfer_id":        transfer.TransferID,
+			"from_wallet_number": transfer.FromWalletNumber,
+			"to_wallet_number":   transfer.ToWalletNumber,
+			"amount":             transfer.Amount,
+			"currency":           transfer.Currency,
+			"balance":            transfer.Balance,
+		},
+		CreatedAt: transfer.Time,
+	}
+	switch {
+	case transfer.Amount > 0:
+		event.Type = EventMoneyReceived
+		event.Message = fmt.Sprintf("You received %d %s from the wallet %d", transfer.Amount, transfer.Currency, transfer.FromWalletNumber)
+	case sink.largeAmount > 0 && -transfer.Amount >= sink.largeAmount:
+		event.Type = EventLargeTransfer
+		event.Data["amount"] = -transfer.Amount
+		event.Message = fmt.Sprintf("You sent %d %s to the wallet %d", -transfer.Amount, transfer.Currency, transfer.ToWalletNumber)
+	default:
+		return nil
+	}
+
+	if err := sink.notifier.Notify(ctx, event); err != nil {
+		log.Println("--- (err) notify transfer:", err)
+	}
+	return nil
+}
@@ -0,0 +1,98 @@
+package notification
+
+import (
+	"bytes"
+	"text/template"
+	"time"
+
+	"simple-bank-system/db/services"
+)
+
+// Content is the rendered notification, it's the same for every channel.
+type Content struct {
+	Subject string
+	Body    string
+}
+
+type eventTemplate struct {
+	subject *template.Template
+	body    *template.Template
+}
+
+func newEventTemplate(subject, body string) eventTemplate {
+	return eventTemplate{
+		subject: template.Must(template.New("subject").Parse(subject)),
+		body:    template.Must(template.New("body").Parse(body)),
+	}
+}
+
+// Templates of the event types, events without their own template use 'defaultTemplate'
+var templates = map[string]eventTemplate{
+	EventLogin: newEventTemplate(
+		"New login to your account",
+		"Hi {{.Name}},\n\nYour account was logged in on {{.Time}} from {{.Data.ip}} ({{.Data.user_agent}}).\n"+
+			"If it wasn't you, change your password now.",
+	),
+	EventLargeTransfer: newEventTemplate(
+		"Large transfer of {{.Data.amount}} {{.Data.currency}}",
+		"Hi {{.Name}},\n\n{{.Data.amount}} {{.Data.currency}} was sent from your wallet {{.Data.from_wallet_number}} "+
+			"to the wallet {{.Data.to_wallet_number}} on {{.Time}}. The balance is {{.Data.balance}} {{.Data.currency}}.",
+	),
+	EventMoneyReceived: newEventTemplate(
+		"You received {{.Data.amount}} {{.Data.currency}}",
+		"Hi {{.Name}},\n\nYour wallet {{.Data.to_wallet_number}} received {{.Data.amount}} {{.Data.currency}} "+
+			"from the wallet {{.Data.from_wallet_number}} on {{.Time}}. The balance is {{.Data.balance}} {{.Data.currency}}.",
+	),
+	EventSavingsGoalReached: newEventTemplate("Savings goal reached", "Hi {{.Name}},\n\n{{.Message}}."),
+	EventWalletInvitation:   newEventTemplate("Invitation to a shared wallet", "Hi {{.Name}},\n\n{{.Message}}."),
+	EventPaymentRequest:     newEventTemplate("New payment request", "Hi {{.Name}},\n\n{{.Message}}."),
+	EventPaymentRequestPaid: newEventTemplate("Payment request paid", "Hi {{.Name}},\n\n{{.Message}}."),
+	EventEmailVerification: newEventTemplate(
+		"Verify your new email",
+		"Hi {{.Name}},\n\nUse this token to verify {{.Data.email}} as the email of your account: {{.Data.token}}\n"+
+			"The token expires on {{.Data.expires_at}}. If you didn't change your email, ignore this email.",
+	),
+	EventPasswordReset: newEventTemplate(
+		"Reset your password",
+		"Hi {{.Name}},\n\nUse this token to choose a new password: {{.Data.token}}\n"+
+			"The token can be used once and expires on {{.Data.expires_at}}. If you didn't forget your password, ignore this email.",
+	),
+}
+
+var defaultTemplate = newEventTemplate("Account activity", "Hi {{.Name}},\n\n{{.Message}}")
+
+type templateData struct {
+	Name    string
+	Message string
+	Data    map[string]interface{}
+	Time    string
+}
+
+// Render return the content of the event for the recipient.
+func Render(recipient services.NotificationRecipient, event Event) (Content, error) {
+	tmpl, ok := templates[event.Type]
+	if !ok {
+		tmpl = defaultTemplate
+	}
+
+	createdAt := event.CreatedAt
+	if createdAt.IsZero() {
+		createdAt = time.Now()
+	}
+	data := templateData{
+		Name:    recipient.FullName,
+		Message: event.Message,
+		Data:    event.Data,
+		Time:    createdAt.UTC().Format("2006-01-02 15:04 MST"),
+	}
+
+	var subject, body bytes.Buffer
+	if err := tmpl.subject.Execute(&subject, data); err != nil {
+		return Content{}, err
+	}
+	if err := tmpl.body.Execute(&body, data); err != nil {
+		return Content{}, err
+	}
+
+	return Content{Subject: subject.String(), Body: body.String()}, nil
+}
@@ -0,0 +1,157 @@
+/*
+ * Package outbox deliver the domain events of the transactional outbox to the sinks outside the process.
+ * The relay deliver every event at least once to every sink and the events of a wallet in order, so
+ * consumers track the 'id' of the last event they handled for a wallet (the cursor) and ignore events
+ * with an id that isn't greater than it.
+ */
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"simple-bank-system/db/pkg"
+	"simple-bank-system/db/services"
+)
+
+// Max events that are claimed together by the relay
+const defaultBatchSize = 100
+
+// Claimed events of a relay that stopped are claimed again after it
+const defaultLease = 5 * time.Minute
+
+// Message is the JSON form of the event that is sent to the sinks.
+type Message struct {
+	ID        int64           `json:"id"`
+	Type      string          `json:"type"`
+	AccountID int64           `json:"account_id"`
+	WalletID  int64           `json:"wallet_id,omitempty"`
+	Payload   json.RawMessage `json:"payload"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+func NewMessage(event pkg.OutboxEvent) Message {
+	return Message{
+		ID:        event.ID,
+		Type:      event.Type,
+		AccountID: event.AccountID,
+		WalletID:  event.WalletID,
+		Payload:   event.Payload,
+		CreatedAt: event.CreatedAt,
+	}
+}
+
+type Sink interface {
+	Name() string
+	// Deliver must return an error when the event isn't delivered, so it's retried.
+	Deliver(ctx context.Context, msg Message) error
+}
+
+// Store is the part of services.Store that is used by the relay.
+type Store interface {
+	ClaimOutboxEvents(ctx context.Context, limit int, lease time.Duration) ([]pkg.OutboxEvent, error)
+	MarkOutboxSinkDelivered(ctx context.Context, id int64, sink string) error
+	CompleteOutboxEvent(ctx context.Context, id int64) error
+	ReleaseOutboxEvent(ctx context.Context, id int64, lastError string) error
+}
+
+var _ Store = (*services.Store)(nil)
+
+type Relay struct {
+	store     Store
+	sinks     []Sink
+	BatchSize int
+	// how long the claimed events aren't claimed by other relays, it must be longer than delivering a batch
+	Lease time.Duration
+}
+
+func NewRelay(store Store, sinks ...Sink) *Relay {
+	return &Relay{
+		store:     store,
+		sinks:     sinks,
+		BatchSize: defaultBatchSize,
+		Lease:     defaultLease,
+	}
+}
+
+/*
+ * deliver send the event to every sink that doesn't have it yet. Each sink is recorded as soon as it has
+ * the event, so when 1 sink fails only that sink get the event again later.
+ */
+func (relay *Relay) deliver(ctx context.Context, event pkg.OutboxEvent) error {
+	delivered := map[string]bool{}
+	for _, name := range event.DeliveredSinks {
+		delivered[name] = true
+	}
+
+	msg := NewMessage(event)
+	for _, sink := range relay.sinks {
+		if delivered[sink.Name()] {
+			continue
+		}
+		if err := sink.Deliver(ctx, msg); err != nil {
+			return fmt.Errorf("%s sink: %w", sink.Name(), err)
+		}
+		if err := relay.store.MarkOutboxSinkDelivered(ctx, event.ID, sink.Name()); err != nil {
+			return err
+		}
+	}
+	return relay.store.CompleteOutboxEvent(ctx, event.ID)
+}
+
+// deliverBatch deliver the claimed events in order, the events of a wallet after a failed one wait for it.
+// It return the number of delivered events and the first delivery error.
+func (relay *Relay) deliverBatch(ctx context.Context, events []pkg.OutboxEvent) (int, error) {
+	var n int
+	var deliverErr error
+	failedWallets := map[int64]bool{}
+	for _, event := range events {
+		if event.WalletID != 0 && failedWallets[event.WalletID] {
+			if err := relay.store.ReleaseOutboxEvent(ctx, event.ID, ""); err != nil {
+				return n, err
+			}
+			continue
+		}
+
+		err := relay.deliver(ctx, event)
+		if err != nil {
+			if deliverErr == nil {
+				deliverErr = fmt.Errorf("deliver event %d: %w", event.ID, err)
+			}
+			failedWallets[event.WalletID] = true
+			if err = relay.store.ReleaseOutboxEvent(ctx, event.ID, err.Error()); err != nil {
+				return n, err
+			}
+			continue
+		}
+		n++
+	}
+	return n, deliverErr
+}
+
+// Run() deliver batches of events until the outbox is empty or a delivery fails.
+func (relay *Relay) Run(ctx context.Context) error {
+	var total int
+	for {
+		events, err := relay.store.ClaimOutboxEvents(ctx, relay.BatchSize, relay.Lease)
+		if err != nil {
+			return err
+		}
+		n, err := relay.deliverBatch(ctx, events)
+		total += n
+		if err != nil {
+			return err
+		}
+		if len(events) < relay.BatchSize || ctx.Err() != nil {
+			break
+		}
+	}
+
+	if total > 0 {
+		log.Printf("--- (outbox) %d events delivered\n", total)
+	}
+	return nil
+}
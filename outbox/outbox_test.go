@@ -0,0 +1,200 @@
+package outbox
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"simple-bank-system/db/pkg"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeStore keep the events like the outbox table, the claimed events aren't claimed again until they're released.
+type fakeStore struct {
+	events  []pkg.OutboxEvent
+	claimed map[int64]bool
+}
+
+func newFakeStore(events []pkg.OutboxEvent) *fakeStore {
+	return &fakeStore{events: events, claimed: map[int64]bool{}}
+}
+
+func (s *fakeStore) ClaimOutboxEvents(ctx context.Context, limit int, lease time.Duration) ([]pkg.OutboxEvent, error) {
+	var res []pkg.OutboxEvent
+	for _, event := range s.events {
+		if len(res) == limit {
+			break
+		}
+		if !s.claimed[event.ID] {
+			s.claimed[event.ID] = true
+			res = append(res, event)
+		}
+	}
+	return res, nil
+}
+
+func (s *fakeStore) MarkOutboxSinkDelivered(ctx context.Context, id int64, sink string) error {
+	for i := range s.events {
+		if s.events[i].ID == id {
+			s.events[i].DeliveredSinks = append(s.events[i].DeliveredSinks, sink)
+		}
+	}
+	return nil
+}
+
+func (s *fakeStore) CompleteOutboxEvent(ctx context.Context, id int64) error {
+	var left []pkg.OutboxEvent
+	for _, event := range s.events {
+		if event.ID != id {
+			left = append(left, event)
+		}
+	}
+	s.events = left
+	delete(s.claimed, id)
+	return nil
+}
+
+func (s *fakeStore) ReleaseOutboxEvent(ctx context.Context, id int64, lastError string) error {
+	delete(s.claimed, id)
+	return nil
+}
+
+type memorySink struct {
+	name string
+	ids  []int64
+	fail bool
+}
+
+func (s *memorySink) Name() string {
+	if s.name == "" {
+		return "memory"
+	}
+	return s.name
+}
+
+func (s *memorySink) Deliver(ctx context.Context, msg Message) error {
+	if s.fail {
+		return errors.New("sink is down")
+	}
+	s.ids = append(s.ids, msg.ID)
+	return nil
+}
+
+func testEvents(n int) []pkg.OutboxEvent {
+	var events []pkg.OutboxEvent
+	for i := 1; i <= n; i++ {
+		events = append(events, pkg.OutboxEvent{
+			ID:        int64(i),
+			Type:      "transfer.completed",
+			AccountID: 1,
+			WalletID:  2,
+			Payload:   []byte(`{"amount":100}`),
+			CreatedAt: time.Now(),
+		})
+	}
+	return events
+}
+
+func TestRelayRun(t *testing.T) {
+	store := newFakeStore(testEvents(5))
+	sink := &memorySink{}
+	relay := NewRelay(store, LogSink{}, sink)
+	relay.BatchSize = 2
+
+	require.NoError(t, relay.Run(context.Background()))
+	assert.Equal(t, []int64{1, 2, 3, 4, 5}, sink.ids)
+	assert.Empty(t, store.events)
+}
+
+func TestRelayRetry(t *testing.T) {
+	store := newFakeStore(testEvents(2))
+	sink := &memorySink{fail: true}
+	relay := NewRelay(store, sink)
+
+	err := relay.Run(context.Background())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "memory sink")
+	assert.Len(t, store.events, 2)
+
+	sink.fail = false
+	require.NoError(t, relay.Run(context.Background()))
+	assert.Equal(t, []int64{1, 2}, sink.ids)
+}
+
+func TestRelayFailingSink(t *testing.T) {
+	store := newFakeStore(testEvents(2))
+	working := &memorySink{name: "working"}
+	failing := &memorySink{name: "failing", fail: true}
+	relay := NewRelay(store, working, failing)
+
+	require.Error(t, relay.Run(context.Background()))
+	// the event of the wallet after the failed one wait for it
+	assert.Equal(t, []int64{1}, working.ids)
+	require.Len(t, store.events, 2)
+	assert.Equal(t, []string{"working"}, store.events[0].DeliveredSinks)
+	assert.Empty(t, store.claimed)
+
+	// the working sink doesn't get the event again
+	failing.fail = false
+	require.NoError(t, relay.Run(context.Background()))
+	assert.Equal(t, []int64{1, 2}, working.ids)
+	assert.Equal(t, []int64{1, 2}, failing.ids)
+	assert.Empty(t, store.events)
+}
+
+func TestFileSink(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.jsonl")
+	sink, err := NewFileSink(path)
+	require.NoError(t, err)
+
+	for _, event := range testEvents(2) {
+		require.NoError(t, sink.Deliver(context.Background(), NewMessage(event)))
+	}
+	require.NoError(t, sink.Close())
+
+	file, err := os.Open(path)
+	require.NoError(t, err)
+	defer file.Close()
+
+	var ids []int64
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var msg Message
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &msg))
+		assert.Equal(t, "transfer.completed", msg.Type)
+		assert.JSONEq(t, `{"amount":100}`, string(msg.Payload))
+		ids = append(ids, msg.ID)
+	}
+	assert.Equal(t, []int64{1, 2}, ids)
+}
+
+func TestHTTPSink(t *testing.T) {
+	var received Message
+	status := http.StatusNoContent
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "1", r.Header.Get("X-Event-Id"))
+		assert.Equal(t, "transfer.completed", r.Header.Get("X-Event-Type"))
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&received))
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	sink := NewHTTPSink(server.URL, time.Second)
+	msg := NewMessage(testEvents(1)[0])
+	require.NoError(t, sink.Deliver(context.Background(), msg))
+	assert.Equal(t, msg.ID, received.ID)
+	assert.Equal(t, msg.WalletID, received.WalletID)
+
+	status = http.StatusInternalServerError
+	require.Error(t, sink.Deliver(context.Background(), msg))
+}
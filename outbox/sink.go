@@ -0,0 +1,105 @@
+package outbox
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// LogSink write the events to the server log.
+type LogSink struct{}
+
+func (LogSink) Name() string { return "log" }
+
+func (LogSink) Deliver(ctx context.Context, msg Message) error {
+	log.Printf("--- (outbox) event %d %s, account %d, wallet %d: %s\n", msg.ID, msg.Type, msg.AccountID, msg.WalletID, msg.Payload)
+	return nil
+}
+
+// FileSink append the events to a file as JSON lines.
+type FileSink struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+func NewFileSink(path string) (*FileSink, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	return &FileSink{file: file}, nil
+}
+
+func (sink *FileSink) Name() string { return "file" }
+
+// Deliver write 1 line and sync it, so the event is on disk before it's marked as delivered.
+func (sink *FileSink) Deliver(ctx context.Context, msg Message) error {
+	line, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	sink.mu.Lock()
+	defer sink.mu.Unlock()
+
+	_, err = sink.file.Write(append(line, '\n'))
+	if err != nil {
+		return err
+	}
+	return sink.file.Sync()
+}
+
+func (sink *FileSink) Close() error {
+	return sink.file.Close()
+}
+
+// HTTPSink POST every event as JSON to the URL, any status other than 2xx is a failed delivery.
+type HTTPSink struct {
+	url    string
+	client *http.Client
+}
+
+func NewHTTPSink(url string, timeout time.Duration) *HTTPSink {
+	return &HTTPSink{
+		url:    url,
+		client: &http.Client{Timeout: timeout},
+	}
+}
+
+func (sink *HTTPSink) Name() string { return "http" }
+
+func (sink *HTTPSink) Deliver(ctx context.Context, msg Message) error {
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, sink.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	// the consumer can dedupe with the id without reading the body
+	req.Header.Set("X-Event-Id", strconv.FormatInt(msg.ID, 10))
+	req.Header.Set("X-Event-Type", msg.Type)
+
+	res, err := sink.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	io.Copy(io.Discard, res.Body)
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return fmt.Errorf("%s answered %s", sink.url, res.Status)
+	}
+	return nil
+}
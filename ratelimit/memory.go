@@ -0,0 +1,47 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// the full buckets are dropped every 'sweepEvery' requests
+const sweepEvery = 1024
+
+// Memory keep the buckets of this server.
+type Memory struct {
+	mu      sync.Mutex
+	buckets map[string]*Bucket
+	takes   int
+}
+
+func NewMemory() *Memory {
+	return &Memory{buckets: map[string]*Bucket{}}
+}
+
+func (memory *Memory) Take(ctx context.Context, key string, policy Policy, now time.Time) (Result, error) {
+	memory.mu.Lock()
+	defer memory.mu.Unlock()
+
+	memory.takes++
+	if memory.takes%sweepEvery == 0 {
+		memory.sweep(now)
+	}
+
+	bucket, ok := memory.buckets[key]
+	if !ok {
+		bucket = &Bucket{}
+		memory.buckets[key] = bucket
+	}
+	return policy.Take(bucket, now), nil
+}
+
+// sweep drop the buckets that are idle for 'MaxPeriod', they are full again.
+func (memory *Memory) sweep(now time.Time) {
+	for key, bucket := range memory.buckets {
+		if now.Sub(bucket.UpdatedAt) > MaxPeriod {
+			delete(memory.buckets, key)
+		}
+	}
+}
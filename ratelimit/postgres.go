@@ -0,0 +1,46 @@
+package ratelimit
+
+import (
+	"context"
+	"log"
+	"sync/atomic"
+	"time"
+
+	"simple-bank-system/db/pkg"
+	"simple-bank-system/db/services"
+)
+
+// Store is the part of services.Store that keep the buckets.
+type Store interface {
+	// UpdateRateLimitBucketTx lock the bucket of the key (the zero bucket when it's new) and save it after 'update'.
+	UpdateRateLimitBucketTx(ctx context.Context, key string, update func(bucket *pkg.RateLimitBucket)) error
+	PruneRateLimitBuckets(ctx context.Context, before time.Time) (int64, error)
+}
+
+var _ Store = (*services.Store)(nil)
+
+// Postgres keep the buckets in the database, so every server share them.
+type Postgres struct {
+	store Store
+	takes int64
+}
+
+func NewPostgres(store Store) *Postgres {
+	return &Postgres{store: store}
+}
+
+func (postgres *Postgres) Take(ctx context.Context, key string, policy Policy, now time.Time) (Result, error) {
+	if atomic.AddInt64(&postgres.takes, 1)%sweepEvery == 0 {
+		if _, err := postgres.store.PruneRateLimitBuckets(ctx, now.Add(-MaxPeriod)); err != nil {
+			log.Println("--- (err) prune rate limit buckets:", err)
+		}
+	}
+
+	var result Result
+	err := postgres.store.UpdateRateLimitBucketTx(ctx, key, func(row *pkg.RateLimitBucket) {
+		bucket := Bucket{Tokens: row.Tokens, UpdatedAt: row.UpdatedAt}
+		result = policy.Take(&bucket, now)
+		row.Tokens, row.UpdatedAt = bucket.Tokens, bucket.UpdatedAt
+	})
+	return result, err
+}
@@ -0,0 +1,98 @@
+/*
+ * Package ratelimit is the token bucket rate limiter of the API. A policy allow 'Limit' requests per 'Period'
+ * with bursts of 'Limit', every key (e.g. an account or a client IP) has its own bucket. The buckets are kept
+ * in memory, or in Postgres when the limit is shared by several servers.
+ */
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MaxPeriod is the longest period of a policy, the buckets idle for longer are full and can be dropped.
+const MaxPeriod = 24 * time.Hour
+
+type Policy struct {
+	Name   string
+	Limit  int
+	Period time.Duration
+}
+
+// ParsePolicy read a policy written '<limit>/<period>', e.g. '10/1m'.
+func ParsePolicy(name, value string) (Policy, error) {
+	limit, period, ok := strings.Cut(value, "/")
+	if !ok {
+		return Policy{}, fmt.Errorf("rate limit %s: '%s' isn't '<limit>/<period>'", name, value)
+	}
+
+	policy := Policy{Name: name}
+	var err error
+	if policy.Limit, err = strconv.Atoi(strings.TrimSpace(limit)); err != nil || policy.Limit < 1 {
+		return Policy{}, fmt.Errorf("rate limit %s: limit must be a positive number", name)
+	}
+	if policy.Period, err = time.ParseDuration(strings.TrimSpace(period)); err != nil || policy.Period <= 0 || policy.Period > MaxPeriod {
+		return Policy{}, fmt.Errorf("rate limit %s: period must be a duration up to %s", name, MaxPeriod)
+	}
+	return policy, nil
+}
+
+// rate is the number of tokens added every second.
+func (policy Policy) rate() float64 {
+	return float64(policy.Limit) / policy.Period.Seconds()
+}
+
+// Bucket is the state of 1 key, the zero bucket is full.
+type Bucket struct {
+	Tokens    float64
+	UpdatedAt time.Time
+}
+
+type Result struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// time until the bucket is full again
+	Reset time.Duration
+	// time until the next request is allowed, it's zero when the request is allowed
+	RetryAfter time.Duration
+}
+
+// Take refill the bucket since its last update and take 1 token, the bucket isn't changed by a refused request.
+func (policy Policy) Take(bucket *Bucket, now time.Time) Result {
+	burst := float64(policy.Limit)
+	tokens := bucket.Tokens
+	if elapsed := now.Sub(bucket.UpdatedAt); elapsed > 0 {
+		tokens = math.Min(burst, tokens+elapsed.Seconds()*policy.rate())
+	}
+
+	result := Result{Limit: policy.Limit}
+	if tokens >= 1 {
+		tokens--
+		result.Allowed = true
+		bucket.Tokens = tokens
+		if now.After(bucket.UpdatedAt) {
+			bucket.UpdatedAt = now
+		}
+	} else {
+		result.RetryAfter = policy.duration(1 - tokens)
+	}
+
+	result.Remaining = int(math.Floor(tokens))
+	result.Reset = policy.duration(burst - tokens)
+	return result
+}
+
+// duration is the time to add 'tokens'.
+func (policy Policy) duration(tokens float64) time.Duration {
+	return time.Duration(math.Ceil(tokens / policy.rate() * float64(time.Second)))
+}
+
+// Backend keep the buckets.
+type Backend interface {
+	Take(ctx context.Context, key string, policy Policy, now time.Time) (Result, error)
+}
@@ -0,0 +1,74 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePolicy(t *testing.T) {
+	policy, err := ParsePolicy("login", "10/1m")
+	require.NoError(t, err)
+	assert.Equal(t, Policy{Name: "login", Limit: 10, Period: time.Minute}, policy)
+
+	for _, value := range []string{"10", "0/1m", "x/1m", "10/x", "10/48h"} {
+		_, err = ParsePolicy("login", value)
+		assert.Error(t, err, value)
+	}
+}
+
+func TestTake(t *testing.T) {
+	policy := Policy{Name: "read", Limit: 3, Period: 3 * time.Second}
+	now := time.Now()
+	var bucket Bucket
+
+	// a new bucket is full
+	for i := 2; i >= 0; i-- {
+		result := policy.Take(&bucket, now)
+		require.True(t, result.Allowed)
+		assert.Equal(t, i, result.Remaining)
+	}
+
+	result := policy.Take(&bucket, now)
+	require.False(t, result.Allowed)
+	assert.Equal(t, 0, result.Remaining)
+	assert.Equal(t, time.Second, result.RetryAfter)
+	assert.Equal(t, 3*time.Second, result.Reset)
+
+	// 1 token is added every second
+	result = policy.Take(&bucket, now.Add(1500*time.Millisecond))
+	require.True(t, result.Allowed)
+	assert.Equal(t, 0, result.Remaining)
+	assert.Equal(t, 500*time.Millisecond, policy.Take(&bucket, now.Add(1500*time.Millisecond)).RetryAfter)
+
+	// the bucket is never more than full
+	result = policy.Take(&bucket, now.Add(time.Hour))
+	require.True(t, result.Allowed)
+	assert.Equal(t, 2, result.Remaining)
+}
+
+func TestMemory(t *testing.T) {
+	ctx := context.Background()
+	memory := NewMemory()
+	policy := Policy{Name: "login", Limit: 1, Period: time.Minute}
+	now := time.Now()
+
+	result, err := memory.Take(ctx, "login:ip:192.0.2.1", policy, now)
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+
+	result, err = memory.Take(ctx, "login:ip:192.0.2.1", policy, now)
+	require.NoError(t, err)
+	assert.False(t, result.Allowed)
+
+	// every key has its own bucket
+	result, err = memory.Take(ctx, "login:ip:192.0.2.2", policy, now)
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+
+	memory.sweep(now.Add(MaxPeriod + time.Second))
+	assert.Empty(t, memory.buckets)
+}
@@ -0,0 +1,162 @@
+/*
+ * Package revocation keep the revoked access tokens. The revocations are saved in the database and cached
+ * in memory, so 'authMiddleware' check them without a query on every request. The cache is refreshed every
+ * interval to get the revocations of the other servers, and the revocations of expired tokens are pruned.
+ */
+package revocation
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"simple-bank-system/db/pkg"
+	"simple-bank-system/db/services"
+	"simple-bank-system/token"
+
+	"github.com/google/uuid"
+)
+
+// Store is the part of services.Store that save the revocations.
+type Store interface {
+	RevokeToken(ctx context.Context, arg services.RevokeTokenParams) error
+	RevokeAccountTokensTx(ctx context.Context, arg services.RevokeAccountTokensParams) error
+	ListTokenRevocations(ctx context.Context, now time.Time) ([]pkg.TokenRevocation, error)
+	PruneTokenRevocations(ctx context.Context, now time.Time) (int64, error)
+}
+
+var _ Store = (*services.Store)(nil)
+
+// accountCutoff revoke the tokens of the account that were issued before 'before'.
+type accountCutoff struct {
+	before    time.Time
+	expiresAt time.Time
+}
+
+type List struct {
+	store Store
+	mu    sync.RWMutex
+	// token id -> expiry of the token
+	tokens   map[uuid.UUID]time.Time
+	accounts map[int64]accountCutoff
+}
+
+func NewList(store Store) *List {
+	return &List{
+		store:    store,
+		tokens:   map[uuid.UUID]time.Time{},
+		accounts: map[int64]accountCutoff{},
+	}
+}
+
+// Revoked() only read the cache.
+func (list *List) Revoked(payload *token.Payload) bool {
+	list.mu.RLock()
+	defer list.mu.RUnlock()
+
+	if _, ok := list.tokens[payload.ID]; ok {
+		return true
+	}
+	cutoff, ok := list.accounts[payload.AccountID]
+	return ok && payload.IssuedBefore(cutoff.before)
+}
+
+// Revoke() revoke the token of the payload (logout).
+func (list *List) Revoke(ctx context.Context, payload *token.Payload) error {
+	err := list.store.RevokeToken(ctx, services.RevokeTokenParams{
+		TokenID:   payload.ID,
+		AccountID: payload.AccountID,
+		ExpiresAt: payload.ExpiredAt,
+	})
+	if err != nil {
+		return err
+	}
+
+	list.mu.Lock()
+	list.tokens[payload.ID] = payload.ExpiredAt
+	list.mu.Unlock()
+	return nil
+}
+
+// RevokeAccount() revoke every token of the account that was issued before 'now' (logout everywhere),
+// 'tokenDuration' is the lifetime of the access tokens.
+func (list *List) RevokeAccount(ctx context.Context, accountID int64, now time.Time, tokenDuration time.Duration) error {
+	cutoff := accountCutoff{before: now, expiresAt: now.Add(tokenDuration)}
+	err := list.store.RevokeAccountTokensTx(ctx, services.RevokeAccountTokensParams{
+		AccountID:     accountID,
+		RevokedBefore: cutoff.before,
+		ExpiresAt:     cutoff.expiresAt,
+	})
+	if err != nil {
+		return err
+	}
+
+	list.mu.Lock()
+	list.addCutoff(accountID, cutoff)
+	list.mu.Unlock()
+	return nil
+}
+
+// addCutoff keep the latest cutoff of the account, 'mu' must be locked.
+func (list *List) addCutoff(accountID int64, cutoff accountCutoff) {
+	if old, ok := list.accounts[accountID]; ok && old.before.After(cutoff.before) {
+		return
+	}
+	list.accounts[accountID] = cutoff
+}
+
+/*
+ * Refresh() prune the expired revocations and load the revocations of the database in the cache.
+ * The entries of the cache that aren't expired are kept, they may be revoked while the list is read.
+ */
+func (list *List) Refresh(ctx context.Context, now time.Time) error {
+	if _, err := list.store.PruneTokenRevocations(ctx, now); err != nil {
+		return err
+	}
+	revocations, err := list.store.ListTokenRevocations(ctx, now)
+	if err != nil {
+		return err
+	}
+
+	list.mu.Lock()
+	defer list.mu.Unlock()
+
+	for id, expiresAt := range list.tokens {
+		if !expiresAt.After(now) {
+			delete(list.tokens, id)
+		}
+	}
+	for accountID, cutoff := range list.accounts {
+		if !cutoff.expiresAt.After(now) {
+			delete(list.accounts, accountID)
+		}
+	}
+
+	for _, revocation := range revocations {
+		if revocation.TokenID.Valid {
+			list.tokens[revocation.TokenID.UUID] = revocation.ExpiresAt
+			continue
+		}
+		list.addCutoff(revocation.AccountID, accountCutoff{before: revocation.RevokedBefore.Time, expiresAt: revocation.ExpiresAt})
+	}
+	return nil
+}
+
+// Run() refresh the cache every 'interval' until the context is canceled.
+func (list *List) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := list.Refresh(ctx, time.Now()); err != nil {
+			log.Println("--- (err) refresh token revocations:", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
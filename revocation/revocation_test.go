@@ -0,0 +1,108 @@
+package revocation
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"simple-bank-system/db/pkg"
+	"simple-bank-system/db/services"
+	"simple-bank-system/token"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeStore keep the revocations like the token_revocations table.
+type fakeStore struct {
+	revocations []pkg.TokenRevocation
+}
+
+func (store *fakeStore) RevokeToken(ctx context.Context, arg services.RevokeTokenParams) error {
+	store.revocations = append(store.revocations, pkg.TokenRevocation{
+		TokenID:   uuid.NullUUID{UUID: arg.TokenID, Valid: true},
+		AccountID: arg.AccountID,
+		ExpiresAt: arg.ExpiresAt,
+	})
+	return nil
+}
+
+func (store *fakeStore) RevokeAccountTokensTx(ctx context.Context, arg services.RevokeAccountTokensParams) error {
+	store.revocations = append(store.revocations, pkg.TokenRevocation{
+		AccountID:     arg.AccountID,
+		RevokedBefore: sql.NullTime{Time: arg.RevokedBefore, Valid: true},
+		ExpiresAt:     arg.ExpiresAt,
+	})
+	return nil
+}
+
+func (store *fakeStore) ListTokenRevocations(ctx context.Context, now time.Time) ([]pkg.TokenRevocation, error) {
+	return store.revocations, nil
+}
+
+func (store *fakeStore) PruneTokenRevocations(ctx context.Context, now time.Time) (int64, error) {
+	var kept []pkg.TokenRevocation
+	for _, revocation := range store.revocations {
+		if revocation.ExpiresAt.After(now) {
+			kept = append(kept, revocation)
+		}
+	}
+	pruned := int64(len(store.revocations) - len(kept))
+	store.revocations = kept
+	return pruned, nil
+}
+
+func newPayload(accountID int64, issuedAt time.Time) *token.Payload {
+	return &token.Payload{ID: uuid.New(), AccountID: accountID, IssuedAt: issuedAt, ExpiredAt: issuedAt.Add(15 * time.Minute)}
+}
+
+func TestRevoke(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	list := NewList(&fakeStore{})
+
+	payload := newPayload(1, now)
+	other := newPayload(1, now)
+	require.NoError(t, list.Revoke(ctx, payload))
+	assert.True(t, list.Revoked(payload))
+	assert.False(t, list.Revoked(other))
+
+	// the tokens issued before the logout everywhere are revoked, not the next ones
+	require.NoError(t, list.RevokeAccount(ctx, 1, now.Add(time.Second), 15*time.Minute))
+	assert.True(t, list.Revoked(other))
+	assert.False(t, list.Revoked(newPayload(1, now.Add(2*time.Second))))
+	assert.False(t, list.Revoked(newPayload(2, now)))
+
+	// 'iat' of the JWT is in whole seconds, a token of the second of the logout may be older than it
+	require.NoError(t, list.RevokeAccount(ctx, 3, now, 15*time.Minute))
+	assert.True(t, list.Revoked(newPayload(3, now.Add(-time.Nanosecond))))
+	assert.True(t, list.Revoked(newPayload(3, now.Truncate(time.Second))))
+	assert.False(t, list.Revoked(newPayload(3, now.Truncate(time.Second).Add(time.Second))))
+}
+
+func TestRefresh(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	store := &fakeStore{}
+
+	// revoked by another server
+	payload := newPayload(1, now)
+	first := NewList(store)
+	require.NoError(t, first.Revoke(ctx, payload))
+	require.NoError(t, first.RevokeAccount(ctx, 2, now.Add(time.Second), 15*time.Minute))
+
+	second := NewList(store)
+	assert.False(t, second.Revoked(payload))
+	require.NoError(t, second.Refresh(ctx, now))
+	assert.True(t, second.Revoked(payload))
+	assert.True(t, second.Revoked(newPayload(2, now)))
+
+	// after the tokens expire the revocations are pruned from the store and the cache
+	later := now.Add(time.Hour)
+	require.NoError(t, second.Refresh(ctx, later))
+	assert.Empty(t, store.revocations)
+	assert.Empty(t, second.tokens)
+	assert.Empty(t, second.accounts)
+}
@@ -0,0 +1,170 @@
+package statement
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+)
+
+const camt053Namespace = "urn:iso:std:iso:20022:tech:xsd:camt.053.001.02"
+
+// ISO 20022 date time without fraction, e.g. 2024-03-01T10:00:00Z
+const isoDateTime = "2006-01-02T15:04:05Z07:00"
+
+type camtAmount struct {
+	Ccy   string `xml:"Ccy,attr"`
+	Value string `xml:",chardata"`
+}
+
+type camtAccount struct {
+	ID  string `xml:"Id>Othr>Id"`
+	Ccy string `xml:"Ccy,omitempty"`
+	Nm  string `xml:"Ownr>Nm,omitempty"`
+}
+
+type camtBalance struct {
+	XMLName   xml.Name   `xml:"Bal"`
+	Code      string     `xml:"Tp>CdOrPrtry>Cd"`
+	Amt       camtAmount `xml:"Amt"`
+	CdtDbtInd string     `xml:"CdtDbtInd"`
+	Dt        string     `xml:"Dt>Dt"`
+}
+
+type camtParty struct {
+	Nm string `xml:"Nm"`
+}
+
+type camtRelatedParties struct {
+	Dbtr     *camtParty   `xml:"Dbtr,omitempty"`
+	DbtrAcct *camtAccount `xml:"DbtrAcct,omitempty"`
+	Cdtr     *camtParty   `xml:"Cdtr,omitempty"`
+	CdtrAcct *camtAccount `xml:"CdtrAcct,omitempty"`
+}
+
+type camtTxDetails struct {
+	TxID      string              `xml:"Refs>TxId"`
+	RltdPties *camtRelatedParties `xml:"RltdPties,omitempty"`
+}
+
+type camtEntry struct {
+	XMLName      xml.Name       `xml:"Ntry"`
+	NtryRef      string         `xml:"NtryRef"`
+	Amt          camtAmount     `xml:"Amt"`
+	CdtDbtInd    string         `xml:"CdtDbtInd"`
+	Sts          string         `xml:"Sts"`
+	BookgDt      string         `xml:"BookgDt>DtTm"`
+	ValDt        string         `xml:"ValDt>Dt"`
+	Domain       string         `xml:"BkTxCd>Domn>Cd"`
+	Family       string         `xml:"BkTxCd>Domn>Fmly>Cd"`
+	SubFamily    string         `xml:"BkTxCd>Domn>Fmly>SubFmlyCd"`
+	TxDtls       *camtTxDetails `xml:"NtryDtls>TxDtls,omitempty"`
+	AddtlNtryInf string         `xml:"AddtlNtryInf,omitempty"`
+}
+
+// camt053Writer write ISO 20022 bank to customer statement (camt.053.001.02) with 1 'Stmt'.
+type camt053Writer struct {
+	w      io.Writer
+	enc    *xml.Encoder
+	header Header
+}
+
+func newCamt053Writer(w io.Writer) *camt053Writer {
+	return &camt053Writer{w: w, enc: xml.NewEncoder(w)}
+}
+
+// creditDebit return the ISO 20022 credit/debit indicator and the absolute amount.
+func creditDebit(amount int64) (string, string) {
+	if amount < 0 {
+		return "DBIT", strconv.FormatInt(-amount, 10)
+	}
+	return "CRDT", strconv.FormatInt(amount, 10)
+}
+
+func (c *camt053Writer) balance(code string, amount int64, date time.Time) camtBalance {
+	indicator, value := creditDebit(amount)
+	return camtBalance{
+		Code:      code,
+		Amt:       camtAmount{Ccy: isoCurrency(c.header.Currency), Value: value},
+		CdtDbtInd: indicator,
+		Dt:        date.Format(time.DateOnly),
+	}
+}
+
+func (c *camt053Writer) WriteHeader(header Header) error {
+	c.header = header
+
+	id := fmt.Sprintf("%d-%s", header.WalletNumber, header.To.Format("20060102"))
+	_, err := fmt.Fprintf(c.w, `<?xml version="1.0" encoding="UTF-8"?>
+<Document xmlns="%s"><BkToCstmrStmt><GrpHdr><MsgId>%s</MsgId><CreDtTm>%s</CreDtTm></GrpHdr><Stmt><Id>%s</Id><CreDtTm>%s</CreDtTm><FrToDt><FrDtTm>%s</FrDtTm><ToDtTm>%s</ToDtTm></FrToDt>`,
+		camt053Namespace, id, header.GeneratedAt.Format(isoDateTime), id, header.GeneratedAt.Format(isoDateTime),
+		header.From.Format(isoDateTime), header.To.AddDate(0, 0, 1).Add(-time.Second).Format(isoDateTime))
+	if err != nil {
+		return err
+	}
+
+	acct := struct {
+		XMLName xml.Name `xml:"Acct"`
+		camtAccount
+	}{camtAccount: camtAccount{ID: strconv.FormatInt(header.WalletNumber, 10), Ccy: isoCurrency(header.Currency), Nm: header.HolderName}}
+	err = c.enc.Encode(acct)
+	if err != nil {
+		return err
+	}
+
+	err = c.enc.Encode(c.balance("OPBD", header.OpeningBalance, header.From))
+	if err != nil {
+		return err
+	}
+	return c.enc.Encode(c.balance("CLBD", header.ClosingBalance, header.To))
+}
+
+func (c *camt053Writer) WriteLine(line Line) error {
+	indicator, value := creditDebit(line.Amount)
+	entry := camtEntry{
+		NtryRef:      strconv.FormatInt(line.EntryID, 10),
+		Amt:          camtAmount{Ccy: isoCurrency(c.header.Currency), Value: value},
+		CdtDbtInd:    indicator,
+		Sts:          "BOOK",
+		BookgDt:      line.Date.Format(isoDateTime),
+		ValDt:        line.Date.Format(time.DateOnly),
+		Domain:       "PMNT",
+		Family:       "RCDT",
+		SubFamily:    "DMCT",
+		AddtlNtryInf: line.Description(),
+	}
+	if line.Amount < 0 {
+		entry.Family = "ICDT"
+	}
+
+	if line.TransferID != 0 {
+		counterparty := &camtAccount{ID: strconv.FormatInt(line.CounterpartyWalletNumber, 10)}
+		party := &camtParty{Nm: line.CounterpartyName}
+		parties := &camtRelatedParties{}
+		if line.Amount < 0 {
+			parties.CdtrAcct = counterparty
+			if party.Nm != "" {
+				parties.Cdtr = party
+			}
+		} else {
+			parties.DbtrAcct = counterparty
+			if party.Nm != "" {
+				parties.Dbtr = party
+			}
+		}
+		entry.TxDtls = &camtTxDetails{TxID: strconv.FormatInt(line.TransferID, 10), RltdPties: parties}
+	}
+
+	return c.enc.Encode(entry)
+}
+
+func (c *camt053Writer) Close() error {
+	err := c.enc.Flush()
+	if err != nil {
+		return err
+	}
+
+	_, err = io.WriteString(c.w, "</Stmt></BkToCstmrStmt></Document>\n")
+	return err
+}
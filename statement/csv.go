@@ -0,0 +1,57 @@
+package statement
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+	"time"
+)
+
+// csvWriter write 1 row per entry between the opening and the closing balance rows.
+type csvWriter struct {
+	w      *csv.Writer
+	header Header
+}
+
+func newCSVWriter(w io.Writer) *csvWriter {
+	return &csvWriter{w: csv.NewWriter(w)}
+}
+
+func (c *csvWriter) WriteHeader(header Header) error {
+	c.header = header
+
+	err := c.w.Write([]string{"date", "description", "counterparty_wallet_number", "counterparty_name", "transfer_id", "amount", "balance", "currency"})
+	if err != nil {
+		return err
+	}
+
+	return c.w.Write([]string{header.From.Format(time.DateOnly), "Opening balance", "", "", "", "", strconv.FormatInt(header.OpeningBalance, 10), header.Currency})
+}
+
+func (c *csvWriter) WriteLine(line Line) error {
+	row := []string{
+		line.Date.Format(time.RFC3339),
+		line.Description(),
+		"",
+		line.CounterpartyName,
+		"",
+		strconv.FormatInt(line.Amount, 10),
+		strconv.FormatInt(line.Balance, 10),
+		c.header.Currency,
+	}
+	if line.TransferID != 0 {
+		row[2] = strconv.FormatInt(line.CounterpartyWalletNumber, 10)
+		row[4] = strconv.FormatInt(line.TransferID, 10)
+	}
+	return c.w.Write(row)
+}
+
+func (c *csvWriter) Close() error {
+	err := c.w.Write([]string{c.header.To.Format(time.DateOnly), "Closing balance", "", "", "", "", strconv.FormatInt(c.header.ClosingBalance, 10), c.header.Currency})
+	if err != nil {
+		return err
+	}
+
+	c.w.Flush()
+	return c.w.Error()
+}
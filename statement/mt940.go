@@ -0,0 +1,89 @@
+package statement
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// mt940Writer write SWIFT MT940 customer statement (block 4 only), lines end with CRLF.
+type mt940Writer struct {
+	w      io.Writer
+	header Header
+}
+
+// mt940Amount return the D/C mark and the amount with the mandatory decimal comma.
+func mt940Amount(amount int64) (string, string) {
+	mark := "C"
+	if amount < 0 {
+		mark = "D"
+		amount = -amount
+	}
+	return mark, strconv.FormatInt(amount, 10) + ","
+}
+
+// mt940Text keep only the SWIFT 'x' character set and cut the text to 'max' characters.
+func mt940Text(s string, max int) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', strings.ContainsRune("/-?:().,'+ ", r):
+			b.WriteRune(r)
+		default:
+			b.WriteRune('.')
+		}
+		if b.Len() == max {
+			break
+		}
+	}
+	return b.String()
+}
+
+func (m *mt940Writer) write(lines ...string) error {
+	for _, line := range lines {
+		_, err := io.WriteString(m.w, line+"\r\n")
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *mt940Writer) WriteHeader(header Header) error {
+	m.header = header
+
+	mark, amount := mt940Amount(header.OpeningBalance)
+	return m.write(
+		":20:"+mt940Text(fmt.Sprintf("%d%s", header.WalletNumber, header.To.Format("060102")), 16),
+		":25:"+strconv.FormatInt(header.WalletNumber, 10),
+		":28C:"+header.To.Format("06002"),
+		":60F:"+mark+header.From.Format("060102")+isoCurrency(header.Currency)+amount,
+	)
+}
+
+func (m *mt940Writer) WriteLine(line Line) error {
+	mark, amount := mt940Amount(line.Amount)
+	reference := "NONREF"
+	if line.TransferID != 0 {
+		reference = strconv.FormatInt(line.TransferID, 10)
+	}
+
+	info := line.Description()
+	if line.CounterpartyName != "" {
+		info += " " + line.CounterpartyName
+	}
+
+	return m.write(
+		":61:"+line.Date.Format("060102")+line.Date.Format("0102")+mark+amount+"NTRF"+reference+"//"+strconv.FormatInt(line.EntryID, 10),
+		":86:"+mt940Text(info, 65),
+	)
+}
+
+func (m *mt940Writer) Close() error {
+	mark, amount := mt940Amount(m.header.ClosingBalance)
+	return m.write(
+		":62F:"+mark+m.header.To.Format("060102")+isoCurrency(m.header.Currency)+amount,
+		"-",
+	)
+}
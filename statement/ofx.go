@@ -0,0 +1,81 @@
+package statement
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+)
+
+const ofxBankID = "SIMPLEBANK"
+
+// ofxWriter write OFX 2.2 (XML) bank statement response.
+// The closing balance is the ledger balance, the opening balance is written in the balance list.
+type ofxWriter struct {
+	w      io.Writer
+	header Header
+}
+
+func ofxTime(t time.Time) string {
+	return t.UTC().Format("20060102150405") + "[0:GMT]"
+}
+
+func ofxText(s string, max int) string {
+	runes := []rune(s)
+	if len(runes) > max {
+		runes = runes[:max]
+	}
+
+	var buf bytes.Buffer
+	xml.EscapeText(&buf, []byte(string(runes)))
+	return buf.String()
+}
+
+func (o *ofxWriter) WriteHeader(header Header) error {
+	o.header = header
+
+	accountType := "CHECKING"
+	if header.WalletType == "savings" {
+		accountType = "SAVINGS"
+	}
+
+	_, err := fmt.Fprintf(o.w, `<?xml version="1.0" encoding="UTF-8" standalone="no"?>
+<?OFX OFXHEADER="200" VERSION="220" SECURITY="NONE" OLDFILEUID="NONE" NEWFILEUID="NONE"?>
+<OFX>
+<SIGNONMSGSRSV1><SONRS><STATUS><CODE>0</CODE><SEVERITY>INFO</SEVERITY></STATUS><DTSERVER>%s</DTSERVER><LANGUAGE>ENG</LANGUAGE></SONRS></SIGNONMSGSRSV1>
+<BANKMSGSRSV1><STMTTRNRS><TRNUID>%d</TRNUID><STATUS><CODE>0</CODE><SEVERITY>INFO</SEVERITY></STATUS>
+<STMTRS><CURDEF>%s</CURDEF>
+<BANKACCTFROM><BANKID>%s</BANKID><ACCTID>%d</ACCTID><ACCTTYPE>%s</ACCTTYPE></BANKACCTFROM>
+<BANKTRANLIST><DTSTART>%s</DTSTART><DTEND>%s</DTEND>
+`, ofxTime(header.GeneratedAt), header.GeneratedAt.Unix(), isoCurrency(header.Currency), ofxBankID, header.WalletNumber, accountType,
+		ofxTime(header.From), ofxTime(header.To))
+	return err
+}
+
+func (o *ofxWriter) WriteLine(line Line) error {
+	trnType := "CREDIT"
+	if line.Amount < 0 {
+		trnType = "DEBIT"
+	}
+
+	name := line.CounterpartyName
+	if name == "" {
+		name = line.Description()
+	}
+
+	_, err := fmt.Fprintf(o.w, "<STMTTRN><TRNTYPE>%s</TRNTYPE><DTPOSTED>%s</DTPOSTED><TRNAMT>%s</TRNAMT><FITID>%d</FITID><NAME>%s</NAME><MEMO>%s</MEMO></STMTTRN>\n",
+		trnType, ofxTime(line.Date), strconv.FormatInt(line.Amount, 10), line.EntryID, ofxText(name, 32), ofxText(line.Description(), 255))
+	return err
+}
+
+func (o *ofxWriter) Close() error {
+	_, err := fmt.Fprintf(o.w, `</BANKTRANLIST>
+<LEDGERBAL><BALAMT>%d</BALAMT><DTASOF>%s</DTASOF></LEDGERBAL>
+<BALLIST><BAL><NAME>Opening balance</NAME><DESC>Balance at %s</DESC><BALTYPE>DOLLAR</BALTYPE><VALUE>%d</VALUE><DTASOF>%s</DTASOF></BAL></BALLIST>
+</STMTRS></STMTTRNRS></BANKMSGSRSV1>
+</OFX>
+`, o.header.ClosingBalance, ofxTime(o.header.To), o.header.From.Format(time.DateOnly), o.header.OpeningBalance, ofxTime(o.header.From))
+	return err
+}
@@ -0,0 +1,101 @@
+/*
+ * Package statement write the statement of a wallet in the formats that accounting tools import:
+ * CSV, OFX 2.x, SWIFT MT940 and ISO 20022 camt.053.
+ * A 'Writer' get the header first, then every line in order, so the lines can be streamed
+ * from the database without keeping them in memory.
+ */
+package statement
+
+import (
+	"fmt"
+	"io"
+	"time"
+)
+
+// Formats
+const (
+	FormatCSV     = "csv"
+	FormatOFX     = "ofx"
+	FormatMT940   = "mt940"
+	FormatCamt053 = "camt053"
+)
+
+// Header of the statement, both balances are known before the lines are written.
+type Header struct {
+	AccountNumber  int64
+	HolderName     string
+	WalletNumber   int64
+	WalletName     string
+	WalletType     string
+	Currency       string
+	From           time.Time
+	To             time.Time
+	OpeningBalance int64
+	ClosingBalance int64
+	GeneratedAt    time.Time
+}
+
+// Line is 1 entry of the wallet. 'Amount' is negative for debit, 'Balance' is the balance after the entry.
+type Line struct {
+	EntryID                  int64
+	Date                     time.Time
+	Amount                   int64
+	Balance                  int64
+	TransferID               int64
+	CounterpartyWalletNumber int64
+	CounterpartyName         string
+}
+
+type Writer interface {
+	WriteHeader(header Header) error
+	WriteLine(line Line) error
+	// Close write the closing part of the statement and flush it, it doesn't close the underlying writer.
+	Close() error
+}
+
+// NewWriter return the writer of the 'format'.
+func NewWriter(format string, w io.Writer) (Writer, error) {
+	switch format {
+	case FormatCSV:
+		return newCSVWriter(w), nil
+	case FormatOFX:
+		return &ofxWriter{w: w}, nil
+	case FormatMT940:
+		return &mt940Writer{w: w}, nil
+	case FormatCamt053:
+		return newCamt053Writer(w), nil
+	}
+	return nil, fmt.Errorf("statement format %q isn't supported", format)
+}
+
+// ContentType return the MIME type and the file extension of the format.
+func ContentType(format string) (string, string) {
+	switch format {
+	case FormatOFX:
+		return "application/x-ofx", "ofx"
+	case FormatMT940:
+		return "text/plain", "sta"
+	case FormatCamt053:
+		return "application/xml", "xml"
+	}
+	return "text/csv", "csv"
+}
+
+// isoCurrency return the ISO 4217 code of the wallet currency.
+func isoCurrency(currency string) string {
+	if currency == "YEN" {
+		return "JPY"
+	}
+	return currency
+}
+
+// Description of the line that is shown by every format.
+func (line Line) Description() string {
+	if line.TransferID == 0 {
+		return "Entry"
+	}
+	if line.Amount < 0 {
+		return fmt.Sprintf("Transfer to %d", line.CounterpartyWalletNumber)
+	}
+	return fmt.Sprintf("Transfer from %d", line.CounterpartyWalletNumber)
+}
@@ -0,0 +1,141 @@
+package statement
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/xml"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testStatement(t *testing.T, format string) string {
+	var buf bytes.Buffer
+	writer, err := NewWriter(format, &buf)
+	require.NoError(t, err)
+
+	from := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	header := Header{
+		AccountNumber:  1010203040,
+		HolderName:     "Budi Santoso",
+		WalletNumber:   1020300001,
+		WalletName:     "Daily",
+		WalletType:     "regular",
+		Currency:       "YEN",
+		From:           from,
+		To:             time.Date(2024, 3, 31, 0, 0, 0, 0, time.UTC),
+		OpeningBalance: 1000,
+		ClosingBalance: 1300,
+		GeneratedAt:    from,
+	}
+	require.NoError(t, writer.WriteHeader(header))
+	require.NoError(t, writer.WriteLine(Line{
+		EntryID:                  11,
+		Date:                     from.Add(10 * time.Hour),
+		Amount:                   500,
+		Balance:                  1500,
+		TransferID:               7,
+		CounterpartyWalletNumber: 1010000001,
+		CounterpartyName:         "A*** & B**",
+	}))
+	require.NoError(t, writer.WriteLine(Line{
+		EntryID:                  12,
+		Date:                     from.AddDate(0, 0, 5),
+		Amount:                   -200,
+		Balance:                  1300,
+		TransferID:               8,
+		CounterpartyWalletNumber: 1010000002,
+	}))
+	require.NoError(t, writer.Close())
+
+	return buf.String()
+}
+
+func TestNewWriterFormat(t *testing.T) {
+	_, err := NewWriter("pdf", &bytes.Buffer{})
+	require.Error(t, err)
+}
+
+func TestCSV(t *testing.T) {
+	rows, err := csv.NewReader(strings.NewReader(testStatement(t, FormatCSV))).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, rows, 5)
+
+	assert.Equal(t, []string{"2024-03-01", "Opening balance", "", "", "", "", "1000", "YEN"}, rows[1])
+	assert.Equal(t, "Transfer from 1010000001", rows[2][1])
+	assert.Equal(t, "A*** & B**", rows[2][3])
+	assert.Equal(t, "-200", rows[3][5])
+	assert.Equal(t, []string{"2024-03-31", "Closing balance", "", "", "", "", "1300", "YEN"}, rows[4])
+}
+
+func TestOFX(t *testing.T) {
+	ofx := testStatement(t, FormatOFX)
+
+	assert.True(t, strings.HasPrefix(ofx, `<?xml version="1.0"`))
+	assert.Contains(t, ofx, `<?OFX OFXHEADER="200" VERSION="220"`)
+	assert.Contains(t, ofx, "<CURDEF>JPY</CURDEF>")
+	assert.Contains(t, ofx, "<ACCTID>1020300001</ACCTID><ACCTTYPE>CHECKING</ACCTTYPE>")
+	assert.Contains(t, ofx, "<TRNTYPE>CREDIT</TRNTYPE><DTPOSTED>20240301100000[0:GMT]</DTPOSTED><TRNAMT>500</TRNAMT><FITID>11</FITID><NAME>A*** &amp; B**</NAME>")
+	assert.Contains(t, ofx, "<TRNTYPE>DEBIT</TRNTYPE>")
+	assert.Contains(t, ofx, "<LEDGERBAL><BALAMT>1300</BALAMT>")
+	assert.Contains(t, ofx, "<VALUE>1000</VALUE>")
+	assert.True(t, strings.HasSuffix(ofx, "</OFX>\n"))
+}
+
+func TestMT940(t *testing.T) {
+	lines := strings.Split(strings.TrimSuffix(testStatement(t, FormatMT940), "\r\n"), "\r\n")
+
+	assert.Equal(t, []string{
+		":20:1020300001240331",
+		":25:1020300001",
+		":28C:24091",
+		":60F:C240301JPY1000,",
+		":61:2403010301C500,NTRF7//11",
+		":86:Transfer from 1010000001 A... . B..",
+		":61:2403060306D200,NTRF8//12",
+		":86:Transfer to 1010000002",
+		":62F:C240331JPY1300,",
+		"-",
+	}, lines)
+}
+
+func TestCamt053(t *testing.T) {
+	var doc struct {
+		XMLName xml.Name `xml:"Document"`
+		Stmt    struct {
+			Acct struct {
+				ID  string `xml:"Id>Othr>Id"`
+				Ccy string `xml:"Ccy"`
+			} `xml:"Acct"`
+			Bal []struct {
+				Code      string     `xml:"Tp>CdOrPrtry>Cd"`
+				Amt       camtAmount `xml:"Amt"`
+				CdtDbtInd string     `xml:"CdtDbtInd"`
+			} `xml:"Bal"`
+			Ntry []struct {
+				Amt       string `xml:"Amt"`
+				CdtDbtInd string `xml:"CdtDbtInd"`
+			} `xml:"Ntry"`
+		} `xml:"BkToCstmrStmt>Stmt"`
+	}
+	require.NoError(t, xml.Unmarshal([]byte(testStatement(t, FormatCamt053)), &doc))
+
+	assert.Equal(t, camt053Namespace, doc.XMLName.Space)
+	assert.Equal(t, "1020300001", doc.Stmt.Acct.ID)
+	assert.Equal(t, "JPY", doc.Stmt.Acct.Ccy)
+
+	require.Len(t, doc.Stmt.Bal, 2)
+	assert.Equal(t, "OPBD", doc.Stmt.Bal[0].Code)
+	assert.Equal(t, camtAmount{Ccy: "JPY", Value: "1000"}, doc.Stmt.Bal[0].Amt)
+	assert.Equal(t, "CLBD", doc.Stmt.Bal[1].Code)
+	assert.Equal(t, camtAmount{Ccy: "JPY", Value: "1300"}, doc.Stmt.Bal[1].Amt)
+
+	require.Len(t, doc.Stmt.Ntry, 2)
+	assert.Equal(t, "500", doc.Stmt.Ntry[0].Amt)
+	assert.Equal(t, "CRDT", doc.Stmt.Ntry[0].CdtDbtInd)
+	assert.Equal(t, "200", doc.Stmt.Ntry[1].Amt)
+	assert.Equal(t, "DBIT", doc.Stmt.Ntry[1].CdtDbtInd)
+}
@@ -0,0 +1,121 @@
+/*
+ * Package stream send events to the clients as Server-Sent Events (text/event-stream).
+ * The 'Broker' wake up every open stream when the store notify that new outbox events are committed,
+ * then each stream read the events that are after its last event id.
+ */
+package stream
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Listener is the part of services.Store that notify the broker.
+type Listener interface {
+	ListenOutbox(ctx context.Context, notify func()) error
+}
+
+// Delay before the broker listen again after the connection failed
+const retryDelay = 5 * time.Second
+
+type Broker struct {
+	mu          sync.Mutex
+	subscribers map[chan struct{}]struct{}
+}
+
+func NewBroker() *Broker {
+	return &Broker{subscribers: map[chan struct{}]struct{}{}}
+}
+
+// Subscribe() return the channel that receive the wake ups and the function that remove it.
+func (broker *Broker) Subscribe() (<-chan struct{}, func()) {
+	// a stream that is busy get 1 wake up for all notifications it missed
+	wake := make(chan struct{}, 1)
+
+	broker.mu.Lock()
+	broker.subscribers[wake] = struct{}{}
+	broker.mu.Unlock()
+
+	return wake, func() {
+		broker.mu.Lock()
+		delete(broker.subscribers, wake)
+		broker.mu.Unlock()
+	}
+}
+
+// Publish() wake up every subscriber, it never block.
+func (broker *Broker) Publish() {
+	broker.mu.Lock()
+	defer broker.mu.Unlock()
+
+	for wake := range broker.subscribers {
+		select {
+		case wake <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// Run() publish the notifications of the listener until the context is done, the listener is restarted when it fails.
+func (broker *Broker) Run(ctx context.Context, listener Listener) {
+	for {
+		err := listener.ListenOutbox(ctx, broker.Publish)
+		if ctx.Err() != nil {
+			return
+		}
+		log.Println("--- (stream) listen failed: ", err)
+
+		// the streams read the events they could have missed
+		broker.Publish()
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(retryDelay):
+		}
+	}
+}
+
+// Event is 1 message of the stream, events without 'ID' don't move the client's last event id.
+type Event struct {
+	ID   int64
+	Type string
+	Data []byte
+}
+
+// Write() write the event in the text/event-stream format.
+func Write(w io.Writer, event Event) error {
+	var buf bytes.Buffer
+	if event.ID != 0 {
+		buf.WriteString("id: " + strconv.FormatInt(event.ID, 10) + "\n")
+	}
+	if event.Type != "" {
+		buf.WriteString("event: " + event.Type + "\n")
+	}
+	for _, line := range bytes.Split(event.Data, []byte("\n")) {
+		buf.WriteString("data: ")
+		buf.Write(line)
+		buf.WriteString("\n")
+	}
+	buf.WriteString("\n")
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// Comment() write a line that clients ignore, it keep the connection open through proxies.
+func Comment(w io.Writer, text string) error {
+	_, err := fmt.Fprintf(w, ": %s\n\n", text)
+	return err
+}
+
+// Retry() set the delay before the client reconnect.
+func Retry(w io.Writer, delay time.Duration) error {
+	_, err := fmt.Fprintf(w, "retry: %d\n\n", delay.Milliseconds())
+	return err
+}
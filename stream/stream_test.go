@@ -0,0 +1,95 @@
+package stream
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWrite(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, Write(&buf, Event{ID: 12, Type: "transfer.completed", Data: []byte(`{"amount":100}`)}))
+	assert.Equal(t, "id: 12\nevent: transfer.completed\ndata: {\"amount\":100}\n\n", buf.String())
+
+	// every line of the data get its own field, events without id don't have the field
+	buf.Reset()
+	require.NoError(t, Write(&buf, Event{Type: "snapshot", Data: []byte("a\nb")}))
+	assert.Equal(t, "event: snapshot\ndata: a\ndata: b\n\n", buf.String())
+
+	buf.Reset()
+	require.NoError(t, Comment(&buf, "ping"))
+	require.NoError(t, Retry(&buf, 3*time.Second))
+	assert.Equal(t, ": ping\n\nretry: 3000\n\n", buf.String())
+}
+
+func TestBroker(t *testing.T) {
+	broker := NewBroker()
+	first, unsubscribe := broker.Subscribe()
+	second, _ := broker.Subscribe()
+
+	// notifications are merged while the subscriber is busy
+	broker.Publish()
+	broker.Publish()
+	for _, wake := range []<-chan struct{}{first, second} {
+		select {
+		case <-wake:
+		default:
+			t.Fatal("subscriber isn't woken up")
+		}
+		select {
+		case <-wake:
+			t.Fatal("subscriber is woken up twice")
+		default:
+		}
+	}
+
+	unsubscribe()
+	broker.Publish()
+	select {
+	case <-first:
+		t.Fatal("removed subscriber is woken up")
+	case <-second:
+	}
+}
+
+type fakeListener struct {
+	notifications int
+}
+
+func (listener *fakeListener) ListenOutbox(ctx context.Context, notify func()) error {
+	for i := 0; i < listener.notifications; i++ {
+		notify()
+	}
+	<-ctx.Done()
+	return errors.New("connection closed")
+}
+
+func TestBrokerRun(t *testing.T) {
+	broker := NewBroker()
+	wake, _ := broker.Subscribe()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		broker.Run(ctx, &fakeListener{notifications: 1})
+		close(done)
+	}()
+
+	select {
+	case <-wake:
+	case <-time.After(time.Second):
+		t.Fatal("notification isn't published")
+	}
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("broker doesn't stop with the context")
+	}
+}
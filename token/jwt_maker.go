@@ -3,9 +3,12 @@ package token
 import (
 	"errors"
 	"fmt"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
 )
 
 var (
@@ -13,46 +16,149 @@ var (
 )
 var minSecretKeySize = 32
 
+// Default 'iss' and 'aud' claims of the JWT
+const (
+	DefaultIssuer   = "simple-bank"
+	DefaultAudience = "simple-bank-api"
+)
+
+// The JWT are signed with the secret key, so only HMAC algorithms are allowed ('none' and the
+// asymmetric ones would let anybody with the algorithm name forge tokens).
+var jwtAlgorithms = map[string]jwt.SigningMethod{
+	jwt.SigningMethodHS256.Alg(): jwt.SigningMethodHS256,
+	jwt.SigningMethodHS384.Alg(): jwt.SigningMethodHS384,
+	jwt.SigningMethodHS512.Alg(): jwt.SigningMethodHS512,
+}
+
 type JWTMaker struct {
 	secretKey string
+	issuer    string
+	audience  string
+	// allow-list of the algorithms, the first one sign the tokens
+	algorithms []string
+}
+
+// JWTOptions of the maker, the empty fields use the defaults (HS256 only).
+type JWTOptions struct {
+	Issuer     string
+	Audience   string
+	Algorithms []string
+}
+
+// jwtClaims are the registered claims, the account is the subject and the token id is 'jti'.
+// The scopes are separated by spaces in 'scope' (RFC 9068).
+type jwtClaims struct {
+	Role    string  `json:"role"`
+	Scope   string  `json:"scope,omitempty"`
+	Wallets []int64 `json:"wallets,omitempty"`
+	jwt.RegisteredClaims
 }
 
 func NewJWTMaker(secretKey string) (Maker, error) {
+	return NewJWTMakerWithOptions(secretKey, JWTOptions{})
+}
+
+func NewJWTMakerWithOptions(secretKey string, options JWTOptions) (Maker, error) {
 	if len(secretKey) < minSecretKeySize {
 		return nil, fmt.Errorf("Invalid key size: must be at least %d characters", minSecretKeySize)
 	}
 
-	return &JWTMaker{secretKey}, nil
+	maker := &JWTMaker{
+		secretKey:  secretKey,
+		issuer:     options.Issuer,
+		audience:   options.Audience,
+		algorithms: options.Algorithms,
+	}
+	if maker.issuer == "" {
+		maker.issuer = DefaultIssuer
+	}
+	if maker.audience == "" {
+		maker.audience = DefaultAudience
+	}
+	if len(maker.algorithms) == 0 {
+		maker.algorithms = []string{jwt.SigningMethodHS256.Alg()}
+	}
+	for _, alg := range maker.algorithms {
+		if _, ok := jwtAlgorithms[alg]; !ok {
+			return nil, fmt.Errorf("JWT algorithm '%s' isn't allowed, use HS256, HS384 or HS512", alg)
+		}
+	}
+
+	return maker, nil
+}
+
+func (maker *JWTMaker) CreateToken(accountID int64, role string, duration time.Duration) (string, error) {
+	token, _, err := maker.CreateRestrictedToken(accountID, role, Restriction{}, duration)
+	return token, err
 }
 
-func (maker *JWTMaker) CreateToken(accountID int64, duration time.Duration) (string, error) {
-	payload, err := NewPayLoad(accountID, duration)
+func (maker *JWTMaker) CreateRestrictedToken(accountID int64, role string, restriction Restriction, duration time.Duration) (string, *Payload, error) {
+	payload, err := newRestrictedPayload(accountID, role, restriction, duration)
+	if err != nil {
+		return "", nil, err
+	}
+
+	claims := jwtClaims{
+		Role:    payload.Role,
+		Scope:   strings.Join(payload.Scopes, " "),
+		Wallets: payload.Wallets,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    maker.issuer,
+			Subject:   strconv.FormatInt(payload.AccountID, 10),
+			Audience:  jwt.ClaimStrings{maker.audience},
+			ExpiresAt: jwt.NewNumericDate(payload.ExpiredAt),
+			NotBefore: jwt.NewNumericDate(payload.IssuedAt),
+			IssuedAt:  jwt.NewNumericDate(payload.IssuedAt),
+			ID:        payload.ID.String(),
+		},
+	}
+	jwtToken := jwt.NewWithClaims(jwtAlgorithms[maker.algorithms[0]], claims)
+	signed, err := jwtToken.SignedString([]byte(maker.secretKey))
 	if err != nil {
-		return "", err
+		return "", nil, err
 	}
-	jwtToken := jwt.NewWithClaims(jwt.SigningMethodHS256, payload)
-	return jwtToken.SignedString([]byte(maker.secretKey))
+	return signed, payload, nil
 }
 
+// VerifyToken check the signature with the allowed algorithms, and the iss, aud, exp, nbf and iat claims.
 func (maker *JWTMaker) VerifyToken(token string) (*Payload, error) {
 	keyfunc := func(token *jwt.Token) (interface{}, error) {
 		_, ok := token.Method.(*jwt.SigningMethodHMAC)
 		if !ok {
-			//log.Print("1")
 			return nil, ErrInvalidToken
 		}
-		//log.Print("2")
 		return []byte(maker.secretKey), nil
 	}
-	jwtToken, err := jwt.ParseWithClaims(token, &Payload{}, keyfunc)
+	parser := jwt.NewParser(
+		jwt.WithValidMethods(maker.algorithms),
+		jwt.WithIssuer(maker.issuer),
+		jwt.WithAudience(maker.audience),
+		jwt.WithIssuedAt(),
+	)
+	jwtToken, err := parser.ParseWithClaims(token, &jwtClaims{}, keyfunc)
 	if err != nil {
-		//log.Print("3")
-		return nil, err
+		// expired tokens can be refreshed, every other error (signature, algorithm, claims) is an invalid token
+		if errors.Is(err, jwt.ErrTokenExpired) {
+			return nil, jwt.ErrTokenExpired
+		}
+		return nil, ErrInvalidToken
 	}
 
-	payload, ok := jwtToken.Claims.(*Payload)
-	if !ok {
-		//log.Print("4")
+	claims, ok := jwtToken.Claims.(*jwtClaims)
+	if !ok || claims.ExpiresAt == nil || claims.IssuedAt == nil {
+		return nil, ErrInvalidToken
+	}
+	payload := Payload{
+		Role:      claims.Role,
+		Scopes:    strings.Fields(claims.Scope),
+		Wallets:   claims.Wallets,
+		IssuedAt:  claims.IssuedAt.Time,
+		ExpiredAt: claims.ExpiresAt.Time,
+	}
+	if payload.ID, err = uuid.Parse(claims.ID); err != nil {
+		return nil, ErrInvalidToken
+	}
+	if payload.AccountID, err = strconv.ParseInt(claims.Subject, 10, 64); err != nil {
 		return nil, ErrInvalidToken
 	}
 
@@ -61,5 +167,5 @@ func (maker *JWTMaker) VerifyToken(token string) (*Payload, error) {
 		return nil, err
 	}
 
-	return payload, nil
+	return &payload, nil
 }
@@ -2,12 +2,15 @@ package token
 
 import (
 	"errors"
+	"strconv"
 	"testing"
 	"time"
 
 	"simple-bank-system/util"
 
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestJWTMaker(t *testing.T) {
@@ -22,7 +25,7 @@ func TestJWTMaker(t *testing.T) {
 	issuedAt := time.Now()
 	expiredAt := issuedAt.Add(duration)
 
-	token, err := maker.CreateToken(accountID, duration)
+	token, err := maker.CreateToken(accountID, "admin", duration)
 	if err != nil {
 		t.Error("CreateToken error, \nerr: ", err)
 	}
@@ -41,6 +44,9 @@ func TestJWTMaker(t *testing.T) {
 	if payload.AccountID != accountID {
 		t.Errorf("input accountID %d != %d payload.AccountID", accountID, payload.AccountID)
 	}
+	if payload.Role != "admin" {
+		t.Errorf("input role admin != %s payload.Role", payload.Role)
+	}
 	if payload.IssuedAt.After(issuedAt.Add(1*time.Second)) && payload.IssuedAt.Before(issuedAt.Add(-1*time.Second)) {
 		t.Errorf("input issuedAt %v != %v payload.IssuedAt", issuedAt, payload.IssuedAt)
 	}
@@ -59,7 +65,7 @@ func TestExpiredJWTToken(t *testing.T) {
 	accountID := util.RandomInt(1, 100)
 	duration := -time.Minute
 
-	token, err := maker.CreateToken(accountID, duration)
+	token, err := maker.CreateToken(accountID, "admin", duration)
 	if err != nil {
 		t.Error("Failed to create token, \nerr: ", err)
 	}
@@ -78,12 +84,23 @@ func TestInvalidJWTTokenAlgNode(t *testing.T) {
 	accountID := util.RandomInt(1, 100)
 	duration := time.Minute
 
-	payload, err := NewPayLoad(accountID, duration)
+	payload, err := NewPayLoad(accountID, "customer", duration)
 	if err != nil {
 		t.Error("Failed to create new payload, \nerr: ", err)
 	}
 
-	jwtToken := jwt.NewWithClaims(jwt.SigningMethodNone, payload)
+	claims := jwtClaims{
+		Role: payload.Role,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    DefaultIssuer,
+			Subject:   strconv.FormatInt(payload.AccountID, 10),
+			Audience:  jwt.ClaimStrings{DefaultAudience},
+			ExpiresAt: jwt.NewNumericDate(payload.ExpiredAt),
+			IssuedAt:  jwt.NewNumericDate(payload.IssuedAt),
+			ID:        payload.ID.String(),
+		},
+	}
+	jwtToken := jwt.NewWithClaims(jwt.SigningMethodNone, claims)
 	if jwtToken == nil {
 		t.Error("jwt token is empty")
 	}
@@ -109,3 +126,102 @@ func TestInvalidJWTTokenAlgNode(t *testing.T) {
 		t.Errorf("Error isn't because of invalid tokens, \nerr: %s \nErrInvalidToken: %s", err, ErrInvalidToken)
 	}
 }
+
+func TestJWTClaims(t *testing.T) {
+	secret := util.RandomString(32)
+	maker, err := NewJWTMakerWithOptions(secret, JWTOptions{Issuer: "bank-a", Audience: "api-a"})
+	require.NoError(t, err)
+
+	token, err := maker.CreateToken(42, "customer", time.Minute)
+	require.NoError(t, err)
+
+	claims := jwtClaims{}
+	_, _, err = jwt.NewParser().ParseUnverified(token, &claims)
+	require.NoError(t, err)
+	assert.Equal(t, "bank-a", claims.Issuer)
+	assert.Equal(t, "42", claims.Subject)
+	assert.Equal(t, jwt.ClaimStrings{"api-a"}, claims.Audience)
+	assert.NotEmpty(t, claims.ID)
+	require.NotNil(t, claims.ExpiresAt)
+	require.NotNil(t, claims.NotBefore)
+	require.NotNil(t, claims.IssuedAt)
+
+	// same key, other issuer or audience
+	for _, options := range []JWTOptions{{Issuer: "bank-b", Audience: "api-a"}, {Issuer: "bank-a", Audience: "api-b"}} {
+		other, err := NewJWTMakerWithOptions(secret, options)
+		require.NoError(t, err)
+		payload, err := other.VerifyToken(token)
+		assert.ErrorIs(t, err, ErrInvalidToken)
+		assert.Nil(t, payload)
+	}
+}
+
+func TestJWTAlgorithms(t *testing.T) {
+	secret := util.RandomString(32)
+
+	for _, alg := range []string{"none", "RS256", "ES256", "EdDSA", "HS1"} {
+		_, err := NewJWTMakerWithOptions(secret, JWTOptions{Algorithms: []string{alg}})
+		assert.Error(t, err, alg)
+	}
+
+	hs512, err := NewJWTMakerWithOptions(secret, JWTOptions{Algorithms: []string{"HS512"}})
+	require.NoError(t, err)
+	token, err := hs512.CreateToken(1, "customer", time.Minute)
+	require.NoError(t, err)
+
+	// the default maker only allow HS256
+	maker, err := NewJWTMaker(secret)
+	require.NoError(t, err)
+	_, err = maker.VerifyToken(token)
+	assert.ErrorIs(t, err, ErrInvalidToken)
+
+	both, err := NewJWTMakerWithOptions(secret, JWTOptions{Algorithms: []string{"HS256", "HS512"}})
+	require.NoError(t, err)
+	payload, err := both.VerifyToken(token)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), payload.AccountID)
+}
+
+func TestJWTRequiredClaims(t *testing.T) {
+	secret := util.RandomString(32)
+	maker, err := NewJWTMaker(secret)
+	require.NoError(t, err)
+
+	payload, err := NewPayLoad(1, "customer", time.Minute)
+	require.NoError(t, err)
+	valid := jwtClaims{
+		Role: payload.Role,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    DefaultIssuer,
+			Subject:   "1",
+			Audience:  jwt.ClaimStrings{DefaultAudience},
+			ExpiresAt: jwt.NewNumericDate(payload.ExpiredAt),
+			IssuedAt:  jwt.NewNumericDate(payload.IssuedAt),
+			ID:        payload.ID.String(),
+		},
+	}
+
+	testCases := map[string]func(claims *jwtClaims){
+		"MissingExp":  func(claims *jwtClaims) { claims.ExpiresAt = nil },
+		"MissingIat":  func(claims *jwtClaims) { claims.IssuedAt = nil },
+		"FutureIat":   func(claims *jwtClaims) { claims.IssuedAt = jwt.NewNumericDate(time.Now().Add(time.Hour)) },
+		"NotBefore":   func(claims *jwtClaims) { claims.NotBefore = jwt.NewNumericDate(time.Now().Add(time.Hour)) },
+		"BadSubject":  func(claims *jwtClaims) { claims.Subject = "alice" },
+		"BadID":       func(claims *jwtClaims) { claims.ID = "" },
+		"NoAudience":  func(claims *jwtClaims) { claims.Audience = nil },
+		"EmptyIssuer": func(claims *jwtClaims) { claims.Issuer = "" },
+	}
+	for name, change := range testCases {
+		change := change
+		t.Run(name, func(t *testing.T) {
+			claims := valid
+			change(&claims)
+			token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(secret))
+			require.NoError(t, err)
+
+			payload, err := maker.VerifyToken(token)
+			assert.ErrorIs(t, err, ErrInvalidToken)
+			assert.Nil(t, payload)
+		})
+	}
+}
@@ -0,0 +1,98 @@
+package token
+
+import (
+	"fmt"
+	"strings"
+
+	"aidanwoods.dev/go-paseto"
+)
+
+/*
+ * KeyRing hold the Ed25519 keys of the v4.public tokens. Only the signing key create tokens, every key of the
+ * ring verify them: after a rotation the public key of the old signing key stay in the ring until its tokens
+ * are expired. Every key has an id, it's in the footer of the tokens ('kid').
+ */
+type KeyRing struct {
+	signingID  string
+	signingKey paseto.V4AsymmetricSecretKey
+	publicKeys map[string]paseto.V4AsymmetricPublicKey
+	// ids in the order they were added, the signing key first
+	ids []string
+}
+
+// PublicKey is a verification key of the ring, 'Key' is the hex of the 32 bytes Ed25519 public key.
+type PublicKey struct {
+	ID      string `json:"kid"`
+	Version string `json:"version"`
+	Purpose string `json:"purpose"`
+	Key     string `json:"public_key"`
+}
+
+func NewKeyRing(signingID string, signingKey paseto.V4AsymmetricSecretKey) (*KeyRing, error) {
+	ring := &KeyRing{publicKeys: map[string]paseto.V4AsymmetricPublicKey{}}
+	if err := ring.AddPublicKey(signingID, signingKey.Public()); err != nil {
+		return nil, err
+	}
+	ring.signingID, ring.signingKey = signingID, signingKey
+	return ring, nil
+}
+
+// AddPublicKey add a key that only verify tokens, e.g. the previous signing key.
+func (ring *KeyRing) AddPublicKey(id string, key paseto.V4AsymmetricPublicKey) error {
+	if id == "" || strings.ContainsAny(id, ":,") {
+		return fmt.Errorf("key id '%s' must not be empty or contain ':' or ','", id)
+	}
+	if _, ok := ring.publicKeys[id]; ok {
+		return fmt.Errorf("key id '%s' is used twice", id)
+	}
+	ring.publicKeys[id] = key
+	ring.ids = append(ring.ids, id)
+	return nil
+}
+
+/*
+ * ParseKeyRing create the ring from the config: the hex seed of the signing key and the verification
+ * keys written '<id>:<hex public key>' separated by commas.
+ */
+func ParseKeyRing(signingID, signingSeed, verificationKeys string) (*KeyRing, error) {
+	signingKey, err := paseto.NewV4AsymmetricSecretKeyFromSeed(signingSeed)
+	if err != nil {
+		return nil, fmt.Errorf("signing key: %w", err)
+	}
+	ring, err := NewKeyRing(signingID, signingKey)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, item := range strings.Split(verificationKeys, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		id, hexKey, ok := strings.Cut(item, ":")
+		if !ok {
+			return nil, fmt.Errorf("verification key '%s' isn't '<id>:<hex public key>'", item)
+		}
+		key, err := paseto.NewV4AsymmetricPublicKeyFromHex(hexKey)
+		if err != nil {
+			return nil, fmt.Errorf("verification key %s: %w", id, err)
+		}
+		if err = ring.AddPublicKey(id, key); err != nil {
+			return nil, err
+		}
+	}
+	return ring, nil
+}
+
+func (ring *KeyRing) SigningID() string {
+	return ring.signingID
+}
+
+// PublicKeys return every key that verify tokens, it can be published.
+func (ring *KeyRing) PublicKeys() []PublicKey {
+	keys := make([]PublicKey, 0, len(ring.ids))
+	for _, id := range ring.ids {
+		keys = append(keys, PublicKey{ID: id, Version: "v4", Purpose: "public", Key: ring.publicKeys[id].ExportHex()})
+	}
+	return keys
+}
@@ -6,8 +6,10 @@ import (
 
 // Maker is interface for managing tokens
 type Maker interface {
-	// 'CreateToken' will create and sign a new token for spesific ID and duration
-	CreateToken(accountID int64, duration time.Duration) (string, error)
+	// 'CreateToken' will create and sign a new token for spesific ID, role and duration
+	CreateToken(accountID int64, role string, duration time.Duration) (string, error)
+	// 'CreateRestrictedToken' create a token that only has the scopes and wallets of 'restriction', it also return its payload
+	CreateRestrictedToken(accountID int64, role string, restriction Restriction, duration time.Duration) (string, *Payload, error)
 	// 'VerifyToken' is to checks if the input token is valid or not
 	VerifyToken(token string) (*Payload, error)
 }
@@ -0,0 +1,163 @@
+package token
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"simple-bank-system/util"
+
+	"aidanwoods.dev/go-paseto"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// makerFactories make 2 makers of every token type with different keys, every maker must pass the same suite
+var makerFactories = map[string]func(t *testing.T) Maker{
+	"paseto": func(t *testing.T) Maker {
+		key, err := util.RandomByte(32)
+		require.NoError(t, err)
+		maker, err := NewPasetoMaker(key)
+		require.NoError(t, err)
+		return maker
+	},
+	"paseto-public": func(t *testing.T) Maker {
+		ring, err := NewKeyRing("k1", paseto.NewV4AsymmetricSecretKey())
+		require.NoError(t, err)
+		maker, err := NewPasetoPublicMaker(ring)
+		require.NoError(t, err)
+		return maker
+	},
+	"jwt": func(t *testing.T) Maker {
+		maker, err := NewJWTMaker(util.RandomString(32))
+		require.NoError(t, err)
+		return maker
+	},
+}
+
+func TestMakerConformance(t *testing.T) {
+	for name, newMaker := range makerFactories {
+		newMaker := newMaker
+		t.Run(name, func(t *testing.T) {
+			t.Run("RoundTrip", func(t *testing.T) {
+				maker := newMaker(t)
+				accountID := util.RandomInt(1, 1000)
+				issuedAt := time.Now()
+
+				token, err := maker.CreateToken(accountID, "operator", time.Minute)
+				require.NoError(t, err)
+				require.NotEmpty(t, token)
+
+				payload, err := maker.VerifyToken(token)
+				require.NoError(t, err)
+				assert.NotZero(t, payload.ID)
+				assert.Equal(t, accountID, payload.AccountID)
+				assert.Equal(t, "operator", payload.Role)
+				assert.WithinDuration(t, issuedAt, payload.IssuedAt, time.Second)
+				assert.WithinDuration(t, issuedAt.Add(time.Minute), payload.ExpiredAt, time.Second)
+			})
+
+			t.Run("Restricted", func(t *testing.T) {
+				maker := newMaker(t)
+				restriction := Restriction{Scopes: []string{"wallets:read", "transfers:write"}, Wallets: []int64{1010000001, 1010000002}}
+				token, created, err := maker.CreateRestrictedToken(7, "customer", restriction, time.Minute)
+				require.NoError(t, err)
+
+				payload, err := maker.VerifyToken(token)
+				require.NoError(t, err)
+				assert.Equal(t, created.ID, payload.ID)
+				assert.Equal(t, int64(7), payload.AccountID)
+				assert.Equal(t, restriction.Scopes, payload.Scopes)
+				assert.Equal(t, restriction.Wallets, payload.Wallets)
+				assert.True(t, payload.Restricted())
+				assert.True(t, payload.HasScope("wallets:read"))
+				assert.False(t, payload.HasScope("admin"))
+				assert.True(t, payload.AllowsWallet(1010000002))
+				assert.False(t, payload.AllowsWallet(1010000003))
+
+				// the session tokens aren't restricted
+				token, err = maker.CreateToken(7, "customer", time.Minute)
+				require.NoError(t, err)
+				payload, err = maker.VerifyToken(token)
+				require.NoError(t, err)
+				assert.False(t, payload.Restricted())
+				assert.True(t, payload.HasScope("admin"))
+				assert.True(t, payload.AllowsWallet(1010000003))
+			})
+
+			t.Run("IssuedBeforePasswordChange", func(t *testing.T) {
+				// a token issued just before the password change is never taken as issued after it
+				issuedAt := time.Now()
+				maker := newMaker(t)
+				token, err := maker.CreateToken(1, "customer", time.Minute)
+				require.NoError(t, err)
+				changedAt := time.Now()
+
+				payload, err := maker.VerifyToken(token)
+				require.NoError(t, err)
+				assert.True(t, payload.IssuedBefore(changedAt))
+				assert.False(t, payload.IssuedBefore(issuedAt.Truncate(time.Second)))
+			})
+
+			t.Run("Expired", func(t *testing.T) {
+				maker := newMaker(t)
+				token, err := maker.CreateToken(1, "customer", -time.Minute)
+				require.NoError(t, err)
+
+				payload, err := maker.VerifyToken(token)
+				assert.Error(t, err)
+				assert.Nil(t, payload)
+			})
+
+			t.Run("OtherKey", func(t *testing.T) {
+				token, err := newMaker(t).CreateToken(1, "customer", time.Minute)
+				require.NoError(t, err)
+
+				payload, err := newMaker(t).VerifyToken(token)
+				assert.Error(t, err)
+				assert.Nil(t, payload)
+			})
+
+			t.Run("Tampered", func(t *testing.T) {
+				maker := newMaker(t)
+				token, err := maker.CreateToken(1, "customer", time.Minute)
+				require.NoError(t, err)
+
+				// change 1 character in the middle of the signed/encrypted part
+				i := len(token) / 2
+				c := byte('A')
+				if token[i] == c {
+					c = 'B'
+				}
+				tampered := token[:i] + string(c) + token[i+1:]
+
+				payload, err := maker.VerifyToken(tampered)
+				assert.Error(t, err)
+				assert.Nil(t, payload)
+			})
+
+			t.Run("Garbage", func(t *testing.T) {
+				maker := newMaker(t)
+				for _, token := range []string{"", "abc", "v4.local.", "v4.public.", "a.b.c", strings.Repeat("x", 200)} {
+					payload, err := maker.VerifyToken(token)
+					assert.Error(t, err, token)
+					assert.Nil(t, payload, token)
+				}
+			})
+
+			t.Run("UniqueID", func(t *testing.T) {
+				maker := newMaker(t)
+				ids := map[string]bool{}
+				for i := 0; i < 10; i++ {
+					token, err := maker.CreateToken(1, "customer", time.Minute)
+					require.NoError(t, err)
+					payload, err := maker.VerifyToken(token)
+					require.NoError(t, err)
+					require.False(t, ids[payload.ID.String()], "duplicate token id")
+					ids[payload.ID.String()] = true
+				}
+			})
+		})
+	}
+}
@@ -4,7 +4,6 @@ import (
 	"time"
 
 	"aidanwoods.dev/go-paseto"
-	"github.com/google/uuid"
 )
 
 // var PasetoPayLoad = make(map[string]interface{})
@@ -34,28 +33,25 @@ func NewPasetoMaker(key []byte) (Maker, error) {
 	return &PasetoMaker{symmetricKey}, nil
 }
 
-func (maker *PasetoMaker) CreateToken(ID int64, duration time.Duration) (string, error) {
-	tokenID, err := uuid.NewRandom()
+func (maker *PasetoMaker) CreateToken(ID int64, role string, duration time.Duration) (string, error) {
+	token, _, err := maker.CreateRestrictedToken(ID, role, Restriction{}, duration)
+	return token, err
+}
+
+func (maker *PasetoMaker) CreateRestrictedToken(ID int64, role string, restriction Restriction, duration time.Duration) (string, *Payload, error) {
+	payload, err := newRestrictedPayload(ID, role, restriction, duration)
 	if err != nil {
-		return "", err
+		return "", nil, err
 	}
 
-	payload := map[string]interface{}{
-		"ID":         tokenID,
-		"account_id": ID,
-		"iat":        time.Now(),
-		"exp":        time.Now().Add(duration),
+	token, err := newPasetoToken(payload, nil)
+	if err != nil {
+		return "", nil, err
 	}
-	/*fmt.Println("Create payload.ID: ", payload["ID"])
-	fmt.Println("Create payload.AccountID: ", payload["AccountID"])
-	fmt.Println("Create payload.IssuedAT: ", payload["iat"])
-	fmt.Println("Create payload.ExpiredAt: ", payload["exp"])
-	fmt.Println()*/
-	token, err := paseto.MakeToken(payload, nil)
 	//key := paseto.V4SymmetricKeyFromHex()
 
 	encrypted := token.V4Encrypt(maker.symmetricKey, nil)
-	return encrypted, nil
+	return encrypted, payload, nil
 }
 
 func (maker *PasetoMaker) VerifyToken(encrypted string) (*Payload, error) {
@@ -64,19 +60,39 @@ func (maker *PasetoMaker) VerifyToken(encrypted string) (*Payload, error) {
 	if err != nil {
 		return nil, err
 	}
+	return pasetoPayload(token)
+}
+
+// newPasetoToken put the payload in the claims of a v4 token, local and public tokens have the same claims.
+func newPasetoToken(payload *Payload, footer []byte) (*paseto.Token, error) {
+	claims := map[string]interface{}{
+		"ID":         payload.ID,
+		"account_id": payload.AccountID,
+		"role":       payload.Role,
+		"iat":        payload.IssuedAt,
+		"exp":        payload.ExpiredAt,
+	}
+	if len(payload.Scopes) > 0 {
+		claims["scopes"] = payload.Scopes
+	}
+	if len(payload.Wallets) > 0 {
+		claims["wallets"] = payload.Wallets
+	}
+	return paseto.MakeToken(claims, footer)
+}
+
+// pasetoPayload read the claims of a verified v4 token.
+func pasetoPayload(token *paseto.Token) (*Payload, error) {
 	payload := Payload{}
 	token.Get("ID", &payload.ID)
 	token.Get("account_id", &payload.AccountID)
+	token.Get("role", &payload.Role)
+	token.Get("scopes", &payload.Scopes)
+	token.Get("wallets", &payload.Wallets)
 	token.Get("iat", &payload.IssuedAt)
 	token.Get("exp", &payload.ExpiredAt)
-	//claims := token.Claims()
-	/*fmt.Println("Verify payload: ", payload)
-	fmt.Println("Verify payload.ID: ", payload.ID)
-	fmt.Println("Verify payload.AccountID: ", payload.AccountID)
-	fmt.Println("Verify payload.IssuedAt: ", payload.IssuedAt)
-	fmt.Println("Verify payload.CreatedAt: ", payload.ExpiredAt)*/
 
-	err = payload.Valid()
+	err := payload.Valid()
 	if err != nil {
 		return nil, err
 	}
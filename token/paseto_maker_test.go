@@ -28,7 +28,7 @@ func TestPasetoMaker(t *testing.T) {
 	expiredAt := issuedAt.Add(duration)
 	//log.Println("id =", id)
 
-	token, err := maker.CreateToken(id, duration)
+	token, err := maker.CreateToken(id, "admin", duration)
 	require.NoError(t, err, "Failed to create encrypted paseto token, \nerr: ", err)
 	require.NotEmpty(t, token, "encypted token is empty")
 
@@ -37,6 +37,7 @@ func TestPasetoMaker(t *testing.T) {
 	require.NotEmpty(t, payload, "payload is empty")
 
 	assert.Equal(t, id, payload.AccountID)
+	assert.Equal(t, "admin", payload.Role)
 	assert.WithinDuration(t, issuedAt, payload.IssuedAt, time.Second)
 	assert.WithinDuration(t, expiredAt, payload.ExpiredAt, time.Second)
 
@@ -59,7 +60,7 @@ func TestExpiredPasetoToken(t *testing.T) {
 	id := util.RandomInt(1, 100)
 	duration := -1 * time.Minute
 
-	token, err := maker.CreateToken(id, duration)
+	token, err := maker.CreateToken(id, "admin", duration)
 	require.NoError(t, err, "Failed to create encrypted paseto token")
 	require.NotEmpty(t, token, "encypted token is empty")
 
@@ -79,7 +80,7 @@ func TestInvalidPasetoToken(t *testing.T) {
 	id := util.RandomInt(0, 100)
 	duration := 1 * time.Minute
 
-	token, err := maker.CreateToken(id, duration)
+	token, err := maker.CreateToken(id, "admin", duration)
 	require.NoError(t, err, "Failed to create encrypted paseto token")
 	require.NotEmpty(t, token, "encypted token is empty")
 
@@ -0,0 +1,67 @@
+package token
+
+import (
+	"encoding/json"
+	"time"
+
+	"aidanwoods.dev/go-paseto"
+)
+
+// PasetoPublicMaker sign v4.public tokens with Ed25519, the other services verify them with the public keys only.
+type PasetoPublicMaker struct {
+	ring *KeyRing
+}
+
+// footer of the tokens, the id of the key that signed it
+type pasetoFooter struct {
+	KeyID string `json:"kid"`
+}
+
+func NewPasetoPublicMaker(ring *KeyRing) (Maker, error) {
+	return &PasetoPublicMaker{ring}, nil
+}
+
+func (maker *PasetoPublicMaker) CreateToken(ID int64, role string, duration time.Duration) (string, error) {
+	token, _, err := maker.CreateRestrictedToken(ID, role, Restriction{}, duration)
+	return token, err
+}
+
+func (maker *PasetoPublicMaker) CreateRestrictedToken(ID int64, role string, restriction Restriction, duration time.Duration) (string, *Payload, error) {
+	payload, err := newRestrictedPayload(ID, role, restriction, duration)
+	if err != nil {
+		return "", nil, err
+	}
+	footer, err := json.Marshal(pasetoFooter{KeyID: maker.ring.signingID})
+	if err != nil {
+		return "", nil, err
+	}
+	token, err := newPasetoToken(payload, footer)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return token.V4Sign(maker.ring.signingKey, nil), payload, nil
+}
+
+// VerifyToken find the key of the 'kid' of the footer, tokens of unknown keys are invalid.
+func (maker *PasetoPublicMaker) VerifyToken(signed string) (*Payload, error) {
+	parser := paseto.NewParser()
+	rawFooter, err := parser.UnsafeParseFooter(paseto.V4Public, signed)
+	if err != nil {
+		return nil, err
+	}
+	var footer pasetoFooter
+	if err = json.Unmarshal(rawFooter, &footer); err != nil {
+		return nil, ErrInvalidToken
+	}
+	key, ok := maker.ring.publicKeys[footer.KeyID]
+	if !ok {
+		return nil, ErrInvalidToken
+	}
+
+	token, err := parser.ParseV4Public(key, signed, nil) // this will fail if the signature or the validation rules fail
+	if err != nil {
+		return nil, err
+	}
+	return pasetoPayload(token)
+}
@@ -0,0 +1,115 @@
+package token
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"simple-bank-system/util"
+
+	"aidanwoods.dev/go-paseto"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestKeyRing(t *testing.T, id string) (*KeyRing, paseto.V4AsymmetricSecretKey) {
+	key := paseto.NewV4AsymmetricSecretKey()
+	ring, err := NewKeyRing(id, key)
+	require.NoError(t, err)
+	return ring, key
+}
+
+func TestPasetoPublicMaker(t *testing.T) {
+	ring, _ := newTestKeyRing(t, "k1")
+	maker, err := NewPasetoPublicMaker(ring)
+	require.NoError(t, err)
+
+	id := util.RandomInt(1, 100)
+	issuedAt := time.Now()
+	token, err := maker.CreateToken(id, "admin", time.Minute)
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(token, "v4.public."))
+
+	payload, err := maker.VerifyToken(token)
+	require.NoError(t, err)
+	assert.Equal(t, id, payload.AccountID)
+	assert.Equal(t, "admin", payload.Role)
+	assert.WithinDuration(t, issuedAt, payload.IssuedAt, time.Second)
+	assert.WithinDuration(t, issuedAt.Add(time.Minute), payload.ExpiredAt, time.Second)
+
+	expired, err := maker.CreateToken(id, "admin", -time.Minute)
+	require.NoError(t, err)
+	_, err = maker.VerifyToken(expired)
+	assert.Error(t, err)
+}
+
+func TestPasetoPublicKeyRotation(t *testing.T) {
+	oldRing, oldKey := newTestKeyRing(t, "k1")
+	oldMaker, err := NewPasetoPublicMaker(oldRing)
+	require.NoError(t, err)
+	oldToken, err := oldMaker.CreateToken(1, "customer", time.Minute)
+	require.NoError(t, err)
+
+	// the new signing key is k2, k1 still verify the tokens it signed
+	ring, err := ParseKeyRing("k2", paseto.NewV4AsymmetricSecretKey().ExportSeedHex(), "k1:"+oldKey.Public().ExportHex())
+	require.NoError(t, err)
+	maker, err := NewPasetoPublicMaker(ring)
+	require.NoError(t, err)
+
+	payload, err := maker.VerifyToken(oldToken)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), payload.AccountID)
+
+	newToken, err := maker.CreateToken(2, "customer", time.Minute)
+	require.NoError(t, err)
+	_, err = oldMaker.VerifyToken(newToken)
+	assert.ErrorIs(t, err, ErrInvalidToken, "the old ring doesn't know k2")
+
+	keys := ring.PublicKeys()
+	require.Len(t, keys, 2)
+	assert.Equal(t, "k2", keys[0].ID)
+	assert.Equal(t, PublicKey{ID: "k1", Version: "v4", Purpose: "public", Key: oldKey.Public().ExportHex()}, keys[1])
+}
+
+func TestInvalidPasetoPublicToken(t *testing.T) {
+	ring, _ := newTestKeyRing(t, "k1")
+	maker, err := NewPasetoPublicMaker(ring)
+	require.NoError(t, err)
+
+	// a key with the same id that isn't in the ring
+	otherRing, _ := newTestKeyRing(t, "k1")
+	otherMaker, err := NewPasetoPublicMaker(otherRing)
+	require.NoError(t, err)
+	token, err := otherMaker.CreateToken(1, "admin", time.Minute)
+	require.NoError(t, err)
+	_, err = maker.VerifyToken(token)
+	assert.Error(t, err)
+
+	// local tokens aren't accepted
+	key, err := util.RandomByte(32)
+	require.NoError(t, err)
+	localMaker, err := NewPasetoMaker(key)
+	require.NoError(t, err)
+	token, err = localMaker.CreateToken(1, "admin", time.Minute)
+	require.NoError(t, err)
+	_, err = maker.VerifyToken(token)
+	assert.Error(t, err)
+}
+
+func TestParseKeyRing(t *testing.T) {
+	seed := paseto.NewV4AsymmetricSecretKey().ExportSeedHex()
+	public := paseto.NewV4AsymmetricSecretKey().Public().ExportHex()
+
+	_, err := ParseKeyRing("k1", "not hex", "")
+	assert.Error(t, err)
+	_, err = ParseKeyRing("k1", seed, "k1:"+public)
+	assert.Error(t, err, "id used twice")
+	_, err = ParseKeyRing("k1", seed, public)
+	assert.Error(t, err, "key without id")
+
+	ring, err := ParseKeyRing("k1", seed, " k0:"+public+", ")
+	require.NoError(t, err)
+	assert.Equal(t, "k1", ring.SigningID())
+	assert.Len(t, ring.PublicKeys(), 2)
+}
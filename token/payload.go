@@ -10,16 +10,24 @@ import (
 // Contain payload data of the token
 type Payload struct {
 	ID        uuid.UUID `json:"id"`
-	Username  string    `json:"username"`
 	AccountID int64     `json:"account_id"`
-	WalletID  int64     `json:"wallet_id"`
+	Role      string    `json:"role"`
+	// Scopes and wallet numbers of a restricted token, the tokens of the login sessions have none
+	// and can do everything their role allows
+	Scopes    []string  `json:"scopes,omitempty"`
+	Wallets   []int64   `json:"wallets,omitempty"`
 	IssuedAt  time.Time `json:"issued_at"`
 	ExpiredAt time.Time `json:"expired_at"`
-	jwt.RegisteredClaims
+}
+
+// Restriction narrow a token to some scopes and wallets, the empty fields don't restrict anything.
+type Restriction struct {
+	Scopes  []string
+	Wallets []int64
 }
 
 // Create new token payload
-func NewPayLoad(accountID int64, duration time.Duration) (*Payload, error) {
+func NewPayLoad(accountID int64, role string, duration time.Duration) (*Payload, error) {
 	tokenID, err := uuid.NewRandom()
 	if err != nil {
 		return nil, err
@@ -28,12 +36,64 @@ func NewPayLoad(accountID int64, duration time.Duration) (*Payload, error) {
 	payload := &Payload{
 		ID:        tokenID,
 		AccountID: accountID,
+		Role:      role,
 		IssuedAt:  time.Now(),
 		ExpiredAt: time.Now().Add(duration),
 	}
 	return payload, nil
 }
 
+// newRestrictedPayload create the payload of a token narrowed by 'restriction'.
+func newRestrictedPayload(accountID int64, role string, restriction Restriction, duration time.Duration) (*Payload, error) {
+	payload, err := NewPayLoad(accountID, role, duration)
+	if err != nil {
+		return nil, err
+	}
+	payload.Scopes = restriction.Scopes
+	payload.Wallets = restriction.Wallets
+	return payload, nil
+}
+
+// Restricted return true for the tokens that can't do everything the role allows.
+func (payload *Payload) Restricted() bool {
+	return len(payload.Scopes) > 0 || len(payload.Wallets) > 0
+}
+
+// HasScope return true when the token has the scope, tokens without scopes have all of them.
+func (payload *Payload) HasScope(scope string) bool {
+	if len(payload.Scopes) == 0 {
+		return true
+	}
+	for _, s := range payload.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// AllowsWallet return true when the token can use the wallet, tokens without wallets can use all of them.
+func (payload *Payload) AllowsWallet(walletNumber int64) bool {
+	if len(payload.Wallets) == 0 {
+		return true
+	}
+	for _, number := range payload.Wallets {
+		if number == walletNumber {
+			return true
+		}
+	}
+	return false
+}
+
+/*
+ * IssuedBefore return true when the token may have been issued before 't' (password change, logout everywhere).
+ * The PASETO 'iat' keep the nanoseconds, the JWT 'iat' is truncated to the second: a JWT issued in the same
+ * second as 't' is taken as issued before it, so a revocation never miss a token.
+ */
+func (payload *Payload) IssuedBefore(t time.Time) bool {
+	return payload.IssuedAt.Before(t)
+}
+
 func (payload *Payload) Valid() error {
 	if time.Now().After(payload.ExpiredAt) {
 		return jwt.ErrTokenExpired
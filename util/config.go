@@ -11,6 +11,64 @@ type Config struct {
 	ServerAddress       string        `mapstructure:"SERVER_ADDRESS"`
 	TokenSymmetricKey   string        `mapstructure:"TOKEN_SYMMETRIC_KEY"`
 	AccessTokenDuration time.Duration `mapstructure:"ACCESS_TOKEN_DURATION"`
+	// 'TokenType' is paseto (v4.local, TOKEN_SYMMETRIC_KEY), paseto-public (v4.public) or jwt (TOKEN_SYMMETRIC_KEY)
+	TokenType string `mapstructure:"TOKEN_TYPE"`
+	// 'iss' and 'aud' claims of the JWT, 'TokenJWTAlgorithms' is the allow-list (HS256, HS384, HS512), the first one sign
+	TokenIssuer        string `mapstructure:"TOKEN_ISSUER"`
+	TokenAudience      string `mapstructure:"TOKEN_AUDIENCE"`
+	TokenJWTAlgorithms string `mapstructure:"TOKEN_JWT_ALGORITHMS"`
+	// v4.public tokens are signed by 'TokenSigningKey' (hex seed of an Ed25519 key), 'TokenVerificationKeys'
+	// are the previous public keys ('<id>:<hex>' separated by commas) that still verify tokens
+	TokenSigningKeyID     string `mapstructure:"TOKEN_SIGNING_KEY_ID"`
+	TokenSigningKey       string `mapstructure:"TOKEN_SIGNING_KEY"`
+	TokenVerificationKeys string `mapstructure:"TOKEN_VERIFICATION_KEYS"`
+	// Restricted tokens minted by the accounts last 'ScopedTokenDuration' unless they ask for another duration, up to the max
+	ScopedTokenDuration    time.Duration `mapstructure:"SCOPED_TOKEN_DURATION"`
+	ScopedTokenMaxDuration time.Duration `mapstructure:"SCOPED_TOKEN_MAX_DURATION"`
+	// Lifetime of a login session, its refresh token is rotated on every use
+	RefreshTokenDuration time.Duration `mapstructure:"REFRESH_TOKEN_DURATION"`
+	InterestJobInterval  time.Duration `mapstructure:"INTEREST_JOB_INTERVAL"`
+	SnapshotJobInterval  time.Duration `mapstructure:"SNAPSHOT_JOB_INTERVAL"`
+	// New payee only receive transfers up to 'PayeeCoolingOffLimit' during 'PayeeCoolingOff'
+	PayeeCoolingOff      time.Duration `mapstructure:"PAYEE_COOLING_OFF"`
+	PayeeCoolingOffLimit int64         `mapstructure:"PAYEE_COOLING_OFF_LIMIT"`
+	// Domain events are always written to the log, the file and HTTP sinks are used when they are set
+	OutboxRelayInterval time.Duration `mapstructure:"OUTBOX_RELAY_INTERVAL"`
+	OutboxFile          string        `mapstructure:"OUTBOX_FILE"`
+	OutboxURL           string        `mapstructure:"OUTBOX_URL"`
+	WebhookJobInterval  time.Duration `mapstructure:"WEBHOOK_JOB_INTERVAL"`
+	// Email notifications are only sent when 'SMTPAddr' is set
+	SMTPAddr     string `mapstructure:"SMTP_ADDR"`
+	SMTPFrom     string `mapstructure:"SMTP_FROM"`
+	SMTPUsername string `mapstructure:"SMTP_USERNAME"`
+	SMTPPassword string `mapstructure:"SMTP_PASSWORD"`
+	// Outgoing transfers from this amount are notified to the sender
+	LargeTransferAmount int64 `mapstructure:"LARGE_TRANSFER_AMOUNT"`
+	// The token sent to a new email has to be verified before this duration
+	EmailVerificationDuration time.Duration `mapstructure:"EMAIL_VERIFICATION_DURATION"`
+	// Forgot password tokens expire after 'PasswordResetDuration', an account get at most 'PasswordResetLimit' tokens per hour
+	PasswordResetDuration time.Duration `mapstructure:"PASSWORD_RESET_DURATION"`
+	PasswordResetLimit    int           `mapstructure:"PASSWORD_RESET_LIMIT"`
+	// The revoked access tokens are reloaded from the database every interval
+	RevocationRefreshInterval time.Duration `mapstructure:"REVOCATION_REFRESH_INTERVAL"`
+	// Login challenge of the accounts with 2FA expire after 'TwoFactorChallengeDuration',
+	// transfers from 'TwoFactorTransferAmount' need a TOTP code
+	TwoFactorChallengeDuration time.Duration `mapstructure:"TWO_FACTOR_CHALLENGE_DURATION"`
+	TwoFactorTransferAmount    int64         `mapstructure:"TWO_FACTOR_TRANSFER_AMOUNT"`
+	// A username is locked after 'LoginMaxAttempts' failed logins in 'LoginFailureWindow' (a client IP after 'LoginIPMaxAttempts'),
+	// the lockout last 'LoginLockoutDuration' and double at every new lockout up to 'LoginMaxLockoutDuration'
+	LoginMaxAttempts        int           `mapstructure:"LOGIN_MAX_ATTEMPTS"`
+	LoginIPMaxAttempts      int           `mapstructure:"LOGIN_IP_MAX_ATTEMPTS"`
+	LoginFailureWindow      time.Duration `mapstructure:"LOGIN_FAILURE_WINDOW"`
+	LoginLockoutDuration    time.Duration `mapstructure:"LOGIN_LOCKOUT_DURATION"`
+	LoginMaxLockoutDuration time.Duration `mapstructure:"LOGIN_MAX_LOCKOUT_DURATION"`
+	// Keys without failure and lock in 'LoginFailureWindow' are deleted every interval, their backoff start again
+	LoginThrottleCleanupInterval time.Duration `mapstructure:"LOGIN_THROTTLE_CLEANUP_INTERVAL"`
+	// Rate limits ('<limit>/<period>') of the login, transfer and read routes, the buckets are in 'memory' or 'postgres'
+	RateLimitBackend  string `mapstructure:"RATE_LIMIT_BACKEND"`
+	RateLimitLogin    string `mapstructure:"RATE_LIMIT_LOGIN"`
+	RateLimitTransfer string `mapstructure:"RATE_LIMIT_TRANSFER"`
+	RateLimitRead     string `mapstructure:"RATE_LIMIT_READ"`
 }
 
 // LoadConfig() takes a 'path' as input, and return 'config' object or error.
@@ -17,12 +17,27 @@ var (
 	ErrEmailExists         = errors.New("email already exists")
 	ErrEmailEmpty          = errors.New("email is empty")
 
-	ErrAccUser      = errors.New("owner data is incorrect or user does not exist (SQLSTATE 23503)")
-	ErrDuplicate    = errors.New("record already exists")
-	ErrNotExist     = errors.New("record does not exist")
-	ErrUpdateFailed = errors.New("update failed")
-	ErrDeleteFailed = errors.New("delete failed")
-	ErrValid        = errors.New("wallet isn't valid")
+	ErrAccUser         = errors.New("owner data is incorrect or user does not exist (SQLSTATE 23503)")
+	ErrDuplicate       = errors.New("record already exists")
+	ErrNotExist        = errors.New("record does not exist")
+	ErrUpdateFailed    = errors.New("update failed")
+	ErrDeleteFailed    = errors.New("delete failed")
+	ErrValid           = errors.New("wallet isn't valid")
+	ErrWalletLocked    = errors.New("wallet is locked until the savings goal is reached")
+	ErrWalletFrozen    = errors.New("wallet is frozen")
+	ErrWalletNotEmpty  = errors.New("wallet balance isn't zero")
+	ErrTokenExpired    = errors.New("token is expired")
+	ErrTooManyRequests = errors.New("too many requests")
+	ErrSessionRevoked  = errors.New("session is revoked")
+	ErrTokenReused     = errors.New("refresh token was already used, the session is revoked")
+	ErrRequestExpired  = errors.New("payment request is expired")
+	ErrCodeReused      = errors.New("code was already used, wait for the next one")
+	ErrPayeeCoolingOff = errors.New("payee is new, large transfers are allowed after the cooling-off period")
+	ErrNotPayee        = errors.New("recipient isn't a saved payee, large transfers are allowed to payees after the cooling-off period")
+
+	// uploads of a payment file whose message id was already used
+	ErrPaymentFileRunning = errors.New("payment file with the same MsgId is being executed")
+	ErrPaymentFileChanged = errors.New("another payment file was uploaded with the same MsgId")
 )
 
 var ErrReturn = []error{ErrUsernameExists, ErrUsernameEmpty, ErrAccountNumberExists, ErrAccountNumberWrong, ErrPasswordEmpty, ErrFullnameEmpty, ErrDOBEmpty, ErrAddressEmpty, ErrEmailExists, ErrEmailEmpty}
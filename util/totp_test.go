@@ -0,0 +1,76 @@
+package util
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTOTPCode(t *testing.T) {
+	// RFC 6238 appendix B (SHA1), the 6 digits code is the end of the 8 digits one
+	secret := totpEncoding.EncodeToString([]byte("12345678901234567890"))
+	vectors := map[int64]string{
+		59:          "94287082",
+		1111111109:  "07081804",
+		1111111111:  "14050471",
+		1234567890:  "89005924",
+		2000000000:  "69279037",
+		20000000000: "65353130",
+	}
+	for unix, want := range vectors {
+		code, err := TOTPCode(secret, TOTPStep(time.Unix(unix, 0)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if code != want[2:] {
+			t.Errorf("time %d: code is %s, want %s", unix, code, want[2:])
+		}
+	}
+}
+
+func TestValidateTOTP(t *testing.T) {
+	secret, err := NewTOTPSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	step := TOTPStep(now)
+
+	code, _ := TOTPCode(secret, step-1)
+	if got, ok := ValidateTOTP(secret, code, now); !ok || got != step-1 {
+		t.Errorf("code of the previous step is refused")
+	}
+	code, _ = TOTPCode(secret, step-2)
+	if _, ok := ValidateTOTP(secret, code, now); ok {
+		t.Errorf("code of 2 steps ago is accepted")
+	}
+	if _, ok := ValidateTOTP(secret, "12345", now); ok {
+		t.Errorf("short code is accepted")
+	}
+}
+
+func TestTOTPURI(t *testing.T) {
+	uri := TOTPURI("Simple Bank", "alice", "ABC")
+	if !strings.HasPrefix(uri, "otpauth://totp/Simple%20Bank:alice?") {
+		t.Errorf("wrong label: %s", uri)
+	}
+	for _, param := range []string{"secret=ABC", "issuer=Simple+Bank", "digits=6", "period=30"} {
+		if !strings.Contains(uri, param) {
+			t.Errorf("%s is missing in %s", param, uri)
+		}
+	}
+}
+
+func TestRecoveryCodes(t *testing.T) {
+	codes, err := NewRecoveryCodes(10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(codes) != 10 {
+		t.Fatalf("%d codes, want 10", len(codes))
+	}
+	code := codes[0]
+	if NormalizeRecoveryCode(strings.ToUpper(strings.ReplaceAll(code, "-", ""))) != code {
+		t.Errorf("code %s isn't normalized", code)
+	}
+}
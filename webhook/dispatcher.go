@@ -0,0 +1,175 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"simple-bank-system/db/pkg"
+	"simple-bank-system/db/services"
+	"simple-bank-system/outbox"
+)
+
+// Store is the part of services.Store that is used by the webhook sink and dispatcher.
+type Store interface {
+	EnqueueWebhookDeliveries(ctx context.Context, eventID, accountID int64, eventType string, payload []byte) (int64, error)
+	DispatchWebhookDeliveries(ctx context.Context, limit int, send func(pkg.WebhookDelivery, pkg.WebhookSubscription) services.WebhookAttemptResult) (int, error)
+}
+
+var _ Store = (*services.Store)(nil)
+
+// EventType return the webhook event type of the outbox message, it's empty when it can't be subscribed.
+func EventType(msg outbox.Message) string {
+	switch msg.Type {
+	case services.EventTransferCompleted:
+		var transfer services.TransferEvent
+		if err := json.Unmarshal(msg.Payload, &transfer); err != nil {
+			return ""
+		}
+		if transfer.Amount < 0 {
+			return EventTransferOutgoing
+		}
+		return EventTransferIncoming
+	case services.EventWalletCreated, services.EventWalletClosed, services.EventWalletFrozen, services.EventWalletUnfrozen:
+		return EventWalletStatus
+	case services.EventSavingsGoalReached:
+		return EventSavingsGoalReached
+	}
+	return ""
+}
+
+// Sink is the outbox sink that create the deliveries of the event.
+type Sink struct {
+	store Store
+}
+
+func NewSink(store Store) *Sink {
+	return &Sink{store: store}
+}
+
+func (sink *Sink) Name() string { return "webhook" }
+
+func (sink *Sink) Deliver(ctx context.Context, msg outbox.Message) error {
+	eventType := EventType(msg)
+	if eventType == "" {
+		return nil
+	}
+
+	data := msg.Payload
+	if eventType == EventWalletStatus {
+		// the subscribers get the status change in the data, e.g. {"status": "wallet.closed", ...}
+		var fields map[string]interface{}
+		if err := json.Unmarshal(msg.Payload, &fields); err != nil {
+			return err
+		}
+		fields["status"] = msg.Type
+		var err error
+		if data, err = json.Marshal(fields); err != nil {
+			return err
+		}
+	}
+
+	body, err := json.Marshal(Body{
+		ID:        msg.ID,
+		Type:      eventType,
+		CreatedAt: msg.CreatedAt,
+		Data:      data,
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = sink.store.EnqueueWebhookDeliveries(ctx, msg.ID, msg.AccountID, eventType, body)
+	return err
+}
+
+// Max deliveries that are locked by 1 db transaction of the dispatcher
+const defaultBatchSize = 20
+
+type Dispatcher struct {
+	store     Store
+	client    *http.Client
+	BatchSize int
+}
+
+func NewDispatcher(store Store, timeout time.Duration) *Dispatcher {
+	return &Dispatcher{
+		store:     store,
+		client:    newClient(timeout),
+		BatchSize: defaultBatchSize,
+	}
+}
+
+// Run() send the due deliveries in batches until there are no more.
+func (dispatcher *Dispatcher) Run(ctx context.Context) error {
+	var total int
+	for {
+		n, err := dispatcher.store.DispatchWebhookDeliveries(ctx, dispatcher.BatchSize, func(delivery pkg.WebhookDelivery, webhook pkg.WebhookSubscription) services.WebhookAttemptResult {
+			return dispatcher.send(ctx, delivery, webhook, time.Now())
+		})
+		total += n
+		if err != nil {
+			return err
+		}
+		if n < dispatcher.BatchSize || ctx.Err() != nil {
+			break
+		}
+	}
+
+	if total > 0 {
+		log.Printf("--- (webhook) %d deliveries sent\n", total)
+	}
+	return nil
+}
+
+// send POST the delivery once, a status other than 2xx is retried after the backoff.
+func (dispatcher *Dispatcher) send(ctx context.Context, delivery pkg.WebhookDelivery, webhook pkg.WebhookSubscription, now time.Time) services.WebhookAttemptResult {
+	var result services.WebhookAttemptResult
+
+	err := dispatcher.post(ctx, delivery, webhook, now, &result)
+	result.Duration = time.Since(now)
+	if err == nil {
+		result.Succeeded = true
+		return result
+	}
+
+	result.Error = err.Error()
+	if attempts := delivery.Attempts + 1; attempts < MaxAttempts {
+		result.NextAttemptAt = now.Add(Backoff(attempts))
+	}
+	return result
+}
+
+func (dispatcher *Dispatcher) post(ctx context.Context, delivery pkg.WebhookDelivery, webhook pkg.WebhookSubscription, now time.Time, result *services.WebhookAttemptResult) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return err
+	}
+
+	timestamp := now.Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Simple-Bank-Webhook/1.0")
+	req.Header.Set(HeaderDelivery, strconv.FormatInt(delivery.ID, 10))
+	req.Header.Set(HeaderEvent, delivery.EventType)
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(HeaderSignature, SignatureHeader(webhook, timestamp, delivery.Payload, now))
+
+	res, err := dispatcher.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(res.Body, 64<<10))
+
+	result.StatusCode = int64(res.StatusCode)
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return fmt.Errorf("endpoint answered %s", res.Status)
+	}
+	return nil
+}
@@ -0,0 +1,151 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"simple-bank-system/db/pkg"
+	"simple-bank-system/db/services"
+	"simple-bank-system/outbox"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type enqueued struct {
+	eventID   int64
+	accountID int64
+	eventType string
+	body      []byte
+}
+
+// fakeStore keep the pending deliveries in memory and record the attempts like the db.
+type fakeStore struct {
+	enqueued   []enqueued
+	webhook    pkg.WebhookSubscription
+	deliveries []*pkg.WebhookDelivery
+	results    []services.WebhookAttemptResult
+}
+
+func (s *fakeStore) EnqueueWebhookDeliveries(ctx context.Context, eventID, accountID int64, eventType string, payload []byte) (int64, error) {
+	s.enqueued = append(s.enqueued, enqueued{eventID, accountID, eventType, payload})
+	return 1, nil
+}
+
+func (s *fakeStore) DispatchWebhookDeliveries(ctx context.Context, limit int, send func(pkg.WebhookDelivery, pkg.WebhookSubscription) services.WebhookAttemptResult) (int, error) {
+	var n int
+	for _, delivery := range s.deliveries {
+		if delivery.Status != services.WebhookPending || n == limit {
+			continue
+		}
+		result := send(*delivery, s.webhook)
+		s.results = append(s.results, result)
+		delivery.Attempts++
+		switch {
+		case result.Succeeded:
+			delivery.Status = services.WebhookSucceeded
+		case result.NextAttemptAt.IsZero():
+			delivery.Status = services.WebhookFailed
+		}
+		n++
+	}
+	return n, nil
+}
+
+func transferMessage(t *testing.T, amount int64) outbox.Message {
+	payload, err := json.Marshal(services.TransferEvent{TransferID: 7, Amount: amount, Currency: "IDR"})
+	require.NoError(t, err)
+	return outbox.Message{ID: 3, Type: services.EventTransferCompleted, AccountID: 9, WalletID: 4, Payload: payload, CreatedAt: time.Now()}
+}
+
+func TestEventType(t *testing.T) {
+	assert.Equal(t, EventTransferIncoming, EventType(transferMessage(t, 100)))
+	assert.Equal(t, EventTransferOutgoing, EventType(transferMessage(t, -100)))
+	assert.Equal(t, EventWalletStatus, EventType(outbox.Message{Type: services.EventWalletClosed}))
+	assert.Equal(t, EventWalletStatus, EventType(outbox.Message{Type: services.EventWalletFrozen}))
+	assert.Equal(t, "", EventType(outbox.Message{Type: services.EventAccountCreated}))
+}
+
+func TestSink(t *testing.T) {
+	store := &fakeStore{}
+	sink := NewSink(store)
+
+	require.NoError(t, sink.Deliver(context.Background(), transferMessage(t, 100)))
+	require.NoError(t, sink.Deliver(context.Background(), outbox.Message{ID: 4, Type: services.EventAccountCreated, Payload: []byte(`{}`)}))
+	require.NoError(t, sink.Deliver(context.Background(), outbox.Message{ID: 5, Type: services.EventWalletClosed, AccountID: 9, Payload: []byte(`{"wallet_number":1}`)}))
+
+	require.Len(t, store.enqueued, 2)
+	assert.Equal(t, int64(3), store.enqueued[0].eventID)
+	assert.Equal(t, int64(9), store.enqueued[0].accountID)
+	assert.Equal(t, EventTransferIncoming, store.enqueued[0].eventType)
+
+	var body Body
+	require.NoError(t, json.Unmarshal(store.enqueued[1].body, &body))
+	assert.Equal(t, EventWalletStatus, body.Type)
+	assert.JSONEq(t, `{"wallet_number":1,"status":"wallet.closed"}`, string(body.Data))
+}
+
+func TestDispatcher(t *testing.T) {
+	secret, err := NewSecret()
+	require.NoError(t, err)
+
+	status := http.StatusInternalServerError
+	var received int
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		err = Verify(secret, r.Header.Get(HeaderTimestamp), r.Header.Get(HeaderSignature), body, time.Minute, time.Now())
+		assert.NoError(t, err)
+		assert.Equal(t, "11", r.Header.Get(HeaderDelivery))
+		assert.Equal(t, EventTransferIncoming, r.Header.Get(HeaderEvent))
+		received++
+		w.WriteHeader(status)
+	}))
+	defer receiver.Close()
+
+	store := &fakeStore{
+		webhook: pkg.WebhookSubscription{ID: 1, URL: receiver.URL, Secret: secret},
+		deliveries: []*pkg.WebhookDelivery{{
+			ID:        11,
+			EventType: EventTransferIncoming,
+			Payload:   []byte(`{"id":3}`),
+			Status:    services.WebhookPending,
+		}},
+	}
+	dispatcher := NewDispatcher(store, time.Second)
+	// the receiver is on the loopback address that the dispatcher client refuse
+	dispatcher.client = receiver.Client()
+
+	// failed attempt is retried after the backoff
+	before := time.Now()
+	require.NoError(t, dispatcher.Run(context.Background()))
+	require.Len(t, store.results, 1)
+	assert.False(t, store.results[0].Succeeded)
+	assert.Equal(t, int64(http.StatusInternalServerError), store.results[0].StatusCode)
+	assert.WithinDuration(t, before.Add(Backoff(1)), store.results[0].NextAttemptAt, time.Second)
+
+	status = http.StatusOK
+	require.NoError(t, dispatcher.Run(context.Background()))
+	require.Len(t, store.results, 2)
+	assert.True(t, store.results[1].Succeeded)
+	assert.Equal(t, services.WebhookSucceeded, store.deliveries[0].Status)
+	assert.Equal(t, 2, received)
+}
+
+func TestDispatcherMaxAttempts(t *testing.T) {
+	dispatcher := NewDispatcher(&fakeStore{}, time.Second)
+	webhook := pkg.WebhookSubscription{URL: "http://127.0.0.1:1", Secret: "secret"}
+
+	result := dispatcher.send(context.Background(), pkg.WebhookDelivery{Attempts: 1}, webhook, time.Now())
+	assert.False(t, result.Succeeded)
+	assert.NotEmpty(t, result.Error)
+	assert.False(t, result.NextAttemptAt.IsZero())
+
+	result = dispatcher.send(context.Background(), pkg.WebhookDelivery{Attempts: MaxAttempts - 1}, webhook, time.Now())
+	assert.True(t, result.NextAttemptAt.IsZero())
+}
@@ -0,0 +1,110 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"syscall"
+	"time"
+)
+
+var ErrForbiddenURL = errors.New("webhook url must be https and the host must have only public addresses")
+
+// Ranges that aren't covered by the net.IP methods
+var reservedNetworks = mustParseCIDRs(
+	"0.0.0.0/8",     // "this" network
+	"100.64.0.0/10", // carrier-grade NAT
+	"192.0.0.0/24",  // IETF protocol assignments
+	"198.18.0.0/15", // benchmarking
+	"240.0.0.0/4",   // reserved
+	"64:ff9b::/96",  // NAT64, it can reach the IPv4 addresses above
+)
+
+func mustParseCIDRs(cidrs ...string) []*net.IPNet {
+	var networks []*net.IPNet
+	for _, cidr := range cidrs {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err)
+		}
+		networks = append(networks, network)
+	}
+	return networks
+}
+
+// PublicIP is false for private, loopback, link-local (with the cloud metadata address 169.254.169.254),
+// multicast and reserved addresses.
+func PublicIP(ip net.IP) bool {
+	if ip4 := ip.To4(); ip4 != nil {
+		ip = ip4
+	}
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() || ip.IsLinkLocalUnicast() ||
+		ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() || ip.IsMulticast() {
+		return false
+	}
+	for _, network := range reservedNetworks {
+		if network.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+/*
+ * CheckURL check the url of a subscription when it's registered: it must be https and every address
+ * of the host must be public, so the webhooks can't be used to reach the internal network of the bank.
+ * The addresses are checked again when the dispatcher connect, because the DNS answer can change.
+ */
+func CheckURL(ctx context.Context, resolver *net.Resolver, rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Scheme != "https" || u.Hostname() == "" || u.User != nil {
+		return ErrForbiddenURL
+	}
+
+	addrs, err := resolver.LookupIPAddr(ctx, u.Hostname())
+	if err != nil {
+		return fmt.Errorf("can't resolve webhook host %s: %w", u.Hostname(), err)
+	}
+	for _, addr := range addrs {
+		if !PublicIP(addr.IP) {
+			return ErrForbiddenURL
+		}
+	}
+	return nil
+}
+
+// dialControl refuse the connections to addresses that aren't public, it run after the host is resolved.
+func dialControl(network, address string, c syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	if ip := net.ParseIP(host); ip == nil || !PublicIP(ip) {
+		return fmt.Errorf("%w: %s", ErrForbiddenURL, host)
+	}
+	return nil
+}
+
+// newClient return the HTTP client of the dispatcher, it only connect to public addresses, without proxy,
+// and doesn't follow redirects (a redirect is a failed attempt).
+func newClient(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{
+		Timeout: timeout,
+		Control: dialControl,
+	}
+	return &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			DialContext:         dialer.DialContext,
+			TLSHandshakeTimeout: timeout,
+			MaxIdleConns:        10,
+			IdleConnTimeout:     90 * time.Second,
+		},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
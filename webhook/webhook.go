@@ -0,0 +1,136 @@
+/*
+ * Package webhook deliver the domain events of the outbox to the HTTP endpoints that accounts subscribed.
+ * The 'Sink' turn every outbox event into 1 delivery per subscription, then the 'Dispatcher' POST the
+ * deliveries and retry failed ones with exponential backoff.
+ *
+ * Every request is signed: 'X-Webhook-Signature' is "v1=<hex HMAC-SHA256 of '<timestamp>.<body>'>"
+ * with the timestamp of 'X-Webhook-Timestamp'. While the previous secret is in its grace period after a
+ * rotation, a second "v1=" signature made with it is added, so receivers can switch secrets without
+ * missing events.
+ */
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"simple-bank-system/db/pkg"
+)
+
+// Event types that can be subscribed
+const (
+	EventTransferIncoming   = "transfer.incoming"
+	EventTransferOutgoing   = "transfer.outgoing"
+	EventWalletStatus       = "wallet.status"
+	EventSavingsGoalReached = "savings_goal.reached"
+)
+
+var EventTypes = []string{EventTransferIncoming, EventTransferOutgoing, EventWalletStatus, EventSavingsGoalReached}
+
+// Headers of the requests
+const (
+	HeaderDelivery  = "X-Webhook-Id"
+	HeaderEvent     = "X-Webhook-Event"
+	HeaderTimestamp = "X-Webhook-Timestamp"
+	HeaderSignature = "X-Webhook-Signature"
+)
+
+// The previous secret still sign the requests during this period after the rotation
+const SecretGracePeriod = 24 * time.Hour
+
+var ErrSignature = errors.New("webhook signature doesn't match")
+
+func ValidEventType(eventType string) bool {
+	for _, t := range EventTypes {
+		if t == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// NewSecret return a random secret of 32 bytes.
+func NewSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return "whsec_" + hex.EncodeToString(b), nil
+}
+
+// Sign return the hex HMAC-SHA256 of '<timestamp>.<body>'.
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// SignatureHeader return the value of 'X-Webhook-Signature' for the subscription at 'now'.
+func SignatureHeader(webhook pkg.WebhookSubscription, timestamp int64, body []byte, now time.Time) string {
+	signatures := []string{"v1=" + Sign(webhook.Secret, timestamp, body)}
+	if webhook.PreviousSecret.Valid && webhook.SecretRotatedAt.Valid && now.Before(webhook.SecretRotatedAt.Time.Add(SecretGracePeriod)) {
+		signatures = append(signatures, "v1="+Sign(webhook.PreviousSecret.String, timestamp, body))
+	}
+	return strings.Join(signatures, ",")
+}
+
+/*
+ * Verify check the headers of a request with the secret of the receiver, it's what the receivers do.
+ * Requests that are older than 'tolerance' are refused, so a captured request can't be replayed later.
+ */
+func Verify(secret, timestampHeader, signatureHeader string, body []byte, tolerance time.Duration, now time.Time) error {
+	timestamp, err := strconv.ParseInt(timestampHeader, 10, 64)
+	if err != nil {
+		return ErrSignature
+	}
+	if age := now.Sub(time.Unix(timestamp, 0)); age > tolerance || age < -tolerance {
+		return ErrSignature
+	}
+
+	expected := []byte(Sign(secret, timestamp, body))
+	for _, signature := range strings.Split(signatureHeader, ",") {
+		value, ok := strings.CutPrefix(strings.TrimSpace(signature), "v1=")
+		if ok && hmac.Equal([]byte(value), expected) {
+			return nil
+		}
+	}
+	return ErrSignature
+}
+
+// Body is the JSON body of the requests.
+type Body struct {
+	// id of the outbox event, it's the same for every retry and replay
+	ID        int64           `json:"id"`
+	Type      string          `json:"type"`
+	CreatedAt time.Time       `json:"created_at"`
+	Data      json.RawMessage `json:"data"`
+}
+
+const (
+	// First retry is after 'baseBackoff', then it's doubled every attempt up to 'maxBackoff'
+	baseBackoff = 30 * time.Second
+	maxBackoff  = 6 * time.Hour
+	// Delivery fails after this number of attempts
+	MaxAttempts = 8
+)
+
+// Backoff return the delay before the next attempt after 'attempts' failed attempts.
+func Backoff(attempts int64) time.Duration {
+	delay := baseBackoff
+	for i := int64(1); i < attempts; i++ {
+		delay *= 2
+		if delay >= maxBackoff {
+			return maxBackoff
+		}
+	}
+	return delay
+}
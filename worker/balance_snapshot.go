@@ -0,0 +1,41 @@
+package worker
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"simple-bank-system/db/services"
+)
+
+// SnapshotStore is the part of services.Store that is used by the balance snapshot job.
+type SnapshotStore interface {
+	WatermarkStore
+	SnapshotBalances(ctx context.Context, day time.Time) (int64, error)
+}
+
+var _ SnapshotStore = (*services.Store)(nil)
+
+// BalanceSnapshotJob() record the end-of-day balance of every day since the last snapshot up to yesterday,
+// re-run for the same day does nothing.
+func BalanceSnapshotJob(store SnapshotStore, interval time.Duration) Job {
+	return Job{
+		Name:     "balance snapshot",
+		Interval: interval,
+		Run: func(ctx context.Context) error {
+			return runBalanceSnapshot(ctx, store, time.Now())
+		},
+	}
+}
+
+func runBalanceSnapshot(ctx context.Context, store SnapshotStore, now time.Time) error {
+	_, err := catchUpDays(ctx, store, services.JobBalanceSnapshot, now, func(day time.Time) error {
+		n, err := store.SnapshotBalances(ctx, day)
+		if err != nil {
+			return err
+		}
+		log.Printf("--- (worker) balance snapshot for %d wallets on %s\n", n, day.Format(time.DateOnly))
+		return nil
+	})
+	return err
+}
@@ -0,0 +1,54 @@
+package worker
+
+import (
+	"context"
+	"time"
+
+	"simple-bank-system/db/services"
+	"simple-bank-system/util"
+)
+
+// WatermarkStore is the part of services.Store that keep the last day processed by the daily jobs.
+type WatermarkStore interface {
+	JobWatermark(ctx context.Context, job string) (time.Time, error)
+	SetJobWatermark(ctx context.Context, job string, day time.Time) error
+}
+
+var _ WatermarkStore = (*services.Store)(nil)
+
+// maxCatchUpDays bound the days processed by 1 run, a long outage is caught up by the next runs.
+const maxCatchUpDays = 366
+
+/*
+ * catchUpDays() run 'fn' for every day after the watermark of the job up to yesterday (the last day that
+ * has an end-of-day balance), in order, and move the watermark after every day. So the days when the
+ * server was down aren't lost. It return the last processed day, the zero time when there is no wallet yet.
+ */
+func catchUpDays(ctx context.Context, store WatermarkStore, job string, now time.Time, fn func(day time.Time) error) (time.Time, error) {
+	last, err := store.JobWatermark(ctx, job)
+	if err == util.ErrNotExist {
+		return time.Time{}, nil
+	}
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	// the watermark is a date, it's the same day in the time zone of 'now'
+	last = time.Date(last.Year(), last.Month(), last.Day(), 0, 0, 0, 0, now.Location())
+	yesterday := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location()).AddDate(0, 0, -1)
+
+	for n := 0; n < maxCatchUpDays && last.Before(yesterday); n++ {
+		if err = ctx.Err(); err != nil {
+			return last, err
+		}
+		day := last.AddDate(0, 0, 1)
+		if err = fn(day); err != nil {
+			return last, err
+		}
+		if err = store.SetJobWatermark(ctx, job, day); err != nil {
+			return last, err
+		}
+		last = day
+	}
+	return last, nil
+}
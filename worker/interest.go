@@ -0,0 +1,62 @@
+package worker
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"simple-bank-system/db/pkg"
+	"simple-bank-system/db/services"
+)
+
+// InterestStore is the part of services.Store that is used by the interest job.
+type InterestStore interface {
+	WatermarkStore
+	AccrueInterest(ctx context.Context, day time.Time) (int64, error)
+	PostInterest(ctx context.Context, periodEnd time.Time) ([]pkg.InterestPosting, error)
+}
+
+var _ InterestStore = (*services.Store)(nil)
+
+/*
+ * InterestJob() accrue interest of every day since the last accrued day up to yesterday (the last day that
+ * has an end-of-day balance), then post the interest of every month that already ended. Both steps are
+ * idempotent, so running the job more than once a day only does the work once.
+ */
+func InterestJob(store InterestStore, interval time.Duration) Job {
+	return Job{
+		Name:     "interest",
+		Interval: interval,
+		Run: func(ctx context.Context) error {
+			return runInterest(ctx, store, time.Now())
+		},
+	}
+}
+
+func runInterest(ctx context.Context, store InterestStore, now time.Time) error {
+	last, err := catchUpDays(ctx, store, services.JobInterestAccrual, now, func(day time.Time) error {
+		n, err := store.AccrueInterest(ctx, day)
+		if err != nil {
+			return err
+		}
+		log.Printf("--- (worker) interest accrued for %d wallets on %s\n", n, day.Format(time.DateOnly))
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// last day of the previous month, it's only posted when all its days are accrued
+	periodEnd := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location()).AddDate(0, 0, -1)
+	if last.Before(periodEnd) {
+		log.Printf("--- (worker) interest isn't posted, days until %s aren't accrued yet\n", periodEnd.Format(time.DateOnly))
+		return nil
+	}
+	postings, err := store.PostInterest(ctx, periodEnd)
+	if err != nil {
+		return err
+	}
+	log.Printf("--- (worker) interest posted for %d wallets until %s\n", len(postings), periodEnd.Format(time.DateOnly))
+
+	return nil
+}
@@ -0,0 +1,37 @@
+package worker
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"simple-bank-system/db/services"
+)
+
+// LoginThrottleStore is the part of services.Store that is used by the login throttle cleanup job.
+type LoginThrottleStore interface {
+	PruneLoginThrottles(ctx context.Context, before time.Time) (int64, error)
+}
+
+var _ LoginThrottleStore = (*services.Store)(nil)
+
+// LoginThrottleCleanupJob() delete the failed logins of the usernames and client IPs that are quiet
+// for 'window' and aren't locked, so the table doesn't keep every IP that ever failed a login.
+func LoginThrottleCleanupJob(store LoginThrottleStore, window, interval time.Duration) Job {
+	return Job{
+		Name:     "login throttle cleanup",
+		Interval: interval,
+		Run: func(ctx context.Context) error {
+			return runLoginThrottleCleanup(ctx, store, window, time.Now())
+		},
+	}
+}
+
+func runLoginThrottleCleanup(ctx context.Context, store LoginThrottleStore, window time.Duration, now time.Time) error {
+	n, err := store.PruneLoginThrottles(ctx, now.Add(-window))
+	if err != nil {
+		return err
+	}
+	log.Printf("--- (worker) login throttle cleanup deleted %d keys\n", n)
+	return nil
+}
@@ -0,0 +1,16 @@
+package worker
+
+import (
+	"time"
+
+	"simple-bank-system/outbox"
+)
+
+// OutboxRelayJob() deliver the events of the outbox, events that failed are retried by the next run.
+func OutboxRelayJob(relay *outbox.Relay, interval time.Duration) Job {
+	return Job{
+		Name:     "outbox relay",
+		Interval: interval,
+		Run:      relay.Run,
+	}
+}
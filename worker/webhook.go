@@ -0,0 +1,16 @@
+package worker
+
+import (
+	"time"
+
+	"simple-bank-system/webhook"
+)
+
+// WebhookJob() send the webhook deliveries that are due, including the retries of failed deliveries.
+func WebhookJob(dispatcher *webhook.Dispatcher, interval time.Duration) Job {
+	return Job{
+		Name:     "webhook",
+		Interval: interval,
+		Run:      dispatcher.Run,
+	}
+}
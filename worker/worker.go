@@ -0,0 +1,51 @@
+/*
+ * Package worker run periodic background jobs (batch processing) next to the API server.
+ * Every job is run once at start, then every 'Interval' until the context is canceled.
+ * Jobs must be safe to re-run, because a restart of the server runs them again.
+ */
+package worker
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+type Job struct {
+	Name     string
+	Interval time.Duration
+	Run      func(ctx context.Context) error
+}
+
+// Start() run every job in its own goroutine and return immediately.
+func Start(ctx context.Context, jobs ...Job) {
+	for _, job := range jobs {
+		go run(ctx, job)
+	}
+}
+
+func run(ctx context.Context, job Job) {
+	if job.Interval <= 0 {
+		log.Printf("--- (worker) %s has no interval, it's run once\n", job.Name)
+		if err := job.Run(ctx); err != nil {
+			log.Printf("--- (worker) %s failed, err: %s\n", job.Name, err)
+		}
+		return
+	}
+
+	ticker := time.NewTicker(job.Interval)
+	defer ticker.Stop()
+
+	for {
+		log.Printf("--- (worker) run %s\n", job.Name)
+		if err := job.Run(ctx); err != nil {
+			log.Printf("--- (worker) %s failed, err: %s\n", job.Name, err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,204 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"simple-bank-system/db/pkg"
+	"simple-bank-system/db/services"
+	"simple-bank-system/util"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStartRunJob(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	var count int32
+	done := make(chan struct{}, 10)
+	Start(ctx, Job{
+		Name:     "test",
+		Interval: 10 * time.Millisecond,
+		Run: func(ctx context.Context) error {
+			atomic.AddInt32(&count, 1)
+			done <- struct{}{}
+			return errors.New("failed job keep running")
+		},
+	})
+
+	for i := 0; i < 3; i++ {
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Fatal("job isn't run by the worker")
+		}
+	}
+	cancel()
+
+	assert.GreaterOrEqual(t, atomic.LoadInt32(&count), int32(3))
+}
+
+// fakeWatermarkStore keep the watermark of every job, a job without watermark has no wallet
+type fakeWatermarkStore struct {
+	watermarks map[string]time.Time
+}
+
+func (s *fakeWatermarkStore) JobWatermark(ctx context.Context, job string) (time.Time, error) {
+	day, ok := s.watermarks[job]
+	if !ok {
+		return time.Time{}, util.ErrNotExist
+	}
+	return day, nil
+}
+
+func (s *fakeWatermarkStore) SetJobWatermark(ctx context.Context, job string, day time.Time) error {
+	s.watermarks[job] = day
+	return nil
+}
+
+type fakeInterestStore struct {
+	fakeWatermarkStore
+	accrued []time.Time
+	posted  []time.Time
+}
+
+func (s *fakeInterestStore) AccrueInterest(ctx context.Context, day time.Time) (int64, error) {
+	s.accrued = append(s.accrued, day)
+	return 1, nil
+}
+
+func (s *fakeInterestStore) PostInterest(ctx context.Context, periodEnd time.Time) ([]pkg.InterestPosting, error) {
+	s.posted = append(s.posted, periodEnd)
+	return nil, nil
+}
+
+func newFakeInterestStore(watermark time.Time) *fakeInterestStore {
+	return &fakeInterestStore{fakeWatermarkStore: fakeWatermarkStore{
+		watermarks: map[string]time.Time{services.JobInterestAccrual: watermark},
+	}}
+}
+
+func TestRunInterest(t *testing.T) {
+	store := newFakeInterestStore(time.Date(2023, 2, 27, 0, 0, 0, 0, time.UTC))
+	now := time.Date(2023, 3, 1, 2, 0, 0, 0, time.UTC)
+
+	err := runInterest(context.Background(), store, now)
+	require.NoError(t, err)
+
+	require.Len(t, store.accrued, 1)
+	assert.Equal(t, "2023-02-28", store.accrued[0].Format(time.DateOnly))
+	require.Len(t, store.posted, 1)
+	assert.Equal(t, "2023-02-28", store.posted[0].Format(time.DateOnly))
+}
+
+func TestRunInterestCatchUp(t *testing.T) {
+	// the server was down from Feb 25, every missed day is accrued before the posting
+	store := newFakeInterestStore(time.Date(2023, 2, 24, 0, 0, 0, 0, time.UTC))
+	now := time.Date(2023, 3, 1, 2, 0, 0, 0, time.UTC)
+
+	err := runInterest(context.Background(), store, now)
+	require.NoError(t, err)
+
+	require.Len(t, store.accrued, 4)
+	assert.Equal(t, "2023-02-25", store.accrued[0].Format(time.DateOnly))
+	assert.Equal(t, "2023-02-28", store.accrued[3].Format(time.DateOnly))
+	assert.Equal(t, "2023-02-28", store.watermarks[services.JobInterestAccrual].Format(time.DateOnly))
+	require.Len(t, store.posted, 1)
+
+	// a second run on the same day has nothing to accrue
+	err = runInterest(context.Background(), store, now.Add(time.Hour))
+	require.NoError(t, err)
+	assert.Len(t, store.accrued, 4)
+}
+
+func TestRunInterestLongOutage(t *testing.T) {
+	// more days than 1 run accrue, the month isn't posted before all its days are accrued
+	store := newFakeInterestStore(time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC))
+	now := time.Date(2023, 3, 1, 2, 0, 0, 0, time.UTC)
+
+	err := runInterest(context.Background(), store, now)
+	require.NoError(t, err)
+	assert.Len(t, store.accrued, maxCatchUpDays)
+	assert.Empty(t, store.posted)
+}
+
+func TestRunInterestNoWallet(t *testing.T) {
+	store := &fakeInterestStore{fakeWatermarkStore: fakeWatermarkStore{watermarks: map[string]time.Time{}}}
+
+	err := runInterest(context.Background(), store, time.Date(2023, 3, 1, 2, 0, 0, 0, time.UTC))
+	require.NoError(t, err)
+	assert.Empty(t, store.accrued)
+	assert.Empty(t, store.posted)
+}
+
+type fakeSnapshotStore struct {
+	fakeWatermarkStore
+	days []time.Time
+}
+
+func (s *fakeSnapshotStore) SnapshotBalances(ctx context.Context, day time.Time) (int64, error) {
+	s.days = append(s.days, day)
+	return 1, nil
+}
+
+func newFakeSnapshotStore(watermark time.Time) *fakeSnapshotStore {
+	return &fakeSnapshotStore{fakeWatermarkStore: fakeWatermarkStore{
+		watermarks: map[string]time.Time{services.JobBalanceSnapshot: watermark},
+	}}
+}
+
+func TestRunBalanceSnapshot(t *testing.T) {
+	store := newFakeSnapshotStore(time.Date(2024, 3, 30, 0, 0, 0, 0, time.UTC))
+	now := time.Date(2024, 4, 1, 0, 30, 0, 0, time.UTC)
+
+	err := runBalanceSnapshot(context.Background(), store, now)
+	require.NoError(t, err)
+
+	require.Len(t, store.days, 1)
+	assert.Equal(t, "2024-03-31", store.days[0].Format(time.DateOnly))
+
+	// re-run the same day does nothing
+	err = runBalanceSnapshot(context.Background(), store, now.Add(time.Hour))
+	require.NoError(t, err)
+	require.Len(t, store.days, 1)
+}
+
+func TestRunBalanceSnapshotCatchUp(t *testing.T) {
+	// the server was down since the snapshot of 2024-03-27
+	store := newFakeSnapshotStore(time.Date(2024, 3, 27, 0, 0, 0, 0, time.UTC))
+	now := time.Date(2024, 4, 1, 0, 30, 0, 0, time.UTC)
+
+	err := runBalanceSnapshot(context.Background(), store, now)
+	require.NoError(t, err)
+
+	var days []string
+	for _, day := range store.days {
+		days = append(days, day.Format(time.DateOnly))
+	}
+	assert.Equal(t, []string{"2024-03-28", "2024-03-29", "2024-03-30", "2024-03-31"}, days)
+	assert.Equal(t, "2024-03-31", store.watermarks[services.JobBalanceSnapshot].Format(time.DateOnly))
+}
+
+type fakeLoginThrottleStore struct {
+	before []time.Time
+}
+
+func (s *fakeLoginThrottleStore) PruneLoginThrottles(ctx context.Context, before time.Time) (int64, error) {
+	s.before = append(s.before, before)
+	return 2, nil
+}
+
+func TestRunLoginThrottleCleanup(t *testing.T) {
+	store := &fakeLoginThrottleStore{}
+	now := time.Date(2024, 4, 1, 0, 30, 0, 0, time.UTC)
+
+	err := runLoginThrottleCleanup(context.Background(), store, 15*time.Minute, now)
+	require.NoError(t, err)
+
+	require.Len(t, store.before, 1)
+	assert.Equal(t, now.Add(-15*time.Minute), store.before[0])
+}